@@ -45,6 +45,8 @@ type buildCommandConfig struct {
 	namespaceFlagPresent bool
 	namespace            string
 	generateOnly         bool
+	// the image is loaded into a local cluster, so it is not pushed
+	localCluster bool
 }
 
 type DeploymentManifest struct {
@@ -83,6 +85,8 @@ func checkBuildOptions(options []string) error {
 
 func newBuildCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	config := &buildCommandConfig{RootCommandConfig: rootConfig}
+	var all bool
+	var parallel int
 	// buildCmd provides the ability run local builds, or setup/delete Tekton builds, for an appsody project
 	var buildCmd = &cobra.Command{
 		Use:   "build",
@@ -98,12 +102,18 @@ Run this command from the root directory of your Appsody project.`,
   Builds the container image, tags it with my-repo/nodejs-express, and pushes it to the container registry the Docker CLI is currently logged into.
 
   appsody build -t my-repo/nodejs-express:0.1 --push-url my-registry-url
-  Builds the container image, tags it with my-repo/nodejs-express, and pushes it to my-registry-url/my-repo/nodejs-express:0.1.`,
+  Builds the container image, tags it with my-repo/nodejs-express, and pushes it to my-registry-url/my-repo/nodejs-express:0.1.
+
+  appsody build --all --parallel 2
+  Builds the container images of all of the projects listed in the "` + WorkspaceFile + `" file, two at a time.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
 			}
 			config.knativeFlagPresent = cmd.Flag("knative").Changed
+			if all {
+				return buildWorkspace(config, parallel)
+			}
 
 			projectDir, err := getProjectDir(config.RootCommandConfig)
 			if err != nil {
@@ -133,6 +143,9 @@ Run this command from the root directory of your Appsody project.`,
 	buildCmd.PersistentFlags().BoolVar(&config.knative, "knative", false, "Deploy as a Knative Service")
 	buildCmd.PersistentFlags().StringVarP(&config.appDeployFile, "file", "f", "app-deploy.yaml", "The file name to use for the deployment configuration.")
 
+	buildCmd.Flags().BoolVar(&all, "all", false, "Build all of the projects in the "+WorkspaceFile+" file of the current or a parent directory. The --tag flag is used as the image repository of the projects.")
+	buildCmd.Flags().IntVar(&parallel, "parallel", defaultWorkspaceParallel, "The number of projects to build at the same time with --all.")
+
 	buildCmd.AddCommand(newBuildDeleteCmd(config))
 	buildCmd.AddCommand(newSetupCmd(config))
 	return buildCmd
@@ -170,7 +183,7 @@ func build(config *buildCommandConfig) error {
 
 	extractDir := filepath.Join(getHome(config.RootCommandConfig), "extract", projectName)
 	dockerfile := filepath.Join(extractDir, "Dockerfile")
-	buildImage := "dev.local/" + projectName //Lowercased
+	buildImage := getBuildImageName(projectName, config.tag, config.pushURL)
 
 	// Regardless of pass or fail, remove the local extracted folder
 	defer os.RemoveAll(extractDir)
@@ -180,15 +193,6 @@ func build(config *buildCommandConfig) error {
 		return extractErr
 	}
 
-	// If a tag is specified, change the buildImage
-	if config.tag != "" {
-		buildImage = config.tag
-	}
-
-	if config.pushURL != "" {
-		buildImage = config.pushURL + "/" + buildImage
-	}
-
 	cmdArgs := []string{"-t", buildImage}
 
 	if buildOptions != "" {
@@ -233,7 +237,9 @@ func build(config *buildCommandConfig) error {
 	if execError != nil {
 		return execError
 	}
-	if config.pushURL != "" || config.push {
+	if config.localCluster && (config.pushURL != "" || config.push) {
+		config.Info.log("Skipping the push of image ", buildImage, ", it is loaded into the local cluster")
+	} else if config.pushURL != "" || config.push {
 		err := ImagePush(config.LoggingConfig, buildImage, config.Buildah, config.Dryrun)
 		if err != nil {
 			return errors.Errorf("Could not push the docker image - exiting. Error: %v", err)
@@ -257,6 +263,67 @@ func build(config *buildCommandConfig) error {
 	return nil
 }
 
+// buildWorkspace builds the projects in the workspace, with at most parallel builds at the same time
+func buildWorkspace(config *buildCommandConfig, parallel int) error {
+	workspace, err := getWorkspace(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	// the projects are prepared one at a time, because this can update shared files in the appsody home directory
+	builds := make(map[string]*buildCommandConfig)
+	prepareErrors := make(map[string]error)
+	for _, project := range workspace.Projects {
+		builds[project.Name], prepareErrors[project.Name] = newWorkspaceBuildConfig(config, workspace.ProjectDir(project))
+	}
+
+	results := RunWorkspaceParallel(workspace.Projects, parallel, func(project WorkspaceProject) error {
+		if prepareErrors[project.Name] != nil {
+			return prepareErrors[project.Name]
+		}
+		return build(builds[project.Name])
+	})
+	summary, err := FormatWorkspaceSummary(results)
+	config.Info.log("\n", summary)
+	return err
+}
+
+// newWorkspaceBuildConfig returns a copy of the build configuration for a project in the workspace
+func newWorkspaceBuildConfig(config *buildCommandConfig, projectDir string) (*buildCommandConfig, error) {
+	rootConfig, err := newWorkspaceProjectConfig(config.RootCommandConfig, projectDir)
+	if err != nil {
+		return nil, err
+	}
+	var project ProjectFile
+	_, _, err = project.EnsureProjectIDAndEntryExists(rootConfig)
+	if err != nil {
+		return nil, err
+	}
+	projectConfig := *config
+	projectConfig.RootCommandConfig = rootConfig
+	projectConfig.appDeployFile = filepath.Join(projectDir, config.appDeployFile)
+	projectConfig.tag, err = workspaceImageTag(rootConfig, config.tag)
+	if err != nil {
+		return nil, err
+	}
+	return &projectConfig, nil
+}
+
+// getBuildImageName returns the name of the image built for the project,
+// which is the tag if one is specified, prefixed by the push URL
+func getBuildImageName(projectName string, tag string, pushURL string) string {
+	buildImage := "dev.local/" + projectName //Lowercased
+
+	// If a tag is specified, change the buildImage
+	if tag != "" {
+		buildImage = tag
+	}
+
+	if pushURL != "" {
+		buildImage = pushURL + "/" + buildImage
+	}
+	return buildImage
+}
+
 func getLabels(config *RootCommandConfig) (map[string]string, error) {
 	var labels = make(map[string]string)
 
@@ -584,6 +651,10 @@ func updateDeploymentConfig(config *buildCommandConfig, imageName string, labels
 	}
 
 	deploymentManifest.Spec["applicationImage"] = imageName
+	if config.localCluster {
+		// the image is only available on the cluster nodes, and can not be pulled from a registry
+		deploymentManifest.Spec["pullPolicy"] = "IfNotPresent"
+	}
 
 	// This only applies to the deploy command flow:
 	// - if the namespace doesn't exist in the manifest, and a namespace flag is not set: we write a "default" namespace
@@ -29,6 +29,15 @@ import (
 
 	cmd "github.com/appsody/appsody/cmd"
 	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const CLEANUP = true
@@ -414,3 +423,61 @@ func ZAndPDevLocal(t *testing.T, sandbox *TestSandbox) {
 		}
 	}
 }
+
+// the kinds known to the fake Kubernetes client, and whether they are namespaced
+var fakeKubeKinds = map[schema.GroupVersionKind]bool{
+	{Version: "v1", Kind: "Namespace"}:                                                    false,
+	{Version: "v1", Kind: "Node"}:                                                         false,
+	{Version: "v1", Kind: "Pod"}:                                                          true,
+	{Version: "v1", Kind: "Service"}:                                                      true,
+	{Version: "v1", Kind: "ConfigMap"}:                                                    true,
+	{Version: "v1", Kind: "Secret"}:                                                       true,
+	{Version: "v1", Kind: "ServiceAccount"}:                                               true,
+	{Group: "apps", Version: "v1", Kind: "Deployment"}:                                    true,
+	{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}:                            true,
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}:              false,
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"}:       false,
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}: false,
+	{Group: "appsody.dev", Version: "v1beta1", Kind: "AppsodyApplication"}:                true,
+	{Group: "route.openshift.io", Version: "v1", Kind: "Route"}:                           true,
+	{Group: "serving.knative.dev", Version: "v1", Kind: "Route"}:                          true,
+	{Group: "serving.knative.dev", Version: "v1", Kind: "Service"}:                        true,
+}
+
+// NewFakeKubeClient returns a Kubernetes client backed by the fake clientsets. Typed objects are
+// served by the typed clientset, and unstructured objects by the dynamic client.
+// Like a cluster without server-side apply, the dynamic client rejects apply patches, so apply
+// falls back to create or update.
+func NewFakeKubeClient(objects ...k8sruntime.Object) *cmd.KubeClient {
+	var typedObjects, unstructuredObjects []k8sruntime.Object
+	for _, object := range objects {
+		if _, ok := object.(*unstructured.Unstructured); ok {
+			unstructuredObjects = append(unstructuredObjects, object)
+		} else {
+			typedObjects = append(typedObjects, object)
+		}
+	}
+	clientset := kubefake.NewSimpleClientset(typedObjects...)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(k8sruntime.NewScheme(), unstructuredObjects...)
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewGenericServerResponse(415, "patch", action.GetResource().GroupResource(), patchAction.GetName(), "", 0, false)
+	})
+
+	var groupVersions []schema.GroupVersion
+	for gvk := range fakeKubeKinds {
+		groupVersions = append(groupVersions, gvk.GroupVersion())
+	}
+	mapper := meta.NewDefaultRESTMapper(groupVersions)
+	for gvk, namespaced := range fakeKubeKinds {
+		scope := meta.RESTScopeRoot
+		if namespaced {
+			scope = meta.RESTScopeNamespace
+		}
+		mapper.Add(gvk, scope)
+	}
+	return cmd.NewKubeClientFromInterfaces(clientset, dynamicClient, mapper, "default")
+}
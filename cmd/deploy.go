@@ -30,6 +30,13 @@ type deployCommandConfig struct {
 	knativeFlagPresent, namespaceFlagPresent                                    bool
 	dockerBuildOptions                                                          string
 	buildahBuildOptions                                                         string
+	canary                                                                      int
+	localCluster                                                                bool
+	portForward                                                                 string
+	localClusterFlagPresent, all                                                bool
+	parallel                                                                    int
+	// the local cluster that the image is loaded into, instead of pushing it to a registry
+	targetCluster *LocalCluster
 }
 
 func findNamespaceRepositoryAndTag(image string) string {
@@ -59,179 +66,58 @@ The command performs the following steps:
 2. Generates a deployment manifest file, "app-deploy.yaml", if one is not present, then applies it to your Kubernetes cluster.
 3. Deploys your image to your Kubernetes cluster via the Appsody operator, or as a Knative service if you specify the "--knative" flag. If an Appsody operator cannot be found, one will be installed on your cluster.
 
+With the "--all" flag, the projects listed in the "` + WorkspaceFile + `" file of the current directory or a parent directory are built at the same time, then deployed in the order of their "depends-on" entries. A summary of each project is shown at the end.
+
+ConfigMaps and Secrets are created from the env files and files listed under "config" and "secrets" in your ".appsody-config.yaml" file, with a hash of their content appended to their names, and are referred to by the deployment manifest. Their keys are passed to your application as environment variables, or mounted as files under "mount-path". List "env" entries, with a "name" and a "key", to set only those environment variables from the keys. A change to their content rolls out your application again, and the previous versions are deleted once the new manifest is applied. Secret values are never written to the deployment manifest.
+
+When the Kubernetes context is a kind, minikube or k3d cluster, or the "--local-cluster" flag is specified, the image is loaded straight into the cluster nodes instead of being pushed to a registry, and the deployment manifest uses the "IfNotPresent" image pull policy.
+
+With the "--canary" flag, a Knative service is updated with a new revision, named after the image tag or git commit, that receives the given percentage of the traffic. The rest of the traffic stays on the current revision until you run "appsody deploy promote" or "appsody deploy abort". The deployment manifest keeps the image that is serving until the canary is promoted.
+
 Run this command from the root directory of your Appsody project.`,
 		Example: `  appsody deploy --namespace my-namespace
   Builds and deploys your project to the "my-namespace" namespace in your local Kubernetes cluster.
   
   appsody deploy -t my-repo/nodejs-express --push-url external-registry-url --pull-url internal-registry-url
-  Builds and tags the image as "my-repo/nodejs-express", pushes the image to "external-registry-url/my-repo/nodejs-express", and creates a deployment manifest that tells the Kubernetes cluster to pull the image from "internal-registry-url/my-repo/nodejs-express".`,
+  Builds and tags the image as "my-repo/nodejs-express", pushes the image to "external-registry-url/my-repo/nodejs-express", and creates a deployment manifest that tells the Kubernetes cluster to pull the image from "internal-registry-url/my-repo/nodejs-express".
+
+  appsody deploy --local-cluster
+  Builds your project and loads the image into the nodes of the local cluster of the current Kubernetes context, without pushing it to a registry.
+
+  appsody deploy --port-forward
+  Deploys your project, then forwards the service port of the application to the same local port until you press Ctrl-C.
+
+  appsody deploy --all -t my-repo --push
+  Builds all of the projects in the workspace, tags and pushes their images as "my-repo/<project-name>", and deploys them in dependency order.
+
+  appsody deploy --knative --canary 10 -t my-repo/nodejs-express:v2 --push
+  Deploys the image as a new revision of the Knative service, named after the "v2" tag, and routes 10% of the traffic to it.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			if len(args) > 0 {
 				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
 			}
-			var project ProjectFile
-			_, _, err := project.EnsureProjectIDAndEntryExists(config.RootCommandConfig)
-			if err != nil {
-				return err
-			}
-			config.Debug.Log("Default stack registry set to: ", &config.RootCommandConfig.StackRegistry)
-			projectDir, err := getProjectDir(config.RootCommandConfig)
-			if err != nil {
-				return err
-			}
-			config.Debug.log("Project config file set to: ", filepath.Join(projectDir, ConfigFile))
 			config.knativeFlagPresent = cmd.Flag("knative").Changed
 			config.namespaceFlagPresent = cmd.Flag("namespace").Changed
+			config.localClusterFlagPresent = cmd.Flag("local-cluster").Changed
 
-			dryrun := config.Dryrun
-			namespace := config.namespace
-
-			configFile := filepath.Join(projectDir, config.appDeployFile)
-
-			exists, err := Exists(configFile)
-			if err != nil {
-				return err
-			}
-
-			if !exists && config.nobuild {
-				return errors.Errorf("--no-build flag was used, but deployment manifest %s was not found. Please remove the --no-build, or generate a deployment manifest first by running \"appsody build\"", configFile)
-			}
-
-			if exists {
-				config.Info.Logf("Found deployment manifest %s", configFile)
-
-				deploymentManifest, err := getDeploymentManifest(configFile)
-				if err != nil {
-					return err
-				}
-
-				manifestNamespace := deploymentManifest.Namespace
-				if manifestNamespace != "" {
-					if namespace != "" && manifestNamespace != namespace {
-						config.Info.Logf("Overriding namespace %s in the deployment manifest to: %s", manifestNamespace, namespace)
-						deploymentManifest.Namespace = namespace
-						err = writeDeploymentManifest(deploymentManifest, configFile)
-						if err != nil {
-							return err
-						}
-					} else {
-						namespace = manifestNamespace
-					}
-				}
-			}
-
-			if namespace == "" {
-				namespace = "default"
+			if cmd.Flag("canary").Changed && (config.canary < 1 || config.canary > 99) {
+				return errors.Errorf("The --canary percentage must be between 1 and 99, but was %d", config.canary)
 			}
 
-			config.Info.Logf("Using namespace %s for deployment", namespace)
-
-			if config.generate {
-				buildConfig := &buildCommandConfig{RootCommandConfig: config.RootCommandConfig}
-				buildConfig.Verbose = config.Verbose
-				buildConfig.pushURL = config.pushURL
-				buildConfig.push = config.push
-				buildConfig.dockerBuildOptions = config.dockerBuildOptions
-				buildConfig.buildahBuildOptions = config.buildahBuildOptions
-
-				buildConfig.tag = config.tag
-				buildConfig.pullURL = config.pullURL
-				buildConfig.knative = config.knative
-				buildConfig.knativeFlagPresent = config.knativeFlagPresent
-				buildConfig.appDeployFile = configFile
-				buildConfig.namespace = namespace
-				buildConfig.namespaceFlagPresent = config.namespaceFlagPresent
-				buildConfig.generateOnly = config.generate
-
-				buildErr := build(buildConfig)
-				if buildErr != nil {
-					return buildErr
-				}
-				return nil
-			}
-
-			if !config.nobuild {
-				config.Info.Log("Building the production image")
-
-				buildConfig := &buildCommandConfig{RootCommandConfig: config.RootCommandConfig}
-				buildConfig.Verbose = config.Verbose
-				buildConfig.pushURL = config.pushURL
-				buildConfig.push = config.push
-				buildConfig.dockerBuildOptions = config.dockerBuildOptions
-				buildConfig.buildahBuildOptions = config.buildahBuildOptions
-
-				buildConfig.tag = config.tag
-				buildConfig.pullURL = config.pullURL
-				buildConfig.knative = config.knative
-				buildConfig.knativeFlagPresent = config.knativeFlagPresent
-				buildConfig.appDeployFile = configFile
-				buildConfig.namespace = namespace
-				buildConfig.namespaceFlagPresent = config.namespaceFlagPresent
-
-				buildErr := build(buildConfig)
-				if buildErr != nil {
-					return buildErr
-				}
+			if config.all {
+				return deployWorkspace(config)
 			}
 
-			deploymentManifest, err := getDeploymentManifest(configFile)
+			projectDir, configFile, namespace, err := prepareDeploy(config)
 			if err != nil {
 				return err
 			}
-
-			if !config.noOperatorInstall && deploymentManifest.Kind == "AppsodyApplication" {
-				// Check for the Appsody Operator
-				operatorExists, existingNamespace, operatorExistsErr := operatorExistsWithWatchspace(config.LoggingConfig, namespace, config.Dryrun, config.noOperatorCheck)
-				if operatorExistsErr != nil {
-					return operatorExistsErr
-				}
-
-				//kargs := []string{"service/appsody-operator"}
-				//_, err := KubeGet(kargs)
-				// Performing the kubectl apply
-				if !operatorExists {
-					config.Debug.logf("Failed to find Appsody operator that watches namespace %s. Attempting to install...", namespace)
-					operatorConfig := &operatorCommandConfig{config.RootCommandConfig, namespace}
-					operatorInstallConfig := &operatorInstallCommandConfig{operatorCommandConfig: operatorConfig}
-					//	operatorInstallConfig.RootCommandConfig = operatorConfig.RootCommandConfig
-					err := operatorInstall(operatorInstallConfig)
-					if err != nil {
-						return errors.Errorf("Failed to install an Appsody operator in namespace %s watching namespace %s. Error was: %v", namespace, namespace, err)
-					}
-				} else {
-					config.Debug.logf("Operator exists in %s, watching %s ", existingNamespace, namespace)
-				}
-			} else {
-				config.Info.logf("The deployment manifest is of kind: %s, you need to install a matching operator.", deploymentManifest.Kind)
-			}
-
-			// Performing the kubectl apply
-			err = KubeApply(config.LoggingConfig, configFile, namespace, dryrun)
-			if err != nil {
-				return errors.Errorf("Failed to deploy to your Kubernetes cluster: %v", err)
-			}
-
-			// Ensure hostname and IP config is set up for deployment
-			time.Sleep(1 * time.Second)
-			config.Info.log("Appsody Deployment name is: ", deploymentManifest.Name)
-			out, err := KubeGetDeploymentURL(config.LoggingConfig, deploymentManifest.Name, deploymentManifest.Spec["service"].(map[string]interface{}), namespace, dryrun)
-			// Performing the kubectl apply
-			if err != nil {
-				return errors.Errorf("Failed to find deployed service IP and Port: %s", err)
-			}
-			if !dryrun {
-				rootConfig.Info.log("Deployed project running at ", out)
-			} else {
-				rootConfig.Info.log("Dry run complete")
-			}
-
-			depErr := GetDeprecated(config.RootCommandConfig)
-			if depErr != nil {
-				return depErr
+			err = buildForDeploy(config, configFile, namespace)
+			if err != nil || config.generate {
+				return err
 			}
-
-			return nil
+			return applyDeploy(config, projectDir, configFile, namespace)
 		},
 	}
 
@@ -247,11 +133,339 @@ Run this command from the root directory of your Appsody project.`,
 	deployCmd.PersistentFlags().StringVar(&config.buildahBuildOptions, "buildah-options", "", "Specify the buildah build options to use. Value must be in \"\".")
 	deployCmd.PersistentFlags().BoolVar(&config.push, "push", false, "Push this image to an external Docker registry. Assumes that you have previously successfully done docker login")
 	deployCmd.PersistentFlags().BoolVar(&config.knative, "knative", false, "Deploy as a Knative Service")
+	deployCmd.Flags().IntVar(&config.canary, "canary", 0, "Deploy a new revision of a Knative service that receives this percentage of the traffic, keeping the rest on the current revision.")
+	deployCmd.PersistentFlags().BoolVar(&config.localCluster, "local-cluster", false, "Load the image into a local kind, minikube, k3d or containerd cluster instead of pushing it to a registry. Detected from the Kubernetes context if not specified. A containerd cluster must run on this machine.")
+	deployCmd.Flags().StringVar(&config.portForward, "port-forward", "", "After deploying, forward a local port to a ready pod of the application until Ctrl-C is pressed. Specify the ports as local:remote, or omit them to forward the service port.")
+	deployCmd.Flags().Lookup("port-forward").NoOptDefVal = portForwardDefault
+	deployCmd.Flags().BoolVar(&config.all, "all", false, "Build and deploy all of the projects in the "+WorkspaceFile+" file of the current or a parent directory. The --tag flag is used as the image repository of the projects.")
+	deployCmd.Flags().IntVar(&config.parallel, "parallel", defaultWorkspaceParallel, "The number of projects to build at the same time with --all.")
 	deployCmd.PersistentFlags().StringVar(&config.pushURL, "push-url", "", "Remote repository to push image to.  This will also trigger a push if the --push flag is not specified.")
 	deployCmd.PersistentFlags().StringVar(&config.pullURL, "pull-url", "", "Remote repository to pull image from.")
 	deployCmd.PersistentFlags().BoolVar(&config.noOperatorCheck, "no-operator-check", false, "Do not check whether existing operators are already watching the namespace")
 	deployCmd.PersistentFlags().BoolVar(&config.noOperatorInstall, "no-operator-install", false, "Deploy your application without installing the Appsody operator")
+	addKubeFlags(deployCmd, rootConfig)
 	deployCmd.AddCommand(newDeleteDeploymentCmd(config))
+	deployCmd.AddCommand(newDeployDiffCmd(config))
+	deployCmd.AddCommand(newDeployHistoryCmd(config))
+	deployCmd.AddCommand(newDeployRollbackCmd(config))
+	deployCmd.AddCommand(newDeployPromoteCmd(config))
+	deployCmd.AddCommand(newDeployAbortCmd(config))
+	deployCmd.AddCommand(newDeployStatusCmd(config))
+	deployCmd.AddCommand(newDeployPortForwardCmd(config))
 
 	return deployCmd
 }
+
+// prepareDeploy finds the project, its deployment manifest and the namespace to deploy to
+func prepareDeploy(config *deployCommandConfig) (string, string, string, error) {
+	var project ProjectFile
+	_, _, err := project.EnsureProjectIDAndEntryExists(config.RootCommandConfig)
+	if err != nil {
+		return "", "", "", err
+	}
+	config.Debug.Log("Default stack registry set to: ", &config.RootCommandConfig.StackRegistry)
+	projectDir, err := getProjectDir(config.RootCommandConfig)
+	if err != nil {
+		return "", "", "", err
+	}
+	config.Debug.log("Project config file set to: ", filepath.Join(projectDir, ConfigFile))
+	configFile := filepath.Join(projectDir, config.appDeployFile)
+
+	namespace, err := resolveDeploymentNamespace(config, configFile, true)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	config.targetCluster, err = getLocalCluster(config, config.localClusterFlagPresent)
+	if err != nil {
+		return "", "", "", err
+	}
+	return projectDir, configFile, namespace, nil
+}
+
+// buildForDeploy builds the production image and generates the deployment manifest,
+// or only generates the manifest with --generate-only
+func buildForDeploy(config *deployCommandConfig, configFile string, namespace string) error {
+	if config.generate {
+		buildConfig := newDeployBuildConfig(config, configFile, namespace)
+		buildConfig.generateOnly = config.generate
+		return build(buildConfig)
+	}
+	if config.nobuild {
+		return nil
+	}
+
+	config.Info.Log("Building the production image")
+	buildErr := build(newDeployBuildConfig(config, configFile, namespace))
+	if buildErr != nil {
+		return buildErr
+	}
+
+	if config.targetCluster != nil {
+		projectName, err := getProjectName(config.RootCommandConfig)
+		if err != nil {
+			return err
+		}
+		return loadLocalClusterImage(config.RootCommandConfig, config.targetCluster, getBuildImageName(projectName, config.tag, config.pushURL))
+	}
+	return nil
+}
+
+// applyDeploy deploys the manifest to the cluster, installing the Appsody operator if it is needed
+func applyDeploy(config *deployCommandConfig, projectDir string, configFile string, namespace string) error {
+	dryrun := config.Dryrun
+	deploymentManifest, err := getDeploymentManifest(configFile)
+	if err != nil {
+		return err
+	}
+
+	portForwardLocal, portForwardRemote := 0, 0
+	if config.portForward != "" {
+		portForwardLocal, portForwardRemote, err = ParsePortForwardSpec(config.portForward, getServicePort(deploymentManifest))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !config.noOperatorInstall && deploymentManifest.Kind == "AppsodyApplication" {
+		// Check for the Appsody Operator
+		operatorExists, existingNamespace, operatorExistsErr := operatorExistsWithWatchspace(config.RootCommandConfig, namespace, config.Dryrun, config.noOperatorCheck)
+		if operatorExistsErr != nil {
+			return operatorExistsErr
+		}
+
+		// Performing the kubectl apply
+		if !operatorExists {
+			config.Debug.logf("Failed to find Appsody operator that watches namespace %s. Attempting to install...", namespace)
+			operatorConfig := &operatorCommandConfig{config.RootCommandConfig, namespace}
+			operatorInstallConfig := &operatorInstallCommandConfig{operatorCommandConfig: operatorConfig}
+			//	operatorInstallConfig.RootCommandConfig = operatorConfig.RootCommandConfig
+			err := operatorInstall(operatorInstallConfig)
+			if err != nil {
+				return errors.Errorf("Failed to install an Appsody operator in namespace %s watching namespace %s. Error was: %v", namespace, namespace, err)
+			}
+		} else {
+			config.Debug.logf("Operator exists in %s, watching %s ", existingNamespace, namespace)
+		}
+	} else {
+		config.Info.logf("The deployment manifest is of kind: %s, you need to install a matching operator.", deploymentManifest.Kind)
+	}
+
+	configObjects, err := deployConfig(config, projectDir, configFile, &deploymentManifest, namespace)
+	if err != nil {
+		return err
+	}
+
+	var canaryImage string
+	if config.canary > 0 {
+		canaryImage, err = keepDeployedImage(config, &deploymentManifest, configFile, namespace)
+	} else {
+		err = resetKnativeTraffic(config, deploymentManifest, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Performing the kubectl apply
+	err = KubeApply(config.RootCommandConfig, configFile, namespace, dryrun)
+	if err != nil {
+		return errors.Errorf("Failed to deploy to your Kubernetes cluster: %v", err)
+	}
+
+	pruneDeployConfig(config, deploymentManifest.Name, configObjects, namespace)
+
+	if config.canary > 0 {
+		err = deployKnativeCanary(config, deploymentManifest, namespace, canaryImage)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !dryrun {
+		var imageCandidates []string
+		// the manifest of a canary keeps the image that is serving, not the image that was built
+		if !config.nobuild && config.canary == 0 {
+			projectName, err := getProjectName(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			imageCandidates = append(imageCandidates, getBuildImageName(projectName, config.tag, config.pushURL))
+		}
+		err = recordDeployment(config.RootCommandConfig, projectDir, configFile, namespace, imageCandidates)
+		if err != nil {
+			config.Warning.log("Could not record the deployment in the deployment history: ", err)
+		}
+	}
+
+	// Ensure hostname and IP config is set up for deployment
+	time.Sleep(1 * time.Second)
+	config.Info.log("Appsody Deployment name is: ", deploymentManifest.Name)
+	out, err := KubeGetDeploymentURL(config.RootCommandConfig, deploymentManifest.Name, deploymentManifest.Spec["service"].(map[string]interface{}), namespace, dryrun)
+	// Performing the kubectl apply
+	if err != nil {
+		if config.portForward == "" {
+			return errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+		}
+		config.Warning.log("Could not find the deployed service IP and Port, using a port forward instead")
+	} else if !dryrun {
+		config.Info.log("Deployed project running at ", out)
+	} else {
+		config.Info.log("Dry run complete")
+	}
+
+	depErr := GetDeprecated(config.RootCommandConfig)
+	if depErr != nil {
+		return depErr
+	}
+
+	if config.portForward != "" {
+		return deployPortForward(config.RootCommandConfig, deploymentManifest.Name, namespace, portForwardLocal, portForwardRemote)
+	}
+	return nil
+}
+
+// deployWorkspace builds the projects in the workspace in parallel, then deploys them in dependency order
+func deployWorkspace(config *deployCommandConfig) error {
+	if config.portForward != "" || config.canary > 0 {
+		return errors.New("The --port-forward and --canary flags can not be used with --all")
+	}
+	workspace, err := getWorkspace(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	order, err := workspace.DeployOrder()
+	if err != nil {
+		return err
+	}
+
+	type projectDeploy struct {
+		config                            *deployCommandConfig
+		projectDir, configFile, namespace string
+		buildTime                         time.Duration
+		err                               error
+	}
+	// the projects are prepared one at a time, because this can update shared files in the appsody home directory
+	deploys := make(map[string]*projectDeploy)
+	for _, project := range order {
+		deploy := &projectDeploy{}
+		deploys[project.Name] = deploy
+		deploy.config, deploy.err = newWorkspaceDeployConfig(config, workspace.ProjectDir(project))
+		if deploy.err == nil {
+			deploy.projectDir, deploy.configFile, deploy.namespace, deploy.err = prepareDeploy(deploy.config)
+		}
+	}
+
+	results := RunWorkspaceParallel(order, config.parallel, func(project WorkspaceProject) error {
+		deploy := deploys[project.Name]
+		if deploy.err != nil {
+			return deploy.err
+		}
+		return buildForDeploy(deploy.config, deploy.configFile, deploy.namespace)
+	})
+	if !config.generate {
+		for _, result := range results {
+			deploys[result.Project.Name].err = result.Err
+			deploys[result.Project.Name].buildTime = result.Duration
+		}
+		results = RunWorkspaceOrdered(order, func(project WorkspaceProject) error {
+			deploy := deploys[project.Name]
+			if deploy.err != nil {
+				return deploy.err
+			}
+			config.Info.log("Deploying project ", project.Name)
+			return applyDeploy(deploy.config, deploy.projectDir, deploy.configFile, deploy.namespace)
+		})
+		for i := range results {
+			results[i].Duration += deploys[results[i].Project.Name].buildTime
+		}
+	}
+
+	summary, err := FormatWorkspaceSummary(results)
+	config.Info.log("\n", summary)
+	return err
+}
+
+// newWorkspaceDeployConfig returns a copy of the deploy configuration for a project in the workspace
+func newWorkspaceDeployConfig(config *deployCommandConfig, projectDir string) (*deployCommandConfig, error) {
+	rootConfig, err := newWorkspaceProjectConfig(config.RootCommandConfig, projectDir)
+	if err != nil {
+		return nil, err
+	}
+	projectConfig := *config
+	projectConfig.RootCommandConfig = rootConfig
+	projectConfig.targetCluster = nil
+	projectConfig.tag, err = workspaceImageTag(rootConfig, config.tag)
+	if err != nil {
+		return nil, err
+	}
+	return &projectConfig, nil
+}
+
+// resolveDeploymentNamespace works out the namespace to deploy to, using the --namespace flag,
+// the namespace in the deployment manifest (if one exists) and falling back to "default".
+// If the flag overrides the manifest namespace and writeOverride is set, the manifest is updated.
+func resolveDeploymentNamespace(config *deployCommandConfig, configFile string, writeOverride bool) (string, error) {
+	namespace := config.namespace
+
+	exists, err := Exists(configFile)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists && config.nobuild {
+		return "", errors.Errorf("--no-build flag was used, but deployment manifest %s was not found. Please remove the --no-build, or generate a deployment manifest first by running \"appsody build\"", configFile)
+	}
+
+	if exists {
+		config.Info.Logf("Found deployment manifest %s", configFile)
+
+		deploymentManifest, err := getDeploymentManifest(configFile)
+		if err != nil {
+			return "", err
+		}
+
+		manifestNamespace := deploymentManifest.Namespace
+		if manifestNamespace != "" {
+			if namespace != "" && manifestNamespace != namespace {
+				config.Info.Logf("Overriding namespace %s in the deployment manifest to: %s", manifestNamespace, namespace)
+				if writeOverride {
+					deploymentManifest.Namespace = namespace
+					err = writeDeploymentManifest(deploymentManifest, configFile)
+					if err != nil {
+						return "", err
+					}
+				}
+			} else {
+				namespace = manifestNamespace
+			}
+		}
+	}
+
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	config.Info.Logf("Using namespace %s for deployment", namespace)
+	return namespace, nil
+}
+
+// newDeployBuildConfig creates the build configuration used by deploy to build the image
+// and generate or update the deployment manifest
+func newDeployBuildConfig(config *deployCommandConfig, configFile string, namespace string) *buildCommandConfig {
+	buildConfig := &buildCommandConfig{RootCommandConfig: config.RootCommandConfig}
+	buildConfig.Verbose = config.Verbose
+	buildConfig.pushURL = config.pushURL
+	buildConfig.push = config.push
+	buildConfig.dockerBuildOptions = config.dockerBuildOptions
+	buildConfig.buildahBuildOptions = config.buildahBuildOptions
+
+	buildConfig.tag = config.tag
+	buildConfig.pullURL = config.pullURL
+	buildConfig.knative = config.knative
+	buildConfig.knativeFlagPresent = config.knativeFlagPresent
+	buildConfig.appDeployFile = configFile
+	buildConfig.namespace = namespace
+	buildConfig.namespaceFlagPresent = config.namespaceFlagPresent
+	buildConfig.localCluster = config.targetCluster != nil
+	return buildConfig
+}
@@ -0,0 +1,558 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var knativeServiceKind = schema.GroupKind{Group: "serving.knative.dev", Kind: "Service"}
+
+// the traffic tags given to the revisions of a canary deployment
+const (
+	knativeCurrentTag   = "current"
+	knativeCandidateTag = "candidate"
+)
+
+// the maximum length of a Knative revision name, which must be a DNS label
+const knativeRevisionNameLimit = 63
+
+var invalidRevisionChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// KnativeTrafficTarget is an entry in the traffic block of a Knative service
+type KnativeTrafficTarget struct {
+	RevisionName   string `json:"revisionName,omitempty"`
+	Tag            string `json:"tag,omitempty"`
+	Percent        int64  `json:"percent"`
+	LatestRevision bool   `json:"latestRevision,omitempty"`
+}
+
+// KnativeRevisionName returns the name of the revision for a service, made from the image tag or git commit.
+// Revision names must start with the name of the service.
+func KnativeRevisionName(service string, suffix string) string {
+	suffix = strings.Trim(invalidRevisionChars.ReplaceAllString(strings.ToLower(suffix), "-"), "-")
+	name := service + "-" + suffix
+	if len(name) > knativeRevisionNameLimit {
+		name = name[:knativeRevisionNameLimit]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+// knativeRevisionSuffix returns the tag of the image, or the short git commit if the image is not tagged or uses "latest"
+func knativeRevisionSuffix(config *RootCommandConfig, image string) (string, error) {
+	if !strings.Contains(image, "@") {
+		if tagIndex := strings.LastIndex(image, ":"); tagIndex > strings.LastIndex(image, "/") {
+			tag := image[tagIndex+1:]
+			if tag != "latest" {
+				return tag, nil
+			}
+		}
+	}
+	gitInfo, err := GetGitInfo(config)
+	if err != nil {
+		config.Debug.log("Could not get the git commit for the revision name: ", err)
+	}
+	if len(gitInfo.Commit.SHA) >= 7 {
+		return gitInfo.Commit.SHA[:7], nil
+	}
+	return "", errors.Errorf("Could not derive a revision name from image %s. Tag the image with the --tag flag, or commit your project to git", image)
+}
+
+// KnativeTraffic returns the traffic split of a Knative service, as reported by its status if it has one
+func KnativeTraffic(service *unstructured.Unstructured) []KnativeTrafficTarget {
+	traffic, found, _ := unstructured.NestedSlice(service.Object, "status", "traffic")
+	if !found {
+		traffic, _, _ = unstructured.NestedSlice(service.Object, "spec", "traffic")
+	}
+	return toKnativeTrafficTargets(traffic)
+}
+
+// knativeSpecTraffic returns the traffic split that has been requested for a Knative service
+func knativeSpecTraffic(service *unstructured.Unstructured) []KnativeTrafficTarget {
+	traffic, _, _ := unstructured.NestedSlice(service.Object, "spec", "traffic")
+	return toKnativeTrafficTargets(traffic)
+}
+
+func toKnativeTrafficTargets(traffic []interface{}) []KnativeTrafficTarget {
+	var targets []KnativeTrafficTarget
+	for _, item := range traffic {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var target KnativeTrafficTarget
+		target.RevisionName, _ = entry["revisionName"].(string)
+		target.Tag, _ = entry["tag"].(string)
+		target.LatestRevision, _ = entry["latestRevision"].(bool)
+		switch percent := entry["percent"].(type) {
+		case int64:
+			target.Percent = percent
+		case float64:
+			target.Percent = int64(percent)
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func setKnativeTraffic(service *unstructured.Unstructured, targets []KnativeTrafficTarget) error {
+	var traffic []interface{}
+	for _, target := range targets {
+		entry := map[string]interface{}{"percent": target.Percent}
+		if target.RevisionName != "" {
+			entry["revisionName"] = target.RevisionName
+		}
+		if target.Tag != "" {
+			entry["tag"] = target.Tag
+		}
+		if target.LatestRevision {
+			entry["latestRevision"] = true
+		}
+		traffic = append(traffic, entry)
+	}
+	return unstructured.SetNestedSlice(service.Object, traffic, "spec", "traffic")
+}
+
+func findTrafficTarget(targets []KnativeTrafficTarget, tag string) *KnativeTrafficTarget {
+	for i := range targets {
+		if targets[i].Tag == tag {
+			return &targets[i]
+		}
+	}
+	return nil
+}
+
+// knativeServingRevision returns the revision that is receiving all of the traffic of a service
+func knativeServingRevision(service *unstructured.Unstructured) (string, error) {
+	for _, target := range knativeSpecTraffic(service) {
+		if target.Percent == 100 && target.RevisionName != "" {
+			return target.RevisionName, nil
+		}
+	}
+	revision, _, _ := unstructured.NestedString(service.Object, "status", "latestReadyRevisionName")
+	if revision == "" {
+		return "", errors.Errorf("Knative service %s does not have a ready revision", service.GetName())
+	}
+	return revision, nil
+}
+
+// knativeContainer returns the container of the template of a Knative service
+func knativeContainer(service *unstructured.Unstructured) ([]interface{}, map[string]interface{}, error) {
+	containers, _, err := unstructured.NestedSlice(service.Object, "spec", "template", "spec", "containers")
+	if err != nil || len(containers) == 0 {
+		return nil, nil, errors.Errorf("Knative service %s does not have a container", service.GetName())
+	}
+	container, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.Errorf("Knative service %s does not have a valid container", service.GetName())
+	}
+	return containers, container, nil
+}
+
+// setKnativeTemplate sets the image of the template of a Knative service, and the name of the revision that it creates.
+// An empty revision name lets Knative generate one.
+func setKnativeTemplate(service *unstructured.Unstructured, revision string, image string) error {
+	containers, container, err := knativeContainer(service)
+	if err != nil {
+		return err
+	}
+	container["image"] = image
+	err = unstructured.SetNestedSlice(service.Object, containers, "spec", "template", "spec", "containers")
+	if err != nil {
+		return err
+	}
+	if revision == "" {
+		unstructured.RemoveNestedField(service.Object, "spec", "template", "metadata", "name")
+		return nil
+	}
+	return unstructured.SetNestedField(service.Object, revision, "spec", "template", "metadata", "name")
+}
+
+// SetKnativeCanary updates a Knative service to create the candidate revision with the image,
+// and splits the traffic between the current revision and the candidate.
+func SetKnativeCanary(service *unstructured.Unstructured, current string, candidate string, image string, percent int) error {
+	err := setKnativeTemplate(service, candidate, image)
+	if err != nil {
+		return err
+	}
+	return setKnativeTraffic(service, []KnativeTrafficTarget{
+		{RevisionName: current, Tag: knativeCurrentTag, Percent: int64(100 - percent)},
+		{RevisionName: candidate, Tag: knativeCandidateTag, Percent: int64(percent)},
+	})
+}
+
+// GetKnativeService returns the Knative service with the name
+func (c *KubeClient) GetKnativeService(name string, namespace string) (*unstructured.Unstructured, error) {
+	return c.GetByKind(knativeServiceKind, name, namespace)
+}
+
+// Update replaces an object that has been read from the cluster
+func (c *KubeClient) Update(object *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	resource, err := c.Resource(object.GroupVersionKind(), object.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	return resource.Update(object, metav1.UpdateOptions{FieldManager: kubeFieldManager})
+}
+
+// StartKnativeCanary creates a revision of a Knative service for the image, and routes the percentage of the traffic to it.
+// The rest of the traffic stays on the revision that is currently serving. It returns the current and candidate revisions.
+func (c *KubeClient) StartKnativeCanary(name string, namespace string, revisionSuffix string, image string, percent int) (string, string, error) {
+	service, err := c.GetKnativeService(name, namespace)
+	if apierrors.IsNotFound(err) {
+		return "", "", errors.Errorf("Knative service %s was not found in namespace %s. A canary can only be deployed over an existing deployment, deploy without --canary first", name, namespace)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if candidate := findTrafficTarget(knativeSpecTraffic(service), knativeCandidateTag); candidate != nil {
+		return "", "", errors.Errorf("A canary of revision %s is already in progress. Use 'appsody deploy promote' or 'appsody deploy abort' first", candidate.RevisionName)
+	}
+	current, err := knativeServingRevision(service)
+	if err != nil {
+		return "", "", err
+	}
+	candidate := KnativeRevisionName(name, revisionSuffix)
+	if candidate == current {
+		return "", "", errors.Errorf("Revision %s is already serving. Use a different image tag for the canary", candidate)
+	}
+	err = SetKnativeCanary(service, current, candidate, image, percent)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = c.Update(service)
+	if err != nil {
+		return "", "", err
+	}
+	return current, candidate, nil
+}
+
+// KnativeServiceImage returns the image of a Knative service that a canary can be started from.
+// This is the image of the AppsodyApplication, which is only changed to the canary image when the canary is promoted.
+func (c *KubeClient) KnativeServiceImage(name string, namespace string) (string, error) {
+	service, err := c.GetKnativeService(name, namespace)
+	if apierrors.IsNotFound(err) {
+		return "", errors.Errorf("Knative service %s was not found in namespace %s. A canary can only be deployed over an existing deployment, deploy without --canary first", name, namespace)
+	}
+	if err != nil {
+		return "", err
+	}
+	if candidate := findTrafficTarget(knativeSpecTraffic(service), knativeCandidateTag); candidate != nil {
+		return "", errors.Errorf("A canary of revision %s is already in progress. Use 'appsody deploy promote' or 'appsody deploy abort' first", candidate.RevisionName)
+	}
+	_, container, err := knativeContainer(service)
+	if err != nil {
+		return "", err
+	}
+	image, _ := container["image"].(string)
+	return image, nil
+}
+
+// PromoteKnativeCanary routes all of the traffic of a Knative service to the candidate revision,
+// and returns its name and image
+func (c *KubeClient) PromoteKnativeCanary(name string, namespace string) (string, string, error) {
+	service, keep, err := c.endKnativeCanary(name, namespace, knativeCandidateTag)
+	if err != nil {
+		return "", "", err
+	}
+	_, container, err := knativeContainer(service)
+	if err != nil {
+		return "", "", err
+	}
+	image, _ := container["image"].(string)
+	_, err = c.Update(service)
+	if err != nil {
+		return "", "", err
+	}
+	return keep, image, nil
+}
+
+// AbortKnativeCanary routes all of the traffic of a Knative service back to the current revision, and returns its name.
+// The template of the service is set back to the image of the AppsodyApplication, so that it matches what the operator deploys.
+func (c *KubeClient) AbortKnativeCanary(name string, namespace string, image string) (string, error) {
+	service, keep, err := c.endKnativeCanary(name, namespace, knativeCurrentTag)
+	if err != nil {
+		return "", err
+	}
+	err = setKnativeTemplate(service, "", image)
+	if err != nil {
+		return "", err
+	}
+	_, err = c.Update(service)
+	if err != nil {
+		return "", err
+	}
+	return keep, nil
+}
+
+// endKnativeCanary routes all of the traffic of a Knative service to the revision with the tag,
+// and returns the service to be updated with the name of the revision
+func (c *KubeClient) endKnativeCanary(name string, namespace string, keepTag string) (*unstructured.Unstructured, string, error) {
+	service, err := c.GetKnativeService(name, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	targets := knativeSpecTraffic(service)
+	if findTrafficTarget(targets, knativeCandidateTag) == nil {
+		return nil, "", errors.Errorf("There is no canary in progress for Knative service %s", name)
+	}
+	keep := findTrafficTarget(targets, keepTag)
+	if keep == nil || keep.RevisionName == "" {
+		return nil, "", errors.Errorf("Knative service %s does not have a %s revision", name, keepTag)
+	}
+	err = setKnativeTraffic(service, []KnativeTrafficTarget{{RevisionName: keep.RevisionName, Percent: 100}})
+	if err != nil {
+		return nil, "", err
+	}
+	return service, keep.RevisionName, nil
+}
+
+// ResetKnativeTraffic routes all of the traffic of a Knative service to its latest revision, if the traffic has been split or pinned.
+// It returns false if the service does not exist or already routes to the latest revision.
+func (c *KubeClient) ResetKnativeTraffic(name string, namespace string) (bool, error) {
+	service, err := c.GetKnativeService(name, namespace)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	targets := knativeSpecTraffic(service)
+	if len(targets) == 0 || (len(targets) == 1 && targets[0].LatestRevision && targets[0].Tag == "") {
+		return false, nil
+	}
+	err = setKnativeTraffic(service, []KnativeTrafficTarget{{LatestRevision: true, Percent: 100}})
+	if err != nil {
+		return false, err
+	}
+	_, err = c.Update(service)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isKnativeManifest returns true if the deployment manifest creates a Knative service
+func isKnativeManifest(deploymentManifest DeploymentManifest) bool {
+	createKnativeService, _ := deploymentManifest.Spec["createKnativeService"].(bool)
+	return createKnativeService
+}
+
+// keepDeployedImage sets the image of the deployment manifest back to the image that is deployed, for a deploy with --canary.
+// The operator owns the Knative service and deploys the image of the AppsodyApplication, so the AppsodyApplication keeps
+// the image that is serving until the canary is promoted. It returns the image of the canary.
+func keepDeployedImage(config *deployCommandConfig, deploymentManifest *DeploymentManifest, configFile string, namespace string) (string, error) {
+	if !isKnativeManifest(*deploymentManifest) {
+		return "", errors.New("The --canary flag can only be used with Knative services. Use the --knative flag to deploy as a Knative service")
+	}
+	image, _ := deploymentManifest.Spec["applicationImage"].(string)
+	if config.Dryrun {
+		return image, nil
+	}
+	client, err := getKubeClient(config.RootCommandConfig)
+	if err != nil {
+		return "", err
+	}
+	deployedImage, err := client.KnativeServiceImage(deploymentManifest.Name, namespace)
+	if err != nil {
+		return "", errors.Errorf("Failed to deploy the canary: %v", err)
+	}
+	if deployedImage != image {
+		deploymentManifest.Spec["applicationImage"] = deployedImage
+		err = writeDeploymentManifest(*deploymentManifest, configFile)
+		if err != nil {
+			return "", err
+		}
+	}
+	return image, nil
+}
+
+// deployKnativeCanary starts a canary of the image, once the deployment manifest has been applied with the image that is serving
+func deployKnativeCanary(config *deployCommandConfig, deploymentManifest DeploymentManifest, namespace string, image string) error {
+	revisionSuffix, err := knativeRevisionSuffix(config.RootCommandConfig, image)
+	if err != nil {
+		return err
+	}
+	if config.Dryrun {
+		config.Info.logf("Dry run - skipping canary of revision %s with %d%% of the traffic", KnativeRevisionName(deploymentManifest.Name, revisionSuffix), config.canary)
+		return nil
+	}
+	client, err := getKubeClient(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	current, candidate, err := client.StartKnativeCanary(deploymentManifest.Name, namespace, revisionSuffix, image, config.canary)
+	if err != nil {
+		return errors.Errorf("Failed to deploy the canary: %v", err)
+	}
+	config.Info.logf("Routing %d%% of the traffic to revision %s and %d%% to revision %s", config.canary, candidate, 100-config.canary, current)
+	config.Info.log("Use 'appsody deploy promote' to route all of the traffic to the canary, or 'appsody deploy abort' to route it back")
+	return nil
+}
+
+// resetKnativeTraffic routes all of the traffic to the latest revision for a deploy without --canary
+func resetKnativeTraffic(config *deployCommandConfig, deploymentManifest DeploymentManifest, namespace string) error {
+	if !isKnativeManifest(deploymentManifest) || config.Dryrun {
+		return nil
+	}
+	client, err := getKubeClient(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	reset, err := client.ResetKnativeTraffic(deploymentManifest.Name, namespace)
+	if err != nil {
+		return errors.Errorf("Failed to reset the traffic of Knative service %s: %v", deploymentManifest.Name, err)
+	}
+	if reset {
+		config.Info.logf("Routing all of the traffic of Knative service %s to the latest revision", deploymentManifest.Name)
+	}
+	return nil
+}
+
+// getDeployedManifestFile returns the deployment manifest file of the project, for the subcommands of deploy
+func getDeployedManifestFile(config *deployCommandConfig) (string, error) {
+	projectDir, err := getProjectDir(config.RootCommandConfig)
+	if err != nil {
+		return "", err
+	}
+	configFile := filepath.Join(projectDir, config.appDeployFile)
+	exists, err := Exists(configFile)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.Errorf("Deployment manifest not found: %s. Use 'appsody deploy' to deploy your project first", configFile)
+	}
+	return configFile, nil
+}
+
+// getDeployedManifest returns the deployment manifest and namespace of the project, for the subcommands of deploy
+func getDeployedManifest(config *deployCommandConfig) (DeploymentManifest, string, error) {
+	var deploymentManifest DeploymentManifest
+	configFile, err := getDeployedManifestFile(config)
+	if err != nil {
+		return deploymentManifest, "", err
+	}
+	namespace, err := resolveDeploymentNamespace(config, configFile, false)
+	if err != nil {
+		return deploymentManifest, "", err
+	}
+	deploymentManifest, err = getDeploymentManifest(configFile)
+	return deploymentManifest, namespace, err
+}
+
+func newDeployPromoteCmd(config *deployCommandConfig) *cobra.Command {
+	return newEndCanaryCmd(config, "promote", true)
+}
+
+func newDeployAbortCmd(config *deployCommandConfig) *cobra.Command {
+	return newEndCanaryCmd(config, "abort", false)
+}
+
+func newEndCanaryCmd(config *deployCommandConfig, use string, promote bool) *cobra.Command {
+	short := "Route all of the traffic of your Knative service to the canary revision."
+	long := `Complete a canary deployment, started by "appsody deploy --knative --canary", by routing all of the traffic of your Knative service to the canary revision.
+
+The image of the canary is then written to the deployment manifest and applied to the AppsodyApplication, which keeps the image that was serving until the canary is promoted.`
+	example := `  appsody deploy promote
+  Routes all of the traffic to the canary revision of the Knative service in the "app-deploy.yaml" deployment manifest.`
+	if !promote {
+		short = "Route all of the traffic of your Knative service back to the current revision."
+		long = `Abandon a canary deployment, started by "appsody deploy --knative --canary", by routing all of the traffic of your Knative service back to the revision that was serving before the canary.
+
+The Knative service is set back to the image of the AppsodyApplication, which is not changed by the canary. The canary revision is kept until your next deployment.`
+		example = `  appsody deploy abort
+  Routes all of the traffic back to the current revision of the Knative service in the "app-deploy.yaml" deployment manifest.`
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long: long + `
+
+Run this command from the root directory of your Appsody project.`,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			deploymentManifest, namespace, err := getDeployedManifest(config)
+			if err != nil {
+				return err
+			}
+			if !isKnativeManifest(deploymentManifest) {
+				return errors.Errorf("%s is not deployed as a Knative service", deploymentManifest.Name)
+			}
+			if config.Dryrun {
+				config.Info.logf("Dry run - skipping %s of the canary of Knative service %s", use, deploymentManifest.Name)
+				return nil
+			}
+			client, err := getKubeClient(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			if !promote {
+				image, _ := deploymentManifest.Spec["applicationImage"].(string)
+				revision, err := client.AbortKnativeCanary(deploymentManifest.Name, namespace, image)
+				if err != nil {
+					return errors.Errorf("Failed to %s the canary: %v", use, err)
+				}
+				config.Info.logf("Routing all of the traffic of Knative service %s to revision %s", deploymentManifest.Name, revision)
+				return nil
+			}
+			revision, image, err := client.PromoteKnativeCanary(deploymentManifest.Name, namespace)
+			if err != nil {
+				return errors.Errorf("Failed to %s the canary: %v", use, err)
+			}
+			config.Info.logf("Routing all of the traffic of Knative service %s to revision %s", deploymentManifest.Name, revision)
+			return promoteCanaryImage(config, deploymentManifest, namespace, image)
+		},
+	}
+}
+
+// promoteCanaryImage writes the image of a promoted canary to the deployment manifest and applies it,
+// so that the AppsodyApplication deploys the same image as the Knative service
+func promoteCanaryImage(config *deployCommandConfig, deploymentManifest DeploymentManifest, namespace string, image string) error {
+	configFile, err := getDeployedManifestFile(config)
+	if err != nil {
+		return err
+	}
+	deploymentManifest.Spec["applicationImage"] = image
+	err = writeDeploymentManifest(deploymentManifest, configFile)
+	if err != nil {
+		return err
+	}
+	err = KubeApply(config.RootCommandConfig, configFile, namespace, config.Dryrun)
+	if err != nil {
+		return errors.Errorf("Failed to apply the image %s of the canary to %s: %v", image, deploymentManifest.Name, err)
+	}
+	projectDir, err := getProjectDir(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	err = recordDeployment(config.RootCommandConfig, projectDir, configFile, namespace, nil)
+	if err != nil {
+		config.Warning.log("Could not record the deployment in the deployment history: ", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newKnativeService(traffic []interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"image": "my-app:v1", "name": "my-app"}},
+				},
+			},
+			"traffic": traffic,
+		},
+		"status": map[string]interface{}{
+			"url":                     "http://my-app.default.example.com",
+			"latestReadyRevisionName": "my-app-v1",
+		},
+	}}
+}
+
+func TestKnativeRevisionName(t *testing.T) {
+	var revisionNameTests = []struct {
+		suffix   string
+		expected string
+	}{
+		{"v2", "my-app-v2"},
+		{"1.0.3", "my-app-1-0-3"},
+		{"Feature_Branch", "my-app-feature-branch"},
+		{strings.Repeat("a", 70), "my-app-" + strings.Repeat("a", 56)},
+	}
+	for _, testData := range revisionNameTests {
+		tt := testData
+		t.Run(tt.suffix, func(t *testing.T) {
+			name := cmd.KnativeRevisionName("my-app", tt.suffix)
+			if name != tt.expected {
+				t.Errorf("Expected revision name %s but got %s", tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestKnativeCanary(t *testing.T) {
+	client := cmdtest.NewFakeKubeClient(newKnativeService([]interface{}{map[string]interface{}{"latestRevision": true, "percent": int64(100)}}))
+
+	deployedImage, err := client.KnativeServiceImage("my-app", "default")
+	if err != nil || deployedImage != "my-app:v1" {
+		t.Errorf("Expected the deployed image to be my-app:v1, got %s %v", deployedImage, err)
+	}
+	current, candidate, err := client.StartKnativeCanary("my-app", "default", "v2", "my-app:v2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != "my-app-v1" || candidate != "my-app-v2" {
+		t.Errorf("Expected a canary from my-app-v1 to my-app-v2 but got %s to %s", current, candidate)
+	}
+	service, err := client.GetKnativeService("my-app", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ := unstructured.NestedSlice(service.Object, "spec", "template", "spec", "containers")
+	image, _ := containers[0].(map[string]interface{})["image"].(string)
+	if image != "my-app:v2" {
+		t.Errorf("Expected the template image to be my-app:v2 but got %s", image)
+	}
+	revisionName, _, _ := unstructured.NestedString(service.Object, "spec", "template", "metadata", "name")
+	if revisionName != "my-app-v2" {
+		t.Errorf("Expected the template revision name to be my-app-v2 but got %s", revisionName)
+	}
+
+	_, _, err = client.StartKnativeCanary("my-app", "default", "v3", "my-app:v3", 10)
+	if err == nil {
+		t.Error("Expected an error starting a second canary, but error was nil")
+	}
+	_, err = client.KnativeServiceImage("my-app", "default")
+	if err == nil || !strings.Contains(err.Error(), "already in progress") {
+		t.Errorf("Expected an error for the deployed image during a canary, got %v", err)
+	}
+
+	// the fake cluster does not update the status, so the split is read from the spec
+	delete(service.Object, "status")
+	expectedSplit := []cmd.KnativeTrafficTarget{
+		{RevisionName: "my-app-v1", Tag: "current", Percent: 90},
+		{RevisionName: "my-app-v2", Tag: "candidate", Percent: 10},
+	}
+	if split := cmd.KnativeTraffic(service); !reflect.DeepEqual(split, expectedSplit) {
+		t.Errorf("Expected traffic split %v but got %v", expectedSplit, split)
+	}
+	if status := cmd.FormatKnativeStatus(service); !strings.Contains(status, "my-app-v2") || !strings.Contains(status, "10%") {
+		t.Errorf("Expected the status to show the canary split but got:\n%s", status)
+	}
+
+	revision, err := client.AbortKnativeCanary("my-app", "default", "my-app:v1")
+	if err != nil || revision != "my-app-v1" {
+		t.Errorf("Expected abort to route traffic to my-app-v1, got %s %v", revision, err)
+	}
+	service, err = client.GetKnativeService("my-app", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ = unstructured.NestedSlice(service.Object, "spec", "template", "spec", "containers")
+	image, _ = containers[0].(map[string]interface{})["image"].(string)
+	if _, found, _ := unstructured.NestedString(service.Object, "spec", "template", "metadata", "name"); image != "my-app:v1" || found {
+		t.Errorf("Expected abort to set the template back to my-app:v1 without a revision name, got %s", image)
+	}
+	_, _, err = client.PromoteKnativeCanary("my-app", "default")
+	if err == nil {
+		t.Error("Expected an error promoting with no canary in progress, but error was nil")
+	}
+
+	_, candidate, err = client.StartKnativeCanary("my-app", "default", "v3", "my-app:v3", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revision, image, err = client.PromoteKnativeCanary("my-app", "default")
+	if err != nil || revision != candidate || image != "my-app:v3" {
+		t.Errorf("Expected promote to route traffic to %s with image my-app:v3, got %s %s %v", candidate, revision, image, err)
+	}
+
+	reset, err := client.ResetKnativeTraffic("my-app", "default")
+	if err != nil || !reset {
+		t.Errorf("Expected the traffic to be reset to the latest revision, got %v %v", reset, err)
+	}
+	reset, err = client.ResetKnativeTraffic("my-app", "default")
+	if err != nil || reset {
+		t.Errorf("Expected the traffic to already route to the latest revision, got %v %v", reset, err)
+	}
+}
+
+func TestKnativeCanaryNotDeployed(t *testing.T) {
+	client := cmdtest.NewFakeKubeClient()
+	_, _, err := client.StartKnativeCanary("my-app", "default", "v2", "my-app:v2", 10)
+	if err == nil || !strings.Contains(err.Error(), "deploy without --canary first") {
+		t.Errorf("Expected an error for a service that is not deployed, got %v", err)
+	}
+	reset, err := client.ResetKnativeTraffic("my-app", "default")
+	if err != nil || reset {
+		t.Errorf("Expected no traffic reset for a service that is not deployed, got %v %v", reset, err)
+	}
+}
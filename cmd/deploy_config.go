@@ -0,0 +1,430 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DeployConfigSource is an entry in the config or secrets section of the project config file.
+// The key value pairs of the env file, and the contents of the files, become the keys of a ConfigMap or Secret.
+// The keys are passed to the application as environment variables, or mounted as files if a mount path is set.
+// If env is set, only the keys that it lists are passed as environment variables, with the names that it gives them.
+type DeployConfigSource struct {
+	Name      string            `mapstructure:"name"`
+	EnvFile   string            `mapstructure:"env-file"`
+	Files     []string          `mapstructure:"files"`
+	MountPath string            `mapstructure:"mount-path"`
+	Env       []DeployConfigEnv `mapstructure:"env"`
+}
+
+// DeployConfigEnv is an environment variable that is set from a key of a ConfigMap or Secret
+type DeployConfigEnv struct {
+	Name string `mapstructure:"name"`
+	Key  string `mapstructure:"key"`
+}
+
+// the length of the content hash appended to the names of the generated ConfigMaps and Secrets
+const configHashLength = 10
+
+var validConfigKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// DeployConfigObject is a ConfigMap or Secret generated from a DeployConfigSource
+type DeployConfigObject struct {
+	Source DeployConfigSource
+	Secret bool
+	// the name of the source, which is used as the volume name
+	BaseName string
+	// the name of the object, with the content hash suffix
+	Name   string
+	Object *unstructured.Unstructured
+}
+
+// readConfigSource returns the keys and values of a config source, with paths relative to the project directory
+func readConfigSource(projectDir string, source DeployConfigSource) (map[string]string, error) {
+	data := make(map[string]string)
+	if source.EnvFile != "" {
+		envVars, err := ExtractDockerEnvFile(filepath.Join(projectDir, source.EnvFile))
+		if err != nil {
+			return nil, errors.Errorf("Could not read env file %s: %v", source.EnvFile, err)
+		}
+		for key, value := range envVars {
+			data[key] = value
+		}
+	}
+	for _, file := range source.Files {
+		key := filepath.Base(file)
+		if !validConfigKey.MatchString(key) {
+			return nil, errors.Errorf("The name of file %s can not be used as a key. Keys can only contain alphanumeric characters, '-', '_' or '.'", file)
+		}
+		if _, exists := data[key]; exists {
+			return nil, errors.Errorf("Key %s from file %s is defined more than once in %s", key, file, source.Name)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(projectDir, file))
+		if err != nil {
+			return nil, errors.Errorf("Could not read file %s: %v", file, err)
+		}
+		data[key] = string(content)
+	}
+	return data, nil
+}
+
+// configHash returns a hash of the keys and values, so that a change of content changes the name of the object
+func configHash(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(data[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:configHashLength]
+}
+
+// GenerateDeployConfig creates the ConfigMaps and Secrets for the config and secrets sources of an application
+func GenerateDeployConfig(projectDir string, appName string, configSources []DeployConfigSource, secretSources []DeployConfigSource) ([]*DeployConfigObject, error) {
+	var objects []*DeployConfigObject
+	names := make(map[string]bool)
+	for _, secret := range []bool{false, true} {
+		sources, section := configSources, "config"
+		if secret {
+			sources, section = secretSources, "secrets"
+		}
+		for i, source := range sources {
+			if source.Name == "" {
+				if len(sources) > 1 {
+					return nil, errors.Errorf("Entry %d of %s in the project config file needs a name", i+1, section)
+				}
+				source.Name = appName + "-" + section
+			}
+			if names[source.Name] {
+				return nil, errors.Errorf("%s is defined more than once in the project config file", source.Name)
+			}
+			names[source.Name] = true
+			if source.EnvFile == "" && len(source.Files) == 0 {
+				return nil, errors.Errorf("%s in the project config file needs an env-file or files", source.Name)
+			}
+			data, err := readConfigSource(projectDir, source)
+			if err != nil {
+				return nil, err
+			}
+			for _, env := range source.Env {
+				if env.Name == "" {
+					return nil, errors.Errorf("The env entries of %s in the project config file need a name", source.Name)
+				}
+				key := env.Key
+				if key == "" {
+					key = env.Name
+				}
+				if _, ok := data[key]; !ok {
+					return nil, errors.Errorf("Environment variable %s refers to key %s, which is not in %s", env.Name, key, source.Name)
+				}
+			}
+			objects = append(objects, newDeployConfigObject(appName, source, secret, data))
+		}
+	}
+	return objects, nil
+}
+
+func newDeployConfigObject(appName string, source DeployConfigSource, secret bool, data map[string]string) *DeployConfigObject {
+	name := source.Name + "-" + configHash(data)
+	objectData := make(map[string]interface{})
+	for key, value := range data {
+		if secret {
+			objectData[key] = base64.StdEncoding.EncodeToString([]byte(value))
+		} else {
+			objectData[key] = value
+		}
+	}
+	content := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": name,
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/part-of":    appName,
+				"app.kubernetes.io/managed-by": "appsody",
+			},
+		},
+		"data": objectData,
+	}
+	if secret {
+		content["kind"] = "Secret"
+		content["type"] = "Opaque"
+	}
+	return &DeployConfigObject{
+		Source:   source,
+		Secret:   secret,
+		BaseName: source.Name,
+		Name:     name,
+		Object:   &unstructured.Unstructured{Object: content},
+	}
+}
+
+// isGeneratedName returns true if name is the base name, or the base name with a content hash suffix
+func isGeneratedName(name string, baseName string) bool {
+	if name == baseName {
+		return true
+	}
+	suffix := len(baseName) + 1 + configHashLength
+	if len(name) != suffix || name[:len(baseName)+1] != baseName+"-" {
+		return false
+	}
+	_, err := hex.DecodeString(name[len(baseName)+1:])
+	return err == nil
+}
+
+// removeConfigReferences removes the entries of a list in the spec that refer to any of the base names
+func removeConfigReferences(spec map[string]interface{}, field string, baseNames []string, refName func(map[string]interface{}) string) []interface{} {
+	items, _ := spec[field].([]interface{})
+	var kept []interface{}
+	for _, item := range items {
+		entry, ok := item.(map[string]interface{})
+		if ok {
+			name := refName(entry)
+			generated := false
+			for _, baseName := range baseNames {
+				if isGeneratedName(name, baseName) {
+					generated = true
+					break
+				}
+			}
+			if generated {
+				continue
+			}
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+func nestedName(entry map[string]interface{}, fields ...string) string {
+	name, _, _ := unstructured.NestedString(entry, fields...)
+	return name
+}
+
+// WireDeployConfig updates the env, envFrom, volumes and volumeMounts of the deployment manifest to refer to the
+// generated ConfigMaps and Secrets, replacing references to previous versions. It returns true if the manifest changed.
+func WireDeployConfig(deploymentManifest *DeploymentManifest, objects []*DeployConfigObject) bool {
+	if len(objects) == 0 {
+		return false
+	}
+	if deploymentManifest.Spec == nil {
+		deploymentManifest.Spec = make(map[string]interface{})
+	}
+	spec := deploymentManifest.Spec
+	var baseNames []string
+	for _, object := range objects {
+		baseNames = append(baseNames, object.BaseName)
+	}
+
+	env := removeConfigReferences(spec, "env", baseNames, func(entry map[string]interface{}) string {
+		if name := nestedName(entry, "valueFrom", "configMapKeyRef", "name"); name != "" {
+			return name
+		}
+		return nestedName(entry, "valueFrom", "secretKeyRef", "name")
+	})
+	envFrom := removeConfigReferences(spec, "envFrom", baseNames, func(entry map[string]interface{}) string {
+		if name := nestedName(entry, "configMapRef", "name"); name != "" {
+			return name
+		}
+		return nestedName(entry, "secretRef", "name")
+	})
+	volumes := removeConfigReferences(spec, "volumes", baseNames, func(entry map[string]interface{}) string {
+		return nestedName(entry, "name")
+	})
+	volumeMounts := removeConfigReferences(spec, "volumeMounts", baseNames, func(entry map[string]interface{}) string {
+		return nestedName(entry, "name")
+	})
+
+	for _, object := range objects {
+		for _, variable := range object.Source.Env {
+			key := variable.Key
+			if key == "" {
+				key = variable.Name
+			}
+			valueFrom := map[string]interface{}{"configMapKeyRef": map[string]interface{}{"name": object.Name, "key": key}}
+			if object.Secret {
+				valueFrom = map[string]interface{}{"secretKeyRef": map[string]interface{}{"name": object.Name, "key": key}}
+			}
+			env = append(env, map[string]interface{}{"name": variable.Name, "valueFrom": valueFrom})
+		}
+		if object.Source.MountPath == "" && len(object.Source.Env) > 0 {
+			continue
+		}
+		if object.Source.MountPath == "" {
+			refField := "configMapRef"
+			if object.Secret {
+				refField = "secretRef"
+			}
+			envFrom = append(envFrom, map[string]interface{}{refField: map[string]interface{}{"name": object.Name}})
+			continue
+		}
+		volume := map[string]interface{}{"name": object.BaseName, "configMap": map[string]interface{}{"name": object.Name}}
+		if object.Secret {
+			volume = map[string]interface{}{"name": object.BaseName, "secret": map[string]interface{}{"secretName": object.Name}}
+		}
+		volumes = append(volumes, volume)
+		volumeMounts = append(volumeMounts, map[string]interface{}{"name": object.BaseName, "mountPath": object.Source.MountPath, "readOnly": true})
+	}
+
+	changed := false
+	for field, value := range map[string][]interface{}{"env": env, "envFrom": envFrom, "volumes": volumes, "volumeMounts": volumeMounts} {
+		existing, _ := spec[field].([]interface{})
+		if len(value) == 0 {
+			if _, found := spec[field]; found {
+				delete(spec, field)
+				changed = true
+			}
+			continue
+		}
+		if !reflect.DeepEqual(existing, value) {
+			spec[field] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// deployConfig creates or updates the ConfigMaps and Secrets for the config and secrets in the project config file,
+// and refers to them from the deployment manifest. Only the names of the objects are written to the manifest.
+// It returns the objects, so that the previous versions can be removed once the manifest has been applied.
+func deployConfig(config *deployCommandConfig, projectDir string, configFile string, deploymentManifest *DeploymentManifest, namespace string) ([]*DeployConfigObject, error) {
+	projectConfig, err := getProjectConfigFileContents(config.RootCommandConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectConfig.Config) == 0 && len(projectConfig.Secrets) == 0 {
+		return nil, nil
+	}
+	objects, err := GenerateDeployConfig(projectDir, deploymentManifest.Name, projectConfig.Config, projectConfig.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	var kubeObjects []*unstructured.Unstructured
+	for _, object := range objects {
+		kind := "ConfigMap"
+		if object.Secret {
+			kind = "Secret"
+		}
+		if config.Dryrun {
+			config.Info.logf("Dry run - skipping apply of %s %s", kind, object.Name)
+		} else {
+			config.Info.logf("Applying %s %s", kind, object.Name)
+		}
+		kubeObjects = append(kubeObjects, object.Object)
+	}
+	if !config.Dryrun {
+		client, err := getKubeClient(config.RootCommandConfig)
+		if err != nil {
+			return nil, err
+		}
+		_, err = client.Apply(kubeObjects, namespace)
+		if err != nil {
+			return nil, errors.Errorf("Failed to apply the config and secrets: %v", err)
+		}
+	}
+
+	if WireDeployConfig(deploymentManifest, objects) {
+		if config.Dryrun {
+			config.Info.log("Dry run - skipping update of the config and secrets references in ", configFile)
+			return objects, nil
+		}
+		config.Info.log("Updating the config and secrets references in ", configFile)
+		return objects, writeDeploymentManifest(*deploymentManifest, configFile)
+	}
+	return objects, nil
+}
+
+// PruneDeployConfig deletes the previous versions of the generated ConfigMaps and Secrets of an application,
+// which have the same base name as one of the objects but a different content hash. It returns the names it deleted.
+func (c *KubeClient) PruneDeployConfig(appName string, objects []*DeployConfigObject, namespace string) ([]string, error) {
+	namespace = c.namespaceOrDefault(namespace)
+	selector := metav1.ListOptions{LabelSelector: "app.kubernetes.io/part-of=" + appName + ",app.kubernetes.io/managed-by=appsody"}
+	isPrevious := func(name string, secret bool) bool {
+		for _, object := range objects {
+			if object.Secret == secret && name != object.Name && isGeneratedName(name, object.BaseName) && name != object.BaseName {
+				return true
+			}
+		}
+		return false
+	}
+	var deleted []string
+	configMaps, err := c.Clientset.CoreV1().ConfigMaps(namespace).List(selector)
+	if err != nil {
+		return deleted, err
+	}
+	for _, configMap := range configMaps.Items {
+		if isPrevious(configMap.Name, false) {
+			err = c.Clientset.CoreV1().ConfigMaps(namespace).Delete(configMap.Name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return deleted, err
+			}
+			deleted = append(deleted, "ConfigMap "+configMap.Name)
+		}
+	}
+	secrets, err := c.Clientset.CoreV1().Secrets(namespace).List(selector)
+	if err != nil {
+		return deleted, err
+	}
+	for _, secret := range secrets.Items {
+		if isPrevious(secret.Name, true) {
+			err = c.Clientset.CoreV1().Secrets(namespace).Delete(secret.Name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return deleted, err
+			}
+			deleted = append(deleted, "Secret "+secret.Name)
+		}
+	}
+	return deleted, nil
+}
+
+// pruneDeployConfig removes the previous versions of the ConfigMaps and Secrets once the manifest that refers to
+// the new versions has been applied. A failure is only a warning, because the deployment itself has succeeded.
+func pruneDeployConfig(config *deployCommandConfig, appName string, objects []*DeployConfigObject, namespace string) {
+	if len(objects) == 0 || config.Dryrun {
+		return
+	}
+	client, err := getKubeClient(config.RootCommandConfig)
+	if err != nil {
+		config.Warning.log("Could not remove the previous config and secrets: ", err)
+		return
+	}
+	deleted, err := client.PruneDeployConfig(appName, objects, namespace)
+	for _, name := range deleted {
+		config.Info.log("Deleted previous ", name)
+	}
+	if err != nil {
+		config.Warning.log("Could not remove the previous config and secrets: ", err)
+	}
+}
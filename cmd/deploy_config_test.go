@@ -0,0 +1,233 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func writeConfigTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "appsody-deploy-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGenerateDeployConfig(t *testing.T) {
+	dir := writeConfigTestFiles(t, map[string]string{
+		"dev.env":        "LOG_LEVEL=debug\n# a comment\nGREETING=hello\n",
+		"app.properties": "feature=on\n",
+		"secret.env":     "DB_PASSWORD=s3cr3t\n",
+	})
+	defer os.RemoveAll(dir)
+
+	configSources := []cmd.DeployConfigSource{
+		{EnvFile: "dev.env"},
+	}
+	secretSources := []cmd.DeployConfigSource{
+		{Name: "db", EnvFile: "secret.env"},
+		{Name: "props", Files: []string{"app.properties"}, MountPath: "/config"},
+	}
+	objects, err := cmd.GenerateDeployConfig(dir, "my-app", configSources, secretSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("Expected 3 objects but got %d", len(objects))
+	}
+	if objects[0].BaseName != "my-app-config" || objects[0].Object.GetKind() != "ConfigMap" {
+		t.Errorf("Expected ConfigMap my-app-config but got %s %s", objects[0].Object.GetKind(), objects[0].BaseName)
+	}
+	if !strings.HasPrefix(objects[1].Name, "db-") || len(objects[1].Name) != len("db-")+10 || objects[1].Object.GetKind() != "Secret" {
+		t.Errorf("Expected a Secret named db with a hash suffix but got %s %s", objects[1].Object.GetKind(), objects[1].Name)
+	}
+
+	// the same content generates the same names, and a change of content changes them
+	again, err := cmd.GenerateDeployConfig(dir, "my-app", configSources, secretSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].Name != objects[0].Name {
+		t.Errorf("Expected the same name for the same content, got %s and %s", objects[0].Name, again[0].Name)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "dev.env"), []byte("LOG_LEVEL=info\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := cmd.GenerateDeployConfig(dir, "my-app", configSources, secretSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed[0].Name == objects[0].Name {
+		t.Errorf("Expected a new name after the content changed, got %s", changed[0].Name)
+	}
+
+	manifest := cmd.DeploymentManifest{Spec: map[string]interface{}{
+		"envFrom": []interface{}{map[string]interface{}{"configMapRef": map[string]interface{}{"name": "user-config"}}},
+	}}
+	if !cmd.WireDeployConfig(&manifest, objects) {
+		t.Error("Expected the manifest to change")
+	}
+	if !cmd.WireDeployConfig(&manifest, changed) {
+		t.Error("Expected the manifest to change after the content changed")
+	}
+	if cmd.WireDeployConfig(&manifest, changed) {
+		t.Error("Expected no change when the references are up to date")
+	}
+	output, err := yaml.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"user-config", changed[0].Name, changed[1].Name, "secretName: " + changed[2].Name, "mountPath: /config"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected the manifest to contain %s:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(string(output), objects[0].Name) {
+		t.Errorf("Expected the reference to %s to be replaced:\n%s", objects[0].Name, output)
+	}
+	if strings.Contains(string(output), "s3cr3t") {
+		t.Errorf("The manifest must not contain secret values:\n%s", output)
+	}
+}
+
+func TestGenerateDeployConfigErrors(t *testing.T) {
+	dir := writeConfigTestFiles(t, map[string]string{"a.env": "A=1\n"})
+	defer os.RemoveAll(dir)
+
+	var generateErrorTests = []struct {
+		testName string
+		config   []cmd.DeployConfigSource
+		secrets  []cmd.DeployConfigSource
+		expected string
+	}{
+		{"Missing name", []cmd.DeployConfigSource{{EnvFile: "a.env"}, {Name: "b", EnvFile: "a.env"}}, nil, "needs a name"},
+		{"Duplicate name", []cmd.DeployConfigSource{{Name: "a", EnvFile: "a.env"}}, []cmd.DeployConfigSource{{Name: "a", EnvFile: "a.env"}}, "more than once"},
+		{"No content", []cmd.DeployConfigSource{{Name: "a"}}, nil, "needs an env-file or files"},
+		{"Missing file", nil, []cmd.DeployConfigSource{{Files: []string{"missing.txt"}}}, "Could not read file"},
+		{"Missing env key", []cmd.DeployConfigSource{{Name: "a", EnvFile: "a.env", Env: []cmd.DeployConfigEnv{{Name: "B"}}}}, nil, "refers to key B, which is not in a"},
+		{"Missing env name", nil, []cmd.DeployConfigSource{{Name: "a", EnvFile: "a.env", Env: []cmd.DeployConfigEnv{{Key: "A"}}}}, "need a name"},
+	}
+	for _, testData := range generateErrorTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			_, err := cmd.GenerateDeployConfig(dir, "my-app", tt.config, tt.secrets)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestWireDeployConfigEnv(t *testing.T) {
+	dir := writeConfigTestFiles(t, map[string]string{"db.env": "DB_USER=app\nDB_PASSWORD=s3cr3t\n"})
+	defer os.RemoveAll(dir)
+
+	secretSources := []cmd.DeployConfigSource{
+		{Name: "db", EnvFile: "db.env", Env: []cmd.DeployConfigEnv{{Name: "DATABASE_PASSWORD", Key: "DB_PASSWORD"}, {Name: "DB_USER"}}},
+	}
+	objects, err := cmd.GenerateDeployConfig(dir, "my-app", nil, secretSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := cmd.DeploymentManifest{Spec: map[string]interface{}{
+		"env": []interface{}{map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"}},
+	}}
+	if !cmd.WireDeployConfig(&manifest, objects) {
+		t.Error("Expected the manifest to change")
+	}
+	if _, found := manifest.Spec["envFrom"]; found {
+		t.Errorf("Expected only the listed keys to be passed to the application, got envFrom %v", manifest.Spec["envFrom"])
+	}
+	env, _ := manifest.Spec["env"].([]interface{})
+	if len(env) != 3 {
+		t.Fatalf("Expected the existing variable and 2 variables from the secret, got %v", env)
+	}
+	output, err := yaml.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"name: LOG_LEVEL", "name: DATABASE_PASSWORD", "key: DB_PASSWORD", "key: DB_USER", "name: " + objects[0].Name} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected the manifest to contain %s:\n%s", expected, output)
+		}
+	}
+
+	// a change of content replaces the references to the previous version of the secret
+	err = ioutil.WriteFile(filepath.Join(dir, "db.env"), []byte("DB_USER=app\nDB_PASSWORD=changed\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := cmd.GenerateDeployConfig(dir, "my-app", nil, secretSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmd.WireDeployConfig(&manifest, changed) {
+		t.Error("Expected the manifest to change after the content changed")
+	}
+	env, _ = manifest.Spec["env"].([]interface{})
+	output, _ = yaml.Marshal(manifest)
+	if len(env) != 3 || strings.Contains(string(output), objects[0].Name) || strings.Contains(string(output), "s3cr3t") {
+		t.Errorf("Expected the references to be replaced without secret values:\n%s", output)
+	}
+}
+
+func TestPruneDeployConfig(t *testing.T) {
+	labels := map[string]string{"app.kubernetes.io/part-of": "my-app", "app.kubernetes.io/managed-by": "appsody"}
+	current := &cmd.DeployConfigObject{BaseName: "my-app-config", Name: "my-app-config-0123456789"}
+	currentSecret := &cmd.DeployConfigObject{BaseName: "db", Name: "db-0123456789", Secret: true}
+	client := cmdtest.NewFakeKubeClient(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-app-config-0123456789", Namespace: "default", Labels: labels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-app-config-abcdef0123", Namespace: "default", Labels: labels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-app-config-abcdef0123", Namespace: "other", Labels: labels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-app-config-fedcba9876", Namespace: "default"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-app-settings-abcdef0123", Namespace: "default", Labels: labels}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-abcdef0123", Namespace: "default", Labels: labels}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db-0123456789", Namespace: "default", Labels: labels}},
+	)
+	deleted, err := client.PruneDeployConfig("my-app", []*cmd.DeployConfigObject{current, currentSecret}, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"ConfigMap my-app-config-abcdef0123", "Secret db-abcdef0123"}
+	if strings.Join(deleted, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v to be deleted, got %v", expected, deleted)
+	}
+	for _, name := range []string{"my-app-config-0123456789", "my-app-config-fedcba9876", "my-app-settings-abcdef0123"} {
+		if _, err := client.Clientset.CoreV1().ConfigMaps("default").Get(name, metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected ConfigMap %s to be kept, got %v", name, err)
+		}
+	}
+	if _, err := client.Clientset.CoreV1().ConfigMaps("other").Get("my-app-config-abcdef0123", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the ConfigMap in another namespace to be kept, got %v", err)
+	}
+}
@@ -48,7 +48,7 @@ Run this command from the root directory of your Appsody project.`,
 			}
 
 			config.Info.log("Deleting deployment using deployment manifest ", deployConfigFile)
-			err = KubeDelete(config.LoggingConfig, deployConfigFile, config.namespace, config.Dryrun)
+			err = KubeDelete(config.RootCommandConfig, deployConfigFile, config.namespace, config.Dryrun)
 			if err != nil {
 				return err
 			}
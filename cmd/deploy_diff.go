@@ -0,0 +1,402 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	diffContextLines = 3
+	colorRed         = "\033[31m"
+	colorGreen       = "\033[32m"
+	colorCyan        = "\033[36m"
+	colorReset       = "\033[0m"
+)
+
+// metadata fields that are populated by the Kubernetes API server and should not be compared
+var serverPopulatedMetadata = []string{
+	"creationTimestamp",
+	"deletionGracePeriodSeconds",
+	"deletionTimestamp",
+	"generation",
+	"managedFields",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+// annotations that are added by kubectl and should not be compared
+var serverPopulatedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+func newDeployDiffCmd(config *deployCommandConfig) *cobra.Command {
+	var noColor bool
+	var diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Compare your deployment manifest with the application deployed in your Kubernetes cluster.",
+		Long: `Compare the deployment manifest that "appsody deploy" would apply with the application that is currently deployed in your Kubernetes cluster.
+
+The deployment manifest is generated in the same way as "appsody deploy", but the image is not built and your "app-deploy.yaml" file is not modified. Fields that are populated by the Kubernetes API server are ignored, and the differences are printed as a unified diff.
+
+The command exits with a non-zero return code if there are differences, so that it can be used to gate continuous integration pipelines.
+
+Run this command from the root directory of your Appsody project.`,
+		Example: `  appsody deploy diff
+  Compares the "app-deploy.yaml" deployment manifest with the application deployed in your Kubernetes cluster.
+
+  appsody deploy diff --no-build --namespace my-namespace
+  Compares the existing "app-deploy.yaml" deployment manifest, without updating it, with the application deployed in the "my-namespace" namespace.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			config.knativeFlagPresent = cmd.Flag("knative").Changed
+			config.namespaceFlagPresent = cmd.Flag("namespace").Changed
+
+			projectDir, err := getProjectDir(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			config.targetCluster, err = getLocalCluster(config, cmd.Flag("local-cluster").Changed)
+			if err != nil {
+				return err
+			}
+			configFile := filepath.Join(projectDir, config.appDeployFile)
+
+			diff, err := deployDiff(config, configFile)
+			if err != nil {
+				return err
+			}
+			if config.Dryrun {
+				config.Info.log("Dry run complete")
+				return nil
+			}
+			if diff == "" {
+				config.Info.log("No differences found between ", config.appDeployFile, " and the deployed application")
+				return nil
+			}
+			if !noColor {
+				diff = colorizeDiff(diff)
+			}
+			config.Info.log("\n", diff)
+			return errors.Errorf("The deployment manifest %s differs from the deployed application", config.appDeployFile)
+		},
+	}
+
+	diffCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Do not colour the diff output.")
+	return diffCmd
+}
+
+// deployDiff generates the deployment manifest into a temporary file and
+// returns the unified diff between the deployed object and that manifest
+func deployDiff(config *deployCommandConfig, configFile string) (string, error) {
+	namespace, err := resolveDeploymentNamespace(config, configFile, false)
+	if err != nil {
+		return "", err
+	}
+
+	tempDir, err := ioutil.TempDir("", "appsody-deploy-diff-")
+	if err != nil {
+		return "", errors.Errorf("Error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	diffFile, err := prepareDiffManifest(config, configFile, tempDir, namespace)
+	if err != nil {
+		return "", err
+	}
+	if config.Dryrun {
+		return "", nil
+	}
+
+	deploymentManifest, err := getDeploymentManifest(diffFile)
+	if err != nil {
+		return "", err
+	}
+	localBytes, err := ioutil.ReadFile(diffFile)
+	if err != nil {
+		return "", errors.Errorf("Could not read %s file: %s", diffFile, err)
+	}
+
+	liveYaml, err := getLiveObject(config.RootCommandConfig, deploymentManifest.APIVersion, deploymentManifest.Kind, deploymentManifest.Name, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	local, err := normalizeManifestForDiff(localBytes)
+	if err != nil {
+		return "", errors.Errorf("%s formatting error: %s", config.appDeployFile, err)
+	}
+	live := ""
+	if liveYaml != "" {
+		live, err = normalizeManifestForDiff([]byte(liveYaml))
+		if err != nil {
+			return "", errors.Errorf("Could not parse the deployed %s %s: %s", deploymentManifest.Kind, deploymentManifest.Name, err)
+		}
+	}
+
+	liveName := fmt.Sprintf("live/%s/%s/%s", namespace, deploymentManifest.Kind, deploymentManifest.Name)
+	return UnifiedDiff(liveName, config.appDeployFile, live, local), nil
+}
+
+// prepareDiffManifest copies the deployment manifest into tempDir and updates it the same
+// way that deploy would, without building the image.
+func prepareDiffManifest(config *deployCommandConfig, configFile string, tempDir string, namespace string) (string, error) {
+	diffFile := filepath.Join(tempDir, filepath.Base(configFile))
+
+	exists, err := Exists(configFile)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		manifestBytes, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return "", errors.Errorf("Could not read %s file: %s", configFile, err)
+		}
+		err = ioutil.WriteFile(diffFile, manifestBytes, 0666)
+		if err != nil {
+			return "", errors.Errorf("Could not write %s file: %s", diffFile, err)
+		}
+
+		deploymentManifest, err := getDeploymentManifest(diffFile)
+		if err != nil {
+			return "", err
+		}
+		if deploymentManifest.Namespace != "" && deploymentManifest.Namespace != namespace {
+			deploymentManifest.Namespace = namespace
+			err = writeDeploymentManifest(deploymentManifest, diffFile)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if !config.nobuild {
+		config.Info.log("Generating the deployment manifest")
+		buildConfig := newDeployBuildConfig(config, diffFile, namespace)
+		buildConfig.generateOnly = true
+		err = build(buildConfig)
+		if err != nil {
+			return "", err
+		}
+	}
+	return diffFile, nil
+}
+
+// getLiveObject returns the YAML of the deployed object, or an empty string if it is not deployed
+func getLiveObject(config *RootCommandConfig, apiVersion string, kind string, name string, namespace string) (string, error) {
+	if kind == "" || name == "" {
+		return "", errors.New("The deployment manifest must contain a kind and a metadata name")
+	}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return "", err
+	}
+	live, err := client.Get(schema.FromAPIVersionAndKind(apiVersion, kind), name, namespace)
+	if apierrors.IsNotFound(err) {
+		config.Info.logf("%s %s is not deployed in namespace %s", kind, name, namespace)
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Errorf("Failed to get the deployed %s %s: %v", kind, name, err)
+	}
+	liveJSON, err := live.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	liveYaml, err := yaml.JSONToYAML(liveJSON)
+	if err != nil {
+		return "", err
+	}
+	return string(liveYaml), nil
+}
+
+// normalizeManifestForDiff removes the fields populated by the server and
+// marshals the object with sorted keys, so that two manifests can be compared line by line
+func normalizeManifestForDiff(manifest []byte) (string, error) {
+	var object map[string]interface{}
+	err := yaml.Unmarshal(manifest, &object)
+	if err != nil {
+		return "", err
+	}
+	stripServerPopulatedFields(object)
+	output, err := yaml.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+func stripServerPopulatedFields(object map[string]interface{}) {
+	delete(object, "status")
+	metadata, ok := object["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range serverPopulatedMetadata {
+		delete(metadata, field)
+	}
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		for _, annotation := range serverPopulatedAnnotations {
+			delete(annotations, annotation)
+		}
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+}
+
+// UnifiedDiff returns the unified diff of two texts, or an empty string if they are the same
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+	fromLines := splitDiffLines(from)
+	toLines := splitDiffLines(to)
+
+	// longest common subsequence table
+	lcs := make([][]int, len(fromLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(toLines)+1)
+	}
+	for i := len(fromLines) - 1; i >= 0; i-- {
+		for j := len(toLines) - 1; j >= 0; j-- {
+			if fromLines[i] == toLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type diffLine struct {
+		op         byte
+		text       string
+		fromLineNo int
+		toLineNo   int
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(fromLines) || j < len(toLines) {
+		switch {
+		case i < len(fromLines) && j < len(toLines) && fromLines[i] == toLines[j]:
+			lines = append(lines, diffLine{' ', fromLines[i], i, j})
+			i++
+			j++
+		case i < len(fromLines) && (j == len(toLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', fromLines[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', toLines[j], i, j})
+			j++
+		}
+	}
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(lines); {
+		// find the next change
+		for start < len(lines) && lines[start].op == ' ' {
+			start++
+		}
+		if start == len(lines) {
+			break
+		}
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		// extend the hunk until there are more than 2*context unchanged lines
+		hunkEnd := start
+		unchanged := 0
+		for hunkEnd < len(lines) && unchanged <= 2*diffContextLines {
+			if lines[hunkEnd].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+			hunkEnd++
+		}
+		if unchanged > diffContextLines {
+			hunkEnd -= unchanged - diffContextLines
+		}
+
+		fromCount, toCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.op != '+' {
+				fromCount++
+			}
+			if line.op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&diff, "@@ -%s +%s @@\n", hunkRange(lines[hunkStart].fromLineNo, fromCount), hunkRange(lines[hunkStart].toLineNo, toCount))
+		for _, line := range lines[hunkStart:hunkEnd] {
+			diff.WriteByte(line.op)
+			diff.WriteString(line.text)
+			diff.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return diff.String()
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func colorizeDiff(diff string) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for index, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++"):
+			// leave the file headers uncoloured
+		case strings.HasPrefix(line, "@@"):
+			lines[index] = colorCyan + line + colorReset
+		case strings.HasPrefix(line, "-"):
+			lines[index] = colorRed + line + colorReset
+		case strings.HasPrefix(line, "+"):
+			lines[index] = colorGreen + line + colorReset
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,51 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	var unifiedDiffTests = []struct {
+		testName     string
+		from         string
+		to           string
+		expectedDiff string
+	}{
+		{"Identical", "a\nb\nc\n", "a\nb\nc\n", ""},
+		{"Not deployed", "", "a\nb\n", "--- live\n+++ local\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"Changed line", "a\nb\nc\n", "a\nx\nc\n", "--- live\n+++ local\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"Separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- live\n+++ local\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n"},
+	}
+	for _, testData := range unifiedDiffTests {
+		// need to set testData to a new variable scoped under the for loop
+		// otherwise tests run in parallel may get the wrong testData
+		// because the for loop reassigns it before the func runs
+		tt := testData
+
+		t.Run(tt.testName, func(t *testing.T) {
+			diff := cmd.UnifiedDiff("live", "local", tt.from, tt.to)
+			if diff != tt.expectedDiff {
+				t.Errorf("Expected diff:\n%s\nbut got:\n%s", tt.expectedDiff, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const deployHistoryFile = ".appsody-deploy-history.yaml"
+
+// the maximum number of entries kept in the deployment history
+const deployHistoryLimit = 20
+
+type DeploymentHistory struct {
+	Entries []*DeploymentHistoryEntry `yaml:"entries" json:"entries"`
+}
+
+type DeploymentHistoryEntry struct {
+	Revision     int       `yaml:"revision" json:"revision"`
+	Timestamp    time.Time `yaml:"timestamp" json:"timestamp"`
+	Namespace    string    `yaml:"namespace" json:"namespace"`
+	Image        string    `yaml:"image" json:"image"`
+	ImageDigest  string    `yaml:"image-digest,omitempty" json:"imageDigest,omitempty"`
+	ManifestHash string    `yaml:"manifest-hash" json:"manifestHash"`
+	Commit       string    `yaml:"commit,omitempty" json:"commit,omitempty"`
+	User         string    `yaml:"user,omitempty" json:"user,omitempty"`
+	RollbackOf   int       `yaml:"rollback-of,omitempty" json:"rollbackOf,omitempty"`
+	Manifest     string    `yaml:"manifest" json:"-"`
+}
+
+func getDeployHistoryPath(projectDir string) string {
+	return filepath.Join(projectDir, deployHistoryFile)
+}
+
+// read the deployment history, an empty history is returned if the file does not exist
+func (h *DeploymentHistory) ReadFile(path string) error {
+	historyReader, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Errorf("Failed reading deployment history file %s: %v", path, err)
+	}
+	err = yaml.Unmarshal(historyReader, h)
+	if err != nil {
+		return errors.Errorf("Failed to parse deployment history file %s: %v", path, err)
+	}
+	return nil
+}
+
+// write the deployment history file
+func (h *DeploymentHistory) WriteFile(path string) error {
+	data, err := yaml.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Add appends an entry with the next revision number, dropping the oldest entries over the limit
+func (h *DeploymentHistory) Add(entry *DeploymentHistoryEntry) {
+	entry.Revision = 1
+	if len(h.Entries) > 0 {
+		entry.Revision = h.Entries[len(h.Entries)-1].Revision + 1
+	}
+	h.Entries = append(h.Entries, entry)
+	if len(h.Entries) > deployHistoryLimit {
+		h.Entries = h.Entries[len(h.Entries)-deployHistoryLimit:]
+	}
+}
+
+// GetRevision returns the entry with the given revision number, or nil if there isn't one
+func (h *DeploymentHistory) GetRevision(revision int) *DeploymentHistoryEntry {
+	for _, entry := range h.Entries {
+		if entry.Revision == revision {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Previous returns the entry deployed before the latest one, or nil if there isn't one
+func (h *DeploymentHistory) Previous() *DeploymentHistoryEntry {
+	if len(h.Entries) < 2 {
+		return nil
+	}
+	return h.Entries[len(h.Entries)-2]
+}
+
+func newDeployHistoryEntry(config *RootCommandConfig, manifest []byte, namespace string, image string, imageDigest string) *DeploymentHistoryEntry {
+	hash := sha256.Sum256(manifest)
+	entry := &DeploymentHistoryEntry{
+		Timestamp:    time.Now().UTC(),
+		Namespace:    namespace,
+		Image:        image,
+		ImageDigest:  imageDigest,
+		ManifestHash: hex.EncodeToString(hash[:]),
+		Manifest:     string(manifest),
+		User:         getDeployUser(),
+	}
+
+	gitInfo, err := GetGitInfo(config)
+	if err != nil {
+		config.Debug.log("Not all git information is available for the deployment history: ", err)
+	}
+	entry.Commit = gitInfo.Commit.SHA
+	return entry
+}
+
+// recordDeployment appends the manifest that has just been applied to the project's deployment history
+func recordDeployment(config *RootCommandConfig, projectDir string, manifestFile string, namespace string, imageCandidates []string) error {
+	manifest, err := ioutil.ReadFile(manifestFile)
+	if err != nil {
+		return errors.Errorf("Could not read %s file: %s", manifestFile, err)
+	}
+	deploymentManifest, err := getDeploymentManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+	image, _ := deploymentManifest.Spec["applicationImage"].(string)
+	imageCandidates = append(imageCandidates, image)
+
+	digest := ""
+	for _, candidate := range imageCandidates {
+		if candidate == "" {
+			continue
+		}
+		digest, err = getImageDigest(config, candidate)
+		if err == nil && digest != "" {
+			break
+		}
+		config.Debug.logf("Could not get the digest of image %s: %v", candidate, err)
+	}
+	if digest == "" {
+		config.Warning.log("Could not determine the image digest, the deployment history will only record the image name")
+	}
+
+	entry := newDeployHistoryEntry(config, manifest, namespace, image, digest)
+	return appendDeployHistory(config, projectDir, entry)
+}
+
+func appendDeployHistory(config *RootCommandConfig, projectDir string, entry *DeploymentHistoryEntry) error {
+	historyPath := getDeployHistoryPath(projectDir)
+	var history DeploymentHistory
+	err := history.ReadFile(historyPath)
+	if err != nil {
+		return err
+	}
+	history.Add(entry)
+	err = history.WriteFile(historyPath)
+	if err != nil {
+		return errors.Errorf("Failed to write deployment history file %s: %v", historyPath, err)
+	}
+	config.Debug.logf("Recorded deployment revision %d in %s", entry.Revision, historyPath)
+	return nil
+}
+
+// getImageDigest returns the registry digest of a local image, or an empty string if the image was never pushed
+func getImageDigest(config *RootCommandConfig, image string) (string, error) {
+	cmdName := "docker"
+	cmdArgs := []string{"image", "inspect", "--format", "{{json .RepoDigests}}", image}
+	if config.Buildah {
+		cmdName = "buildah"
+		cmdArgs = []string{"images", "--digests", "--format", "{{.Digest}}", image}
+	}
+	config.Debug.log("Running command: ", cmdName, " ", ArgsToString(cmdArgs))
+	inspectCmd := exec.Command(cmdName, cmdArgs...)
+	inspectOut, inspectErr := SeparateOutput(inspectCmd)
+	if inspectErr != nil {
+		return "", errors.Errorf("Could not inspect the image: %s", inspectOut)
+	}
+	if config.Buildah {
+		return strings.TrimSpace(strings.Split(inspectOut, "\n")[0]), nil
+	}
+	var repoDigests []string
+	err := json.Unmarshal([]byte(inspectOut), &repoDigests)
+	if err != nil {
+		return "", errors.Errorf("Error unmarshaling data from inspect command: %v", err)
+	}
+	for _, repoDigest := range repoDigests {
+		if digestIndex := strings.Index(repoDigest, "@"); digestIndex >= 0 {
+			return repoDigest[digestIndex+1:], nil
+		}
+	}
+	return "", nil
+}
+
+// ImageWithDigest replaces the tag or digest of an image reference with the given digest
+func ImageWithDigest(image string, digest string) string {
+	if digest == "" {
+		return image
+	}
+	if digestIndex := strings.Index(image, "@"); digestIndex >= 0 {
+		image = image[:digestIndex]
+	}
+	// a colon after the last slash separates the tag, otherwise it is a registry port
+	if tagIndex := strings.LastIndex(image, ":"); tagIndex > strings.LastIndex(image, "/") {
+		image = image[:tagIndex]
+	}
+	return image + "@" + digest
+}
+
+func getDeployUser() string {
+	currentUser, err := user.Current()
+	if err == nil && currentUser.Username != "" {
+		return currentUser.Username
+	}
+	return os.Getenv("USER")
+}
+
+func shortHash(hash string) string {
+	hash = strings.TrimPrefix(hash, "sha256:")
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func newDeployHistoryCmd(config *deployCommandConfig) *cobra.Command {
+	var output string
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "List the deployment history of your Appsody project.",
+		Long: `List the deployments of your Appsody project that were applied to your Kubernetes cluster by "appsody deploy" or "appsody deploy rollback".
+
+The history is recorded in the "` + deployHistoryFile + `" file in the root directory of your Appsody project.`,
+		Example: `  appsody deploy history
+  Lists the revisions that have been deployed, with their image digest, manifest hash and git commit.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			projectDir, err := getProjectDir(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			var history DeploymentHistory
+			err = history.ReadFile(getDeployHistoryPath(projectDir))
+			if err != nil {
+				return err
+			}
+
+			if output == "yaml" {
+				bytes, err := yaml.Marshal(&history)
+				if err != nil {
+					return err
+				}
+				config.Info.log("\n", string(bytes))
+				return nil
+			} else if output == "json" {
+				bytes, err := json.Marshal(&history)
+				if err != nil {
+					return err
+				}
+				config.Info.log("\n", string(bytes))
+				return nil
+			}
+
+			if len(history.Entries) == 0 {
+				config.Info.log("There is no deployment history for this project")
+				return nil
+			}
+			table := uitable.New()
+			table.MaxColWidth = 60
+			table.AddRow("REVISION", "DEPLOYED", "NAMESPACE", "IMAGE", "DIGEST", "MANIFEST", "COMMIT", "USER", "DESCRIPTION")
+			for _, entry := range history.Entries {
+				description := ""
+				if entry.RollbackOf > 0 {
+					description = "Rollback to " + strconv.Itoa(entry.RollbackOf)
+				}
+				table.AddRow(entry.Revision, entry.Timestamp.Local().Format(time.RFC3339), entry.Namespace, entry.Image, shortHash(entry.ImageDigest), shortHash(entry.ManifestHash), shortHash(entry.Commit), entry.User, description)
+			}
+			config.Info.log("\n", table.String())
+			return nil
+		},
+	}
+
+	historyCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output the deployment history in yaml or json format")
+	return historyCmd
+}
@@ -0,0 +1,86 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+func TestDeploymentHistoryAddAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appsody-deploy-history-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	historyFile := filepath.Join(dir, "history.yaml")
+
+	var history cmd.DeploymentHistory
+	for i := 0; i < 25; i++ {
+		history.Add(&cmd.DeploymentHistoryEntry{Image: "my-image", Manifest: "kind: AppsodyApplication\n"})
+	}
+	if len(history.Entries) != 20 {
+		t.Errorf("Expected the history to be limited to 20 entries but found %d", len(history.Entries))
+	}
+	if history.Entries[0].Revision != 6 || history.Entries[19].Revision != 25 {
+		t.Errorf("Expected revisions 6 to 25 but found %d to %d", history.Entries[0].Revision, history.Entries[19].Revision)
+	}
+
+	err = history.WriteFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readHistory cmd.DeploymentHistory
+	err = readHistory.ReadFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := readHistory.GetRevision(10); entry == nil || entry.Manifest != "kind: AppsodyApplication\n" {
+		t.Errorf("Expected to find revision 10 with its manifest, found %v", entry)
+	}
+	if entry := readHistory.GetRevision(1); entry != nil {
+		t.Errorf("Expected revision 1 to have been dropped from the history, found %v", entry)
+	}
+	if entry := readHistory.Previous(); entry == nil || entry.Revision != 24 {
+		t.Errorf("Expected the previous revision to be 24, found %v", entry)
+	}
+}
+
+func TestImageWithDigest(t *testing.T) {
+	var imageWithDigestTests = []struct {
+		image    string
+		digest   string
+		expected string
+	}{
+		{"my-repo/my-image:1.0", "", "my-repo/my-image:1.0"},
+		{"my-repo/my-image:1.0", "sha256:abc", "my-repo/my-image@sha256:abc"},
+		{"my-repo/my-image", "sha256:abc", "my-repo/my-image@sha256:abc"},
+		{"registry:5000/my-image", "sha256:abc", "registry:5000/my-image@sha256:abc"},
+		{"registry:5000/my-image:latest@sha256:def", "sha256:abc", "registry:5000/my-image@sha256:abc"},
+	}
+	for _, testData := range imageWithDigestTests {
+		tt := testData
+		t.Run(tt.image, func(t *testing.T) {
+			output := cmd.ImageWithDigest(tt.image, tt.digest)
+			if output != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// the value of --port-forward when it is specified without local and remote ports
+const portForwardDefault = "default"
+
+// how often to look for a ready pod while the application is starting or restarting
+const readyPodPollInterval = 2 * time.Second
+
+// the longest wait before reconnecting a port forward or watching a pod again, after repeated failures
+const portForwardMaxBackoff = 30 * time.Second
+
+// ParsePortForwardSpec parses a port forward in the local:remote, local or :remote forms.
+// A missing remote port is the service port, and a missing local port is the remote port.
+func ParsePortForwardSpec(spec string, servicePort int) (int, int, error) {
+	if spec == portForwardDefault {
+		spec = ""
+	}
+	localSpec, remoteSpec := spec, ""
+	if colon := strings.Index(spec, ":"); colon >= 0 {
+		localSpec, remoteSpec = spec[:colon], spec[colon+1:]
+	}
+	remote := servicePort
+	if remoteSpec != "" {
+		port, err := strconv.Atoi(remoteSpec)
+		if err != nil || port < 1 || port > 65535 {
+			return 0, 0, errors.Errorf("The remote port %s is not a valid port number", remoteSpec)
+		}
+		remote = port
+	}
+	if remote == 0 {
+		return 0, 0, errors.New("The deployment manifest does not have a service port. Specify the port to forward to as --port-forward local:remote")
+	}
+	local := remote
+	if localSpec != "" {
+		port, err := strconv.Atoi(localSpec)
+		if err != nil || port < 0 || port > 65535 {
+			return 0, 0, errors.Errorf("The local port %s is not a valid port number", localSpec)
+		}
+		local = port
+	}
+	return local, remote, nil
+}
+
+// getServicePort returns the service port in the deployment manifest, or 0 if there isn't one
+func getServicePort(deploymentManifest DeploymentManifest) int {
+	service, _ := deploymentManifest.Spec["service"].(map[string]interface{})
+	switch port := service["port"].(type) {
+	case float64:
+		return int(port)
+	case int64:
+		return int(port)
+	case int:
+		return port
+	}
+	return 0
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// ReadyPod returns a ready pod of the application, or nil if none of its pods are ready
+func (c *KubeClient) ReadyPod(name string, namespace string) (*corev1.Pod, error) {
+	pods, err := c.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: "app.kubernetes.io/name=" + name})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if podReady(&pods.Items[i]) {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// waitForReadyPod polls for a ready pod of the application, returning nil if stopCh is closed first
+func waitForReadyPod(config *RootCommandConfig, client *KubeClient, name string, namespace string, stopCh <-chan struct{}) (*corev1.Pod, error) {
+	waiting := false
+	for {
+		pod, err := client.ReadyPod(name, namespace)
+		if err != nil || pod != nil {
+			return pod, err
+		}
+		if !waiting {
+			config.Info.logf("Waiting for a pod of %s to be ready in namespace %s", name, namespace)
+			waiting = true
+		}
+		select {
+		case <-stopCh:
+			return nil, nil
+		case <-time.After(readyPodPollInterval):
+		}
+	}
+}
+
+// podRestarts returns the number of times the containers of a pod have restarted
+func podRestarts(pod *corev1.Pod) int32 {
+	var count int32
+	for _, status := range pod.Status.ContainerStatuses {
+		count += status.RestartCount
+	}
+	return count
+}
+
+// nextBackoff returns how long to wait after another failure, doubling the previous wait up to portForwardMaxBackoff
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return readyPodPollInterval
+	}
+	backoff *= 2
+	if backoff > portForwardMaxBackoff {
+		return portForwardMaxBackoff
+	}
+	return backoff
+}
+
+// WatchPodRestart closes podDone when the pod is deleted, stops being ready or restarts a container, or when stop is closed.
+// A failure of the watch is not a restart of the pod: the pod is checked and watched again after a backoff.
+func (c *KubeClient) WatchPodRestart(log *LoggingConfig, pod *corev1.Pod, podDone chan struct{}, stop <-chan struct{}) {
+	defer close(podDone)
+	initialRestarts := podRestarts(pod)
+	restarted := func(p *corev1.Pod) bool {
+		return !podReady(p) || podRestarts(p) != initialRestarts
+	}
+	resourceVersion := pod.ResourceVersion
+	var backoff time.Duration
+	for {
+		watcher, err := c.Clientset.CoreV1().Pods(pod.Namespace).Watch(metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", pod.Name).String(),
+			ResourceVersion: resourceVersion,
+		})
+		if err == nil {
+			var done bool
+			done, err = watchPodEvents(watcher, &resourceVersion, restarted, stop)
+			if done {
+				return
+			}
+		}
+		backoff = nextBackoff(backoff)
+		log.Debug.logf("Watching pod %s failed, watching it again in %v: %v", pod.Name, backoff, err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+		// the pod may have changed while it was not watched
+		current, err := c.Clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && restarted(current)) {
+			return
+		}
+		if err == nil {
+			resourceVersion = current.ResourceVersion
+		}
+	}
+}
+
+// watchPodEvents reads the events of a pod watch. It returns true if the pod restarted or stop is closed,
+// or an error if the watch fails or ends, keeping the resource version of the last event to watch again from.
+func watchPodEvents(watcher watch.Interface, resourceVersion *string, restarted func(*corev1.Pod) bool, stop <-chan struct{}) (bool, error) {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-stop:
+			return true, nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, errors.New("the watch ended")
+			}
+			switch event.Type {
+			case watch.Deleted:
+				return true, nil
+			case watch.Error:
+				return false, apierrors.FromObject(event.Object)
+			}
+			if updated, ok := event.Object.(*corev1.Pod); ok {
+				if restarted(updated) {
+					return true, nil
+				}
+				*resourceVersion = updated.ResourceVersion
+			}
+		}
+	}
+}
+
+// forwardToPod forwards the local port to the pod until stopCh or podDone is closed, or the connection is lost.
+// It returns the local port, which is chosen by the system if local is 0.
+func forwardToPod(config *RootCommandConfig, client *KubeClient, pod *corev1.Pod, local int, remote int, stopCh <-chan struct{}, podDone <-chan struct{}, ready func(int)) error {
+	if client.RestConfig == nil {
+		return errors.New("Port forwarding needs a connection to a Kubernetes API server")
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(client.RestConfig)
+	if err != nil {
+		return err
+	}
+	url := client.Clientset.CoreV1().RESTClient().Post().Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	forwardStop := make(chan struct{})
+	readyCh := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, []string{fmt.Sprintf("%d:%d", local, remote)}, forwardStop, readyCh, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- forwarder.ForwardPorts()
+	}()
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-readyCh:
+			ports, err := forwarder.GetPorts()
+			if err == nil && len(ports) > 0 {
+				ready(int(ports[0].Local))
+			}
+			readyCh = nil
+		case <-podDone:
+			config.Info.logf("Pod %s restarted or stopped, reconnecting", pod.Name)
+			close(forwardStop)
+			return <-errCh
+		case <-stopCh:
+			close(forwardStop)
+			return <-errCh
+		}
+	}
+}
+
+// deployPortForward forwards a local port to a ready pod of the application until Ctrl-C is pressed,
+// moving to another pod if the pod restarts or the connection is lost
+func deployPortForward(config *RootCommandConfig, name string, namespace string, local int, remote int) error {
+	if config.Dryrun {
+		config.Info.logf("Dry run - skipping port forward of localhost:%d to port %d of %s", local, remote, name)
+		return nil
+	}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return err
+	}
+
+	stopCh := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stopCh)
+	}()
+
+	announced := false
+	var backoff time.Duration
+	for {
+		pod, err := waitForReadyPod(config, client, name, namespace, stopCh)
+		if err != nil {
+			return errors.Errorf("Could not find a pod of %s: %v", name, err)
+		}
+		if pod == nil {
+			return nil
+		}
+		config.Debug.logf("Forwarding to pod %s", pod.Name)
+		podDone := make(chan struct{})
+		watchStop := make(chan struct{})
+		go client.WatchPodRestart(config.LoggingConfig, pod, podDone, watchStop)
+		connected := false
+		err = forwardToPod(config, client, pod, local, remote, stopCh, podDone, func(port int) {
+			// keep the same local port when reconnecting
+			local = port
+			connected = true
+			if !announced {
+				config.Info.logf("Forwarding http://localhost:%d to port %d of %s. Press Ctrl-C to stop", local, remote, name)
+				announced = true
+			}
+		})
+		close(watchStop)
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+		if connected {
+			backoff = 0
+		}
+		if err != nil {
+			if !announced {
+				return errors.Errorf("Could not forward port %d: %v", local, err)
+			}
+			// wait longer after each failure to connect, so that an unreachable cluster is not retried in a tight loop
+			backoff = nextBackoff(backoff)
+			config.Warning.logf("Port forward failed, reconnecting in %v: %v", backoff, err)
+			select {
+			case <-stopCh:
+				return nil
+			case <-time.After(backoff):
+			}
+		}
+	}
+}
+
+func newDeployPortForwardCmd(config *deployCommandConfig) *cobra.Command {
+	var portForwardCmd = &cobra.Command{
+		Use:   "port-forward [local:remote]",
+		Short: "Forward a local port to your deployed Appsody project.",
+		Long: `Forward a local port to a ready pod of your deployed Appsody project, using your existing deployment manifest.
+
+By default, the service port in the deployment manifest is forwarded to the same local port. If the pod restarts, the port is forwarded to a ready pod again. The command runs until you press Ctrl-C.
+
+Run this command from the root directory of your Appsody project.`,
+		Example: `  appsody deploy port-forward
+  Forwards the service port of the application in the "app-deploy.yaml" deployment manifest to the same local port.
+
+  appsody deploy port-forward 9000:3000
+  Forwards local port 9000 to port 3000 of the application.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			spec := portForwardDefault
+			if len(args) == 1 {
+				spec = args[0]
+			}
+			deploymentManifest, namespace, err := getDeployedManifest(config)
+			if err != nil {
+				return err
+			}
+			local, remote, err := ParsePortForwardSpec(spec, getServicePort(deploymentManifest))
+			if err != nil {
+				return err
+			}
+			return deployPortForward(config.RootCommandConfig, deploymentManifest.Name, namespace, local, remote)
+		},
+	}
+	return portForwardCmd
+}
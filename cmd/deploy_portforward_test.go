@@ -0,0 +1,135 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestParsePortForwardSpec(t *testing.T) {
+	var portForwardTests = []struct {
+		spec           string
+		servicePort    int
+		expectedLocal  int
+		expectedRemote int
+		expectError    bool
+	}{
+		{"default", 3000, 3000, 3000, false},
+		{"", 8080, 8080, 8080, false},
+		{"9000", 3000, 9000, 3000, false},
+		{"9000:8080", 3000, 9000, 8080, false},
+		{":8080", 0, 8080, 8080, false},
+		{"0:8080", 0, 0, 8080, false},
+		{"default", 0, 0, 0, true},
+		{"abc", 3000, 0, 0, true},
+		{"9000:abc", 3000, 0, 0, true},
+		{"9000:70000", 3000, 0, 0, true},
+	}
+	for _, testData := range portForwardTests {
+		tt := testData
+		t.Run(tt.spec, func(t *testing.T) {
+			local, remote, err := cmd.ParsePortForwardSpec(tt.spec, tt.servicePort)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if local != tt.expectedLocal || remote != tt.expectedRemote {
+				t.Errorf("Expected %d:%d but got %d:%d", tt.expectedLocal, tt.expectedRemote, local, remote)
+			}
+		})
+	}
+}
+
+func newAppPod(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app.kubernetes.io/name": "my-app"}},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+func TestReadyPod(t *testing.T) {
+	client := cmdtest.NewFakeKubeClient(
+		newAppPod("my-app-pending", corev1.PodPending, corev1.ConditionFalse),
+		newAppPod("my-app-starting", corev1.PodRunning, corev1.ConditionFalse),
+	)
+	pod, err := client.ReadyPod("my-app", "default")
+	if err != nil || pod != nil {
+		t.Errorf("Expected no ready pod, got %v %v", pod, err)
+	}
+
+	_, err = client.Clientset.CoreV1().Pods("default").Create(newAppPod("my-app-ready", corev1.PodRunning, corev1.ConditionTrue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, err = client.ReadyPod("my-app", "default")
+	if err != nil || pod == nil || pod.Name != "my-app-ready" {
+		t.Errorf("Expected pod my-app-ready, got %v %v", pod, err)
+	}
+}
+
+func TestWatchPodRestart(t *testing.T) {
+	pod := newAppPod("my-app-ready", corev1.PodRunning, corev1.ConditionTrue)
+	client := cmdtest.NewFakeKubeClient(pod)
+	watchers := make(chan *watch.FakeWatcher, 2)
+	client.Clientset.(*kubefake.Clientset).PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFake()
+		watchers <- watcher
+		return true, watcher, nil
+	})
+	var outBuffer bytes.Buffer
+	log := &cmd.LoggingConfig{}
+	log.InitLogging(&outBuffer, &outBuffer)
+
+	podDone := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go client.WatchPodRestart(log, pod, podDone, stop)
+
+	// a failed watch is watched again, instead of being taken as a restart of the pod
+	(<-watchers).Error(&metav1.Status{Status: metav1.StatusFailure, Message: "too old resource version", Code: 410})
+	select {
+	case <-podDone:
+		t.Fatal("Expected the pod to be watched again after the watch failed")
+	case watcher := <-watchers:
+		updated := pod.DeepCopy()
+		updated.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "my-app", RestartCount: 1}}
+		watcher.Modify(updated)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the pod to be watched again after the watch failed")
+	}
+	select {
+	case <-podDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the restart of a container to be reported")
+	}
+}
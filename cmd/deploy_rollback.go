@@ -0,0 +1,135 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+func newDeployRollbackCmd(config *deployCommandConfig) *cobra.Command {
+	var toRevision int
+	var rollbackCmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back your deployed Appsody project to a previous deployment.",
+		Long: `Roll back your deployed Appsody project by re-applying a deployment manifest recorded in the deployment history.
+
+The recorded manifest is applied to the namespace it was originally deployed to. If the image digest was recorded, the image is pinned to that digest. The image is not rebuilt and your "app-deploy.yaml" file is not modified.
+
+Run this command from the root directory of your Appsody project.`,
+		Example: `  appsody deploy rollback
+  Re-applies the deployment before the latest one.
+
+  appsody deploy rollback --to 3
+  Re-applies revision 3 from the deployment history, as listed by "appsody deploy history".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			projectDir, err := getProjectDir(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			var history DeploymentHistory
+			err = history.ReadFile(getDeployHistoryPath(projectDir))
+			if err != nil {
+				return err
+			}
+			if len(history.Entries) == 0 {
+				return errors.New("There is no deployment history for this project. Use 'appsody deploy' to deploy your project first")
+			}
+
+			var entry *DeploymentHistoryEntry
+			if toRevision > 0 {
+				entry = history.GetRevision(toRevision)
+				if entry == nil {
+					return errors.Errorf("Revision %d was not found in the deployment history", toRevision)
+				}
+			} else {
+				entry = history.Previous()
+				if entry == nil {
+					return errors.New("There is no previous deployment to roll back to")
+				}
+			}
+			return deployRollback(config, projectDir, entry)
+		},
+	}
+
+	rollbackCmd.PersistentFlags().IntVar(&toRevision, "to", 0, "The revision to roll back to. Defaults to the deployment before the latest one.")
+	return rollbackCmd
+}
+
+func deployRollback(config *deployCommandConfig, projectDir string, entry *DeploymentHistoryEntry) error {
+	manifest, err := rollbackManifest(entry)
+	if err != nil {
+		return err
+	}
+
+	manifestFile, err := ioutil.TempFile("", "appsody-rollback-*.yaml")
+	if err != nil {
+		return errors.Errorf("Error creating temporary file: %v", err)
+	}
+	defer os.Remove(manifestFile.Name())
+	_, err = manifestFile.Write(manifest)
+	closeErr := manifestFile.Close()
+	if err != nil || closeErr != nil {
+		return errors.Errorf("Failed to write the deployment manifest for revision %d: %v %v", entry.Revision, err, closeErr)
+	}
+
+	config.Info.logf("Rolling back to revision %d in namespace %s", entry.Revision, entry.Namespace)
+	err = KubeApply(config.RootCommandConfig, manifestFile.Name(), entry.Namespace, config.Dryrun)
+	if err != nil {
+		return errors.Errorf("Failed to roll back your Kubernetes deployment: %v", err)
+	}
+	if config.Dryrun {
+		config.Info.log("Dry run complete")
+		return nil
+	}
+
+	rollbackEntry := newDeployHistoryEntry(config.RootCommandConfig, manifest, entry.Namespace, entry.Image, entry.ImageDigest)
+	rollbackEntry.Commit = entry.Commit
+	rollbackEntry.RollbackOf = entry.Revision
+	err = appendDeployHistory(config.RootCommandConfig, projectDir, rollbackEntry)
+	if err != nil {
+		config.Warning.log("Could not record the rollback in the deployment history: ", err)
+	}
+	config.Info.logf("Rolled back to revision %d", entry.Revision)
+	return nil
+}
+
+// rollbackManifest returns the recorded manifest, with the application image pinned to the recorded digest
+func rollbackManifest(entry *DeploymentHistoryEntry) ([]byte, error) {
+	if entry.ImageDigest == "" {
+		return []byte(entry.Manifest), nil
+	}
+	var deploymentManifest DeploymentManifest
+	err := yaml.Unmarshal([]byte(entry.Manifest), &deploymentManifest)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the deployment manifest for revision %d: %v", entry.Revision, err)
+	}
+	if deploymentManifest.Spec == nil {
+		deploymentManifest.Spec = make(map[string]interface{})
+	}
+	deploymentManifest.Spec["applicationImage"] = ImageWithDigest(entry.Image, entry.ImageDigest)
+	output, err := yaml.Marshal(deploymentManifest)
+	if err != nil {
+		return nil, errors.Errorf("Could not marshall the deployment manifest for revision %d: %v", entry.Revision, err)
+	}
+	return output, nil
+}
@@ -0,0 +1,129 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDeployStatusCmd(config *deployCommandConfig) *cobra.Command {
+	var statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of your deployed Appsody project.",
+		Long: `Show the status of your deployed Appsody project in your Kubernetes cluster, using your existing deployment manifest.
+
+For a Knative service, the revisions that are receiving traffic are listed with their share of the traffic.
+
+Run this command from the root directory of your Appsody project.`,
+		Example: `  appsody deploy status
+  Shows the status of the application in the "app-deploy.yaml" deployment manifest.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			deploymentManifest, namespace, err := getDeployedManifest(config)
+			if err != nil {
+				return err
+			}
+			if config.Dryrun {
+				config.Info.log("Dry run - skipping status of ", deploymentManifest.Name)
+				return nil
+			}
+			client, err := getKubeClient(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			var status string
+			if isKnativeManifest(deploymentManifest) {
+				status, err = knativeServiceStatus(client, deploymentManifest.Name, namespace)
+			} else {
+				status, err = deploymentStatus(client, deploymentManifest.Name, namespace)
+			}
+			if err != nil {
+				return err
+			}
+			config.Info.log("\n", status)
+			return nil
+		},
+	}
+	return statusCmd
+}
+
+func deploymentStatus(client *KubeClient, name string, namespace string) (string, error) {
+	deployment, err := client.Clientset.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", errors.Errorf("Deployment %s was not found in namespace %s", name, namespace)
+	}
+	if err != nil {
+		return "", err
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	table := uitable.New()
+	table.AddRow("Name:", name)
+	table.AddRow("Namespace:", namespace)
+	table.AddRow("Ready:", fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, replicas))
+	table.AddRow("Available:", deploymentAvailable(deployment))
+	return table.String(), nil
+}
+
+func knativeServiceStatus(client *KubeClient, name string, namespace string) (string, error) {
+	service, err := client.GetKnativeService(name, namespace)
+	if apierrors.IsNotFound(err) {
+		return "", errors.Errorf("Knative service %s was not found in namespace %s", name, namespace)
+	}
+	if err != nil {
+		return "", err
+	}
+	return FormatKnativeStatus(service), nil
+}
+
+// FormatKnativeStatus describes a Knative service and its traffic split
+func FormatKnativeStatus(service *unstructured.Unstructured) string {
+	url, _, _ := unstructured.NestedString(service.Object, "status", "url")
+	latestReady, _, _ := unstructured.NestedString(service.Object, "status", "latestReadyRevisionName")
+	latestCreated, _, _ := unstructured.NestedString(service.Object, "status", "latestCreatedRevisionName")
+
+	summary := uitable.New()
+	summary.AddRow("Name:", service.GetName())
+	summary.AddRow("Namespace:", service.GetNamespace())
+	summary.AddRow("URL:", url)
+	summary.AddRow("Latest created revision:", latestCreated)
+	summary.AddRow("Latest ready revision:", latestReady)
+
+	traffic := uitable.New()
+	traffic.AddRow("REVISION", "TAG", "PERCENT")
+	for _, target := range KnativeTraffic(service) {
+		revision := target.RevisionName
+		if revision == "" && target.LatestRevision {
+			revision = "@latest"
+			if latestReady != "" {
+				revision += " (" + latestReady + ")"
+			}
+		}
+		traffic.AddRow(revision, target.Tag, fmt.Sprintf("%d%%", target.Percent))
+	}
+	return strings.Join([]string{summary.String(), "", "Traffic:", traffic.String()}, "\n")
+}
@@ -142,6 +142,8 @@ func addDevCommonFlags(cmd *cobra.Command, config *devCommonConfig) {
 	cmd.PersistentFlags().BoolVar(&config.disableWatcher, "no-watcher", false, "Disable file watching, regardless of container environment variable settings.")
 	cmd.PersistentFlags().BoolVarP(&config.interactive, "interactive", "i", false, "Attach STDIN to the container for interactive TTY mode")
 	cmd.PersistentFlags().StringVar(&config.dockerOptions, "docker-options", "", "Specify the docker run options to use.  Value must be in \"\". The following Docker options are not supported:  '--help','-p','--publish-all','-P','-u','-—user','-—name','-—network','-t','-—tty,'—rm','—entrypoint', '--mount'.")
+	// the cluster is used when APPSODY_K8S_EXPERIMENTAL is set
+	addKubeFlags(cmd, config.RootCommandConfig)
 }
 
 func commonCmd(config *devCommonConfig, mode string) error {
@@ -420,7 +422,7 @@ func commonCmd(config *devCommonConfig, mode string) error {
 		//hack
 		namespace := ""
 		//endhack
-		err = KubeApply(config.LoggingConfig, deploymentYaml, namespace, dryrun)
+		err = KubeApply(config.RootCommandConfig, deploymentYaml, namespace, dryrun)
 		if err != nil {
 			return err
 		}
@@ -429,7 +431,7 @@ func commonCmd(config *devCommonConfig, mode string) error {
 			return err
 		}
 
-		err = KubeApply(config.LoggingConfig, serviceYaml, namespace, dryrun)
+		err = KubeApply(config.RootCommandConfig, serviceYaml, namespace, dryrun)
 		if err != nil {
 			return err
 		}
@@ -437,12 +439,12 @@ func commonCmd(config *devCommonConfig, mode string) error {
 			port := getIngressPort(config.RootCommandConfig)
 			// Generate the Ingress only if it makes sense - i.e. there's a port to expose
 			if port > 0 {
-				routeYaml, err := GenRouteYaml(config.LoggingConfig, config.containerName, projectDir, port, dryrun)
+				routeYaml, err := GenRouteYaml(config.RootCommandConfig, config.containerName, projectDir, port, dryrun)
 				if err != nil {
 					return err
 				}
 
-				err = KubeApply(config.LoggingConfig, routeYaml, namespace, dryrun)
+				err = KubeApply(config.RootCommandConfig, routeYaml, namespace, dryrun)
 				if err != nil {
 					return err
 				}
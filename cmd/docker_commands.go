@@ -75,7 +75,15 @@ func RunDockerVolumeList(log *LoggingConfig, volName string) (string, error) {
 }
 func RunKubeCommandAndListen(config *RootCommandConfig, args []string, logger appsodylogger, interactive bool) (*exec.Cmd, error) {
 	command := "kubectl"
-	return RunCommandAndListen(config, command, args, logger, interactive)
+	// kubectl uses the same cluster as the Kubernetes client, as selected by --kubeconfig and --context
+	var kubeArgs []string
+	if config.KubeConfigFile != "" {
+		kubeArgs = append(kubeArgs, "--kubeconfig", config.KubeConfigFile)
+	}
+	if config.KubeContext != "" {
+		kubeArgs = append(kubeArgs, "--context", config.KubeContext)
+	}
+	return RunCommandAndListen(config, command, append(kubeArgs, args...), logger, interactive)
 }
 func RunDockerCommandAndListen(config *RootCommandConfig, args []string, logger appsodylogger, interactive bool) (*exec.Cmd, error) {
 	command := "docker"
@@ -0,0 +1,218 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	"github.com/pkg/errors"
+)
+
+// DockerfileVariable is an environment variable of a build stage, with its value after ARG and ENV substitution
+type DockerfileVariable struct {
+	Name  string
+	Value string
+	// StartLine and EndLine are the lines of the ENV instruction that sets the variable,
+	// or of the FROM instruction of the stage if the variable is inherited from the base image
+	StartLine int
+	EndLine   int
+	// Inherited is set for variables of the base image, which are not set in the Dockerfile
+	Inherited bool
+}
+
+// DockerfileStage is a build stage of a Dockerfile, from its FROM instruction to the next one
+type DockerfileStage struct {
+	// Name is the name given with FROM <image> AS <name>, if any
+	Name string
+	// BaseImage is the image of the FROM instruction, with the global ARGs substituted
+	BaseImage string
+	// BaseStage is the earlier stage that the stage starts from, if BaseImage is the name of one
+	BaseStage *DockerfileStage
+	// Line is the line of the FROM instruction
+	Line int
+	// Env is the environment of the stage at its end, including the variables of its base image or stage
+	Env map[string]DockerfileVariable
+	// Set are the variables set by the ENV instructions of the stage itself, in order
+	Set []DockerfileVariable
+}
+
+// Dockerfile is the result of evaluating the FROM, ARG and ENV instructions of a Dockerfile
+type Dockerfile struct {
+	Stages []*DockerfileStage
+}
+
+// FinalStage returns the last stage of the Dockerfile, which is the stage that is built into the image
+func (d *Dockerfile) FinalStage() *DockerfileStage {
+	return d.Stages[len(d.Stages)-1]
+}
+
+// InImage returns whether the ENV instructions of the stage reach the image,
+// which is the case for the final stage and the stages that it is built from
+func (d *Dockerfile) InImage(stage *DockerfileStage) bool {
+	for s := d.FinalStage(); s != nil; s = s.BaseStage {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// DockerfileError is an error in an instruction of a Dockerfile
+type DockerfileError struct {
+	Line    int
+	Message string
+}
+
+func (e *DockerfileError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ReadDockerfile parses and evaluates a Dockerfile. baseImageEnv returns the environment variables
+// of a base image that is not a stage of the Dockerfile. It can be nil if they are not needed.
+func ReadDockerfile(file string, baseImageEnv func(image string) (map[string]string, error)) (*Dockerfile, error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Errorf("Could not read %s: %v", file, err)
+	}
+	defer reader.Close()
+	dockerfile, err := ParseDockerfile(reader, baseImageEnv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse %s", file)
+	}
+	return dockerfile, nil
+}
+
+// ParseDockerfile parses a Dockerfile with the buildkit parser, and evaluates the environment of each of its
+// stages the way a build does: ARGs before the first FROM are only visible to FROM instructions and to ARGs of
+// a stage that declare them again, ARG defaults and earlier variables are substituted into ENV values, and a stage
+// that starts from an earlier stage inherits its environment.
+func ParseDockerfile(reader io.Reader, baseImageEnv func(image string) (map[string]string, error)) (*Dockerfile, error) {
+	result, err := parser.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+	lex := shell.NewLex(result.EscapeToken)
+	dockerfile := &Dockerfile{}
+	globalArgs := make(map[string]string)
+	var stage *DockerfileStage
+	var args map[string]string
+
+	for _, node := range result.AST.Children {
+		switch node.Value {
+		case "from":
+			if node.Next == nil {
+				return nil, &DockerfileError{Line: node.StartLine, Message: "FROM requires an image"}
+			}
+			image, err := lex.ProcessWordWithMap(node.Next.Value, globalArgs)
+			if err != nil {
+				return nil, &DockerfileError{Line: node.StartLine, Message: err.Error()}
+			}
+			stage = &DockerfileStage{BaseImage: image, Line: node.StartLine, Env: make(map[string]DockerfileVariable)}
+			if as := node.Next.Next; as != nil && strings.EqualFold(as.Value, "as") && as.Next != nil {
+				stage.Name = as.Next.Value
+			}
+			for _, earlier := range dockerfile.Stages {
+				if earlier.Name != "" && strings.EqualFold(earlier.Name, image) {
+					stage.BaseStage = earlier
+				}
+			}
+			if stage.BaseStage != nil {
+				for name, variable := range stage.BaseStage.Env {
+					stage.Env[name] = variable
+				}
+			} else if baseImageEnv != nil && image != "scratch" {
+				env, err := baseImageEnv(image)
+				if err != nil {
+					return nil, err
+				}
+				for name, value := range env {
+					stage.Env[name] = DockerfileVariable{Name: name, Value: value, StartLine: node.StartLine, EndLine: node.EndLine, Inherited: true}
+				}
+			}
+			dockerfile.Stages = append(dockerfile.Stages, stage)
+			args = make(map[string]string)
+
+		case "arg":
+			for word := node.Next; word != nil; word = word.Next {
+				parts := strings.SplitN(word.Value, "=", 2)
+				if stage == nil {
+					if len(parts) == 2 {
+						value, err := lex.ProcessWordWithMap(parts[1], globalArgs)
+						if err != nil {
+							return nil, &DockerfileError{Line: node.StartLine, Message: err.Error()}
+						}
+						globalArgs[parts[0]] = value
+					}
+					continue
+				}
+				if len(parts) == 2 {
+					value, err := lex.ProcessWordWithMap(parts[1], stage.variables(args))
+					if err != nil {
+						return nil, &DockerfileError{Line: node.StartLine, Message: err.Error()}
+					}
+					args[parts[0]] = value
+				} else if value, ok := globalArgs[parts[0]]; ok {
+					args[parts[0]] = value
+				}
+			}
+
+		case "env":
+			if stage == nil {
+				return nil, &DockerfileError{Line: node.StartLine, Message: "ENV is not allowed before the first FROM"}
+			}
+			// every value of an ENV instruction is substituted with the environment from before the instruction
+			variables := stage.variables(args)
+			for key := node.Next; key != nil && key.Next != nil; key = key.Next.Next {
+				name, err := lex.ProcessWordWithMap(key.Value, variables)
+				if err != nil {
+					return nil, &DockerfileError{Line: node.StartLine, Message: err.Error()}
+				}
+				value, err := lex.ProcessWordWithMap(key.Next.Value, variables)
+				if err != nil {
+					return nil, &DockerfileError{Line: node.StartLine, Message: err.Error()}
+				}
+				variable := DockerfileVariable{Name: name, Value: value, StartLine: node.StartLine, EndLine: node.EndLine}
+				stage.Env[name] = variable
+				stage.Set = append(stage.Set, variable)
+			}
+		}
+	}
+	if len(dockerfile.Stages) == 0 {
+		return nil, errors.New("There is no FROM instruction")
+	}
+	return dockerfile, nil
+}
+
+// variables returns the values that are substituted into instructions of the stage, where ENV overrides ARG
+func (s *DockerfileStage) variables(args map[string]string) map[string]string {
+	variables := make(map[string]string, len(args)+len(s.Env))
+	for name, value := range args {
+		variables[name] = value
+	}
+	for name, variable := range s.Env {
+		variables[name] = variable.Value
+	}
+	return variables
+}
+
+// EnvValues returns the names and values of the environment of the stage
+func (s *DockerfileStage) EnvValues() map[string]string {
+	return s.variables(nil)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/appsody/appsody/cmd"
+	"github.com/pkg/errors"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		dockerfile string
+		expected   map[string]string
+	}{
+		{"Name value pairs", "FROM ubi\nENV A=1 B=\"two words\" \\\n    C='$A'\n", map[string]string{"A": "1", "B": "two words", "C": "$A"}},
+		{"Old form", "FROM ubi\nENV APPSODY_RUN npm start --inspect\n", map[string]string{"APPSODY_RUN": "npm start --inspect"}},
+		{"Substitution", "FROM ubi\nENV A=1\nENV B=$A C=${A}-2 A=3\nENV D=${A:+set}\n", map[string]string{"A": "3", "B": "1", "C": "1-2", "D": "set"}},
+		{"ARG defaults", "FROM ubi\nARG PORT=3000\nARG DIR\nENV APPSODY_DEBUG_PORT=$PORT APPSODY_DIR=/project$DIR\n", map[string]string{"APPSODY_DEBUG_PORT": "3000", "APPSODY_DIR": "/project"}},
+		{"ENV overrides ARG", "FROM ubi\nARG A=arg\nENV A=env\nENV B=$A\n", map[string]string{"A": "env", "B": "env"}},
+		{"Global ARG", "ARG VERSION=12\nARG UNUSED=1\nFROM node:$VERSION\nARG VERSION\nENV NODE=$VERSION UNUSED=$UNUSED\n", map[string]string{"NODE": "12", "UNUSED": ""}},
+		{"Final stage", "FROM ubi AS build\nENV A=build B=build\nFROM ubi\nENV A=final\n", map[string]string{"A": "final"}},
+		{"Stage inheritance", "FROM ubi AS base\nENV A=base B=base\nFROM ubi AS build\nENV C=build\nFROM base\nENV A=final\n", map[string]string{"A": "final", "B": "base"}},
+		{"Escape directive", "# escape=`\nFROM ubi\nENV A=one `\n    B=two\n", map[string]string{"A": "one", "B": "two"}},
+		{"Comments in continuations", "FROM ubi\nENV A=1 \\\n# a comment\n    B=2\n", map[string]string{"A": "1", "B": "2"}},
+	}
+	for _, testData := range tests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			dockerfile, err := cmd.ParseDockerfile(strings.NewReader(tt.dockerfile), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := dockerfile.FinalStage().EnvValues()
+			if !reflect.DeepEqual(env, tt.expected) {
+				t.Errorf("Expected the environment %v, got %v", tt.expected, env)
+			}
+		})
+	}
+}
+
+func TestParseDockerfileStages(t *testing.T) {
+	dockerfile, err := cmd.ParseDockerfile(strings.NewReader(`# a multi-stage Dockerfile
+ARG BASE=ubi
+FROM $BASE AS build
+ENV APPSODY_RUN=build
+
+FROM node:12 as base
+ENV APPSODY_DEPS=/project/deps \
+    APPSODY_PATH=$PATH:/project
+
+FROM base
+ENV APPSODY_MOUNTS=.:/project/user-app
+`), func(image string) (map[string]string, error) {
+		if image != "node:12" {
+			return nil, nil
+		}
+		return map[string]string{"PATH": "/usr/bin", "NODE_VERSION": "12.13.0"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dockerfile.Stages) != 3 {
+		t.Fatalf("Expected 3 stages, got %d", len(dockerfile.Stages))
+	}
+	build, base, final := dockerfile.Stages[0], dockerfile.Stages[1], dockerfile.Stages[2]
+	if build.Name != "build" || build.BaseImage != "ubi" || build.Line != 3 {
+		t.Errorf("Expected the build stage to start from ubi on line 3, got %+v", build)
+	}
+	if final.BaseStage != base || final.Line != 10 {
+		t.Errorf("Expected the final stage to start from the base stage on line 10, got %+v", final)
+	}
+	if dockerfile.InImage(build) || !dockerfile.InImage(base) || !dockerfile.InImage(final) {
+		t.Error("Expected the base and final stages to be in the image, and the build stage not to be")
+	}
+
+	deps := final.Env["APPSODY_DEPS"]
+	if deps.StartLine != 7 || deps.EndLine != 8 || deps.Inherited {
+		t.Errorf("Expected APPSODY_DEPS to be set on lines 7 to 8, got %+v", deps)
+	}
+	if path := final.Env["APPSODY_PATH"]; path.Value != "/usr/bin:/project" {
+		t.Errorf("Expected APPSODY_PATH to extend the PATH of the base image, got %+v", path)
+	}
+	if version := final.Env["NODE_VERSION"]; !version.Inherited || version.Value != "12.13.0" || version.StartLine != 6 {
+		t.Errorf("Expected NODE_VERSION to be inherited from the base image on line 6, got %+v", version)
+	}
+	if len(final.Set) != 1 || final.Set[0].Name != "APPSODY_MOUNTS" || final.Set[0].StartLine != 11 {
+		t.Errorf("Expected the final stage to set APPSODY_MOUNTS on line 11, got %+v", final.Set)
+	}
+	if _, ok := final.Env["APPSODY_RUN"]; ok {
+		t.Error("Expected APPSODY_RUN of the build stage not to be in the final stage")
+	}
+}
+
+func TestParseDockerfileErrors(t *testing.T) {
+	var tests = []struct {
+		testName   string
+		dockerfile string
+		line       int
+		expected   string
+	}{
+		{"No FROM", "# no instructions\nENV A=1\n", 2, "ENV is not allowed before the first FROM"},
+		{"No FROM at all", "ARG A=1\n", 0, "There is no FROM instruction"},
+		{"Bad substitution", "FROM ubi\nENV A=${B\n", 2, "failed to process"},
+		{"Missing equals", "FROM ubi\nENV A=1 #comment\n", 0, "Syntax error"},
+	}
+	for _, testData := range tests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			_, err := cmd.ParseDockerfile(strings.NewReader(tt.dockerfile), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("Expected an error with %s, got %v", tt.expected, err)
+			}
+			line := 0
+			if dockerfileErr, ok := errors.Cause(err).(*cmd.DockerfileError); ok {
+				line = dockerfileErr.Line
+			}
+			if line != tt.line {
+				t.Errorf("Expected the error to be on line %d, got %d", tt.line, line)
+			}
+		})
+	}
+}
@@ -33,10 +33,11 @@ import (
 
 type initCommandConfig struct {
 	*RootCommandConfig
-	overwrite       bool
-	noTemplate      bool
-	projectName     string
-	applicationName string
+	overwrite          bool
+	noTemplate         bool
+	projectName        string
+	applicationName    string
+	insecureSkipVerify bool
 }
 
 // these are global constants
@@ -55,7 +56,9 @@ func newInitCmd(rootConfig *RootCommandConfig) *cobra.Command {
 By default, the command creates an Appsody stack configuration file and provides a simple default application. You can also initialize a project with a different template application, or no template. 
 
 To initialize a project with a template application, in a directory that is not empty, you need to specify the "overwrite" option [--overwrite].
-Use 'appsody list' to see the available stacks and templates.`,
+Use 'appsody list' to see the available stacks and templates.
+
+If the repository has several versions of the stack, the newest version is used. To use another version, add a version or a semver range to the stack, such as <stack>@1.4, which is the newest 1.4.x version, or <stack>@~1.4.2. Use 'appsody list --all-versions' to see the available versions.`,
 		Example: `  appsody init nodejs-express
   Initializes a project with the default template from the "nodejs-express" stack in the default repository.
   
@@ -68,6 +71,12 @@ Use 'appsody list' to see the available stacks and templates.`,
   appsody init nodejs none
   Initializes a project without a template for the "nodejs" stack in the default repository.
 
+  appsody init incubator/java-microprofile@0.2
+  Initializes a project with the default template from the newest 0.2.x version of the "java-microprofile" stack in the "incubator" repository.
+
+  appsody init incubator/nodejs@">=0.2.0 <0.3.0"
+  Initializes a project with the default template from the newest version of the "nodejs" stack in the "incubator" repository that matches the semver range.
+
   appsody init
   Runs the stack init script to set up the local development environment on an existing Appsody project.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -93,6 +102,7 @@ Use 'appsody list' to see the available stacks and templates.`,
 	defaultName := defaultProjectName(rootConfig)
 	initCmd.PersistentFlags().StringVar(&config.projectName, "project-name", defaultName, "Project Name for Kubernetes Service.")
 	initCmd.PersistentFlags().StringVar(&config.applicationName, "application-name", "", "Specifies the greater application which this project belongs to.")
+	initCmd.PersistentFlags().BoolVar(&config.insecureSkipVerify, "insecure-skip-verify", false, "Extract the template project even if it does not match the checksum in the repository index.")
 	return initCmd
 }
 
@@ -127,7 +137,7 @@ func initAppsody(stack string, template string, config *initCommandConfig) error
 
 	//err = index.getIndex()
 
-	indices, err := repos.GetIndices(config.LoggingConfig)
+	indices, err := repos.GetIndices(config.RootCommandConfig)
 
 	if err != nil {
 		config.Error.logf("Does the APIVersion of your repository match what the Appsody CLI currently supports? (%v). The following indices could not be read. skipping:\n%v", supportedIndexAPIVersion, err)
@@ -139,7 +149,10 @@ func initAppsody(stack string, template string, config *initCommandConfig) error
 
 	if stack != "" {
 		var projectName string
-		projectParm := stack
+		projectParm, stackVersion, err := SplitStackVersion(stack)
+		if err != nil {
+			return err
+		}
 
 		repoName, projectType, err := parseProjectParm(projectParm, config.RootCommandConfig)
 		if err != nil {
@@ -163,35 +176,35 @@ func initAppsody(stack string, template string, config *initCommandConfig) error
 
 		config.Debug.log("Attempting to locate stack ", projectType, " in repo ", repoName)
 		index = indices[repoName]
-		stackFound := false
 		var stackReqs StackRequirement
+		var templateChecksum string
+		var templateSize int64
 
-		if strings.Compare(index.APIVersion, supportedIndexAPIVersion) == 1 {
-			config.Warning.log("The repository .yaml for " + repoName + " has a more recent APIVersion than the current Appsody CLI supports (" + supportedIndexAPIVersion + "), it is strongly suggested that you update your Appsody CLI to the latest version.")
+		if isNewerIndexAPIVersion(index.APIVersion) {
+			config.Warning.log("The repository .yaml for " + repoName + " has a more recent APIVersion than the current Appsody CLI supports (" + multiVersionIndexAPIVersion + "), it is strongly suggested that you update your Appsody CLI to the latest version.")
 		}
 		if index.APIVersion == "v1" {
 			return errors.Errorf("The repository .yaml for " + repoName + " has an older APIVersion that the Appsody CLI no longer supports. Supported APIVersion: " + supportedIndexAPIVersion)
 		}
-		for _, stack := range index.Stacks {
-			if stack.ID == projectType {
-				stackReqs = stack.Requirements
-				stackFound = true
-				config.Debug.log("Stack ", projectType, " found in repo ", repoName)
-				URL := ""
-				if templateName == "" || templateName == "none" {
-					templateName = stack.DefaultTemplate
-					if templateName == "" {
-						return errors.Errorf("Cannot proceed, no template or \"none\" was specified and there is no default template.")
-					}
-				}
-				URL = findTemplateURL(stack, templateName)
-
-				projectName = URL
-			}
+		stackEntry, err := FindStackVersion(index, projectType, stackVersion)
+		if err != nil {
+			return err
 		}
-		if !stackFound {
+		if stackEntry == nil {
 			return errors.Errorf("Could not find a stack with the id \"%s\" in repository \"%s\". Run `appsody list` to see the available stacks or -h for help.", projectType, repoName)
 		}
+		stackReqs = stackEntry.Requirements
+		config.Debug.log("Stack ", projectType, " version ", stackEntry.Version, " found in repo ", repoName)
+		if templateName == "" || templateName == "none" {
+			templateName = stackEntry.DefaultTemplate
+			if templateName == "" {
+				return errors.Errorf("Cannot proceed, no template or \"none\" was specified and there is no default template.")
+			}
+		}
+		projectName = findTemplateURL(*stackEntry, templateName)
+		if template := findTemplate(*stackEntry, templateName); template != nil {
+			templateChecksum, templateSize = template.SHA256, template.Size
+		}
 
 		if projectName == "" && inputTemplateName != "none" {
 			return errors.Errorf("Could not find a template \"%s\" for stack id \"%s\" in repository \"%s\"", templateName, projectType, repoName)
@@ -259,11 +272,18 @@ func initAppsody(stack string, template string, config *initCommandConfig) error
 		config.Info.logf("Downloading %s template project from %s", projectType, projectName)
 		filename := filepath.Join(dir, projectType+".tar.gz")
 
-		err = downloadFileToDisk(config.LoggingConfig, projectName, filename, config.Dryrun)
+		err = downloadFileToDisk(config.LoggingConfig, getCredentialStore(config.RootCommandConfig), projectName, filename, config.Dryrun)
 		if err != nil {
 			return errors.Errorf("Error downloading tar %v", err)
 
 		}
+		err = verifyArchive(config.RootCommandConfig, filename, projectName, templateChecksum, templateSize, config.insecureSkipVerify)
+		if err != nil {
+			if removeErr := os.Remove(filename); removeErr != nil {
+				config.Warning.log("Unable to remove temporary file ", filename)
+			}
+			return err
+		}
 		if inputTemplateName != "none" {
 			config.Info.log("Download complete. Extracting files from ", filename)
 		} else {
@@ -642,16 +662,16 @@ func extractAndInitialize(config *initCommandConfig) error {
 func parseProjectParm(projectParm string, config *RootCommandConfig) (string, string, error) {
 	parms := strings.Split(projectParm, "/")
 	if len(parms) == 1 {
-		config.Debug.log("Non-fully qualified stack - retrieving default repo...")
+		config.Debug.log("Non-fully qualified stack - finding the repository with the highest priority that has the stack...")
 		var r RepositoryFile
 		if _, err := r.getRepos(config); err != nil {
 			return "", "", err
 		}
-		defaultRepoName, err := r.GetDefaultRepoName(config)
+		repoName, err := r.resolveStackRepo(config, parms[0])
 		if err != nil {
 			return "", parms[0], err
 		}
-		return defaultRepoName, parms[0], nil
+		return repoName, parms[0], nil
 	}
 
 	if len(parms) == 2 {
@@ -0,0 +1,361 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// the field manager recorded against the fields that appsody applies to the cluster
+const kubeFieldManager = "appsody"
+
+// KubeClient is the client for the Kubernetes cluster selected by the kubeconfig file and context.
+// The typed clientset is used for core resources, and the dynamic client for everything that
+// is read from a manifest, such as AppsodyApplications, Knative services and OpenShift routes.
+type KubeClient struct {
+	Clientset kubernetes.Interface
+	Dynamic   dynamic.Interface
+	Mapper    meta.RESTMapper
+	// the namespace of the kubeconfig context, used when no namespace is specified
+	Namespace  string
+	RestConfig *rest.Config
+}
+
+// NewKubeClient creates a client from the kubeconfig file, or the default loading rules
+// ($KUBECONFIG, then $HOME/.kube/config, then the in-cluster config) if the file is empty.
+// An empty context selects the current context of the kubeconfig.
+func NewKubeClient(kubeconfig string, context string) (*KubeClient, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Errorf("Could not load the Kubernetes configuration: %v", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, errors.Errorf("Could not determine the namespace of the Kubernetes context: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Errorf("Could not create the Kubernetes client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Errorf("Could not create the Kubernetes client: %v", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	client := NewKubeClientFromInterfaces(clientset, dynamicClient, mapper, namespace)
+	client.RestConfig = restConfig
+	return client, nil
+}
+
+// NewKubeClientFromInterfaces creates a client from existing clients, for example the fake clientsets used by the tests
+func NewKubeClientFromInterfaces(clientset kubernetes.Interface, dynamicClient dynamic.Interface, mapper meta.RESTMapper, namespace string) *KubeClient {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &KubeClient{
+		Clientset: clientset,
+		Dynamic:   dynamicClient,
+		Mapper:    mapper,
+		Namespace: namespace,
+	}
+}
+
+// getKubeClient returns the client for the --kubeconfig and --context flags, creating it on first use
+func getKubeClient(config *RootCommandConfig) (*KubeClient, error) {
+	if config.KubeClient != nil {
+		return config.KubeClient, nil
+	}
+	client, err := NewKubeClient(config.KubeConfigFile, config.KubeContext)
+	if err != nil {
+		return nil, err
+	}
+	config.Debug.log("Using Kubernetes API server ", client.RestConfig.Host)
+	config.KubeClient = client
+	return client, nil
+}
+
+// addKubeFlags adds the flags that select the Kubernetes cluster to a command and its subcommands
+func addKubeFlags(cmd *cobra.Command, config *RootCommandConfig) {
+	cmd.PersistentFlags().StringVar(&config.KubeConfigFile, "kubeconfig", "", "Path to the kubeconfig file to use for requests to the Kubernetes cluster. Defaults to $KUBECONFIG or $HOME/.kube/config.")
+	cmd.PersistentFlags().StringVar(&config.KubeContext, "context", "", "The name of the kubeconfig context to use. Defaults to the current context.")
+}
+
+// DecodeManifests splits a YAML or JSON manifest, which may contain several documents or a List, into objects
+func DecodeManifests(manifest []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		var content map[string]interface{}
+		err := decoder.Decode(&content)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// skip empty documents
+		if len(content) == 0 {
+			continue
+		}
+		object := &unstructured.Unstructured{Object: content}
+		if object.IsList() {
+			err = object.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if object.GetKind() == "" || object.GetAPIVersion() == "" {
+			return nil, errors.Errorf("Object %s is missing its apiVersion or kind", object.GetName())
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// DecodeManifestFile reads and decodes a manifest file
+func DecodeManifestFile(file string) ([]*unstructured.Unstructured, error) {
+	manifest, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := DecodeManifests(manifest)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse %s: %v", file, err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.Errorf("No objects found in %s", file)
+	}
+	return objects, nil
+}
+
+// Resource returns the dynamic client for a kind, scoped to the namespace if the kind is namespaced.
+// An empty namespace selects the namespace of the kubeconfig context.
+func (c *KubeClient) Resource(gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	mapping, err := c.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return c.Dynamic.Resource(mapping.Resource), nil
+	}
+	if namespace == "" {
+		namespace = c.Namespace
+	}
+	return c.Dynamic.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+func (c *KubeClient) namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return c.Namespace
+	}
+	return namespace
+}
+
+// objectNamespace returns the namespace to use for an object, like kubectl does for --namespace
+func objectNamespace(object *unstructured.Unstructured, namespace string) (string, error) {
+	if object.GetNamespace() == "" {
+		return namespace, nil
+	}
+	if namespace != "" && object.GetNamespace() != namespace {
+		return "", errors.Errorf("The namespace of %s %s (%s) does not match the namespace %s", object.GetKind(), object.GetName(), object.GetNamespace(), namespace)
+	}
+	return object.GetNamespace(), nil
+}
+
+// Apply creates or updates the objects with server-side apply, taking ownership of the fields that conflict.
+// Clusters that do not support server-side apply are updated with a plain create or update instead.
+func (c *KubeClient) Apply(objects []*unstructured.Unstructured, namespace string) ([]*unstructured.Unstructured, error) {
+	force := true
+	var applied []*unstructured.Unstructured
+	for _, object := range objects {
+		objectNS, err := objectNamespace(object, namespace)
+		if err != nil {
+			return applied, err
+		}
+		resource, err := c.Resource(object.GroupVersionKind(), objectNS)
+		if err != nil {
+			return applied, err
+		}
+		data, err := object.MarshalJSON()
+		if err != nil {
+			return applied, err
+		}
+		result, err := resource.Patch(object.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: kubeFieldManager, Force: &force})
+		if apierrors.IsUnsupportedMediaType(err) {
+			result, err = createOrUpdate(resource, object)
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, result)
+	}
+	return applied, nil
+}
+
+func createOrUpdate(resource dynamic.ResourceInterface, object *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	existing, err := resource.Get(object.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return resource.Create(object, metav1.CreateOptions{FieldManager: kubeFieldManager})
+	}
+	if err != nil {
+		return nil, err
+	}
+	object = object.DeepCopy()
+	object.SetResourceVersion(existing.GetResourceVersion())
+	return resource.Update(object, metav1.UpdateOptions{FieldManager: kubeFieldManager})
+}
+
+// Delete deletes the objects, in the order they are listed
+func (c *KubeClient) Delete(objects []*unstructured.Unstructured, namespace string) error {
+	propagation := metav1.DeletePropagationBackground
+	for _, object := range objects {
+		objectNS, err := objectNamespace(object, namespace)
+		if err != nil {
+			return err
+		}
+		err = c.DeleteResource(object.GroupVersionKind(), object.GetName(), objectNS, propagation)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteResource deletes a single object by name
+func (c *KubeClient) DeleteResource(gvk schema.GroupVersionKind, name string, namespace string, propagation metav1.DeletionPropagation) error {
+	resource, err := c.Resource(gvk, namespace)
+	if err != nil {
+		return err
+	}
+	return resource.Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// Get returns a single object by name
+func (c *KubeClient) Get(gvk schema.GroupVersionKind, name string, namespace string) (*unstructured.Unstructured, error) {
+	resource, err := c.Resource(gvk, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return resource.Get(name, metav1.GetOptions{})
+}
+
+// GetByKind returns a single object by name, using the preferred version of the group and kind
+func (c *KubeClient) GetByKind(gk schema.GroupKind, name string, namespace string) (*unstructured.Unstructured, error) {
+	mapping, err := c.Mapper.RESTMapping(gk)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(mapping.GroupVersionKind, name, namespace)
+}
+
+// List returns the objects of a kind in the namespace, or in all namespaces if allNamespaces is set
+func (c *KubeClient) List(gk schema.GroupKind, namespace string, allNamespaces bool, labelSelector string) (*unstructured.UnstructuredList, error) {
+	mapping, err := c.Mapper.RESTMapping(gk)
+	if err != nil {
+		return nil, err
+	}
+	options := metav1.ListOptions{LabelSelector: labelSelector}
+	if allNamespaces || mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return c.Dynamic.Resource(mapping.Resource).List(options)
+	}
+	resource, err := c.Resource(mapping.GroupVersionKind, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return resource.List(options)
+}
+
+// WaitForDeployment watches a deployment until it reports the Available condition, or the timeout expires
+func (c *KubeClient) WaitForDeployment(name string, namespace string, timeout time.Duration) error {
+	deployments := c.Clientset.AppsV1().Deployments(namespace)
+	deployment, err := deployments.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if deploymentAvailable(deployment) {
+		return nil
+	}
+	watcher, err := deployments.Watch(metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+		ResourceVersion: deployment.ResourceVersion,
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return errors.Errorf("The watch on deployment %s closed before it became available", name)
+			}
+			if event.Type == watch.Deleted {
+				return errors.Errorf("Deployment %s was deleted", name)
+			}
+			deployment, ok := event.Object.(*appsv1.Deployment)
+			if ok && deployment.Name == name && deploymentAvailable(deployment) {
+				return nil
+			}
+		case <-timer.C:
+			return errors.Errorf("Timed out after %v waiting for deployment %s to become available", timeout, name)
+		}
+	}
+}
+
+func deploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
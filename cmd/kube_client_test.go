@@ -0,0 +1,231 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newOperatorDeployment(namespace string, env corev1.EnvVar) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "appsody-operator", Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "appsody-operator", Env: []corev1.EnvVar{env}}}},
+			},
+		},
+	}
+}
+
+func newOperatorPod(namespace string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "appsody-operator-1234", Namespace: namespace, Labels: map[string]string{"name": "appsody-operator"}}}
+}
+
+func TestDecodeManifests(t *testing.T) {
+	var decodeManifestsTests = []struct {
+		testName      string
+		manifest      string
+		expectedNames []string
+		expectError   bool
+	}{
+		{"Single object", "apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n", []string{"a"}, false},
+		{"Multiple documents", "---\napiVersion: v1\nkind: Service\nmetadata:\n  name: a\n---\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: b\n", []string{"a", "b"}, false},
+		{"List", "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: Service\n  metadata:\n    name: a\n- apiVersion: v1\n  kind: Pod\n  metadata:\n    name: b\n", []string{"a", "b"}, false},
+		{"JSON", `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "a"}}`, []string{"a"}, false},
+		{"Empty", "", nil, false},
+		{"Missing kind", "apiVersion: v1\nmetadata:\n  name: a\n", nil, true},
+	}
+	for _, testData := range decodeManifestsTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			objects, err := cmd.DecodeManifests([]byte(tt.manifest))
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for _, object := range objects {
+				names = append(names, object.GetName())
+			}
+			if !reflect.DeepEqual(names, tt.expectedNames) {
+				t.Errorf("Expected objects %v but got %v", tt.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestOperatorWatchspace(t *testing.T) {
+	client := cmdtest.NewFakeKubeClient(
+		newOperatorDeployment("single", corev1.EnvVar{Name: "WATCH_NAMESPACE", Value: "ns1,ns2"}),
+		newOperatorDeployment("all", corev1.EnvVar{Name: "WATCH_NAMESPACE", Value: ""}),
+		newOperatorDeployment("own", corev1.EnvVar{Name: "WATCH_NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}}),
+	)
+	var watchspaceTests = []struct {
+		namespace string
+		expected  string
+	}{
+		{"single", "ns1,ns2"},
+		{"all", ""},
+		{"own", "own"},
+	}
+	for _, testData := range watchspaceTests {
+		tt := testData
+		t.Run(tt.namespace, func(t *testing.T) {
+			watchspace, err := client.OperatorWatchspace(tt.namespace)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if watchspace != tt.expected {
+				t.Errorf("Expected watchspace %q but got %q", tt.expected, watchspace)
+			}
+		})
+	}
+}
+
+func TestOperatorQueries(t *testing.T) {
+	app := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "appsody.dev/v1beta1",
+		"kind":       "AppsodyApplication",
+		"metadata":   map[string]interface{}{"name": "my-app", "namespace": "ns1"},
+	}}
+	client := cmdtest.NewFakeKubeClient(
+		newOperatorDeployment("op1", corev1.EnvVar{Name: "WATCH_NAMESPACE", Value: "ns1"}),
+		newOperatorDeployment("op2", corev1.EnvVar{Name: "WATCH_NAMESPACE", Value: "ns2"}),
+		newOperatorPod("op1"),
+		newOperatorPod("op2"),
+		app,
+	)
+
+	exists, err := client.OperatorExistsInNamespace("op1")
+	if err != nil || !exists {
+		t.Errorf("Expected an operator in namespace op1, got %v %v", exists, err)
+	}
+	exists, err = client.OperatorExistsInNamespace("ns1")
+	if err != nil || exists {
+		t.Errorf("Expected no operator in namespace ns1, got %v %v", exists, err)
+	}
+
+	namespaces, err := client.OperatorNamespaces("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(namespaces, []string{"op1", "op2"}) {
+		t.Errorf("Expected operators in namespaces [op1 op2] but got %v", namespaces)
+	}
+
+	count, err := client.OperatorCount()
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 operators, got %d %v", count, err)
+	}
+
+	count, err = client.AppsodyApplicationCount("ns1")
+	if err != nil || count != 1 {
+		t.Errorf("Expected 1 AppsodyApplication in ns1, got %d %v", count, err)
+	}
+	count, err = client.AppsodyApplicationCount("ns2")
+	if err != nil || count != 0 {
+		t.Errorf("Expected no AppsodyApplications in ns2, got %d %v", count, err)
+	}
+}
+
+func TestDeleteAppsodyApplications(t *testing.T) {
+	client := cmdtest.NewFakeKubeClient(
+		newAppsodyApplication("app1", "ns1"),
+		newAppsodyApplication("app2", "ns1"),
+		newAppsodyApplication("app3", "ns2"),
+	)
+	// like the API server, refuse to delete a collection of a namespaced resource across all namespaces
+	client.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("delete-collection", "*", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.GetNamespace() == "" {
+			return true, nil, apierrors.NewMethodNotSupported(action.GetResource().GroupResource(), "deletecollection")
+		}
+		return false, nil, nil
+	})
+
+	err := client.DeleteAppsodyApplications("ns2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := client.AppsodyApplicationCount("")
+	if err != nil || count != 2 {
+		t.Errorf("Expected the 2 AppsodyApplications of ns1 to be left, got %d %v", count, err)
+	}
+
+	err = client.DeleteAppsodyApplications("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err = client.AppsodyApplicationCount("")
+	if err != nil || count != 0 {
+		t.Errorf("Expected the AppsodyApplications of all namespaces to be deleted, got %d %v", count, err)
+	}
+}
+
+func TestWaitForDeployment(t *testing.T) {
+	deployment := newOperatorDeployment("default", corev1.EnvVar{Name: "WATCH_NAMESPACE"})
+	client := cmdtest.NewFakeKubeClient(deployment)
+
+	err := client.WaitForDeployment("appsody-operator", "default", 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected a timeout waiting for the deployment, but error was nil")
+	}
+
+	available := deployment.DeepCopy()
+	available.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = client.Clientset.AppsV1().Deployments("default").UpdateStatus(available)
+	}()
+	err = client.WaitForDeployment("appsody-operator", "default", 10*time.Second)
+	if err != nil {
+		t.Errorf("Expected the deployment to become available, got %v", err)
+	}
+}
+
+func TestRunKubeCommandSelectsCluster(t *testing.T) {
+	var outBuffer bytes.Buffer
+	loggingConfig := &cmd.LoggingConfig{}
+	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config := &cmd.RootCommandConfig{LoggingConfig: loggingConfig, Dryrun: true, KubeConfigFile: "/tmp/other-kubeconfig", KubeContext: "kind-appsody"}
+
+	_, err := cmd.RunKubeCommandAndListen(config, []string{"logs", "deployment/my-app", "-f"}, loggingConfig.Info, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "kubectl --kubeconfig /tmp/other-kubeconfig --context kind-appsody logs deployment/my-app -f"
+	if !strings.Contains(outBuffer.String(), expected) {
+		t.Errorf("Expected kubectl to use the cluster of --kubeconfig and --context, got:\n%s", outBuffer.String())
+	}
+}
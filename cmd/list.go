@@ -24,7 +24,8 @@ import (
 
 type listCommandConfig struct {
 	*RootCommandConfig
-	output string
+	output      string
+	allVersions bool
 }
 
 func newListCmd(rootConfig *RootCommandConfig) *cobra.Command {
@@ -35,12 +36,17 @@ func newListCmd(rootConfig *RootCommandConfig) *cobra.Command {
 		Short: "List the available Appsody stacks.",
 		Long: `List all the Appsody stacks available in your repositories. 
 
-An asterisk in the repository column denotes the default repository. An asterisk in the template column denotes the default template that is used when you initialise an Appsody project.`,
+An asterisk in the repository column denotes the default repository. An asterisk in the template column denotes the default template that is used when you initialise an Appsody project.
+
+Only the newest version of each stack is listed, unless you specify --all-versions. You can initialise a project with an older version of a stack with 'appsody init <repository>/<stack>@<version>'.`,
 		Example: `  appsody list
   Lists all available stacks for each of your repositories.
   
   appsody list my-repo
-  Lists available stacks only in your "my-repo" repository.`,
+  Lists available stacks only in your "my-repo" repository.
+
+  appsody list my-repo --all-versions
+  Lists every version of the stacks in your "my-repo" repository.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var repos RepositoryFile
 
@@ -52,12 +58,12 @@ An asterisk in the repository column denotes the default repository. An asterisk
 			}
 			//var index RepoIndex
 			if len(args) < 1 {
-				projects, err := repos.listProjects(rootConfig)
+				projects, err := repos.listProjects(rootConfig, listConfig.allVersions)
 				if err != nil {
 					return errors.Errorf("%v", err)
 				}
 				if len(rootConfig.UnsupportedRepos) > 0 {
-					rootConfig.Warning.log("The following repositories .yaml have an  APIVersion greater than "+supportedIndexAPIVersion+" which your installed Appsody CLI supports, it is strongly suggested that you update your Appsody CLI to the latest version: ", rootConfig.UnsupportedRepos)
+					rootConfig.Warning.log("The following repositories .yaml have an  APIVersion greater than "+multiVersionIndexAPIVersion+" which your installed Appsody CLI supports, it is strongly suggested that you update your Appsody CLI to the latest version: ", rootConfig.UnsupportedRepos)
 				}
 
 				if listConfig.output == "" {
@@ -65,7 +71,7 @@ An asterisk in the repository column denotes the default repository. An asterisk
 					return nil
 				}
 
-				list, err := repos.getRepositories(rootConfig.LoggingConfig)
+				list, err := repos.getRepositories(rootConfig, listConfig.allVersions)
 				if err != nil {
 					return err
 				}
@@ -91,12 +97,12 @@ An asterisk in the repository column denotes the default repository. An asterisk
 				if err != nil {
 					return err
 				}
-				repoProjects, err := repos.listRepoProjects(repoName, rootConfig)
+				repoProjects, err := repos.listRepoProjects(repoName, rootConfig, listConfig.allVersions)
 				if err != nil {
 					return err
 				}
 				if len(rootConfig.UnsupportedRepos) > 0 {
-					rootConfig.Warning.log("The following repositories are of APIVersion greater than "+supportedIndexAPIVersion+" which your installed Appsody CLI supports, it is strongly suggested that you update your Appsody CLI to the latest version: ", rootConfig.UnsupportedRepos)
+					rootConfig.Warning.log("The following repositories are of APIVersion greater than "+multiVersionIndexAPIVersion+" which your installed Appsody CLI supports, it is strongly suggested that you update your Appsody CLI to the latest version: ", rootConfig.UnsupportedRepos)
 				}
 
 				if listConfig.output == "" {
@@ -104,7 +110,7 @@ An asterisk in the repository column denotes the default repository. An asterisk
 					return nil
 				}
 
-				repoList, err := repos.getRepository(rootConfig.LoggingConfig, repoName)
+				repoList, err := repos.getRepository(rootConfig, repoName, listConfig.allVersions)
 				if err != nil {
 					return err
 				}
@@ -130,5 +136,6 @@ An asterisk in the repository column denotes the default repository. An asterisk
 	}
 
 	listCmd.PersistentFlags().StringVarP(&listConfig.output, "output", "o", "", "Output list in yaml or json format")
+	listCmd.PersistentFlags().BoolVar(&listConfig.allVersions, "all-versions", false, "List every version of each stack, not only the newest")
 	return listCmd
 }
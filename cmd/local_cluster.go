@@ -0,0 +1,181 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// the types of local cluster that images can be loaded into
+const (
+	localClusterKind          = "kind"
+	localClusterMinikube      = "minikube"
+	localClusterK3d           = "k3d"
+	localClusterDockerDesktop = "docker-desktop"
+	localClusterContainerd    = "containerd"
+)
+
+// LocalCluster is a Kubernetes cluster that runs on this machine, and can use images without a registry
+type LocalCluster struct {
+	Type string
+	// the name of the kind or k3d cluster, or the minikube profile
+	Name string
+}
+
+// DetectLocalCluster works out the type of local cluster from the name of the kubeconfig context,
+// using the context names that kind, minikube, k3d and Docker Desktop create. It returns nil for other clusters.
+func DetectLocalCluster(context string) *LocalCluster {
+	switch {
+	case strings.HasPrefix(context, "kind-"):
+		return &LocalCluster{Type: localClusterKind, Name: strings.TrimPrefix(context, "kind-")}
+	case strings.HasPrefix(context, "k3d-"):
+		return &LocalCluster{Type: localClusterK3d, Name: strings.TrimPrefix(context, "k3d-")}
+	case context == "minikube":
+		return &LocalCluster{Type: localClusterMinikube, Name: context}
+	case context == "docker-desktop" || context == "docker-for-desktop":
+		return &LocalCluster{Type: localClusterDockerDesktop, Name: context}
+	}
+	return nil
+}
+
+// DetectContainerdCluster returns a containerd cluster, such as k3s, for a context whose API server runs on this machine,
+// so that importing the image into the containerd of this machine reaches the cluster node. It returns nil for other clusters.
+func DetectContainerdCluster(context string, server string) *LocalCluster {
+	serverURL, err := url.Parse(server)
+	if err != nil || serverURL.Hostname() == "" {
+		return nil
+	}
+	host := serverURL.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil
+	}
+	return &LocalCluster{Type: localClusterContainerd, Name: context}
+}
+
+// LoadCommand returns the command that loads an image archive into the cluster nodes,
+// or nil if the cluster shares the image store of the local Docker daemon
+func (l *LocalCluster) LoadCommand(archive string) []string {
+	switch l.Type {
+	case localClusterKind:
+		return []string{"kind", "load", "image-archive", archive, "--name", l.Name}
+	case localClusterMinikube:
+		return []string{"minikube", "image", "load", archive, "--profile", l.Name}
+	case localClusterK3d:
+		return []string{"k3d", "image", "import", archive, "--cluster", l.Name}
+	case localClusterContainerd:
+		return []string{"ctr", "--namespace", "k8s.io", "images", "import", archive}
+	}
+	return nil
+}
+
+// currentKubeContext returns the name of the context selected by the --context flag or the kubeconfig,
+// and the address of the API server of its cluster
+func currentKubeContext(config *RootCommandConfig) (string, string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = config.KubeConfigFile
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return "", "", errors.Errorf("Could not load the Kubernetes configuration: %v", err)
+	}
+	context := config.KubeContext
+	if context == "" {
+		context = rawConfig.CurrentContext
+	}
+	server := ""
+	if kubeContext, ok := rawConfig.Contexts[context]; ok {
+		if cluster, ok := rawConfig.Clusters[kubeContext.Cluster]; ok {
+			server = cluster.Server
+		}
+	}
+	return context, server, nil
+}
+
+// getLocalCluster returns the local cluster to load the image into, or nil if the image should be pushed to a registry.
+// The cluster is detected from the kubeconfig context, unless --local-cluster is set. Detection is skipped if --push or --push-url are set.
+func getLocalCluster(config *deployCommandConfig, localClusterFlagPresent bool) (*LocalCluster, error) {
+	if localClusterFlagPresent && !config.localCluster {
+		return nil, nil
+	}
+	if !localClusterFlagPresent && (config.push || config.pushURL != "") {
+		return nil, nil
+	}
+	context, server, err := currentKubeContext(config.RootCommandConfig)
+	if err != nil {
+		if localClusterFlagPresent {
+			return nil, err
+		}
+		config.Debug.log("Could not detect a local cluster: ", err)
+		return nil, nil
+	}
+	cluster := DetectLocalCluster(context)
+	if cluster == nil {
+		if !localClusterFlagPresent {
+			return nil, nil
+		}
+		// the image can only be imported into a containerd cluster that runs on this machine
+		cluster = DetectContainerdCluster(context, server)
+		if cluster == nil {
+			return nil, errors.Errorf("The Kubernetes context %s is not a local kind, minikube, k3d or Docker Desktop cluster, and its API server %s does not run on this machine. Use --local-cluster=false to push the image to a registry instead", context, server)
+		}
+	}
+	if localClusterFlagPresent {
+		config.Info.logf("Deploying to local %s cluster %s", cluster.Type, cluster.Name)
+	} else {
+		config.Info.logf("Detected local %s cluster %s from the Kubernetes context. Use --local-cluster=false to push the image to a registry instead", cluster.Type, cluster.Name)
+	}
+	return cluster, nil
+}
+
+// loadLocalClusterImage saves the image to an archive and loads it into the nodes of the local cluster
+func loadLocalClusterImage(config *RootCommandConfig, cluster *LocalCluster, image string) error {
+	if cluster.LoadCommand("") == nil {
+		config.Info.logf("The %s cluster uses the local image %s", cluster.Type, image)
+		return nil
+	}
+	archiveDir, err := ioutil.TempDir("", "appsody-image-")
+	if err != nil {
+		return errors.Errorf("Error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(archiveDir)
+	archive := filepath.Join(archiveDir, "image.tar")
+
+	saveCmd := []string{"docker", "save", "--output", archive, image}
+	if config.Buildah {
+		saveCmd = []string{"buildah", "push", image, "docker-archive:" + archive + ":" + image}
+	}
+	config.Info.logf("Loading image %s into the %s cluster %s", image, cluster.Type, cluster.Name)
+	for _, cmdArgs := range [][]string{saveCmd, cluster.LoadCommand(archive)} {
+		if config.Dryrun {
+			config.Info.log("Dry run - skipping execution of: ", strings.Join(cmdArgs, " "))
+			continue
+		}
+		config.Debug.log("Running command: ", strings.Join(cmdArgs, " "))
+		execCmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		output, err := SeparateOutput(execCmd)
+		if err != nil {
+			return errors.Errorf("Could not load the image into the %s cluster: %s %s", cluster.Type, err, output)
+		}
+	}
+	return nil
+}
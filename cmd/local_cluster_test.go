@@ -0,0 +1,85 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"reflect"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+func TestDetectLocalCluster(t *testing.T) {
+	var detectTests = []struct {
+		context         string
+		expectedType    string
+		expectedName    string
+		expectedCommand []string
+	}{
+		{"kind-dev", "kind", "dev", []string{"kind", "load", "image-archive", "image.tar", "--name", "dev"}},
+		{"k3d-mycluster", "k3d", "mycluster", []string{"k3d", "image", "import", "image.tar", "--cluster", "mycluster"}},
+		{"minikube", "minikube", "minikube", []string{"minikube", "image", "load", "image.tar", "--profile", "minikube"}},
+		{"docker-desktop", "docker-desktop", "docker-desktop", nil},
+		{"my-cloud-cluster", "", "", nil},
+		{"", "", "", nil},
+	}
+	for _, testData := range detectTests {
+		tt := testData
+		t.Run(tt.context, func(t *testing.T) {
+			cluster := cmd.DetectLocalCluster(tt.context)
+			if tt.expectedType == "" {
+				if cluster != nil {
+					t.Errorf("Expected no local cluster but got %v", cluster)
+				}
+				return
+			}
+			if cluster == nil {
+				t.Fatalf("Expected a %s cluster but got nil", tt.expectedType)
+			}
+			if cluster.Type != tt.expectedType || cluster.Name != tt.expectedName {
+				t.Errorf("Expected %s cluster %s but got %s cluster %s", tt.expectedType, tt.expectedName, cluster.Type, cluster.Name)
+			}
+			if command := cluster.LoadCommand("image.tar"); !reflect.DeepEqual(command, tt.expectedCommand) {
+				t.Errorf("Expected load command %v but got %v", tt.expectedCommand, command)
+			}
+		})
+	}
+}
+
+func TestDetectContainerdCluster(t *testing.T) {
+	var detectTests = []struct {
+		server   string
+		expected bool
+	}{
+		{"https://127.0.0.1:6443", true},
+		{"https://localhost:6443", true},
+		{"https://[::1]:6443", true},
+		{"https://10.0.0.5:6443", false},
+		{"https://api.my-cloud-cluster.example.com:6443", false},
+		{"", false},
+	}
+	for _, testData := range detectTests {
+		tt := testData
+		t.Run(tt.server, func(t *testing.T) {
+			cluster := cmd.DetectContainerdCluster("default", tt.server)
+			if (cluster != nil) != tt.expected {
+				t.Errorf("Expected a local containerd cluster to be %v for server %s, got %v", tt.expected, tt.server, cluster)
+			}
+			if cluster != nil && cluster.LoadCommand("image.tar")[0] != "ctr" {
+				t.Errorf("Expected the image to be imported with ctr, got %v", cluster.LoadCommand("image.tar"))
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -30,6 +31,9 @@ const operatorYamlName = "appsody-app-operator.yaml"
 const appsodyCRDName = "appsody-app-crd.yaml"
 const operatorRBACName = "appsody-app-cluster-rbac.yaml"
 
+// the release download URL that --version replaces with the URL of a specific release
+const operatorLatestDownload = "/releases/latest/download"
+
 type operatorCommandConfig struct {
 	*RootCommandConfig
 	namespace string
@@ -39,8 +43,8 @@ func newOperatorCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	operatorConfig := &operatorCommandConfig{RootCommandConfig: rootConfig}
 	var operatorCmd = &cobra.Command{
 		Use:   "operator",
-		Short: "Install or uninstall the Appsody operator from your Kubernetes cluster.",
-		Long:  `This command allows you to "install" or "uninstall" the Appsody operator from the configured Kubernetes cluster. An installed Appsody operator is required to deploy your Appsody projects.`,
+		Short: "Install, upgrade or uninstall the Appsody operator from your Kubernetes cluster.",
+		Long:  `This command allows you to "install", "upgrade" or "uninstall" the Appsody operator from the configured Kubernetes cluster, and to show its "status". An installed Appsody operator is required to deploy your Appsody projects.`,
 	}
 
 	// rootCmd.AddCommand(operatorCmd)
@@ -48,36 +52,39 @@ func newOperatorCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	//operatorCmd.AddCommand(uninstallCmd)
 	operatorCmd.PersistentFlags().StringVarP(&operatorConfig.namespace, "namespace", "n", "default", "The namespace in which the operator will run.")
 	//operatorCmd.PersistentFlags().StringVarP(&watchspace, "watchspace", "w", "''", "The namespace which the operator will watch. Use '' for all namespaces.")
+	addKubeFlags(operatorCmd, rootConfig)
 	operatorCmd.AddCommand(newOperatorInstallCmd(operatorConfig))
 	operatorCmd.AddCommand(newOperatorUninstallCmd(operatorConfig))
+	operatorCmd.AddCommand(newOperatorStatusCmd(operatorConfig))
+	operatorCmd.AddCommand(newOperatorUpgradeCmd(operatorConfig))
+	operatorCmd.AddCommand(newOperatorBundleCmd(operatorConfig))
 	return operatorCmd
 }
 
-func downloadOperatorYaml(log *LoggingConfig, url string, operatorNamespace string, watchNamespace string, target string) (string, error) {
-
-	file, err := downloadYaml(log, url, target)
-	if err != nil {
-		return "", fmt.Errorf("Could not download Operator YAML file %s", url)
+// OperatorReleaseURL returns the URL to download the operator files of a release from.
+// Without a version, the files come from the operator home, which is the latest release by default.
+func OperatorReleaseURL(operatorHome string, version string) (string, error) {
+	if version == "" {
+		return operatorHome, nil
 	}
-
-	yamlReader, err := ioutil.ReadFile(file)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", errors.Errorf("Downloaded file does not exist %s. ", target)
-
-		}
-		return "", errors.Errorf("Failed reading file %s", target)
-
+	operatorHome = strings.TrimSuffix(operatorHome, "/")
+	if !strings.HasSuffix(operatorHome, operatorLatestDownload) {
+		return "", errors.Errorf("--version can only be used when the operator home is a GitHub latest release download URL, but it is %s", operatorHome)
+	}
+	// the operator releases are tagged v<major>.<minor>.<patch>
+	if version[0] >= '0' && version[0] <= '9' {
+		version = "v" + version
 	}
+	return strings.TrimSuffix(operatorHome, operatorLatestDownload) + "/releases/download/" + version, nil
+}
 
-	//output := bytes.Replace(yamlReader, []byte("APPSODY_OPERATOR_NAMESPACE"), []byte(operatorNamespace), -1)
-	output := bytes.Replace(yamlReader, []byte("APPSODY_WATCH_NAMESPACE"), []byte(watchNamespace), -1)
+func downloadOperatorYaml(log *LoggingConfig, url string, operatorNamespace string, watchNamespace string, target string) (string, error) {
 
-	err = ioutil.WriteFile(target, output, 0666)
+	file, err := downloadYaml(log, url, target)
 	if err != nil {
-		return "", errors.Errorf("Failed to write local operator definition file: %s", err)
+		return "", fmt.Errorf("Could not download Operator YAML file %s", url)
 	}
-	return target, nil
+	return file, setOperatorWatchspace(file, watchNamespace)
 }
 
 func downloadRBACYaml(log *LoggingConfig, url string, operatorNamespace string, target string, dryrun bool) (string, error) {
@@ -90,32 +97,44 @@ func downloadRBACYaml(log *LoggingConfig, url string, operatorNamespace string,
 	if err != nil {
 		return "", fmt.Errorf("Could not download RBAC YAML file %s", url)
 	}
+	return file, setRBACNamespace(file, operatorNamespace)
+}
 
+// setOperatorWatchspace sets the namespaces that the operator watches in the operator YAML file
+func setOperatorWatchspace(file string, watchNamespace string) error {
+	return replaceInYaml(file, "APPSODY_WATCH_NAMESPACE", watchNamespace)
+}
+
+// setRBACNamespace sets the namespace of the operator service account in the RBAC YAML file
+func setRBACNamespace(file string, operatorNamespace string) error {
+	return replaceInYaml(file, "APPSODY_OPERATOR_NAMESPACE", operatorNamespace)
+}
+
+func replaceInYaml(file string, placeholder string, value string) error {
 	yamlReader, err := ioutil.ReadFile(file)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", errors.Errorf("Downloaded file does not exist %s. ", target)
+			return errors.Errorf("Downloaded file does not exist %s. ", file)
 
 		}
-		return "", errors.Errorf("Failed reading file %s", target)
+		return errors.Errorf("Failed reading file %s", file)
 
 	}
 
-	output := bytes.Replace(yamlReader, []byte("APPSODY_OPERATOR_NAMESPACE"), []byte(operatorNamespace), -1)
-	//output = bytes.Replace(output, []byte("APPSODY_WATCH_NAMESPACE"), []byte(watchNamespace), -1)
+	output := bytes.Replace(yamlReader, []byte(placeholder), []byte(value), -1)
 
-	err = ioutil.WriteFile(target, output, 0666)
+	err = ioutil.WriteFile(file, output, 0666)
 	if err != nil {
-		return "", errors.Errorf("Failed to write local operator definition file: %s", err)
+		return errors.Errorf("Failed to write local operator definition file: %s", err)
 	}
-	return target, nil
+	return nil
 }
 
 func downloadYaml(log *LoggingConfig, url string, target string) (string, error) {
 	log.Debug.log("Downloading file: ", url)
 
 	fileBuffer := bytes.NewBuffer(nil)
-	err := downloadFile(log, url, fileBuffer)
+	err := downloadFile(log, nil, url, fileBuffer)
 	if err != nil {
 		return "", errors.Errorf("Failed to get file: %s", err)
 	}
@@ -0,0 +1,258 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// OperatorBundleFile describes the operator release in a bundle created by "appsody operator bundle"
+const OperatorBundleFile = "operator-bundle.yaml"
+
+const operatorBundleSuffix = ".tar.gz"
+
+// the image references in the container specs of a Kubernetes manifest
+var imageLineRegexp = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?image:\s*["']?)([^"'\s#]+)`)
+
+type OperatorBundle struct {
+	// the release of the operator, or latest
+	Version string `yaml:"version"`
+	// the URL that the operator files were downloaded from
+	Source string   `yaml:"source"`
+	Images []string `yaml:"images"`
+}
+
+type operatorBundleCommandConfig struct {
+	*operatorCommandConfig
+	version, output string
+}
+
+// OperatorImages returns the images referenced by the containers in a manifest
+func OperatorImages(manifest []byte) []string {
+	var images []string
+	seen := make(map[string]bool)
+	for _, match := range imageLineRegexp.FindAllSubmatch(manifest, -1) {
+		image := string(match[2])
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// MirrorImage returns the reference of the image in the mirror registry, replacing the registry of the image if it has one
+func MirrorImage(image string, registry string) string {
+	if slash := strings.Index(image, "/"); slash >= 0 {
+		// like docker, the first part of the name is a registry if it looks like a host name
+		host := image[:slash]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			image = image[slash+1:]
+		}
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + image
+}
+
+// MirrorImages replaces the image references in a manifest with references to the mirror registry
+func MirrorImages(manifest []byte, registry string) []byte {
+	return imageLineRegexp.ReplaceAllFunc(manifest, func(line []byte) []byte {
+		match := imageLineRegexp.FindSubmatch(line)
+		mirrored := append([]byte{}, match[1]...)
+		return append(mirrored, MirrorImage(string(match[2]), registry)...)
+	})
+}
+
+// ReadOperatorBundle extracts a bundle into the directory and reads its description
+func ReadOperatorBundle(log *LoggingConfig, bundle string, dir string) (*OperatorBundle, error) {
+	bundleFile, err := os.Open(bundle)
+	if err != nil {
+		return nil, errors.Errorf("Could not open the operator bundle %s: %v", bundle, err)
+	}
+	defer bundleFile.Close()
+	err = untar(log, dir, bundleFile, false)
+	if err != nil {
+		return nil, errors.Errorf("Could not extract the operator bundle %s: %v", bundle, err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, OperatorBundleFile))
+	if err != nil {
+		return nil, errors.Errorf("%s is not an operator bundle created by appsody operator bundle: %v", bundle, err)
+	}
+	var operatorBundle OperatorBundle
+	err = yaml.Unmarshal(data, &operatorBundle)
+	if err != nil {
+		return nil, errors.Errorf("Failed to parse %s in the operator bundle %s: %v", OperatorBundleFile, bundle, err)
+	}
+	for _, name := range []string{appsodyCRDName, operatorRBACName, operatorYamlName} {
+		exists, err := Exists(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.Errorf("The operator bundle %s does not contain %s", bundle, name)
+		}
+	}
+	return &operatorBundle, nil
+}
+
+// bundleSource copies the operator files from a bundle that was extracted into dir,
+// pointing the operator images to the mirror registry if one is given
+func bundleSource(dir string, imageRegistry string) operatorSource {
+	return func(name string, target string) error {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return errors.Errorf("Failed reading %s from the operator bundle: %v", name, err)
+		}
+		if name == operatorYamlName && imageRegistry != "" {
+			data = MirrorImages(data, imageRegistry)
+		}
+		err = ioutil.WriteFile(target, data, 0666)
+		if err != nil {
+			return errors.Errorf("Failed to write local operator definition file: %s", err)
+		}
+		return nil
+	}
+}
+
+// getOperatorSource returns where to get the operator files from, which is a bundle if one is given or else a release.
+// The returned function removes the files extracted from the bundle.
+func getOperatorSource(config *operatorCommandConfig, version string, bundle string, imageRegistry string) (operatorSource, func(), error) {
+	if bundle == "" {
+		if imageRegistry != "" {
+			return nil, nil, errors.New("--image-registry can only be used with --from-bundle")
+		}
+		releaseURL, err := OperatorReleaseURL(getOperatorHome(config.RootCommandConfig), version)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.Dryrun {
+			return dryRunSource(config.LoggingConfig, releaseURL), func() {}, nil
+		}
+		return releaseSource(config.LoggingConfig, releaseURL), func() {}, nil
+	}
+	if version != "" {
+		return nil, nil, errors.New("--version cannot be used with --from-bundle. The version is the one in the bundle")
+	}
+	if config.Dryrun {
+		config.Info.log("Dry run - skipping extraction of the operator bundle: ", bundle)
+		return dryRunSource(config.LoggingConfig, bundle), func() {}, nil
+	}
+	dir, err := ioutil.TempDir("", "appsody-operator-bundle")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+	description, err := ReadOperatorBundle(config.LoggingConfig, bundle, dir)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	config.Info.logf("Using version %s of the Appsody operator from bundle %s", description.Version, bundle)
+	if imageRegistry != "" {
+		for _, image := range description.Images {
+			config.Info.logf("Using image %s instead of %s", MirrorImage(image, imageRegistry), image)
+		}
+	}
+	return bundleSource(dir, imageRegistry), cleanup, nil
+}
+
+func newOperatorBundleCmd(operatorConfig *operatorCommandConfig) *cobra.Command {
+	config := &operatorBundleCommandConfig{operatorCommandConfig: operatorConfig}
+	var bundleCmd = &cobra.Command{
+		Use:   "bundle",
+		Short: "Save the Appsody Operator files in a bundle for clusters without internet access.",
+		Long: `Save the AppsodyApplication CRD, the cluster RBAC and the operator YAML of an Appsody Operator release in a .tar.gz bundle, with the images that the operator uses.
+
+Copy the images to a registry that your cluster can reach, and use "appsody operator install --from-bundle" with the "--image-registry" flag to install the operator from the bundle.`,
+		Example: `  appsody operator bundle --version 0.3.0 -o operator-bundle.tar.gz
+  Saves release 0.3.0 of the Appsody Operator in "operator-bundle.tar.gz".
+
+  appsody operator install --from-bundle operator-bundle.tar.gz --image-registry mirror.example.com:5000
+  Installs the Appsody Operator from "operator-bundle.tar.gz", using the images in the "mirror.example.com:5000" registry.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			return operatorBundle(config)
+		},
+	}
+
+	bundleCmd.PersistentFlags().StringVar(&config.version, "version", "", "The release of the operator to save, for example 0.3.0. The latest release is saved by default.")
+	bundleCmd.PersistentFlags().StringVarP(&config.output, "output", "o", "operator-bundle"+operatorBundleSuffix, "The .tar.gz file to save the bundle in.")
+	return bundleCmd
+}
+
+func operatorBundle(config *operatorBundleCommandConfig) error {
+	if !strings.HasSuffix(config.output, operatorBundleSuffix) {
+		return errors.Errorf("The bundle file %s must have the %s extension", config.output, operatorBundleSuffix)
+	}
+	releaseURL, err := OperatorReleaseURL(getOperatorHome(config.RootCommandConfig), config.version)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "appsody-operator-bundle")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	source := releaseSource(config.LoggingConfig, releaseURL)
+	for _, name := range []string{appsodyCRDName, operatorRBACName, operatorYamlName} {
+		err = source(name, filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+	}
+	operatorYaml, err := ioutil.ReadFile(filepath.Join(dir, operatorYamlName))
+	if err != nil {
+		return errors.Errorf("Failed reading file %s", operatorYamlName)
+	}
+	version := config.version
+	if version == "" {
+		version = "latest"
+	}
+	description := OperatorBundle{Version: version, Source: releaseURL, Images: OperatorImages(operatorYaml)}
+	data, err := yaml.Marshal(description)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, OperatorBundleFile), data, 0666)
+	if err != nil {
+		return errors.Errorf("Failed to write %s: %v", OperatorBundleFile, err)
+	}
+
+	output, err := filepath.Abs(config.output)
+	if err != nil {
+		return err
+	}
+	err = Targz(config.LoggingConfig, dir, filepath.Dir(output)+string(os.PathSeparator), strings.TrimSuffix(filepath.Base(output), operatorBundleSuffix))
+	if err != nil {
+		return errors.Errorf("Could not create the operator bundle %s: %v", config.output, err)
+	}
+	config.Info.log("Copy these images to a registry that your cluster can reach:")
+	for _, image := range description.Images {
+		config.Info.log("  ", image)
+	}
+	return nil
+}
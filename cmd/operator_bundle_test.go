@@ -0,0 +1,175 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+)
+
+const bundleOperatorYaml = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: appsody-operator
+spec:
+  template:
+    spec:
+      initContainers:
+        - image: "quay.io/appsody/init:1.0"
+      containers:
+      - name: appsody-operator
+        image: appsody/application-operator:0.3.0 # the operator
+        env:
+        - name: WATCH_NAMESPACE
+          value: APPSODY_WATCH_NAMESPACE
+`
+
+func TestMirrorImage(t *testing.T) {
+	var mirrorImageTests = []struct {
+		image    string
+		expected string
+	}{
+		{"appsody/application-operator:0.3.0", "mirror.example.com/appsody/application-operator:0.3.0"},
+		{"busybox", "mirror.example.com/busybox"},
+		{"quay.io/appsody/init:1.0", "mirror.example.com/appsody/init:1.0"},
+		{"registry:5000/appsody/init", "mirror.example.com/appsody/init"},
+		{"localhost/init@sha256:abcd", "mirror.example.com/init@sha256:abcd"},
+	}
+	for _, testData := range mirrorImageTests {
+		tt := testData
+		t.Run(tt.image, func(t *testing.T) {
+			if image := cmd.MirrorImage(tt.image, "mirror.example.com/"); image != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, image)
+			}
+		})
+	}
+}
+
+func TestMirrorImages(t *testing.T) {
+	manifest := []byte(bundleOperatorYaml)
+	images := cmd.OperatorImages(manifest)
+	if !reflect.DeepEqual(images, []string{"quay.io/appsody/init:1.0", "appsody/application-operator:0.3.0"}) {
+		t.Errorf("Unexpected images %v", images)
+	}
+	mirrored := string(cmd.MirrorImages(manifest, "mirror:5000"))
+	for _, expected := range []string{`- image: "mirror:5000/appsody/init:1.0"`, "image: mirror:5000/appsody/application-operator:0.3.0 # the operator"} {
+		if !strings.Contains(mirrored, expected) {
+			t.Errorf("Expected the manifest to contain %q:\n%s", expected, mirrored)
+		}
+	}
+	if string(manifest) != bundleOperatorYaml {
+		t.Error("The original manifest was changed")
+	}
+}
+
+func TestReadOperatorBundle(t *testing.T) {
+	var outBuffer bytes.Buffer
+	loggingConfig := &cmd.LoggingConfig{}
+	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+
+	dir, err := ioutil.TempDir("", "appsody-operator-bundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bundleDir := filepath.Join(dir, "bundle")
+	err = os.Mkdir(bundleDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"appsody-app-crd.yaml":          "kind: CustomResourceDefinition\n",
+		"appsody-app-cluster-rbac.yaml": "kind: ClusterRole\n",
+		"appsody-app-operator.yaml":     bundleOperatorYaml,
+		cmd.OperatorBundleFile:          "version: 0.3.0\nimages:\n- appsody/application-operator:0.3.0\n",
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(bundleDir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = cmd.Targz(loggingConfig, bundleDir, dir+string(os.PathSeparator), "operator-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extractDir := filepath.Join(dir, "extract")
+	err = os.Mkdir(extractDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := cmd.ReadOperatorBundle(loggingConfig, filepath.Join(dir, "operator-bundle.tar.gz"), extractDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &cmd.OperatorBundle{Version: "0.3.0", Images: []string{"appsody/application-operator:0.3.0"}}
+	if !reflect.DeepEqual(bundle, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, bundle)
+	}
+
+	err = os.Remove(filepath.Join(extractDir, "appsody-app-crd.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove(filepath.Join(bundleDir, "appsody-app-crd.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Targz(loggingConfig, bundleDir, dir+string(os.PathSeparator), "incomplete-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmd.ReadOperatorBundle(loggingConfig, filepath.Join(dir, "incomplete-bundle.tar.gz"), extractDir)
+	if err == nil || !strings.Contains(err.Error(), "does not contain appsody-app-crd.yaml") {
+		t.Errorf("Expected an error for the missing CRD, got %v", err)
+	}
+}
+
+func TestOperatorInstallDryRun(t *testing.T) {
+	var tests = []struct {
+		testName string
+		args     []string
+		expected string
+	}{
+		{"Release", []string{"--version", "0.3.0"}, "Dry run - skipping copy of appsody-app-operator.yaml from https://github.com/appsody/appsody-operator/releases/download/v0.3.0"},
+		{"Bundle", []string{"--from-bundle", "missing-bundle.tar.gz"}, "Dry run - skipping extraction of the operator bundle: missing-bundle.tar.gz"},
+	}
+	for _, testData := range tests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+			defer cleanup()
+
+			// the operator files are not downloaded or extracted, so a dry run works without network access
+			args := append([]string{"operator", "install", "--dryrun"}, tt.args...)
+			output, err := cmdtest.RunAppsody(sandbox, args...)
+			if err != nil {
+				t.Fatalf("Expected the dry run to succeed without the operator files, got %v:\n%s", err, output)
+			}
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected the output to contain %s, got:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
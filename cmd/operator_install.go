@@ -16,16 +16,23 @@ package cmd
 
 import (
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/spf13/cobra"
 )
 
+// how long install waits for the operator deployment to become available
+const operatorAvailableTimeout = 2 * time.Minute
+
 type operatorInstallCommandConfig struct {
 	*operatorCommandConfig
 	all, noOperatorCheck bool
-	watchspace           string
+	watchspace, version  string
+	fromBundle           string
+	imageRegistry        string
 }
 
 func newOperatorInstallCmd(operatorConfig *operatorCommandConfig) *cobra.Command {
@@ -40,7 +47,13 @@ The Appsody Operator listens for incoming AppsodyApplication resources on your c
 
 By default, the operator watches a single namespace. You can specify the ‘--watch-all’ flag to tell the operator to watch all namespaces in the cluster. If you want to watch multiple, but not all, namespaces within your cluster, install an additional operator to watch each additional namespace.`,
 		Example: `  appsody operator install --namespace my-namespace --watchspace my-watchspace
-  Installs the Appsody Operator into your Kubernetes cluster in the "my-namespace" namespace, and sets it to watch for AppsodyApplication resources in the "my-watchspace" namespace.`,
+  Installs the Appsody Operator into your Kubernetes cluster in the "my-namespace" namespace, and sets it to watch for AppsodyApplication resources in the "my-watchspace" namespace.
+
+  appsody operator install --version 0.3.0
+  Installs release 0.3.0 of the Appsody Operator, instead of the latest release.
+
+  appsody operator install --from-bundle operator-bundle.tar.gz --image-registry mirror.example.com:5000
+  Installs the Appsody Operator from a bundle created by "appsody operator bundle", using the images in the "mirror.example.com:5000" registry.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
@@ -52,10 +65,18 @@ By default, the operator watches a single namespace. You can specify the ‘--wa
 	installCmd.PersistentFlags().StringVarP(&config.watchspace, "watchspace", "w", "", "The namespace that the operator watches.")
 	installCmd.PersistentFlags().BoolVar(&config.all, "watch-all", false, "Specifies that the operator watches all namespaces.")
 	installCmd.PersistentFlags().BoolVar(&config.noOperatorCheck, "no-operator-check", false, "Suppresses check for operator existing in namespace")
+	installCmd.PersistentFlags().StringVar(&config.version, "version", "", "The release of the operator to install, for example 0.3.0. The latest release is installed by default.")
+	installCmd.PersistentFlags().StringVar(&config.fromBundle, "from-bundle", "", "Install the operator from a bundle created by 'appsody operator bundle', instead of downloading it.")
+	installCmd.PersistentFlags().StringVar(&config.imageRegistry, "image-registry", "", "The registry that the images in the bundle were copied to.")
 	return installCmd
 }
 
 func operatorInstall(config *operatorInstallCommandConfig) error {
+	source, cleanup, err := getOperatorSource(config.operatorCommandConfig, config.version, config.fromBundle, config.imageRegistry)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	namespace := config.namespace
 	watchspace := config.watchspace
 
@@ -74,21 +95,21 @@ func operatorInstall(config *operatorInstallCommandConfig) error {
 		watchNamespace = ""
 	}
 	config.Debug.log("watchNamespace is:  ", watchNamespace)
-	operatorExists, existsErr := operatorExistsInNamespace(config.LoggingConfig, operatorNamespace, config.Dryrun)
+	operatorExists, existsErr := operatorExistsInNamespace(config.RootCommandConfig, operatorNamespace, config.Dryrun)
 	if existsErr != nil {
 		return existsErr
 	}
 	if operatorExists {
-		existingOperatorWatchspace, err := getOperatorWatchspace(config.LoggingConfig, operatorNamespace, config.Dryrun)
+		existingWatchspaces, err := getOperatorWatchspaces(config.RootCommandConfig, operatorNamespace, config.Dryrun)
 		if err != nil {
 			config.Debug.log("Could not retrieve the watchspace of this operator - this should never happen...")
 		}
+		existingOperatorWatchspace := strings.Join(existingWatchspaces, ",")
 		if existingOperatorWatchspace == "" {
 			existingOperatorWatchspace = "all namespaces"
 		}
 		match := false
-		watchSpaces := getWatchSpaces(existingOperatorWatchspace, config.Dryrun)
-		for _, existingWatchspace := range watchSpaces {
+		for _, existingWatchspace := range existingWatchspaces {
 			if existingWatchspace == operatorNamespace {
 				match = true
 			}
@@ -100,7 +121,7 @@ func operatorInstall(config *operatorInstallCommandConfig) error {
 		return errors.Errorf("An operator already exists in namespace %s and it is watching the %s namespace.", operatorNamespace, existingOperatorWatchspace)
 	}
 
-	watchExists, existingNamespace, watchExistsErr := operatorExistsWithWatchspace(config.LoggingConfig, watchNamespace, config.Dryrun, config.noOperatorCheck)
+	watchExists, existingNamespace, watchExistsErr := operatorExistsWithWatchspace(config.RootCommandConfig, watchNamespace, config.Dryrun, config.noOperatorCheck)
 	if watchExistsErr != nil {
 
 		return watchExistsErr
@@ -109,51 +130,101 @@ func operatorInstall(config *operatorInstallCommandConfig) error {
 		return errors.Errorf("An operator watching namespace %s or all namespaces already exists in namespace %s", watchNamespace, existingNamespace)
 	}
 
+	err = applyOperator(config.operatorCommandConfig, source, operatorNamespace, watchNamespace, (operatorNamespace != watchNamespace) || config.all)
+	if err != nil {
+		return err
+	}
+
+	config.Info.log("Appsody operator deployed to Kubernetes")
+	if config.Dryrun {
+		return nil
+	}
+	return waitForOperator(config.RootCommandConfig, operatorNamespace)
+}
+
+// operatorSource copies an operator file, such as the CRD, from a release or a bundle into the target file
+type operatorSource func(name string, target string) error
+
+func releaseSource(log *LoggingConfig, releaseURL string) operatorSource {
+	return func(name string, target string) error {
+		url := releaseURL + "/" + name
+		_, err := downloadYaml(log, url, target)
+		if err != nil {
+			return errors.Errorf("Could not download %s: %v", url, err)
+		}
+		return nil
+	}
+}
+
+// dryRunSource is the source of a dry run, which does not download or extract the operator files
+func dryRunSource(log *LoggingConfig, from string) operatorSource {
+	return func(name string, target string) error {
+		log.Info.logf("Dry run - skipping copy of %s from %s to %s", name, from, target)
+		return nil
+	}
+}
+
+// applyOperator gets the CRD, RBAC and operator files from the source and applies them.
+// The cluster RBAC is only needed when the operator watches namespaces other than its own.
+func applyOperator(config *operatorCommandConfig, source operatorSource, operatorNamespace string, watchNamespace string, rbac bool) error {
 	deployConfigDir, err := getDeployConfigDir(config.RootCommandConfig)
 	if err != nil {
 		return errors.Errorf("Error getting deploy config dir: %v", err)
 	}
 
-	var crdURL = getOperatorHome(config.RootCommandConfig) + "/" + appsodyCRDName
 	appsodyCRD := filepath.Join(deployConfigDir, appsodyCRDName)
-	var file string
-
-	file, err = downloadCRDYaml(config.LoggingConfig, crdURL, appsodyCRD)
+	err = source(appsodyCRDName, appsodyCRD)
 	if err != nil {
 		return err
 	}
 
-	err = KubeApply(config.LoggingConfig, file, config.namespace, config.Dryrun)
+	err = KubeApply(config.RootCommandConfig, appsodyCRD, config.namespace, config.Dryrun)
 	if err != nil {
 		return err
 	}
-	rbacYaml := filepath.Join(deployConfigDir, operatorRBACName)
-	var rbacURL = getOperatorHome(config.RootCommandConfig) + "/" + operatorRBACName
-	if (operatorNamespace != watchNamespace) || config.all {
-		config.Debug.log("Downloading: ", rbacURL)
-		file, err = downloadRBACYaml(config.LoggingConfig, rbacURL, operatorNamespace, rbacYaml, config.Dryrun)
-		if err != nil {
-			return err
+	if rbac {
+		rbacYaml := filepath.Join(deployConfigDir, operatorRBACName)
+		if !config.Dryrun {
+			err = source(operatorRBACName, rbacYaml)
+			if err != nil {
+				return err
+			}
+			err = setRBACNamespace(rbacYaml, operatorNamespace)
+			if err != nil {
+				return err
+			}
 		}
 
-		err = KubeApply(config.LoggingConfig, file, config.namespace, config.Dryrun)
+		err = KubeApply(config.RootCommandConfig, rbacYaml, config.namespace, config.Dryrun)
 		if err != nil {
 			return err
 		}
 	}
 
 	operatorYaml := filepath.Join(deployConfigDir, operatorYamlName)
-	var operatorURL = getOperatorHome(config.RootCommandConfig) + "/" + operatorYamlName
-	file, err = downloadOperatorYaml(config.LoggingConfig, operatorURL, operatorNamespace, watchNamespace, operatorYaml)
+	err = source(operatorYamlName, operatorYaml)
 	if err != nil {
 		return err
 	}
+	if !config.Dryrun {
+		err = setOperatorWatchspace(operatorYaml, watchNamespace)
+		if err != nil {
+			return err
+		}
+	}
+
+	return KubeApply(config.RootCommandConfig, operatorYaml, config.namespace, config.Dryrun)
+}
 
-	err = KubeApply(config.LoggingConfig, file, config.namespace, config.Dryrun)
+func waitForOperator(config *RootCommandConfig, operatorNamespace string) error {
+	config.Info.log("Waiting for the Appsody operator to become available ...")
+	client, err := getKubeClient(config)
 	if err != nil {
 		return err
 	}
-
-	config.Info.log("Appsody operator deployed to Kubernetes")
+	err = client.WaitForDeployment(operatorName, operatorNamespace, operatorAvailableTimeout)
+	if err != nil {
+		config.Warning.log("The Appsody operator is not available yet: ", err)
+	}
 	return nil
 }
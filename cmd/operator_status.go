@@ -0,0 +1,184 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// the name of the CustomResourceDefinition of AppsodyApplications
+const appsodyCRDResourceName = "appsodyapplications.appsody.dev"
+
+var crdKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+
+type OperatorStatus struct {
+	Namespace string
+	// the namespaces the operator watches, which is empty when it watches all namespaces
+	Watchspaces []string
+	Image       string
+	// the tag of the operator image
+	Version      string
+	CRDVersion   string
+	Ready        string
+	Applications int
+}
+
+// ImageVersion returns the tag or digest of an image, or "latest" if it has neither
+func ImageVersion(image string) string {
+	if at := strings.LastIndex(image, "@"); at >= 0 {
+		return image[at+1:]
+	}
+	// a colon before the last slash separates the registry host and port
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[colon+1:]
+	}
+	return "latest"
+}
+
+// AppsodyCRDVersion returns the storage version of the AppsodyApplication CRD, or an empty string if it is not installed
+func (c *KubeClient) AppsodyCRDVersion() (string, error) {
+	crd, err := c.GetByKind(crdKind, appsodyCRDResourceName, "")
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, version := range versions {
+		version, _ := version.(map[string]interface{})
+		if storage, _ := version["storage"].(bool); storage {
+			name, _ := version["name"].(string)
+			return name, nil
+		}
+	}
+	version, _, _ := unstructured.NestedString(crd.Object, "spec", "version")
+	return version, nil
+}
+
+// OperatorStatus describes the appsody-operator in the namespace
+func (c *KubeClient) OperatorStatus(namespace string) (*OperatorStatus, error) {
+	deployment, err := c.Clientset.AppsV1().Deployments(namespace).Get(operatorName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errors.Errorf("An appsody operator could not be found in namespace: %s", namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	status := &OperatorStatus{Namespace: namespace}
+	containers := deployment.Spec.Template.Spec.Containers
+	for _, container := range containers {
+		if container.Name == operatorName || status.Image == "" {
+			status.Image = container.Image
+		}
+	}
+	if status.Image != "" {
+		status.Version = ImageVersion(status.Image)
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status.Ready = fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, replicas)
+
+	watchspace, err := c.OperatorWatchspace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	status.Watchspaces = getWatchSpaces(watchspace)
+	status.CRDVersion, err = c.AppsodyCRDVersion()
+	if err != nil {
+		return nil, err
+	}
+	watchspaces := status.Watchspaces
+	if len(watchspaces) == 0 {
+		watchspaces = []string{""}
+	}
+	for _, watchspace := range watchspaces {
+		count, err := c.AppsodyApplicationCount(watchspace)
+		if err != nil {
+			return nil, err
+		}
+		status.Applications += count
+	}
+	return status, nil
+}
+
+// FormatOperatorStatus describes the operator as a table
+func FormatOperatorStatus(status *OperatorStatus) string {
+	watching := strings.Join(status.Watchspaces, ", ")
+	if watching == "" {
+		watching = "all namespaces"
+	}
+	crdVersion := status.CRDVersion
+	if crdVersion == "" {
+		crdVersion = "not installed"
+	}
+	table := uitable.New()
+	table.AddRow("Namespace:", status.Namespace)
+	table.AddRow("Watching:", watching)
+	table.AddRow("Image:", status.Image)
+	table.AddRow("Version:", status.Version)
+	table.AddRow("Ready:", status.Ready)
+	table.AddRow("CRD version:", crdVersion)
+	table.AddRow("Applications:", status.Applications)
+	return table.String()
+}
+
+func newOperatorStatusCmd(config *operatorCommandConfig) *cobra.Command {
+	var statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the Appsody Operator.",
+		Long: `Show the status of the Appsody Operator in your configured Kubernetes cluster.
+
+The status includes the namespaces that the operator watches, the version of the operator image, the version of the AppsodyApplication CRD and the number of AppsodyApplications that the operator manages.`,
+		Example: `  appsody operator status --namespace my-namespace
+  Shows the status of the Appsody Operator in the "my-namespace" namespace.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			operatorNamespace := "default"
+			if config.namespace != "" {
+				operatorNamespace = config.namespace
+			}
+			if config.Dryrun {
+				config.Info.log("Dry run - skipping status of the appsody-operator in namespace: ", operatorNamespace)
+				return nil
+			}
+			client, err := getKubeClient(config.RootCommandConfig)
+			if err != nil {
+				return err
+			}
+			status, err := client.OperatorStatus(operatorNamespace)
+			if err != nil {
+				return err
+			}
+			config.Info.log("\n", FormatOperatorStatus(status))
+			return nil
+		},
+	}
+	return statusCmd
+}
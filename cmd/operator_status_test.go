@@ -0,0 +1,127 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newAppsodyApplication(name string, namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "appsody.dev/v1beta1",
+		"kind":       "AppsodyApplication",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}}
+}
+
+func TestOperatorReleaseURL(t *testing.T) {
+	latest := "https://github.com/appsody/appsody-operator/releases/latest/download"
+	var releaseURLTests = []struct {
+		home        string
+		version     string
+		expectedURL string
+		expectError bool
+	}{
+		{latest, "", latest, false},
+		{latest, "0.3.0", "https://github.com/appsody/appsody-operator/releases/download/v0.3.0", false},
+		{latest + "/", "v0.2.2", "https://github.com/appsody/appsody-operator/releases/download/v0.2.2", false},
+		{"https://example.com/operator", "", "https://example.com/operator", false},
+		{"https://example.com/operator", "0.3.0", "", true},
+	}
+	for _, testData := range releaseURLTests {
+		tt := testData
+		t.Run(tt.home+" "+tt.version, func(t *testing.T) {
+			url, err := cmd.OperatorReleaseURL(tt.home, tt.version)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error but got %s", url)
+				}
+				return
+			}
+			if err != nil || url != tt.expectedURL {
+				t.Errorf("Expected %s but got %s %v", tt.expectedURL, url, err)
+			}
+		})
+	}
+}
+
+func TestImageVersion(t *testing.T) {
+	var imageVersionTests = []struct {
+		image    string
+		expected string
+	}{
+		{"appsody/application-operator:0.3.0", "0.3.0"},
+		{"registry:5000/appsody/application-operator", "latest"},
+		{"registry:5000/appsody/application-operator:daily", "daily"},
+		{"appsody/application-operator@sha256:abcd", "sha256:abcd"},
+	}
+	for _, testData := range imageVersionTests {
+		tt := testData
+		t.Run(tt.image, func(t *testing.T) {
+			if version := cmd.ImageVersion(tt.image); version != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestOperatorStatus(t *testing.T) {
+	deployment := newOperatorDeployment("op1", corev1.EnvVar{Name: "WATCH_NAMESPACE", Value: "ns1,ns2"})
+	deployment.Spec.Template.Spec.Containers[0].Image = "appsody/application-operator:0.3.0"
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1beta1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "appsodyapplications.appsody.dev"},
+		"spec": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{"name": "v1alpha1", "storage": false},
+				map[string]interface{}{"name": "v1beta1", "storage": true},
+			},
+		},
+	}}
+	client := cmdtest.NewFakeKubeClient(deployment, crd, newAppsodyApplication("app1", "ns1"), newAppsodyApplication("app2", "ns2"), newAppsodyApplication("app3", "other"))
+
+	status, err := client.OperatorStatus("op1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &cmd.OperatorStatus{
+		Namespace:    "op1",
+		Watchspaces:  []string{"ns1", "ns2"},
+		Image:        "appsody/application-operator:0.3.0",
+		Version:      "0.3.0",
+		CRDVersion:   "v1beta1",
+		Ready:        "0/1",
+		Applications: 2,
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, status)
+	}
+	if table := cmd.FormatOperatorStatus(status); !strings.Contains(table, "ns1, ns2") {
+		t.Errorf("Expected the watched namespaces in the status:\n%s", table)
+	}
+
+	_, err = client.OperatorStatus("ns1")
+	if err == nil {
+		t.Error("Expected an error for a namespace without an operator")
+	}
+}
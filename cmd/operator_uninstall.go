@@ -15,7 +15,6 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type operatorUninstallCommandConfig struct {
@@ -54,7 +54,7 @@ func newOperatorUninstallCmd(operatorConfig *operatorCommandConfig) *cobra.Comma
 				return removeErr
 			}
 
-			operCount, operCountErr := operatorCount(config.LoggingConfig, config.Dryrun)
+			operCount, operCountErr := operatorCount(config.RootCommandConfig, config.Dryrun)
 			config.Debug.log("Appsody operator count is: ", operCount)
 			if operCountErr != nil {
 				return operCountErr
@@ -88,7 +88,7 @@ func removeOperatorCRDs(config *operatorUninstallCommandConfig) error {
 		return err
 
 	}
-	err = KubeDelete(config.LoggingConfig, appsodyCRD, config.namespace, config.Dryrun)
+	err = KubeDelete(config.RootCommandConfig, appsodyCRD, config.namespace, config.Dryrun)
 	if err != nil {
 		return err
 	}
@@ -113,7 +113,7 @@ func removeOperatorRBAC(operatorNamespace string, config *operatorUninstallComma
 		return err
 
 	}
-	err = KubeDelete(config.LoggingConfig, appsodyRBAC, config.namespace, config.Dryrun)
+	err = KubeDelete(config.RootCommandConfig, appsodyRBAC, config.namespace, config.Dryrun)
 	if err != nil {
 		config.Debug.log("Error in KubeDelete: ", err)
 		return err
@@ -128,37 +128,37 @@ func removeOperatorRBAC(operatorNamespace string, config *operatorUninstallComma
 }
 
 func removeOperator(operatorNamespace string, config *operatorUninstallCommandConfig) error {
-	var watchNamespace string
+	var watchSpaces []string
 	deployConfigDir, err := getDeployConfigDir(config.RootCommandConfig)
 	if err != nil {
 		return errors.Errorf("Error getting deploy config dir: %v", err)
 	}
 	operatorYaml := filepath.Join(deployConfigDir, operatorYamlName)
 	if !config.Dryrun {
-		watchNamespace, err = getOperatorWatchspace(config.LoggingConfig, operatorNamespace, config.Dryrun)
-		config.Debug.logf("Operator is watching the '%s' namespace", watchNamespace)
+		watchSpaces, err = getOperatorWatchspaces(config.RootCommandConfig, operatorNamespace, config.Dryrun)
 		if err != nil {
 			return err
 		}
+		config.Debug.logf("Operator is watching the '%s' namespace", strings.Join(watchSpaces, ","))
 	} else {
-		config.Info.log("Dry run - skipping execution of: getOperatorWatchspace(" + operatorNamespace + ")")
+		config.Info.log("Dry run - skipping execution of: getOperatorWatchspaces(" + operatorNamespace + ")")
 	}
+	watchNamespace := strings.Join(watchSpaces, ",")
 
-	watchSpaces := getWatchSpaces(watchNamespace, config.Dryrun)
 	if watchSpaces == nil {
 		watchSpaces = append(watchSpaces, "")
 	}
 	for _, currentWatchSpace := range watchSpaces {
 		// If there are running apps...
-		appsCount, err := appsodyApplicationCount(config.LoggingConfig, currentWatchSpace, config.Dryrun)
+		appsCount, err := appsodyApplicationCount(config.RootCommandConfig, currentWatchSpace, config.Dryrun)
 		if err != nil {
 			return errors.Errorf("Could not determine if there are AppsodyApplication instances: %v", err)
 		}
 		if appsCount > 0 {
 			if config.force {
-				deleteOut, err := deleteAppsodyApps(config.LoggingConfig, currentWatchSpace, config.Dryrun)
+				err := deleteAppsodyApps(config.RootCommandConfig, currentWatchSpace, config.Dryrun)
 				if err != nil {
-					return errors.Errorf("Could not remove appsody apps: %v %s", err, deleteOut)
+					return errors.Errorf("Could not remove appsody apps: %v", err)
 				}
 			} else {
 				config.Debug.log("There are outstanding appsody applications for this operator - resubmit the command with --force if you want to remove them.")
@@ -171,8 +171,8 @@ func removeOperator(operatorNamespace string, config *operatorUninstallCommandCo
 	for _, currentWatchSpace := range watchSpaces {
 		if currentWatchSpace != operatorNamespace {
 			if err := removeOperatorRBAC(operatorNamespace, config); err != nil {
-				config.Debug.logf("Error from removeOperatorRBAC: %s", fmt.Sprintf("%v", err))
-				if !strings.Contains(fmt.Sprintf("%v", err), "(NotFound)") {
+				config.Debug.logf("Error from removeOperatorRBAC: %v", err)
+				if !apierrors.IsNotFound(errors.Cause(err)) {
 					return err
 				}
 			}
@@ -186,7 +186,7 @@ func removeOperator(operatorNamespace string, config *operatorUninstallCommandCo
 		return err
 	}
 
-	err = KubeDelete(config.LoggingConfig, operatorYaml, config.namespace, config.Dryrun)
+	err = KubeDelete(config.RootCommandConfig, operatorYaml, config.namespace, config.Dryrun)
 	if err != nil {
 		return err
 	}
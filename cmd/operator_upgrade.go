@@ -0,0 +1,109 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type operatorUpgradeCommandConfig struct {
+	*operatorCommandConfig
+	version       string
+	fromBundle    string
+	imageRegistry string
+}
+
+func newOperatorUpgradeCmd(operatorConfig *operatorCommandConfig) *cobra.Command {
+	config := &operatorUpgradeCommandConfig{operatorCommandConfig: operatorConfig}
+	var upgradeCmd = &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the Appsody Operator.",
+		Long: `Upgrade the Appsody Operator in your configured Kubernetes cluster to the latest release, or to the release given by the --version flag.
+
+The AppsodyApplication CRD, the cluster RBAC and the operator deployment are updated in place. The operator keeps watching the same namespaces, and your AppsodyApplications are not deleted.`,
+		Example: `  appsody operator upgrade --namespace my-namespace
+  Upgrades the Appsody Operator in the "my-namespace" namespace to the latest release.
+
+  appsody operator upgrade --version 0.3.0
+  Upgrades the Appsody Operator in the "default" namespace to release 0.3.0.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			return operatorUpgrade(config)
+		},
+	}
+
+	upgradeCmd.PersistentFlags().StringVar(&config.version, "version", "", "The release of the operator to upgrade to, for example 0.3.0. The latest release is used by default.")
+	upgradeCmd.PersistentFlags().StringVar(&config.fromBundle, "from-bundle", "", "Upgrade the operator from a bundle created by 'appsody operator bundle', instead of downloading it.")
+	upgradeCmd.PersistentFlags().StringVar(&config.imageRegistry, "image-registry", "", "The registry that the images in the bundle were copied to.")
+	return upgradeCmd
+}
+
+func operatorUpgrade(config *operatorUpgradeCommandConfig) error {
+	source, cleanup, err := getOperatorSource(config.operatorCommandConfig, config.version, config.fromBundle, config.imageRegistry)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	operatorNamespace := "default"
+	if config.namespace != "" {
+		operatorNamespace = config.namespace
+	}
+
+	watchNamespace := operatorNamespace
+	if config.Dryrun {
+		config.Info.log("Dry run - skipping check of the appsody-operator in namespace: ", operatorNamespace)
+	} else {
+		client, err := getKubeClient(config.RootCommandConfig)
+		if err != nil {
+			return err
+		}
+		status, err := client.OperatorStatus(operatorNamespace)
+		if err != nil {
+			return err
+		}
+		// keep the namespaces that the operator watches, where an empty list means all namespaces
+		watchNamespace = strings.Join(status.Watchspaces, ",")
+		config.Info.logf("Upgrading the Appsody operator in namespace %s from version %s", operatorNamespace, status.Version)
+	}
+
+	err = applyOperator(config.operatorCommandConfig, source, operatorNamespace, watchNamespace, watchNamespace != operatorNamespace)
+	if err != nil {
+		return err
+	}
+
+	config.Info.log("Appsody operator upgraded in Kubernetes")
+	if config.Dryrun {
+		return nil
+	}
+	err = waitForOperator(config.RootCommandConfig, operatorNamespace)
+	if err != nil {
+		return err
+	}
+	client, err := getKubeClient(config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	status, err := client.OperatorStatus(operatorNamespace)
+	if err != nil {
+		return err
+	}
+	config.Info.log("\n", FormatOperatorStatus(status))
+	return nil
+}
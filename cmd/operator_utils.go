@@ -14,319 +14,261 @@
 package cmd
 
 import (
-	"os/exec"
 	"strings"
 
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-//RunKubeExec issues kubectl exec <arg>
-func RunKubeExec(log *LoggingConfig, args []string, dryrun bool) (string, error) {
-	log.Info.log("Attempting to get resource from Kubernetes ...")
-	kargs := []string{"exec"}
-	kargs = append(kargs, args...)
-	return RunKube(log, kargs, dryrun)
+const operatorName = "appsody-operator"
 
-}
-
-//RunKubeGet issues kubectl get <arg>
-func RunKubeGet(log *LoggingConfig, args []string, dryrun bool) (string, error) {
-	log.Info.log("Attempting to get resource from Kubernetes ...")
-	kargs := []string{"get"}
-	kargs = append(kargs, args...)
-	return RunKube(log, kargs, dryrun)
-
-}
-
-//RunKubeDelete issues kubectl delete <args>
-func RunKubeDelete(log *LoggingConfig, args []string, dryrun bool) (string, error) {
-	log.Info.log("Attempting to delete resource from Kubernetes ...")
-	kargs := []string{"delete"}
-	kargs = append(kargs, args...)
-	return RunKube(log, kargs, dryrun)
-}
+var appsodyApplicationKind = schema.GroupKind{Group: "appsody.dev", Kind: "AppsodyApplication"}
 
-//RunKube runs a generic kubectl command
-func RunKube(log *LoggingConfig, kargs []string, dryrun bool) (string, error) {
-	kcmd := "kubectl"
-	if dryrun {
-		log.Info.log("Dry run - skipping execution of: ", kcmd, " ", ArgsToString(kargs))
-		return "", nil
+// OperatorExistsInNamespace checks whether the appsody-operator deployment exists in the namespace
+func (c *KubeClient) OperatorExistsInNamespace(namespace string) (bool, error) {
+	_, err := c.Clientset.AppsV1().Deployments(namespace).Get(operatorName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
 	}
-	log.Info.log("Running command: ", kcmd, " ", ArgsToString(kargs))
-	execCmd := exec.Command(kcmd, kargs...)
-	kout, err := execCmd.Output()
-
 	if err != nil {
-		kerr := kout
-
-		// On failure, stderr is most likely to have the diagnostics.
-		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
-			kerr = ee.Stderr
-		}
-		return "", errors.Errorf("kubectl command failed: %s", kerr)
+		return false, err
 	}
-	log.Debug.log("Command successful...")
-	return string(kout), nil
+	return true, nil
 }
 
-/*
-func downloadOperatorYaml(url string, operatorNamespace string, watchNamespace string, target string) (string, error) {
-	if dryrun {
-		Info.log("Skipping download of operator yaml: ", url)
-		return "", nil
-
+// OperatorNamespaces returns the namespaces that have an appsody-operator pod,
+// either in the given namespace or, if allNamespaces is set, across the cluster
+func (c *KubeClient) OperatorNamespaces(namespace string, allNamespaces bool) ([]string, error) {
+	if allNamespaces {
+		namespace = metav1.NamespaceAll
 	}
-	file, err := downloadYaml(url, target)
+	pods, err := c.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: "name=" + operatorName})
 	if err != nil {
-		return "", fmt.Errorf("Could not download Operator YAML file %s", url)
+		return nil, err
+	}
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if !seen[pod.Namespace] {
+			seen[pod.Namespace] = true
+			namespaces = append(namespaces, pod.Namespace)
+		}
 	}
+	return namespaces, nil
+}
 
-	yamlReader, err := ioutil.ReadFile(file)
+// OperatorWatchspace returns the value of WATCH_NAMESPACE in the appsody-operator deployment.
+// An empty value means that the operator watches all namespaces.
+func (c *KubeClient) OperatorWatchspace(namespace string) (string, error) {
+	deployment, err := c.Clientset.AppsV1().Deployments(namespace).Get(operatorName, metav1.GetOptions{})
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", errors.Errorf("Downloaded file does not exist %s. ", target)
-
+		return "", err
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name != "WATCH_NAMESPACE" {
+				continue
+			}
+			// the operator can be set up to watch the namespace that it runs in
+			if env.ValueFrom != nil && env.ValueFrom.FieldRef != nil && env.ValueFrom.FieldRef.FieldPath == "metadata.namespace" {
+				return namespace, nil
+			}
+			return env.Value, nil
 		}
-		return "", errors.Errorf("Failed reading file %s", target)
-
 	}
+	return "", nil
+}
 
-	//output := bytes.Replace(yamlReader, []byte("APPSODY_OPERATOR_NAMESPACE"), []byte(operatorNamespace), -1)
-	output := bytes.Replace(yamlReader, []byte("APPSODY_WATCH_NAMESPACE"), []byte(watchNamespace), -1)
-
-	err = ioutil.WriteFile(target, output, 0666)
+// OperatorCount returns the number of appsody-operator deployments in the cluster
+func (c *KubeClient) OperatorCount() (int, error) {
+	deployments, err := c.Clientset.AppsV1().Deployments(metav1.NamespaceAll).List(metav1.ListOptions{})
 	if err != nil {
-		return "", errors.Errorf("Failed to write local operator definition file: %s", err)
+		return 0, err
 	}
-	return target, nil
+	count := 0
+	for _, deployment := range deployments.Items {
+		if deployment.Name == operatorName {
+			count++
+		}
+	}
+	return count, nil
 }
 
-func downloadRBACYaml(url string, operatorNamespace string, target string) (string, error) {
-	if dryrun {
-		Info.log("Skipping download of RBAC yaml: ", url)
-		return "", nil
-
+// AppsodyApplicationCount returns the number of AppsodyApplications in the namespace, or in all namespaces if the namespace is empty
+func (c *KubeClient) AppsodyApplicationCount(namespace string) (int, error) {
+	apps, err := c.List(appsodyApplicationKind, namespace, namespace == "", "")
+	if meta.IsNoMatchError(err) {
+		// the AppsodyApplication CRD is not installed, so there can't be any applications
+		return 0, nil
 	}
-	file, err := downloadYaml(url, target)
 	if err != nil {
-		return "", fmt.Errorf("Could not download RBAC YAML file %s", url)
+		return 0, err
 	}
+	return len(apps.Items), nil
+}
 
-	yamlReader, err := ioutil.ReadFile(file)
+// DeleteAppsodyApplications deletes the AppsodyApplications in the namespace, or in all namespaces if the namespace is empty.
+// Each one is deleted in its own namespace, as the API server does not delete a collection of a namespaced resource
+// across all namespaces.
+func (c *KubeClient) DeleteAppsodyApplications(namespace string) error {
+	mapping, err := c.Mapper.RESTMapping(appsodyApplicationKind)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", errors.Errorf("Downloaded file does not exist %s. ", target)
-
-		}
-		return "", errors.Errorf("Failed reading file %s", target)
-
+		return err
 	}
-
-	output := bytes.Replace(yamlReader, []byte("APPSODY_OPERATOR_NAMESPACE"), []byte(operatorNamespace), -1)
-	//output = bytes.Replace(output, []byte("APPSODY_WATCH_NAMESPACE"), []byte(watchNamespace), -1)
-
-	err = ioutil.WriteFile(target, output, 0666)
+	apps, err := c.List(appsodyApplicationKind, namespace, namespace == "", "")
 	if err != nil {
-		return "", errors.Errorf("Failed to write local operator definition file: %s", err)
+		return err
+	}
+	for _, app := range apps.Items {
+		err = c.Dynamic.Resource(mapping.Resource).Namespace(app.GetNamespace()).Delete(app.GetName(), &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Errorf("Could not delete AppsodyApplication %s in namespace %s: %v", app.GetName(), app.GetNamespace(), err)
+		}
 	}
-	return target, nil
+	return nil
 }
-*/
-func operatorExistsInNamespace(log *LoggingConfig, operatorNamespace string, dryrun bool) (bool, error) {
-
-	// check to see if this namespace already has an appsody-operator
-	//var args = []string{"deployment", "appsody-operator", "-n", operatorNamespace}
-	var args = []string{"deployments", "-o=jsonpath='{.items[?(@.metadata.name==\"appsody-operator\")].metadata.namespace}'", "-n", operatorNamespace}
 
-	getOutput, getErr := RunKubeGet(log, args, dryrun)
-	if getErr != nil {
-		log.Debug.log("Received an err: ", getErr)
-		return false, getErr
-	}
-	getOutput = strings.Trim(getOutput, "'")
-	if getOutput == "" {
-		log.Info.log("There are no deployments with appsody-operator")
+func operatorExistsInNamespace(config *RootCommandConfig, operatorNamespace string, dryrun bool) (bool, error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping check for an appsody-operator in namespace: ", operatorNamespace)
 		return false, nil
 	}
-	return true, nil
-
+	client, err := getKubeClient(config)
+	if err != nil {
+		return false, err
+	}
+	exists, err := client.OperatorExistsInNamespace(operatorNamespace)
+	if err != nil {
+		config.Debug.log("Received an err: ", err)
+		return false, err
+	}
+	if !exists {
+		config.Info.log("There are no deployments with appsody-operator")
+	}
+	return exists, nil
 }
 
 // Check to see if any other operator is watching the watchNameSpace
-func operatorExistsWithWatchspace(log *LoggingConfig, watchNamespace string, dryrun bool, noOperatorCheck bool) (bool, string, error) {
-	log.Debug.log("Looking for an operator matching watchspace: ", watchNamespace)
-	var namespacesWithOperatorsGetArgs []string
-	if noOperatorCheck {
-		namespacesWithOperatorsGetArgs = []string{"pods", "-o=jsonpath='{.items[?(@.metadata.labels.name==\"appsody-operator\")].metadata.namespace}'", "-n", watchNamespace}
-	} else {
-		namespacesWithOperatorsGetArgs = []string{"pods", "-o=jsonpath='{.items[?(@.metadata.labels.name==\"appsody-operator\")].metadata.namespace}'", "--all-namespaces"}
-	}
-	getNamespacesOutput, getNamespacesErr := RunKubeGet(log, namespacesWithOperatorsGetArgs, dryrun)
-
-	if getNamespacesErr != nil {
-		return false, "", errors.Errorf("Unable to query namespaces. Please check with your cluster administrator if an operator is already watching the target namespace. If so, please use the --no-operator-install flag. Otherwise, try the --no-operator-check flag. %v", getNamespacesErr)
+func operatorExistsWithWatchspace(config *RootCommandConfig, watchNamespace string, dryrun bool, noOperatorCheck bool) (bool, string, error) {
+	config.Debug.log("Looking for an operator matching watchspace: ", watchNamespace)
+	if dryrun {
+		config.Info.log("Dry run - skipping check for operators watching namespace: ", watchNamespace)
+		return false, "", nil
+	}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return false, "", err
+	}
+	namespaces, err := client.OperatorNamespaces(watchNamespace, !noOperatorCheck)
+	if err != nil {
+		return false, "", errors.Errorf("Unable to query namespaces. Please check with your cluster administrator if an operator is already watching the target namespace. If so, please use the --no-operator-install flag. Otherwise, try the --no-operator-check flag. %v", err)
 	}
-	getNamespacesOutput = strings.Trim(getNamespacesOutput, "'’\n")
 
-	if getNamespacesOutput == "" {
-		log.Info.log("There are no deployments with appsody-operator")
+	if len(namespaces) == 0 {
+		config.Info.log("There are no deployments with appsody-operator")
 		return false, "", nil
 	}
-	if watchNamespace == "" && getNamespacesOutput != "" {
+	if watchNamespace == "" {
 		watchAllErr := errors.Errorf("You specified --watch-all, but there are already instances of the appsody operator on the cluster")
 		return true, "", watchAllErr
 	}
 
-	namespaces := strings.Split(getNamespacesOutput, " ")
-	log.Debug.log("namespaces with operators: ", namespaces)
+	config.Debug.log("namespaces with operators: ", namespaces)
 	for _, podNamespace := range namespaces {
-
-		podWatchSpace, watchspaceErr := getOperatorWatchspace(log, podNamespace, dryrun)
+		watchSpaces, watchspaceErr := getOperatorWatchspaces(config, podNamespace, dryrun)
 		if watchspaceErr != nil {
 			return false, "", watchspaceErr
 		}
 		// the operator is watching all namespaces
-		if podWatchSpace == "" {
-			log.Info.logf("An operator exists in namespace %s, that is watching all namespaces", podNamespace)
+		if len(watchSpaces) == 0 {
+			config.Info.logf("An operator exists in namespace %s, that is watching all namespaces", podNamespace)
 			return true, podNamespace, nil
 		}
-		// split the podwatchSpace by using new function
-		// do the iff "" check first
-		// loop around this if check
-		watchSpaces := getWatchSpaces(podWatchSpace, dryrun)
 		for _, value := range watchSpaces {
 			if value == watchNamespace {
-				log.Debug.logf("An operator that is watching namespace %s already exists in namespace %s", watchNamespace, podNamespace)
+				config.Debug.logf("An operator that is watching namespace %s already exists in namespace %s", watchNamespace, podNamespace)
 				return true, podNamespace, nil
 			}
 		}
-
 	}
 	return false, "", nil
 }
 
-/*
-
-func operatorExistsWithWatchspace(watchNamespace string, dryrun bool) (bool, string, error) {
-	Debug.log("Looking for an operator matching watchspace: ", watchNamespace)
-	var deploymentsWithOperatorsGetArgs = []string{"deployments", "-o=jsonpath='{.items[?(@.metadata.name==\"appsody-operator\")].metadata.namespace}'", "--all-namespaces"}
-	getOutput, getErr := RunKubeGet(deploymentsWithOperatorsGetArgs, dryrun)
-	if getErr != nil {
-		return false, "", getErr
-	}
-	getOutput = strings.Trim(getOutput, "'")
-	if getOutput == "" {
-		Info.log("There are no deployments with appsody-operator")
-		return false, "", nil
-	}
-	if watchNamespace == "" && getOutput != "" {
-		watchAllErr := errors.Errorf("You specified --watch-all, but there are already instances of the appsody operator on the cluster")
-		return true, "", watchAllErr
+func operatorCount(config *RootCommandConfig, dryrun bool) (int, error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping count of appsody-operator deployments")
+		return 0, nil
 	}
-	deployments := strings.Split(getOutput, " ")
-	Debug.log("deployments with operators: ", deployments)
-	for _, deploymentNamespace := range deployments {
-		var getDeploymentWatchNamespaceArgs = []string{"deployment", "-o=jsonpath='{.items[?(@.metadata.name==\"appsody-operator\")].spec.template.spec.containers[0].env[?(@.name==\"WATCH_NAMESPACE\")].value}'", "-n", deploymentNamespace}
-		getOutput, getErr = RunKubeGet(getDeploymentWatchNamespaceArgs, dryrun)
-		Debug.logf("Deployment: %s is watching namespace %s", deploymentNamespace, getOutput)
-		if getErr != nil {
-			return false, "", getErr
-		}
-		if strings.Trim(getOutput, "'") == watchNamespace {
-			Debug.logf("An operator that is watching namespace %s already exists in namespace %s", watchNamespace, deploymentNamespace)
-			return true, deploymentNamespace, nil
-		}
-		// the operator is watching all namespaces
-		if strings.Trim(getOutput, "'") == "" {
-
-			Info.logf("An operator exists in namespace %s, that is watching all namespaces", deploymentNamespace)
-			return true, deploymentNamespace, nil
-		}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return 0, err
 	}
-	return false, "", nil
+	return client.OperatorCount()
 }
 
-*/
-
-func operatorCount(log *LoggingConfig, dryrun bool) (int, error) {
-	var getAllOperatorsArgs = []string{"deployments", "-o=jsonpath='{.items[?(@.metadata.name==\"appsody-operator\")].metadata.name}'", "--all-namespaces"}
-	getOutput, getErr := RunKubeGet(log, getAllOperatorsArgs, dryrun)
-	if getErr != nil {
-		return 0, getErr
+func appsodyApplicationCount(config *RootCommandConfig, namespace string, dryrun bool) (int, error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping count of AppsodyApplications")
+		return 0, nil
 	}
-	return strings.Count(getOutput, "appsody-operator"), nil
-}
-
-func appsodyApplicationCount(log *LoggingConfig, namespace string, dryrun bool) (int, error) {
-	var getAppsodyAppsArgs = []string{"AppsodyApplication", "-o=jsonpath='{.items[*].kind}'"}
-	if namespace == "" {
-		getAppsodyAppsArgs = append(getAppsodyAppsArgs, "--all-namespaces")
-	} else {
-		getAppsodyAppsArgs = append(getAppsodyAppsArgs, "-n", namespace)
-	}
-	getOutput, getErr := RunKubeGet(log, getAppsodyAppsArgs, dryrun)
-	if getErr != nil {
-		return 0, getErr
-	}
-	return strings.Count(getOutput, "AppsodyApplication"), nil
+	client, err := getKubeClient(config)
+	if err != nil {
+		return 0, err
+	}
+	return client.AppsodyApplicationCount(namespace)
 }
 
-func deleteAppsodyApps(log *LoggingConfig, namespace string, dryrun bool) (string, error) {
-	var deleteAppsodyAppsArgs = []string{"AppsodyApplication", "--all"}
-	if namespace != "" {
-		deleteAppsodyAppsArgs = append(deleteAppsodyAppsArgs, "-n", namespace)
+func deleteAppsodyApps(config *RootCommandConfig, namespace string, dryrun bool) error {
+	config.Info.log("Attempting to delete resource from Kubernetes ...")
+	if dryrun {
+		config.Info.log("Dry run - skipping delete of the AppsodyApplications in namespace: ", namespace)
+		return nil
 	}
-	return RunKubeDelete(log, deleteAppsodyAppsArgs, dryrun)
-
+	client, err := getKubeClient(config)
+	if err != nil {
+		return err
+	}
+	return client.DeleteAppsodyApplications(namespace)
 }
 
-func getOperatorWatchspace(log *LoggingConfig, namespace string, dryrun bool) (string, error) {
-	operatorExists, existsErr := operatorExistsInNamespace(log, namespace, dryrun)
+// getOperatorWatchspaces returns the namespaces watched by the operator in the namespace,
+// or an empty list if the operator watches the entire cluster
+func getOperatorWatchspaces(config *RootCommandConfig, namespace string, dryrun bool) ([]string, error) {
+	operatorExists, existsErr := operatorExistsInNamespace(config, namespace, dryrun)
 	if existsErr != nil {
-		return "", existsErr
+		return nil, existsErr
 	}
 	if !operatorExists {
-		return "", errors.Errorf("An appsody operator could not be found in namespace: %s", namespace)
+		return nil, errors.Errorf("An appsody operator could not be found in namespace: %s", namespace)
 	}
-
-	var getPodWatchNamespaceArgs = []string{"pod", "-o=jsonpath='{.items[?(@.metadata.labels.name==\"appsody-operator\")].metadata.name}'", "-n", namespace}
-
-	getPodsOutput, getPodsErr := RunKubeGet(log, getPodWatchNamespaceArgs, dryrun)
-
-	if getPodsErr != nil {
-		return "", getPodsErr
+	client, err := getKubeClient(config)
+	if err != nil {
+		return nil, err
 	}
-	// we should now have the pod name
-	podName := strings.Trim(getPodsOutput, "'’\n")
-
-	getWatchspaceArgs := []string{"-n", namespace, "-it", podName, "--", "/bin/printenv", "WATCH_NAMESPACE"}
-
-	getWatchspaceOutput, getWatchspaceErr := RunKubeExec(log, getWatchspaceArgs, dryrun)
-
-	if getWatchspaceErr != nil {
-		return "", getWatchspaceErr
+	watchspaceForOperator, err := client.OperatorWatchspace(namespace)
+	if err != nil {
+		return nil, err
 	}
-
-	watchspaceForOperator := strings.Trim(getWatchspaceOutput, "'’\n")
 	if watchspaceForOperator == "" {
-		log.Debug.log("This operator watches the entire cluster ")
+		config.Debug.log("This operator watches the entire cluster ")
 	}
-	log.Debug.Logf("Pod: %s in namespace: %s is watching namespace: %s", podName, namespace, watchspaceForOperator)
-
-	return watchspaceForOperator, nil
+	config.Debug.Logf("Operator in namespace: %s is watching namespace: %s", namespace, watchspaceForOperator)
+	return getWatchSpaces(watchspaceForOperator), nil
 }
 
-// create a function to parse the watchlist
-func getWatchSpaces(csvList string, dryrun bool) []string {
+// WATCH_NAMESPACE is a comma separated list of namespaces
+func getWatchSpaces(csvList string) []string {
 	if csvList == "" {
 		return nil
 	}
-	// split the string and clean up any issues
-	watchList := strings.Split(csvList, ",")
-	for index := range watchList {
-		watchList[index] = strings.Trim(watchList[index], " '’\n")
-
+	var watchList []string
+	for _, watchspace := range strings.Split(csvList, ",") {
+		if watchspace = strings.TrimSpace(watchspace); watchspace != "" {
+			watchList = append(watchList, watchspace)
+		}
 	}
 	return watchList
 }
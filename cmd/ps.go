@@ -33,14 +33,18 @@ type StackContainer struct {
 	ports         string
 }
 
-func newPsCmd(log *LoggingConfig) *cobra.Command {
+func newPsCmd(config *RootCommandConfig) *cobra.Command {
+	log := config.LoggingConfig
+	var workspace bool
 	// psCmd represents the ps command
 	var psCmd = &cobra.Command{
 		Use:   "ps",
 		Short: "List the Appsody containers running in the local Docker environment.",
 		Long: `List all stack-based containers that are currently running in the local Docker environment. 
 		
-Shows the following information about the Appsody containers that are currently running: container ID, container name, image and status.`,
+Shows the following information about the Appsody containers that are currently running: container ID, container name, image and status.
+
+With the "--workspace" flag, only the containers of the projects in the "` + WorkspaceFile + `" file of the current or a parent directory are shown, with the name of their project.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			if len(args) > 0 {
@@ -51,6 +55,15 @@ Shows the following information about the Appsody containers that are currently
 				return err
 			}
 
+			if workspace {
+				table, err := formatWorkspaceTable(config, containers)
+				if err != nil {
+					return err
+				}
+				log.Info.log(table)
+				return nil
+			}
+
 			table, err := formatTable(log, containers)
 			if err != nil {
 				return errors.Errorf("%v", err)
@@ -61,6 +74,7 @@ Shows the following information about the Appsody containers that are currently
 			return nil
 		},
 	}
+	psCmd.PersistentFlags().BoolVar(&workspace, "workspace", false, "Show the development containers of all of the projects in the workspace.")
 	return psCmd
 }
 
@@ -130,3 +144,36 @@ func formatTable(log *LoggingConfig, containers []StackContainer) (string, error
 
 	return table.String(), nil
 }
+
+// formatWorkspaceTable lists the containers of the projects in the workspace, which are named after their project by default
+func formatWorkspaceTable(config *RootCommandConfig, containers []StackContainer) (string, error) {
+	workspace, err := getWorkspace(config)
+	if err != nil {
+		return "", err
+	}
+	table := uitable.New()
+	table.MaxColWidth = 60
+	table.Wrap = true
+	table.AddRow("PROJECT", "CONTAINER ID", "NAME", "IMAGE", "PORTS", "STATUS")
+	for _, project := range workspace.Projects {
+		projectConfig, err := newWorkspaceProjectConfig(config, workspace.ProjectDir(project))
+		if err != nil {
+			return "", err
+		}
+		containerName, err := getProjectName(projectConfig)
+		if err != nil {
+			return "", err
+		}
+		running := false
+		for _, container := range containers {
+			if container.containerName == containerName {
+				table.AddRow(project.Name, container.ID, container.containerName, container.stackName, container.ports, container.status)
+				running = true
+			}
+		}
+		if !running {
+			table.AddRow(project.Name, "", containerName, "", "", "Not running")
+		}
+	}
+	return table.String(), nil
+}
@@ -0,0 +1,331 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReleaseFile is a file read from a release target, with the ETag that the target returned for it, if any
+type ReleaseFile struct {
+	Data []byte
+	ETag string
+}
+
+// ReleaseTarget is where appsody stack publish uploads the archives and the repository index of a stack.
+// Names are relative to the target and use forward slashes.
+type ReleaseTarget interface {
+	// URL returns the URL that a published file is downloaded from
+	URL(name string) string
+	// Get returns a published file, or nil if the file does not exist
+	Get(name string) (*ReleaseFile, error)
+	// Put publishes a file, replacing it as a whole. If etag is not empty,
+	// the file is only replaced if it still has that ETag.
+	Put(name string, data []byte, etag string) error
+}
+
+// ErrReleaseFileChanged is returned by Put when the file changed since it was read
+var ErrReleaseFileChanged = errors.New("The file was changed by someone else while it was being updated")
+
+// NewReleaseTarget returns the release target for a directory, an http(s):// URL that accepts PUT requests,
+// or an s3://<bucket>/<prefix> location in S3 or an S3 compatible object store
+func NewReleaseTarget(log *LoggingConfig, creds *CredentialStore, target string) (ReleaseTarget, error) {
+	switch {
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		if _, err := url.Parse(target); err != nil {
+			return nil, errors.Errorf("The release target %s is not a valid URL: %v", target, err)
+		}
+		return &httpReleaseTarget{log: log, creds: creds, baseURL: strings.TrimSuffix(target, "/"), authorize: func(req *http.Request, body []byte) error {
+			return creds.Authorize(req)
+		}}, nil
+	case strings.HasPrefix(target, "s3://"):
+		return newS3ReleaseTarget(log, target)
+	case strings.Contains(target, "://"):
+		return nil, errors.Errorf("The release target %s is not supported. Specify a directory, an http:// or https:// URL, or an s3:// location", target)
+	}
+	dir, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+	return &dirReleaseTarget{dir: dir}, nil
+}
+
+// dirReleaseTarget publishes files to a local directory, for example one that is served by a web server
+type dirReleaseTarget struct {
+	dir string
+}
+
+func (t *dirReleaseTarget) file(name string) string {
+	return filepath.Join(t.dir, filepath.FromSlash(name))
+}
+
+func (t *dirReleaseTarget) URL(name string) string {
+	fileURL, err := MirrorURL(t.dir, "", name)
+	if err != nil {
+		return "file://" + filepath.ToSlash(t.file(name))
+	}
+	return fileURL
+}
+
+func (t *dirReleaseTarget) Get(name string) (*ReleaseFile, error) {
+	data, err := ioutil.ReadFile(t.file(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read %s: %v", t.file(name), err)
+	}
+	return &ReleaseFile{Data: data}, nil
+}
+
+// Put writes the file next to its destination and renames it, so that readers never see a partly written file
+func (t *dirReleaseTarget) Put(name string, data []byte, etag string) error {
+	file := t.file(name)
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return errors.Errorf("Could not create directory %s: %v", filepath.Dir(file), err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".")
+	if err != nil {
+		return errors.Errorf("Could not create a temporary file for %s: %v", file, err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Errorf("Could not write %s: %v", file, err)
+	}
+	return nil
+}
+
+// httpReleaseTarget publishes files with PUT requests to URLs under a base URL
+type httpReleaseTarget struct {
+	log *LoggingConfig
+	// creds are used when a request is redirected to another host, which does not get the credentials of the target
+	creds     *CredentialStore
+	baseURL   string
+	authorize func(req *http.Request, body []byte) error
+}
+
+func (t *httpReleaseTarget) URL(name string) string {
+	return t.baseURL + "/" + name
+}
+
+func (t *httpReleaseTarget) do(method string, name string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, t.URL(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	err = t.authorize(req, body)
+	if err != nil {
+		return nil, err
+	}
+	t.log.Debug.logf("%s %s", method, req.URL)
+	return newDownloadClient(t.log, t.creds).Do(req)
+}
+
+func (t *httpReleaseTarget) Get(name string) (*ReleaseFile, error) {
+	resp, err := t.do(http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, errors.Errorf("Could not read %s: %v", t.URL(name), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Could not read %s: %s", t.URL(name), resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Errorf("Could not read %s: %v", t.URL(name), err)
+	}
+	return &ReleaseFile{Data: data, ETag: resp.Header.Get("ETag")}, nil
+}
+
+func (t *httpReleaseTarget) Put(name string, data []byte, etag string) error {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	resp, err := t.do(http.MethodPut, name, data, header)
+	if err != nil {
+		return errors.Errorf("Could not upload %s: %v", t.URL(name), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return ErrReleaseFileChanged
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		t.log.Debug.logf("Contents http response:\n%s", body)
+		return errors.Errorf("Could not upload %s: %s", t.URL(name), resp.Status)
+	}
+	return nil
+}
+
+// S3Credentials are the credentials used to sign requests to S3 or an S3 compatible object store
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// newS3ReleaseTarget returns a release target for s3://<bucket>/<prefix>, using path style URLs of the endpoint
+// in AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL, or else of the AWS endpoint for the region
+func newS3ReleaseTarget(log *LoggingConfig, target string) (ReleaseTarget, error) {
+	location := strings.Trim(strings.TrimPrefix(target, "s3://"), "/")
+	if location == "" {
+		return nil, errors.Errorf("The release target %s does not have a bucket", target)
+	}
+	credentials := S3Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, errors.New("Set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY to publish to an s3:// release target")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	log.Debug.logf("Using S3 endpoint %s in region %s", endpoint, region)
+	return &httpReleaseTarget{log: log, baseURL: strings.TrimSuffix(endpoint, "/") + "/" + location, authorize: func(req *http.Request, body []byte) error {
+		SignS3Request(req, body, credentials, region, time.Now())
+		return nil
+	}}, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignS3Request adds an AWS signature version 4 to a request for S3, signing the host and every header of the request
+func SignS3Request(req *http.Request, body []byte, credentials S3Credentials, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		if strings.EqualFold(key, "Authorization") {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			canonicalQuery = append(canonicalQuery, url.QueryEscape(key)+"="+strings.Replace(url.QueryEscape(value), "+", "%20", -1))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := path.Join(date, region, "s3", "aws4_request")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
@@ -15,11 +15,9 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
 	"io/ioutil"
 	"sort"
-	"strings"
 
 	//"math/rand"
 
@@ -40,6 +38,7 @@ type Stack struct {
 	Description string                   `yaml:"description" json:"description"`
 	Templates   []IndexYamlStackTemplate `yaml:"templates,omitempty" json:"templates,omitempty"`
 	Deprecated  string                   `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	ShadowedBy  string                   `yaml:"shadowedBy,omitempty" json:"shadowedBy,omitempty"`
 }
 
 // RepoIndices maps repos to their RepoIndex (i.e. the projects in a repo)
@@ -63,18 +62,30 @@ type RepositoryEntry struct {
 	Name      string `yaml:"name" json:"name"`
 	URL       string `yaml:"url" json:"url"`
 	IsDefault bool   `yaml:"default,omitempty" json:"default,omitempty"`
+	TrustKey  string `yaml:"trust-key,omitempty" json:"trustKey,omitempty"`
+	// Priority decides which repository a stack is taken from when more than one repository has it, highest first
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+	// Source is "team" for the repositories from the team repository file, which are not written to your repository file
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
 }
 
 func findTemplateURL(stackData IndexYamlStack, templateName string) string {
+	if template := findTemplate(stackData, templateName); template != nil {
+		return template.URL
+	}
+	return ""
+}
+
+func findTemplate(stackData IndexYamlStack, templateName string) *IndexYamlStackTemplate {
 	templates := stackData.Templates
 
-	for _, value := range templates {
+	for i, value := range templates {
 		if value.ID == templateName {
-			return value.URL
+			return &templates[i]
 		}
 
 	}
-	return ""
+	return nil
 }
 
 type indexError struct {
@@ -116,6 +127,13 @@ func newRepoCmd(rootConfig *RootCommandConfig) *cobra.Command {
 		newRepoListCmd(rootConfig),
 		newRepoRemoveCmd(rootConfig),
 		newRepoDefaultCmd(rootConfig),
+		newRepoUpdateCmd(rootConfig),
+		newRepoVerifyCmd(rootConfig),
+		newRepoMirrorCmd(rootConfig),
+		newRepoServeCmd(rootConfig),
+		newRepoExportCmd(rootConfig),
+		newRepoImportCmd(rootConfig),
+		newRepoSetPriorityCmd(rootConfig),
 	)
 	return repoCmd
 }
@@ -167,15 +185,20 @@ func ensureConfig(rootConfig *RootCommandConfig) error {
 		} else {
 
 			repo := NewRepoFile()
-			repo.Add(&RepositoryEntry{
-				Name:      "incubator",
-				URL:       incubatorRepositoryURL,
-				IsDefault: true,
-			})
-			repo.Add(&RepositoryEntry{
-				Name: "experimental",
-				URL:  experimentalRepositoryURL,
-			})
+			if team, location, _ := readTeamRepoFile(); team != nil && len(team.Repositories) > 0 {
+				// the team repository file provides the initial repositories
+				rootConfig.Debug.log("Using the repositories of the team repository file ", location)
+			} else {
+				repo.Add(&RepositoryEntry{
+					Name:      "incubator",
+					URL:       incubatorRepositoryURL,
+					IsDefault: true,
+				})
+				repo.Add(&RepositoryEntry{
+					Name: "experimental",
+					URL:  experimentalRepositoryURL,
+				})
+			}
 			rootConfig.Debug.log("Creating ", repoFileLocation)
 			if err := repo.WriteFile(repoFileLocation); err != nil {
 				return errors.Errorf("Error writing %s file: %s ", repoFileLocation, err)
@@ -216,39 +239,38 @@ func ensureConfig(rootConfig *RootCommandConfig) error {
 	return nil
 }
 
-func downloadIndex(log *LoggingConfig, url string) (*IndexYaml, error) {
-	log.Debug.log("Downloading appsody repository index from ", url)
-	indexBuffer := bytes.NewBuffer(nil)
-	err := downloadFile(log, url, indexBuffer)
-	if err != nil {
-		return nil, err
-	}
+// getIndex returns the index of a repository, from the index cache unless refresh is set.
+// The index is verified if the repository has a trusted key.
+func getIndex(config *RootCommandConfig, repo *RepositoryEntry, refresh bool) (*IndexYaml, error) {
+	return getIndexFromCache(config, getIndexCache(config), repo, refresh)
+}
 
-	yamlFile, err := ioutil.ReadAll(indexBuffer)
+func getIndexFromCache(config *RootCommandConfig, cache *IndexCache, repo *RepositoryEntry, refresh bool) (*IndexYaml, error) {
+	yamlFile, err := getVerifiedIndex(cache, repo, refresh)
 	if err != nil {
-		return nil, fmt.Errorf("Could not read buffer into byte array")
+		return nil, err
 	}
 	var index IndexYaml
 	err = yaml.Unmarshal(yamlFile, &index)
 	if err != nil {
-		log.Debug.logf("Contents of downloaded index from %s\n%s", url, yamlFile)
+		config.Debug.logf("Contents of downloaded index from %s\n%s", repo.URL, yamlFile)
 		return nil, fmt.Errorf("Repository index formatting error: %s", err)
 	}
 	return &index, nil
 }
 
-func (index *IndexYaml) listProjects(repoName string, config *RootCommandConfig) (string, error) {
+func (index *IndexYaml) listProjects(repoName string, config *RootCommandConfig, allVersions bool) (string, error) {
 	var Stacks []Stack
 	table := uitable.New()
 	table.MaxColWidth = 60
 	table.Wrap = true
-	if strings.Compare(index.APIVersion, supportedIndexAPIVersion) == 1 {
+	if isNewerIndexAPIVersion(index.APIVersion) {
 		config.Debug.log("Adding unsupported repository", repoName)
 		config.UnsupportedRepos = append(config.UnsupportedRepos, repoName)
 	}
 	table.AddRow("REPO", "ID", "VERSION  ", "TEMPLATES", "DESCRIPTION")
 
-	Stacks = index.buildStacksFromIndex(repoName, Stacks)
+	Stacks = index.buildStacksFromIndex(repoName, Stacks, allVersions)
 
 	for _, value := range Stacks {
 		templatesListString := convertTemplatesArrayToString(value.Templates)
@@ -260,14 +282,13 @@ func (index *IndexYaml) listProjects(repoName string, config *RootCommandConfig)
 	return table.String(), nil
 }
 
-func (r *RepositoryFile) listRepoProjects(repoName string, config *RootCommandConfig) (string, error) {
+func (r *RepositoryFile) listRepoProjects(repoName string, config *RootCommandConfig, allVersions bool) (string, error) {
 	if repo := r.GetRepo(repoName); repo != nil {
-		url := repo.URL
-		index, err := downloadIndex(config.LoggingConfig, url)
+		index, err := getIndex(config, repo, false)
 		if err != nil {
 			return "", err
 		}
-		tableString, err := index.listProjects(repoName, config)
+		tableString, err := index.listProjects(repoName, config, allVersions)
 		if err != nil {
 			return "", err
 		}
@@ -292,6 +313,10 @@ func (r *RepositoryFile) getRepos(rootConfig *RootCommandConfig) (*RepositoryFil
 		return nil, errors.Errorf("Failed to parse repository file %v", err)
 
 	}
+	for _, repo := range r.Repositories {
+		repo.Source = ""
+	}
+	r.mergeTeamRepoFile(rootConfig)
 	return r, nil
 }
 
@@ -299,7 +324,21 @@ func (r *RepositoryFile) listRepos(rootConfig *RootCommandConfig) (string, error
 	var entries = []RepositoryEntry{}
 	table := uitable.New()
 	table.MaxColWidth = 1024
-	table.AddRow("NAME", "URL")
+	// the priority and source columns are only shown when there are repositories with priorities,
+	// or repositories from the team repository file
+	showPriority := false
+	for _, value := range r.Repositories {
+		showPriority = showPriority || value.Priority != 0
+	}
+	showSource := r.hasTeamRepos()
+	header := []interface{}{"NAME", "URL"}
+	if showPriority {
+		header = append(header, "PRIORITY")
+	}
+	if showSource {
+		header = append(header, "SOURCE")
+	}
+	table.AddRow(header...)
 	for _, value := range r.Repositories {
 		repoName := value.Name
 		defaultRepoName, err := r.GetDefaultRepoName(rootConfig)
@@ -309,11 +348,22 @@ func (r *RepositoryFile) listRepos(rootConfig *RootCommandConfig) (string, error
 		if repoName == defaultRepoName {
 			repoName = "*" + repoName
 		}
-		entries = append(entries, RepositoryEntry{repoName, value.URL, value.IsDefault})
+		entries = append(entries, RepositoryEntry{Name: repoName, URL: value.URL, IsDefault: value.IsDefault, Source: value.Source, Priority: value.Priority})
 	}
 	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
 	for _, value := range entries {
-		table.AddRow(value.Name, value.URL)
+		row := []interface{}{value.Name, value.URL}
+		if showPriority {
+			row = append(row, value.Priority)
+		}
+		if showSource {
+			source := value.Source
+			if source == "" {
+				source = "user"
+			}
+			row = append(row, source)
+		}
+		table.AddRow(row...)
 	}
 
 	return table.String(), nil
@@ -372,15 +422,17 @@ func (r *RepositoryFile) GetDefaultRepoName(rootConfig *RootCommandConfig) (stri
 	// If there's only one repo - set it as default
 	// And if incubator isn't there set the first one as default
 	var repoName string
-	if len(r.Repositories) == 1 || !r.Has("incubator") {
-		r.Repositories[0].IsDefault = true
-		repoName = r.Repositories[0].Name
-	} else {
+	repo := r.Repositories[0]
+	if len(r.Repositories) > 1 && r.Has("incubator") {
 		// If there's more than one, let's search for incubator first
-		repo := r.GetRepo("incubator")
-		repo.IsDefault = true
-		repoName = repo.Name
+		repo = r.GetRepo("incubator")
+	}
+	if repo.Source == repoSourceTeam {
+		// the team repository file can't be changed, and its repositories are used in the same order every time
+		return repo.Name, nil
 	}
+	repo.IsDefault = true
+	repoName = repo.Name
 	if err := r.WriteFile(getRepoFileLocation(rootConfig)); err != nil {
 		return "", err
 	}
@@ -410,6 +462,14 @@ func (r *RepositoryFile) SetDefaultRepoName(name string, defaultRepoName string,
 		if rf.Name == name {
 			r.Repositories[index].IsDefault = true
 			repoName = rf.Name
+			adoptRepo(rootConfig, rf)
+		}
+	}
+	// the default repository is the first of the repositories with the highest priority
+	if repo := r.GetRepo(repoName); repo != nil {
+		if highest, ok := r.highestPriority(repoName); ok && repo.Priority < highest {
+			rootConfig.Info.logf("Setting the priority of repository %s to %d, the highest priority of your repositories", repoName, highest)
+			repo.Priority = highest
 		}
 	}
 	if err := r.WriteFile(getRepoFileLocation(rootConfig)); err != nil {
@@ -419,19 +479,27 @@ func (r *RepositoryFile) SetDefaultRepoName(name string, defaultRepoName string,
 	return repoName, nil
 }
 
+// WriteFile writes the repositories, except for the ones from the team repository file
 func (r *RepositoryFile) WriteFile(path string) error {
-	data, err := yaml.Marshal(r)
+	userRepos := *r
+	userRepos.Repositories = make([]*RepositoryEntry, 0, len(r.Repositories))
+	for _, repo := range r.Repositories {
+		if repo.Source != repoSourceTeam {
+			userRepos.Repositories = append(userRepos.Repositories, repo)
+		}
+	}
+	data, err := yaml.Marshal(&userRepos)
 	if err != nil {
 		return err
 	}
 	return ioutil.WriteFile(path, data, 0644)
 }
 
-func (r *RepositoryFile) GetIndices(log *LoggingConfig) (RepoIndices, error) {
+func (r *RepositoryFile) GetIndices(config *RootCommandConfig) (RepoIndices, error) {
 	indices := make(map[string]*IndexYaml)
 	brokenRepos := make([]indexError, 0)
 	for _, rf := range r.Repositories {
-		var index, err = downloadIndex(log, rf.URL)
+		var index, err = getIndex(config, rf, false)
 		if err != nil {
 			repoErr := indexError{rf.Name, err}
 			brokenRepos = append(brokenRepos, repoErr)
@@ -474,15 +542,21 @@ func setDefaultTemplate(Templates []IndexYamlStackTemplate, DefaultTemplate stri
 		}
 	}
 }
-func (index *IndexYaml) buildStacksFromIndex(repoName string, Stacks []Stack) []Stack {
+
+// buildStacksFromIndex adds the stacks of the index to Stacks, with only their newest version unless allVersions is set
+func (index *IndexYaml) buildStacksFromIndex(repoName string, Stacks []Stack, allVersions bool) []Stack {
 
 	for id, value := range index.Projects {
 		setDefaultTemplate(value[0].Templates[:], value[0].DefaultTemplate)
-		Stacks = append(Stacks, Stack{repoName, id, value[0].Version, value[0].Description, value[0].Templates, value[0].Deprecated})
+		Stacks = append(Stacks, Stack{repoName: repoName, ID: id, Version: value[0].Version, Description: value[0].Description, Templates: value[0].Templates, Deprecated: value[0].Deprecated})
 	}
-	for _, value := range index.Stacks {
+	stacks := index.Stacks
+	if !allVersions {
+		stacks = index.latestStackVersions()
+	}
+	for _, value := range stacks {
 		setDefaultTemplate(value.Templates[:], value.DefaultTemplate)
-		Stacks = append(Stacks, Stack{repoName, value.ID, value.Version, value.Description, value.Templates, value.Deprecated})
+		Stacks = append(Stacks, Stack{repoName: repoName, ID: value.ID, Version: value.Version, Description: value.Description, Templates: value.Templates, Deprecated: value.Deprecated})
 	}
 
 	sort.Slice(Stacks, func(i, j int) bool {
@@ -493,20 +567,23 @@ func (index *IndexYaml) buildStacksFromIndex(repoName string, Stacks []Stack) []
 			return true
 
 		}
+		if Stacks[i].repoName == Stacks[j].repoName && Stacks[i].ID == Stacks[j].ID {
+			return compareStackVersions(Stacks[i].Version, Stacks[j].Version) > 0
+		}
 		return false
 	})
 
 	return Stacks
 }
 
-func (r *RepositoryFile) listProjects(config *RootCommandConfig) (string, error) {
+func (r *RepositoryFile) listProjects(config *RootCommandConfig, allVersions bool) (string, error) {
 	var Stacks []Stack
 	table := uitable.New()
 	table.MaxColWidth = 60
 	table.Wrap = true
 
 	table.AddRow("REPO", "ID", "VERSION  ", "TEMPLATES", "DESCRIPTION")
-	indices, err := r.GetIndices(config.LoggingConfig)
+	indices, err := r.GetIndices(config)
 
 	if err != nil {
 		config.Error.logf("The following indices could not be read, skipping:\n%v", err)
@@ -514,12 +591,12 @@ func (r *RepositoryFile) listProjects(config *RootCommandConfig) (string, error)
 	if len(indices) != 0 {
 		for repoName, index := range indices {
 
-			if strings.Compare(index.APIVersion, supportedIndexAPIVersion) == 1 {
+			if isNewerIndexAPIVersion(index.APIVersion) {
 				config.Debug.log("Adding unsupported repository", repoName)
 				config.UnsupportedRepos = append(config.UnsupportedRepos, repoName)
 			}
 
-			Stacks = index.buildStacksFromIndex(repoName, Stacks)
+			Stacks = index.buildStacksFromIndex(repoName, Stacks, allVersions)
 
 		}
 
@@ -531,7 +608,9 @@ func (r *RepositoryFile) listProjects(config *RootCommandConfig) (string, error)
 	if err != nil {
 		return "", err
 	}
+	shadows := r.StackShadows(indices)
 	for _, value := range Stacks {
+		shadowedBy := shadows[value.repoName+"/"+value.ID]
 
 		if value.repoName == defaultRepoName {
 			value.repoName = "*" + value.repoName
@@ -540,6 +619,9 @@ func (r *RepositoryFile) listProjects(config *RootCommandConfig) (string, error)
 		if value.Deprecated != "" {
 			value.ID = value.ID + " [Deprecated]"
 		}
+		if shadowedBy != "" {
+			value.ID = value.ID + " [Shadowed by " + shadowedBy + "]"
+		}
 
 		templatesListString := convertTemplatesArrayToString(value.Templates)
 		table.AddRow(value.repoName, value.ID, value.Version, templatesListString, value.Description)
@@ -558,19 +640,23 @@ type RepositoryOutputFormat struct {
 	Stacks []Stack `yaml:"stacks" json:"stacks"`
 }
 
-func (r *RepositoryFile) getRepositories(log *LoggingConfig) (IndexOutputFormat, error) {
+func (r *RepositoryFile) getRepositories(config *RootCommandConfig, allVersions bool) (IndexOutputFormat, error) {
 	var indexOutput IndexOutputFormat
 	indexOutput.APIVersion = r.APIVersion
 	indexOutput.Generated = r.Generated
-	indices, err := r.GetIndices(log)
+	indices, err := r.GetIndices(config)
 	if err != nil {
 		return indexOutput, errors.Errorf("Could not read indices: %v", err)
 	}
 
 	if len(indices) != 0 {
+		shadows := r.StackShadows(indices)
 		for repoName, index := range indices {
 			var Stacks []Stack
-			Stacks = index.buildStacksFromIndex(repoName, Stacks)
+			Stacks = index.buildStacksFromIndex(repoName, Stacks, allVersions)
+			for i := range Stacks {
+				Stacks[i].ShadowedBy = shadows[repoName+"/"+Stacks[i].ID]
+			}
 
 			indexOutput.Repositories = append(indexOutput.Repositories, RepositoryOutputFormat{Name: repoName, Stacks: Stacks})
 		}
@@ -578,18 +664,18 @@ func (r *RepositoryFile) getRepositories(log *LoggingConfig) (IndexOutputFormat,
 	return indexOutput, nil
 }
 
-func (r *RepositoryFile) getRepository(log *LoggingConfig, repoName string) (IndexOutputFormat, error) {
+func (r *RepositoryFile) getRepository(config *RootCommandConfig, repoName string, allVersions bool) (IndexOutputFormat, error) {
 	var indexOutput IndexOutputFormat
 	indexOutput.APIVersion = r.APIVersion
 	indexOutput.Generated = r.Generated
-	indices, err := r.GetIndices(log)
+	indices, err := r.GetIndices(config)
 	if err != nil {
 		return indexOutput, errors.Errorf("Could not read indices: %v", err)
 	}
 
 	if indices[repoName] != nil {
 		var Stacks []Stack
-		Stacks = indices[repoName].buildStacksFromIndex(repoName, Stacks)
+		Stacks = indices[repoName].buildStacksFromIndex(repoName, Stacks, allVersions)
 
 		indexOutput.Repositories = append(indexOutput.Repositories, RepositoryOutputFormat{Name: repoName, Stacks: Stacks})
 	}
@@ -15,22 +15,53 @@
 package cmd
 
 import (
+	"os"
 	"regexp"
-	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/spf13/cobra"
 )
 
+// repoAuthOptions are the flags of appsody repo add that describe how to authenticate to the repository
+type repoAuthOptions struct {
+	auth          string
+	username      string
+	header        string
+	secretStdin   bool
+	secretEnv     string
+	secretCommand string
+}
+
 func newRepoAddCmd(config *RootCommandConfig) *cobra.Command {
+	var trustKey string
+	var priority int
+	var authOptions repoAuthOptions
 	// initCmd represents the init command
 	var addCmd = &cobra.Command{
 		Use:   "add <repository> <url>",
 		Short: "Add an Appsody repository.",
-		Long:  `Add an Appsody repository to your list of configured Appsody repositories.`,
+		Long: `Add an Appsody repository to your list of configured Appsody repositories.
+
+If you specify a public key with --trust-key, the key is pinned for the repository in your repository.yaml file, and the index of the repository is rejected unless its detached signature, published next to the index with a .sig suffix, matches the key. Use 'appsody stack add-to-repo --sign-key' to sign an index.
+
+If the repository requires authentication, specify the type with --auth:
+  basic   a --username and a password
+  bearer  a bearer token
+  header  a custom --header, such as X-JFrog-Art-Api, and its value
+The secret is read from stdin with --secret-stdin and stored in the credentials.yaml file of your Appsody home directory, which only you can read. Otherwise it is resolved when it is needed, from an environment variable with --secret-env or from the output of a credential helper command with --secret-command, which is run by the shell.
+The credentials are only sent to the host of the repository URL, for the index and for the template and source archives on that host. When several repositories on the same host have credentials, a request uses the credentials of the repository whose URL has the longest path in common with it.`,
 		Example: `  appsody repo add my-local-repo file:///absolute/path/to/my-local-repo.yaml
-  Adds the "my-local-repo" repository, specified by the "file:///absolute/path/to/my-local-repo.yaml" file to your list of repositories.`,
+  Adds the "my-local-repo" repository, specified by the "file:///absolute/path/to/my-local-repo.yaml" file to your list of repositories.
+
+  appsody repo add my-private-repo https://example.com/my-private-repo-index.yaml --trust-key my-private-repo.pub
+  Adds the "my-private-repo" repository, and only accepts its index if it is signed by the private key of "my-private-repo.pub".
+
+  appsody repo add my-artifactory-repo https://artifactory.example.com/stacks/index.yaml --auth header --header X-JFrog-Art-Api --secret-env ARTIFACTORY_API_KEY
+  Adds the "my-artifactory-repo" repository, authenticating with the API key in the ARTIFACTORY_API_KEY environment variable.
+
+  echo $GITLAB_TOKEN | appsody repo add my-gitlab-repo https://gitlab.example.com/stacks/index.yaml --auth bearer --secret-stdin
+  Adds the "my-gitlab-repo" repository, and stores its token in the credentials file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 2 {
 				return errors.New("You must specify a repository name and URL")
@@ -38,13 +69,25 @@ func newRepoAddCmd(config *RootCommandConfig) *cobra.Command {
 			if len(args) > 2 {
 				return errors.Errorf("Two arguments expected. Use 'appsody [command] --help' for more information about a command")
 			}
-			return repoAdd(args[0], args[1], config)
+			credential, err := newRepositoryCredential(args[0], args[1], &authOptions, os.Stdin)
+			if err != nil {
+				return err
+			}
+			return repoAdd(args[0], args[1], trustKey, priority, credential, config)
 		},
 	}
+	addCmd.PersistentFlags().IntVar(&priority, "priority", 0, "The priority of the repository. Stacks specified without a repository are taken from the repository with the highest priority that has them")
+	addCmd.PersistentFlags().StringVar(&trustKey, "trust-key", "", "A PEM encoded public key file. The index of the repository must be signed by the matching private key")
+	addCmd.PersistentFlags().StringVar(&authOptions.auth, "auth", "", "The type of authentication to the repository host: basic, bearer or header")
+	addCmd.PersistentFlags().StringVar(&authOptions.username, "username", "", "The username for --auth basic")
+	addCmd.PersistentFlags().StringVar(&authOptions.header, "header", "", "The name of the header for --auth header")
+	addCmd.PersistentFlags().BoolVar(&authOptions.secretStdin, "secret-stdin", false, "Read the password, token or header value from stdin and store it in the credentials file")
+	addCmd.PersistentFlags().StringVar(&authOptions.secretEnv, "secret-env", "", "The environment variable with the password, token or header value")
+	addCmd.PersistentFlags().StringVar(&authOptions.secretCommand, "secret-command", "", "A credential helper command, run by the shell, that prints the password, token or header value")
 	return addCmd
 }
 
-func repoAdd(repoName, repoURL string, config *RootCommandConfig) error {
+func repoAdd(repoName, repoURL, trustKeyFile string, priority int, credential *RepositoryCredential, config *RootCommandConfig) error {
 
 	if len(repoName) > 50 {
 		return errors.Errorf("Invalid repository name. The <name> must be less than 50 characters")
@@ -70,12 +113,32 @@ func repoAdd(repoName, repoURL string, config *RootCommandConfig) error {
 		return errors.Errorf("A repository with the URL '%s' already exists.", repoURL)
 
 	}
-	index, err := downloadIndex(config.LoggingConfig, repoURL)
+	var newEntry = RepositoryEntry{
+		Name:     repoName,
+		URL:      repoURL,
+		Priority: priority,
+	}
+	if trustKeyFile != "" {
+		trustKey, err := readTrustKey(trustKeyFile)
+		if err != nil {
+			return err
+		}
+		newEntry.TrustKey = trustKey
+	}
+	credentials, err := readCredentialsFile(config)
+	if err != nil {
+		return err
+	}
+	// the credentials of a previous repository with the same name are replaced
+	credentials.set(repoName, credential)
+	cache := getIndexCache(config)
+	cache.Credentials = NewCredentialStore(credentials.Credentials)
+	index, err := getIndexFromCache(config, cache, &newEntry, true)
 	if err != nil {
 		return errors.Errorf("Could not download index. Does the APIVersion of your repository match what the Appsody CLI currently supports? (%v) Full error:  %v", supportedIndexAPIVersion, err)
 	}
-	if strings.Compare(index.APIVersion, supportedIndexAPIVersion) == 1 {
-		config.Warning.log("The repository " + repoName + " contains an APIVersion in its .yaml file more recent than the current Appsody CLI supports(" + supportedIndexAPIVersion + "), it is strongly suggested that you update your Appsody CLI to the latest version.")
+	if isNewerIndexAPIVersion(index.APIVersion) {
+		config.Warning.log("The repository " + repoName + " contains an APIVersion in its .yaml file more recent than the current Appsody CLI supports(" + multiVersionIndexAPIVersion + "), it is strongly suggested that you update your Appsody CLI to the latest version.")
 	}
 	if index.APIVersion == "v1" {
 		return errors.Errorf("The repository .yaml for " + repoName + " has an older APIVersion that the Appsody CLI no longer supports. Supported APIVersion: " + supportedIndexAPIVersion)
@@ -84,16 +147,17 @@ func repoAdd(repoName, repoURL string, config *RootCommandConfig) error {
 	if config.Dryrun {
 		config.Info.logf("Dry Run - Skipping appsody repo add repository Name: %s, URL: %s", repoName, repoURL)
 	} else {
-		var newEntry = RepositoryEntry{
-			Name: repoName,
-			URL:  repoURL,
-		}
-
 		repoFile.Add(&newEntry)
 		err = repoFile.WriteFile(getRepoFileLocation(config))
 		if err != nil {
 			return errors.Errorf("Failed to write file to repository location: %v", err)
 		}
+		if credential != nil {
+			err = credentials.write(config)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,239 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// how long a cached repository index is used before it is checked for changes
+const defaultIndexCacheTTL = time.Hour
+
+// IndexCache keeps downloaded repository indices, so that they are only downloaded again when they change
+type IndexCache struct {
+	Dir string
+	// how long a cached index is used without checking the repository for changes
+	TTL time.Duration
+	// use only the cached indices
+	Offline bool
+	// the credentials used to download the indices
+	Credentials *CredentialStore
+	log         *LoggingConfig
+}
+
+// IndexCacheEntry describes a cached index, with the headers used to check it for changes
+type IndexCacheEntry struct {
+	URL          string    `yaml:"url"`
+	ETag         string    `yaml:"etag,omitempty"`
+	LastModified string    `yaml:"lastModified,omitempty"`
+	Fetched      time.Time `yaml:"fetched"`
+}
+
+func NewIndexCache(dir string, ttl time.Duration, offline bool, log *LoggingConfig) *IndexCache {
+	return &IndexCache{Dir: dir, TTL: ttl, Offline: offline, log: log}
+}
+
+func getIndexCacheDir(config *RootCommandConfig) string {
+	return filepath.Join(getRepoDir(config), "cache")
+}
+
+// isOffline returns true if the --offline flag or the offline config setting is set
+func isOffline(config *RootCommandConfig) bool {
+	return config.Offline || config.CliConfig.GetBool("offline")
+}
+
+func getIndexCache(config *RootCommandConfig) *IndexCache {
+	ttl := defaultIndexCacheTTL
+	if setting := config.CliConfig.GetString("indexcachettl"); setting != "" {
+		parsed, err := time.ParseDuration(setting)
+		if err != nil {
+			config.Warning.logf("The indexcachettl setting %s is not a valid duration, such as 30m or 2h. Using %s", setting, defaultIndexCacheTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+	cache := NewIndexCache(getIndexCacheDir(config), ttl, isOffline(config), config.LoggingConfig)
+	cache.Credentials = getCredentialStore(config)
+	return cache
+}
+
+// the cache files of an index are named after a hash of its URL
+func (c *IndexCache) files(url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	name := hex.EncodeToString(sum[:])[:16]
+	return filepath.Join(c.Dir, name+".yaml"), filepath.Join(c.Dir, name+"-entry.yaml")
+}
+
+func (c *IndexCache) read(url string) (*IndexCacheEntry, []byte) {
+	indexFile, entryFile := c.files(url)
+	entryData, err := ioutil.ReadFile(entryFile)
+	if err != nil {
+		return nil, nil
+	}
+	var entry IndexCacheEntry
+	err = yaml.Unmarshal(entryData, &entry)
+	if err != nil || entry.URL != url {
+		c.log.Debug.logf("Ignoring cache entry %s for %s: %v", entryFile, url, err)
+		return nil, nil
+	}
+	index, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		return nil, nil
+	}
+	return &entry, index
+}
+
+func (c *IndexCache) write(entry *IndexCacheEntry, index []byte) error {
+	err := os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return err
+	}
+	indexFile, entryFile := c.files(entry.URL)
+	entryData, err := yaml.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if index != nil {
+		err = ioutil.WriteFile(indexFile, index, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(entryFile, entryData, 0644)
+}
+
+// Get returns the index at the URL. A cached index is used until its TTL expires, then it is
+// revalidated with the ETag and Last-Modified headers. If refresh is set, the index is always revalidated.
+// Local file:// indices are not cached.
+func (c *IndexCache) Get(url string, refresh bool) ([]byte, error) {
+	if strings.HasPrefix(url, "file://") {
+		return c.download(url, nil)
+	}
+	entry, index := c.read(url)
+	if c.Offline {
+		if entry == nil {
+			return nil, errors.Errorf("The repository index %s is not in the cache, so it can't be used offline. Run 'appsody repo update' when you are online to cache it", url)
+		}
+		c.log.Debug.logf("Offline - using the index of %s cached at %s", url, entry.Fetched.Format(time.RFC3339))
+		return index, nil
+	}
+	if entry != nil && !refresh && time.Since(entry.Fetched) < c.TTL {
+		c.log.Debug.logf("Using the index of %s cached at %s", url, entry.Fetched.Format(time.RFC3339))
+		return index, nil
+	}
+
+	latest, err := c.download(url, entry)
+	if err != nil {
+		if entry != nil {
+			c.log.Warning.logf("Could not check the repository index %s for changes, using the index cached at %s: %v", url, entry.Fetched.Format(time.RFC3339), err)
+			return index, nil
+		}
+		return nil, err
+	}
+	if latest == nil {
+		c.log.Debug.logf("The index of %s has not changed", url)
+		latest = index
+	}
+	return latest, nil
+}
+
+// download gets the index, sending the validators of the cached entry if there is one.
+// It returns nil if the cached index has not changed.
+func (c *IndexCache) download(url string, entry *IndexCacheEntry) ([]byte, error) {
+	c.log.Debug.log("Downloading appsody repository index from ", url)
+	req, err := newDownloadRequest(url, c.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := newDownloadClient(c.log, c.Credentials).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.Fetched = time.Now()
+		if err := c.write(entry, nil); err != nil {
+			c.log.Debug.logf("Could not update the cache entry of %s: %v", url, err)
+		}
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the response from %s: %s", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.log.Debug.logf("Contents http response:\n%s", body)
+		return nil, fmt.Errorf("Could not download %s: %s", url, resp.Status)
+	}
+	if strings.HasPrefix(url, "file://") {
+		return body, nil
+	}
+	latest := &IndexCacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}
+	if err := c.write(latest, body); err != nil {
+		c.log.Warning.logf("Could not cache the repository index %s: %v", url, err)
+	}
+	return body, nil
+}
+
+// Prune removes the cached indices that are not for one of the URLs
+func (c *IndexCache) Prune(urls []string) error {
+	keep := make(map[string]bool)
+	for _, url := range urls {
+		indexFile, entryFile := c.files(url)
+		keep[filepath.Base(indexFile)] = true
+		keep[filepath.Base(entryFile)] = true
+	}
+	files, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !keep[file.Name()] {
+			c.log.Debug.log("Removing cached index ", file.Name())
+			if err := os.Remove(filepath.Join(c.Dir, file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+// newIndexServer serves an index with an ETag, counting the full and the not modified responses
+func newIndexServer(index *string, downloads *int, notModified *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + strconv.Itoa(len(*index)) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			*notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*downloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(*index))
+	}))
+}
+
+func TestIndexCache(t *testing.T) {
+	var outBuffer bytes.Buffer
+	loggingConfig := &cmd.LoggingConfig{}
+	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	dir, err := ioutil.TempDir("", "appsody-index-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := "apiVersion: v2\nstacks: []\n"
+	downloads, notModified := 0, 0
+	server := newIndexServer(&index, &downloads, &notModified)
+	defer server.Close()
+	url := server.URL + "/index.yaml"
+
+	cache := cmd.NewIndexCache(dir, time.Hour, false, loggingConfig)
+	get := func(cache *cmd.IndexCache, refresh bool, expected string) {
+		t.Helper()
+		data, err := cache.Get(url, refresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Expected index %q but got %q", expected, data)
+		}
+	}
+
+	get(cache, false, index)
+	get(cache, false, index)
+	if downloads != 1 || notModified != 0 {
+		t.Errorf("Expected the second index to come from the cache, got %d downloads and %d revalidations", downloads, notModified)
+	}
+
+	// an expired entry is revalidated with its ETag
+	expired := cmd.NewIndexCache(dir, 0, false, loggingConfig)
+	get(expired, false, index)
+	if downloads != 1 || notModified != 1 {
+		t.Errorf("Expected the expired index to be revalidated, got %d downloads and %d revalidations", downloads, notModified)
+	}
+
+	// refresh revalidates an index that has not expired, downloading it if it changed
+	oldIndex := index
+	index = "apiVersion: v2\nstacks:\n- id: java\n"
+	get(cache, false, oldIndex)
+	get(cache, true, index)
+	if downloads != 2 {
+		t.Errorf("Expected the changed index to be downloaded, got %d downloads", downloads)
+	}
+
+	// offline uses the cache even when it has expired, and does not contact the server
+	offline := cmd.NewIndexCache(dir, 0, true, loggingConfig)
+	get(offline, true, index)
+	if downloads != 2 || notModified != 1 {
+		t.Errorf("Expected no requests offline, got %d downloads and %d revalidations", downloads, notModified)
+	}
+	_, err = offline.Get(server.URL+"/other-index.yaml", false)
+	if err == nil || !strings.Contains(err.Error(), "appsody repo update") {
+		t.Errorf("Expected an error for an index that is not cached, got %v", err)
+	}
+
+	// an expired index is still used if the server can't be reached
+	server.Close()
+	get(expired, false, index)
+
+	err = cache.Prune(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected the cache to be empty after pruning, got %d files", len(files))
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// the types of authentication supported by appsody repo add --auth
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authHeader = "header"
+)
+
+// RepositoryCredential is how to authenticate to the host of a repository.
+// The secret is stored in the credentials file, or resolved from an environment variable or a credential helper command.
+type RepositoryCredential struct {
+	Repository string `yaml:"repository"`
+	Host       string `yaml:"host"`
+	// URL is the URL of the repository index, to choose between the credentials of repositories on the same host
+	URL           string `yaml:"url,omitempty"`
+	Type          string `yaml:"type"`
+	Username      string `yaml:"username,omitempty"`
+	Header        string `yaml:"header,omitempty"`
+	Secret        string `yaml:"secret,omitempty"`
+	SecretEnv     string `yaml:"secretEnv,omitempty"`
+	SecretCommand string `yaml:"secretCommand,omitempty"`
+	resolved      string
+}
+
+// CredentialsFile is the credentials.yaml file in the repository directory of the Appsody home directory.
+// It is only readable by its owner, as it can contain secrets.
+type CredentialsFile struct {
+	Credentials []*RepositoryCredential `yaml:"credentials"`
+}
+
+// CredentialStore adds the credentials of a repository to the requests for its host
+type CredentialStore struct {
+	Credentials []*RepositoryCredential
+}
+
+func NewCredentialStore(credentials []*RepositoryCredential) *CredentialStore {
+	return &CredentialStore{Credentials: credentials}
+}
+
+func getCredentialsFileLocation(config *RootCommandConfig) string {
+	return filepath.Join(getRepoDir(config), "credentials.yaml")
+}
+
+func readCredentialsFile(config *RootCommandConfig) (*CredentialsFile, error) {
+	var file CredentialsFile
+	data, err := ioutil.ReadFile(getCredentialsFileLocation(config))
+	if os.IsNotExist(err) {
+		return &file, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read the repository credentials file: %v", err)
+	}
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the repository credentials file %s: %v", getCredentialsFileLocation(config), err)
+	}
+	return &file, nil
+}
+
+func (f *CredentialsFile) write(config *RootCommandConfig) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	location := getCredentialsFileLocation(config)
+	err = ioutil.WriteFile(location, data, 0600)
+	if err != nil {
+		return errors.Errorf("Could not write the repository credentials file: %v", err)
+	}
+	// WriteFile only sets the permissions of new files
+	return os.Chmod(location, 0600)
+}
+
+// set replaces the credentials of a repository, or removes them if credential is nil
+func (f *CredentialsFile) set(repoName string, credential *RepositoryCredential) {
+	credentials := make([]*RepositoryCredential, 0, len(f.Credentials)+1)
+	for _, existing := range f.Credentials {
+		if existing.Repository != repoName {
+			credentials = append(credentials, existing)
+		}
+	}
+	if credential != nil {
+		credentials = append(credentials, credential)
+	}
+	f.Credentials = credentials
+}
+
+// removeRepoCredentials removes the credentials of a repository that is no longer configured
+func removeRepoCredentials(config *RootCommandConfig, repoName string) error {
+	file, err := readCredentialsFile(config)
+	if err != nil {
+		return err
+	}
+	count := len(file.Credentials)
+	file.set(repoName, nil)
+	if len(file.Credentials) == count {
+		return nil
+	}
+	config.Debug.log("Removing the credentials of repository ", repoName)
+	return file.write(config)
+}
+
+// getCredentialStore returns the credentials of the configured repositories.
+// If the credentials file can't be read, downloads are not authenticated.
+func getCredentialStore(config *RootCommandConfig) *CredentialStore {
+	file, err := readCredentialsFile(config)
+	if err != nil {
+		config.Warning.log(err)
+		return NewCredentialStore(nil)
+	}
+	return NewCredentialStore(file.Credentials)
+}
+
+// newRepositoryCredential validates the authentication options of appsody repo add
+func newRepositoryCredential(repoName string, repoURL string, options *repoAuthOptions, stdin io.Reader) (*RepositoryCredential, error) {
+	if options.auth == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(repoURL)
+	if err != nil || parsed.Host == "" {
+		return nil, errors.Errorf("Credentials can only be used with http and https repositories, not %s", repoURL)
+	}
+	credential := &RepositoryCredential{
+		Repository:    repoName,
+		Host:          parsed.Host,
+		URL:           repoURL,
+		Type:          options.auth,
+		Username:      options.username,
+		Header:        options.header,
+		SecretEnv:     options.secretEnv,
+		SecretCommand: options.secretCommand,
+	}
+	switch options.auth {
+	case authBasic:
+		if options.username == "" {
+			return nil, errors.New("--auth basic requires --username")
+		}
+	case authBearer:
+	case authHeader:
+		if options.header == "" {
+			return nil, errors.New("--auth header requires --header")
+		}
+	default:
+		return nil, errors.Errorf("The authentication type %s is not supported. Use basic, bearer or header", options.auth)
+	}
+	sources := 0
+	for _, source := range []bool{options.secretStdin, options.secretEnv != "", options.secretCommand != ""} {
+		if source {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.New("Specify the secret of the repository with exactly one of --secret-stdin, --secret-env or --secret-command")
+	}
+	if options.secretStdin {
+		secret, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, errors.Errorf("Could not read the secret from stdin: %v", err)
+		}
+		credential.Secret = strings.TrimSpace(secret)
+		if credential.Secret == "" {
+			return nil, errors.New("The secret read from stdin is empty")
+		}
+	}
+	return credential, nil
+}
+
+// secret returns the secret of the credential, running its credential helper command at most once
+func (c *RepositoryCredential) secret() (string, error) {
+	if c.Secret != "" {
+		return c.Secret, nil
+	}
+	if c.resolved != "" {
+		return c.resolved, nil
+	}
+	if c.SecretEnv != "" {
+		value := os.Getenv(c.SecretEnv)
+		if value == "" {
+			return "", errors.Errorf("The environment variable %s, with the secret of repository %s, is not set", c.SecretEnv, c.Repository)
+		}
+		return value, nil
+	}
+	if c.SecretCommand != "" {
+		// the command is run by the shell, so that its arguments can be quoted
+		shell, shellArgs := "/bin/sh", []string{"-c", c.SecretCommand}
+		if runtime.GOOS == "windows" {
+			shell, shellArgs = "CMD", []string{"/C", c.SecretCommand}
+		}
+		output, err := exec.Command(shell, shellArgs...).Output()
+		if err != nil {
+			return "", errors.Errorf("The credential helper command of repository %s failed: %v", c.Repository, err)
+		}
+		c.resolved = strings.TrimSpace(string(output))
+		if c.resolved == "" {
+			return "", errors.Errorf("The credential helper command of repository %s did not return a secret", c.Repository)
+		}
+		return c.resolved, nil
+	}
+	return "", errors.Errorf("The credentials of repository %s do not have a secret", c.Repository)
+}
+
+// find returns the credentials for the host of a URL. When several repositories on the host have credentials,
+// the repository whose URL has the longest path prefix in common with the URL is chosen.
+func (s *CredentialStore) find(target *url.URL) *RepositoryCredential {
+	if s == nil {
+		return nil
+	}
+	var found *RepositoryCredential
+	longest := -1
+	for _, credential := range s.Credentials {
+		if !strings.EqualFold(credential.Host, target.Host) {
+			continue
+		}
+		length := 0
+		if repoURL, err := url.Parse(credential.URL); err == nil && credential.URL != "" {
+			length = commonPathPrefix(repoURL.Path, target.Path)
+		}
+		if length > longest {
+			found = credential
+			longest = length
+		}
+	}
+	return found
+}
+
+// commonPathPrefix returns the number of leading path segments that two paths have in common
+func commonPathPrefix(a string, b string) int {
+	aSegments := strings.Split(strings.Trim(a, "/"), "/")
+	bSegments := strings.Split(strings.Trim(b, "/"), "/")
+	count := 0
+	for count < len(aSegments) && count < len(bSegments) && aSegments[count] == bSegments[count] && aSegments[count] != "" {
+		count++
+	}
+	return count
+}
+
+// Authorize adds the credentials for the host of the request, if there are any.
+// Otherwise GH_READ_TOKEN is added to requests for GitHub.
+func (s *CredentialStore) Authorize(req *http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil
+	}
+	credential := s.find(req.URL)
+	if credential == nil {
+		if token := os.Getenv("GH_READ_TOKEN"); token != "" && isGitHubHost(req.URL.Hostname()) {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		return nil
+	}
+	secret, err := credential.secret()
+	if err != nil {
+		return err
+	}
+	switch credential.Type {
+	case authBasic:
+		req.SetBasicAuth(credential.Username, secret)
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+secret)
+	case authHeader:
+		req.Header.Set(credential.Header, secret)
+	default:
+		return errors.Errorf("The authentication type %s of repository %s is not supported", credential.Type, credential.Repository)
+	}
+	return nil
+}
+
+// redirect removes the credentials of the original host when a request is redirected to another host,
+// and adds the credentials of the new host. The http client copies the headers of the original request
+// onto every redirect, so each one is compared with the original request, not with the previous redirect.
+func (s *CredentialStore) redirect(req *http.Request, original *http.Request) error {
+	if strings.EqualFold(req.URL.Host, original.URL.Host) {
+		return nil
+	}
+	req.Header.Del("Authorization")
+	// the session token of a signed S3 request
+	req.Header.Del("X-Amz-Security-Token")
+	if credential := s.find(original.URL); credential != nil && credential.Type == authHeader {
+		req.Header.Del(credential.Header)
+	}
+	return s.Authorize(req)
+}
+
+func isGitHubHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "github.com" || strings.HasSuffix(host, ".github.com") || strings.HasSuffix(host, ".githubusercontent.com")
+}
@@ -0,0 +1,62 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ExportRepos returns the repositories to share with other developers, including the ones from the team repository file
+func ExportRepos(r *RepositoryFile) *RepositoryFile {
+	exported := NewRepoFile()
+	for _, repo := range r.Repositories {
+		entry := *repo
+		entry.Source = ""
+		exported.Add(&entry)
+	}
+	return exported
+}
+
+func newRepoExportCmd(config *RootCommandConfig) *cobra.Command {
+	var output string
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export your Appsody repositories.",
+		Long: `Write your configured Appsody repositories, including the repositories from the team repository file, to stdout in the format of the repository file.
+
+Use "appsody repo import" to add the exported repositories on another machine. The credentials of the repositories are not exported.`,
+		Example: `  appsody repo export > repos.yaml
+  Saves your repositories in "repos.yaml".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(config)
+			if repoErr != nil {
+				return repoErr
+			}
+			result, err := formatOutput(output, ExportRepos(&repoFile))
+			if err != nil {
+				return err
+			}
+			config.Info.log(result)
+			return nil
+		},
+	}
+	exportCmd.PersistentFlags().StringVarP(&output, "output", "o", "yaml", "Export the repositories in yaml or json format")
+	return exportCmd
+}
@@ -0,0 +1,167 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type repoImportCommandConfig struct {
+	*RootCommandConfig
+	merge   bool
+	replace bool
+}
+
+// ImportRepos adds the imported repositories, replacing the repositories with the same names.
+// With replace, your repositories that are not imported are removed, and their names are returned.
+// The default repository only changes if an imported repository is the default.
+func (r *RepositoryFile) ImportRepos(imported *RepositoryFile, replace bool) ([]string, error) {
+	names := make(map[string]bool)
+	importedDefault := false
+	for _, repo := range imported.Repositories {
+		if repo.Name == "" || repo.URL == "" {
+			return nil, errors.New("Every imported repository must have a name and a URL")
+		}
+		if names[repo.Name] {
+			return nil, errors.Errorf("The repository %s is imported more than once", repo.Name)
+		}
+		names[repo.Name] = true
+		if repo.IsDefault {
+			if importedDefault {
+				return nil, errors.New("More than one imported repository is the default repository")
+			}
+			importedDefault = true
+		}
+	}
+
+	var removed []string
+	defaultName := ""
+	repos := make([]*RepositoryEntry, 0, len(r.Repositories)+len(imported.Repositories))
+	for _, repo := range r.Repositories {
+		if repo.IsDefault && !importedDefault {
+			defaultName = repo.Name
+		}
+		if names[repo.Name] {
+			continue
+		}
+		if replace && repo.Source != repoSourceTeam {
+			removed = append(removed, repo.Name)
+			continue
+		}
+		if importedDefault {
+			repo.IsDefault = false
+		}
+		repos = append(repos, repo)
+	}
+	for _, importedRepo := range imported.Repositories {
+		repo := *importedRepo
+		repo.Source = ""
+		if defaultName == repo.Name {
+			repo.IsDefault = true
+		}
+		repos = append(repos, &repo)
+	}
+	r.Repositories = repos
+	return removed, nil
+}
+
+func readImportFile(file string) (*RepositoryFile, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read the repositories to import from %s: %v", file, err)
+	}
+	var imported RepositoryFile
+	err = yaml.Unmarshal(data, &imported)
+	if err != nil {
+		return nil, errors.Errorf("Failed to parse the repositories to import from %s: %v", file, err)
+	}
+	if len(imported.Repositories) == 0 {
+		return nil, errors.Errorf("There are no repositories to import in %s", file)
+	}
+	return &imported, nil
+}
+
+func newRepoImportCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &repoImportCommandConfig{RootCommandConfig: rootConfig}
+	var importCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import Appsody repositories.",
+		Long: `Add the repositories from a file that was created by "appsody repo export", or from stdin if the file is "-".
+
+By default, the imported repositories are merged with your repositories, replacing the repositories that have the same names. With --replace, your repositories that are not in the file are removed. Your default repository only changes if one of the imported repositories is the default.
+
+The indices of the repositories are not downloaded. Use "appsody repo update" to check that the imported repositories can be reached.`,
+		Example: `  appsody repo import repos.yaml
+  Adds the repositories in "repos.yaml" to your repositories.
+
+  appsody repo import repos.yaml --replace
+  Replaces your repositories with the repositories in "repos.yaml".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("You must specify the file to import the repositories from")
+			}
+			if len(args) > 1 {
+				return errors.Errorf("One argument expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			if config.merge && config.replace {
+				return errors.New("--merge and --replace cannot be used together")
+			}
+			imported, err := readImportFile(args[0])
+			if err != nil {
+				return err
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(rootConfig)
+			if repoErr != nil {
+				return repoErr
+			}
+			removed, err := repoFile.ImportRepos(imported, config.replace)
+			if err != nil {
+				return err
+			}
+			if rootConfig.Dryrun {
+				rootConfig.Info.logf("Dry Run - Skipping the import of %d repositories", len(imported.Repositories))
+				return nil
+			}
+			err = repoFile.WriteFile(getRepoFileLocation(rootConfig))
+			if err != nil {
+				return errors.Errorf("Failed to write file repository location: %v", err)
+			}
+			for _, repoName := range removed {
+				rootConfig.Info.logf("The %v repository has been removed from your configured list of repositories.", repoName)
+				err = removeRepoCredentials(rootConfig, repoName)
+				if err != nil {
+					return err
+				}
+			}
+			rootConfig.Info.logf("Imported %d repositories", len(imported.Repositories))
+			return nil
+		},
+	}
+	importCmd.PersistentFlags().BoolVar(&config.merge, "merge", false, "Add the imported repositories to your repositories (the default)")
+	importCmd.PersistentFlags().BoolVar(&config.replace, "replace", false, "Replace your repositories with the imported repositories")
+	return importCmd
+}
@@ -0,0 +1,216 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	"gopkg.in/yaml.v2"
+)
+
+func newTestRepoFile(repos ...cmd.RepositoryEntry) *cmd.RepositoryFile {
+	repoFile := cmd.NewRepoFile()
+	for i := range repos {
+		repoFile.Add(&repos[i])
+	}
+	return repoFile
+}
+
+func repoNames(repoFile *cmd.RepositoryFile) string {
+	var names []string
+	for _, repo := range repoFile.Repositories {
+		name := repo.Name
+		if repo.IsDefault {
+			name = "*" + name
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestImportRepos(t *testing.T) {
+	var importTests = []struct {
+		testName      string
+		imported      []cmd.RepositoryEntry
+		replace       bool
+		expectedRepos string
+		expectedError string
+	}{
+		{"Merge", []cmd.RepositoryEntry{{Name: "team", URL: "https://example.com/team-index.yaml"}}, false, "*incubator,experimental,team", ""},
+		{"Merge with a new default", []cmd.RepositoryEntry{{Name: "team", URL: "https://example.com/team-index.yaml", IsDefault: true}}, false, "incubator,experimental,*team", ""},
+		{"Merge keeps the default", []cmd.RepositoryEntry{{Name: "incubator", URL: "https://example.com/incubator-index.yaml"}}, false, "experimental,*incubator", ""},
+		{"Replace", []cmd.RepositoryEntry{{Name: "team", URL: "https://example.com/team-index.yaml", IsDefault: true}}, true, "*team", ""},
+		{"Missing URL", []cmd.RepositoryEntry{{Name: "team"}}, false, "", "must have a name and a URL"},
+		{"Duplicate name", []cmd.RepositoryEntry{{Name: "team", URL: "https://example.com/a.yaml"}, {Name: "team", URL: "https://example.com/b.yaml"}}, false, "", "imported more than once"},
+	}
+	for _, testData := range importTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			repoFile := newTestRepoFile(
+				cmd.RepositoryEntry{Name: "incubator", URL: "https://example.com/incubator.yaml", IsDefault: true},
+				cmd.RepositoryEntry{Name: "experimental", URL: "https://example.com/experimental.yaml"},
+			)
+			removed, err := repoFile.ImportRepos(newTestRepoFile(tt.imported...), tt.replace)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Expected an error containing %q but got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := repoNames(repoFile); actual != tt.expectedRepos {
+				t.Errorf("Expected the repositories %s but got %s", tt.expectedRepos, actual)
+			}
+			if tt.replace && len(removed) != 2 {
+				t.Errorf("Expected the replaced repositories to be returned, got %v", removed)
+			}
+		})
+	}
+}
+
+func TestMergeTeamRepos(t *testing.T) {
+	repoFile := newTestRepoFile(
+		cmd.RepositoryEntry{Name: "incubator", URL: "https://example.com/my-incubator.yaml", IsDefault: true},
+	)
+	team := newTestRepoFile(
+		cmd.RepositoryEntry{Name: "incubator", URL: "https://example.com/team-incubator.yaml"},
+		cmd.RepositoryEntry{Name: "team", URL: "https://example.com/team-index.yaml", IsDefault: true},
+	)
+	repoFile.MergeTeamRepos(team)
+	if actual := repoNames(repoFile); actual != "*incubator,team" {
+		t.Errorf("Expected your repositories and default to take precedence, got %s", actual)
+	}
+	if repoFile.GetRepo("incubator").URL != "https://example.com/my-incubator.yaml" {
+		t.Error("Expected your repository to override the team repository with the same name")
+	}
+	if repoFile.GetRepo("team").Source != "team" {
+		t.Error("Expected the team repository to be marked as coming from the team repository file")
+	}
+
+	// team repositories are not written to your repository file
+	dir, err := ioutil.TempDir("", "appsody-repo-team-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = repoFile.WriteFile(filepath.Join(dir, "repository.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "repository.yaml"))
+	if strings.Contains(string(data), "team") {
+		t.Errorf("Expected the team repositories not to be written to the repository file:\n%s", data)
+	}
+}
+
+func TestRepoExportImport(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	output, err := cmdtest.RunAppsody(sandbox, "repo", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "name: incubator") {
+		t.Errorf("Expected the incubator repository to be exported:\n%s", output)
+	}
+
+	importFile := filepath.Join(sandbox.ProjectDir, "repos.yaml")
+	imported := newTestRepoFile(cmd.RepositoryEntry{Name: "team", URL: "https://example.com/team-index.yaml", IsDefault: true})
+	data, err := yaml.Marshal(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(importFile, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "import", importFile, "--replace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := cmdtest.ParseRepoList(output)
+	if len(repos) != 1 || repos[0].Name != "*team" {
+		t.Errorf("Expected only the imported repository to be configured, got %v", repos)
+	}
+
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "import", importFile, "--merge", "--replace")
+	if err == nil || !strings.Contains(output, "cannot be used together") {
+		t.Errorf("Expected an error for --merge with --replace, got %v", err)
+	}
+}
+
+func TestTeamRepoFile(t *testing.T) {
+	// not parallel, as the team repository file is set in the environment
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, false)
+	defer cleanup()
+
+	teamFile := filepath.Join(sandbox.ProjectDir, "team-repository.yaml")
+	team := newTestRepoFile(cmd.RepositoryEntry{Name: "team", URL: "https://example.com/team-index.yaml"})
+	data, err := yaml.Marshal(team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(teamFile, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// your repository file is created before the team repository file is set
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("APPSODY_TEAM_REPOSITORY_FILE", teamFile)
+	defer os.Unsetenv("APPSODY_TEAM_REPOSITORY_FILE")
+
+	output, err := cmdtest.RunAppsody(sandbox, "repo", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		found = found || (len(fields) == 3 && fields[0] == "team" && fields[2] == "team")
+	}
+	if !strings.Contains(output, "SOURCE") || !found {
+		t.Errorf("Expected the team repository to be listed with its source:\n%s", output)
+	}
+
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "remove", "team")
+	if err == nil || !strings.Contains(output, "is configured in the team repository file") {
+		t.Errorf("Expected an error removing a team repository, got %v", err)
+	}
+
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "set-default", "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(sandbox.ConfigDir, "repository", "repository.yaml"))
+	if !strings.Contains(string(data), "name: team") {
+		t.Errorf("Expected the new default team repository to be added to your repository file:\n%s", data)
+	}
+}
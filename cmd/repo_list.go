@@ -33,7 +33,9 @@ func newRepoListCmd(config *RootCommandConfig) *cobra.Command {
 	var repoListCmd = &cobra.Command{
 		Use:   "list",
 		Short: "List your Appsody repositories.",
-		Long:  `List all your configured Appsody repositories. The "incubator" repository is the initial default repository for Appsody.`,
+		Long: `List all your configured Appsody repositories. The "incubator" repository is the initial default repository for Appsody.
+
+The repositories of your team repository file, which is the file in the APPSODY_TEAM_REPOSITORY_FILE environment variable or /etc/appsody/repository.yaml, are added to your repositories without being copied into your repository file. When there are team repositories, the SOURCE column shows where each repository comes from. Your repositories override the team repositories that have the same names.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			if len(args) > 0 {
@@ -0,0 +1,511 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// MirrorStateFile records what is in a mirror, so that mirroring the repository again only downloads what changed
+const MirrorStateFile = "mirror.yaml"
+
+const mirrorIndexFile = "index.yaml"
+
+// the characters of an image reference that are replaced in the name of its archive
+var imageArchiveNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// MirrorArtifact is an archive or an image in a mirror, with the path of its file relative to the mirror directory
+type MirrorArtifact struct {
+	URL     string `yaml:"url"`
+	File    string `yaml:"file"`
+	SHA256  string `yaml:"sha256,omitempty"`
+	Size    int64  `yaml:"size,omitempty"`
+	ImageID string `yaml:"imageId,omitempty"`
+}
+
+type MirrorState struct {
+	Repository string           `yaml:"repository"`
+	Source     string           `yaml:"source"`
+	Artifacts  []MirrorArtifact `yaml:"artifacts"`
+}
+
+type repoMirrorCommandConfig struct {
+	*RootCommandConfig
+	to      string
+	baseURL string
+	stacks  []string
+	images  bool
+	signKey string
+}
+
+// repoMirror copies the artifacts of a repository into a directory, and compares them with what was copied before
+type repoMirror struct {
+	config   *repoMirrorCommandConfig
+	creds    *CredentialStore
+	previous map[string]MirrorArtifact
+	state    *MirrorState
+	fetched  int
+	skipped  int
+}
+
+// MirrorArchivePath returns where an archive of a stack is stored in a mirror, relative to the mirror directory.
+// The kind is "source" or the ID of a template.
+func MirrorArchivePath(stackID string, version string, kind string, archiveURL string) string {
+	name := ""
+	if parsed, err := url.Parse(archiveURL); err == nil {
+		name = path.Base(parsed.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "archive.tar.gz"
+	}
+	return path.Join("stacks", stackID, version, kind, name)
+}
+
+// MirrorImagePath returns where the archive of an image is stored in a mirror, relative to the mirror directory
+func MirrorImagePath(image string) string {
+	return path.Join("images", imageArchiveNameRegexp.ReplaceAllString(image, "_")+".tar")
+}
+
+// MirrorURL returns the URL of a file in the mirror, under the base URL if one is given, or else as a file:// URL
+func MirrorURL(dir string, baseURL string, file string) (string, error) {
+	if baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/") + "/" + file, nil
+	}
+	absPath, err := filepath.Abs(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		// for windows, add a leading slash and convert to unix style slashes
+		absPath = "/" + filepath.ToSlash(absPath)
+	}
+	return "file://" + absPath, nil
+}
+
+// selectMirrorStacks returns the index with only the stacks to mirror, or every stack if no stacks are given
+func selectMirrorStacks(index *IndexYaml, stackIDs []string) (*IndexYaml, error) {
+	if len(stackIDs) == 0 {
+		return index, nil
+	}
+	selected := *index
+	selected.Stacks = make([]IndexYamlStack, 0, len(index.Stacks))
+	for _, stackID := range stackIDs {
+		versions := index.stackVersions(stackID)
+		if len(versions) == 0 {
+			return nil, errors.Errorf("Could not find stack %s in the repository index", stackID)
+		}
+		for _, stack := range index.Stacks {
+			if stack.ID == stackID {
+				selected.Stacks = append(selected.Stacks, stack)
+			}
+		}
+	}
+	return &selected, nil
+}
+
+func readMirrorState(dir string) (*MirrorState, error) {
+	var state MirrorState
+	data, err := ioutil.ReadFile(filepath.Join(dir, MirrorStateFile))
+	if os.IsNotExist(err) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read the mirror state file: %v", err)
+	}
+	err = yaml.Unmarshal(data, &state)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the mirror state file %s: %v", filepath.Join(dir, MirrorStateFile), err)
+	}
+	return &state, nil
+}
+
+// unchanged returns the previous copy of a file if it is still in the mirror and still matches the source
+func (m *repoMirror) unchanged(file string, sourceURL string, expectedChecksum string, expectedSize int64) (*MirrorArtifact, bool) {
+	previous, ok := m.previous[file]
+	if !ok || previous.URL != sourceURL {
+		return nil, false
+	}
+	if expectedChecksum != "" && previous.SHA256 != expectedChecksum {
+		return nil, false
+	}
+	if expectedSize != 0 && previous.Size != expectedSize {
+		return nil, false
+	}
+	checksum, size, err := ArchiveChecksum(filepath.Join(m.config.to, filepath.FromSlash(file)))
+	if err != nil || checksum != previous.SHA256 || size != previous.Size {
+		m.config.Debug.logf("The copy of %s in the mirror has changed, downloading it again", sourceURL)
+		return nil, false
+	}
+	return &previous, true
+}
+
+// archive downloads an archive into the mirror, unless the mirror already has it, and returns its mirror URL
+func (m *repoMirror) archive(stackID string, version string, kind string, sourceURL string, expectedChecksum string, expectedSize int64) (*MirrorArtifact, string, error) {
+	file := MirrorArchivePath(stackID, version, kind, sourceURL)
+	artifact, ok := m.unchanged(file, sourceURL, expectedChecksum, expectedSize)
+	if ok {
+		m.config.Debug.logf("Skipping %s, which has not changed", sourceURL)
+		m.skipped++
+	} else {
+		target := filepath.Join(m.config.to, filepath.FromSlash(file))
+		err := os.MkdirAll(filepath.Dir(target), os.FileMode(0755))
+		if err != nil {
+			return nil, "", errors.Errorf("Error creating directory: %v", err)
+		}
+		m.config.Info.logf("Downloading %s", sourceURL)
+		download := target + ".download"
+		err = downloadFileToDisk(m.config.LoggingConfig, m.creds, sourceURL, download, false)
+		if err != nil {
+			os.Remove(download)
+			return nil, "", errors.Errorf("Could not download %s of stack %s: %v", sourceURL, stackID, err)
+		}
+		checksum, size, err := ArchiveChecksum(download)
+		if err == nil {
+			err = VerifyChecksum(sourceURL, checksum, size, expectedChecksum, expectedSize)
+		}
+		if err == nil {
+			err = os.Rename(download, target)
+		}
+		if err != nil {
+			os.Remove(download)
+			return nil, "", err
+		}
+		artifact = &MirrorArtifact{URL: sourceURL, File: file, SHA256: checksum, Size: size}
+		m.fetched++
+	}
+	m.state.Artifacts = append(m.state.Artifacts, *artifact)
+	mirrorURL, err := MirrorURL(m.config.to, m.config.baseURL, file)
+	return artifact, mirrorURL, err
+}
+
+// imageID returns the ID of a local image, which changes when a tag is pushed again
+func imageID(config *RootCommandConfig, image string) (string, error) {
+	inspectOut, err := inspectImage(image, config)
+	if err != nil {
+		return "", err
+	}
+	if config.Buildah {
+		var buildahData map[string]interface{}
+		err = json.Unmarshal([]byte(inspectOut), &buildahData)
+		if err == nil {
+			if id, ok := buildahData["FromImageID"].(string); ok {
+				return id, nil
+			}
+		}
+	} else {
+		var data []map[string]interface{}
+		err = json.Unmarshal([]byte(inspectOut), &data)
+		if err == nil && len(data) > 0 {
+			if id, ok := data[0]["Id"].(string); ok {
+				return id, nil
+			}
+		}
+	}
+	return "", errors.Errorf("Could not read the ID of image %s from the inspect command", image)
+}
+
+// image pulls a stack image and saves it into the mirror, unless the mirror already has the same image
+func (m *repoMirror) image(image string) error {
+	file := MirrorImagePath(image)
+	err := pullImage(image, m.config.RootCommandConfig)
+	if err != nil {
+		return err
+	}
+	id, err := imageID(m.config.RootCommandConfig, image)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(m.config.to, filepath.FromSlash(file))
+	if previous, ok := m.previous[file]; ok && previous.URL == image && previous.ImageID == id {
+		if exists, _ := Exists(target); exists {
+			m.config.Debug.logf("Skipping image %s, which has not changed", image)
+			m.skipped++
+			m.state.Artifacts = append(m.state.Artifacts, previous)
+			return nil
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(target), os.FileMode(0755))
+	if err != nil {
+		return errors.Errorf("Error creating directory: %v", err)
+	}
+	os.Remove(target)
+	// docker save writes a docker archive, so the image is copied from the docker daemon to an OCI archive by skopeo
+	saveCmd := []string{"skopeo", "copy", "docker-daemon:" + image, "oci-archive:" + target + ":" + image}
+	if m.config.Buildah {
+		saveCmd = []string{"buildah", "push", image, "oci-archive:" + target + ":" + image}
+	}
+	m.config.Info.logf("Saving image %s", image)
+	m.config.Debug.log("Running command: ", strings.Join(saveCmd, " "))
+	output, err := SeparateOutput(exec.Command(saveCmd[0], saveCmd[1:]...))
+	if err != nil {
+		return errors.Errorf("Could not save image %s: %s %s", image, err, output)
+	}
+	m.state.Artifacts = append(m.state.Artifacts, MirrorArtifact{URL: image, File: file, ImageID: id})
+	m.fetched++
+	return nil
+}
+
+// keepUnselected adds the stacks of the previous mirror that are not selected with --stacks to the mirrored index,
+// and keeps their archives and images, so that they are not removed as stale
+func (m *repoMirror) keepUnselected(mirrored *IndexYaml, previousIndex *IndexYaml, previousArtifacts []MirrorArtifact) {
+	selected := make(map[string]bool)
+	for _, stackID := range m.config.stacks {
+		selected[stackID] = true
+	}
+	kept := make(map[string]bool)
+	keptImages := make(map[string]bool)
+	for _, stack := range previousIndex.Stacks {
+		if selected[stack.ID] {
+			continue
+		}
+		mirrored.Stacks = append(mirrored.Stacks, stack)
+		kept[stack.ID] = true
+		if stack.Image != "" {
+			keptImages[stack.Image] = true
+		}
+	}
+	current := make(map[string]bool)
+	for _, artifact := range m.state.Artifacts {
+		current[artifact.File] = true
+	}
+	for _, artifact := range previousArtifacts {
+		if current[artifact.File] {
+			continue
+		}
+		// the archives of a stack are under stacks/<stack ID>/ in the mirror
+		parts := strings.SplitN(artifact.File, "/", 3)
+		if (len(parts) == 3 && parts[0] == "stacks" && kept[parts[1]]) || (artifact.ImageID != "" && keptImages[artifact.URL]) {
+			m.state.Artifacts = append(m.state.Artifacts, artifact)
+			current[artifact.File] = true
+		}
+	}
+}
+
+// readMirrorIndex reads the index of a previous mirror, which is empty if there is none
+func readMirrorIndex(dir string) (*IndexYaml, error) {
+	var index IndexYaml
+	data, err := ioutil.ReadFile(filepath.Join(dir, mirrorIndexFile))
+	if os.IsNotExist(err) {
+		return &index, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read the mirrored index: %v", err)
+	}
+	err = yaml.Unmarshal(data, &index)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the mirrored index %s: %v", filepath.Join(dir, mirrorIndexFile), err)
+	}
+	return &index, nil
+}
+
+// removeStale removes the files of the previous mirror that are no longer in the repository.
+// The files of the stacks that were not selected with --stacks are kept by keepUnselected.
+func (m *repoMirror) removeStale() {
+	current := make(map[string]bool)
+	for _, artifact := range m.state.Artifacts {
+		current[artifact.File] = true
+	}
+	for file := range m.previous {
+		if current[file] {
+			continue
+		}
+		m.config.Info.logf("Removing %s, which is no longer mirrored", file)
+		err := os.Remove(filepath.Join(m.config.to, filepath.FromSlash(file)))
+		if err != nil && !os.IsNotExist(err) {
+			m.config.Warning.logf("Could not remove %s: %v", file, err)
+		}
+	}
+}
+
+func mirrorRepo(config *repoMirrorCommandConfig, repo *RepositoryEntry, index *IndexYaml) error {
+	previousState, err := readMirrorState(config.to)
+	if err != nil {
+		return err
+	}
+	m := &repoMirror{
+		config:   config,
+		creds:    getCredentialStore(config.RootCommandConfig),
+		previous: make(map[string]MirrorArtifact),
+		state:    &MirrorState{Repository: repo.Name, Source: repo.URL},
+	}
+	for _, artifact := range previousState.Artifacts {
+		m.previous[artifact.File] = artifact
+	}
+
+	mirrored := *index
+	mirrored.Stacks = make([]IndexYamlStack, 0, len(index.Stacks))
+	images := make([]string, 0)
+	seenImages := make(map[string]bool)
+	for _, stack := range index.Stacks {
+		if stack.SourceURL != "" {
+			artifact, mirrorURL, err := m.archive(stack.ID, stack.Version, "source", stack.SourceURL, stack.SourceSHA256, stack.SourceSize)
+			if err != nil {
+				return err
+			}
+			stack.SourceURL, stack.SourceSHA256, stack.SourceSize = mirrorURL, artifact.SHA256, artifact.Size
+		}
+		templates := make([]IndexYamlStackTemplate, 0, len(stack.Templates))
+		for _, template := range stack.Templates {
+			artifact, mirrorURL, err := m.archive(stack.ID, stack.Version, template.ID, template.URL, template.SHA256, template.Size)
+			if err != nil {
+				return err
+			}
+			template.URL, template.SHA256, template.Size = mirrorURL, artifact.SHA256, artifact.Size
+			templates = append(templates, template)
+		}
+		stack.Templates = templates
+		mirrored.Stacks = append(mirrored.Stacks, stack)
+		if stack.Image != "" && !seenImages[stack.Image] {
+			seenImages[stack.Image] = true
+			images = append(images, stack.Image)
+		}
+	}
+	if config.images {
+		for _, image := range images {
+			err = m.image(image)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// with --stacks, only the selected stacks are updated, and the other stacks of a previous mirror of the repository are kept
+	if len(config.stacks) > 0 && previousState.Repository == repo.Name {
+		previousIndex, err := readMirrorIndex(config.to)
+		if err != nil {
+			return err
+		}
+		m.keepUnselected(&mirrored, previousIndex, previousState.Artifacts)
+	}
+
+	data, err := yaml.Marshal(&mirrored)
+	if err != nil {
+		return err
+	}
+	indexFile := filepath.Join(config.to, mirrorIndexFile)
+	err = ioutil.WriteFile(indexFile, data, 0666)
+	if err != nil {
+		return errors.Errorf("Error writing the mirrored index: %v", err)
+	}
+	err = signIndexFile(config.LoggingConfig, indexFile, config.signKey)
+	if err != nil {
+		return err
+	}
+	m.removeStale()
+	data, err = yaml.Marshal(m.state)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(config.to, MirrorStateFile), data, 0666)
+	if err != nil {
+		return errors.Errorf("Error writing the mirror state file: %v", err)
+	}
+
+	indexURL, err := MirrorURL(config.to, config.baseURL, mirrorIndexFile)
+	if err != nil {
+		return err
+	}
+	config.Info.logf("Mirrored %d stacks of repository %s to %s: %d files downloaded, %d unchanged", len(mirrored.Stacks), repo.Name, config.to, m.fetched, m.skipped)
+	config.Info.logf("Add the mirror with: appsody repo add <name> %s", indexURL)
+	if config.images && len(images) > 0 {
+		config.Info.logf("The images in %s are OCI archives. Load them with 'skopeo copy oci-archive:<file> docker-daemon:<image>', or push them to a registry that your network can reach with 'skopeo copy oci-archive:<file> docker://<registry>/<image>'", filepath.Join(config.to, "images"))
+	}
+	return nil
+}
+
+func newRepoMirrorCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &repoMirrorCommandConfig{RootCommandConfig: rootConfig}
+	var mirrorCmd = &cobra.Command{
+		Use:   "mirror <repository> --to <directory>",
+		Short: "Copy an Appsody repository for use without internet access.",
+		Long: `Download the index of an Appsody repository, with the source and template archives of its stacks, into a directory. The directory gets an index.yaml that points to the copies of the archives, so that you can add it as a repository and create projects without internet access.
+
+The URLs in the copied index are file:// URLs of the directory, unless you specify --base-url, which is the URL that the directory will be served from. With --images, the stack images are also saved as OCI image archives in the "images" directory. The images are copied from docker with skopeo, which must be installed, or pushed with buildah if you use --buildah.
+
+Mirroring to the same directory again only downloads the archives and images that have changed, and removes the ones that are no longer in the repository. With --stacks, only the selected stacks are updated, and the other stacks that were mirrored before are kept. The mirror.yaml file in the directory records what was copied.`,
+		Example: `  appsody repo mirror incubator --to /mnt/usb/incubator
+  Copies the "incubator" repository to "/mnt/usb/incubator". Add it with "appsody repo add incubator-mirror file:///mnt/usb/incubator/index.yaml".
+
+  appsody repo mirror incubator --to ./mirror --stacks nodejs,java-microprofile --images --base-url https://repo.example.com/incubator
+  Copies the nodejs and java-microprofile stacks of the "incubator" repository, with their images, to be served from "https://repo.example.com/incubator".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("You must specify repository name")
+			}
+			if len(args) > 1 {
+				return errors.Errorf("One argument expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			if config.to == "" {
+				return errors.New("You must specify the directory to mirror the repository to with --to")
+			}
+			if config.baseURL != "" {
+				parsed, err := url.Parse(config.baseURL)
+				if err != nil || parsed.Scheme == "" {
+					return errors.Errorf("The base URL %s is not a valid URL", config.baseURL)
+				}
+			}
+			if config.images && !rootConfig.Buildah {
+				if _, err := exec.LookPath("skopeo"); err != nil {
+					return errors.New("Saving the stack images with --images requires skopeo, to write them as OCI image archives. Install skopeo, or use --buildah")
+				}
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(rootConfig)
+			if repoErr != nil {
+				return repoErr
+			}
+			repo := repoFile.GetRepo(args[0])
+			if repo == nil {
+				return errors.New("cannot locate repository named " + args[0])
+			}
+			index, err := getIndex(rootConfig, repo, !isOffline(rootConfig))
+			if err != nil {
+				return err
+			}
+			index, err = selectMirrorStacks(index, config.stacks)
+			if err != nil {
+				return err
+			}
+			if rootConfig.Dryrun {
+				rootConfig.Info.logf("Dry Run - Skipping the mirror of %d stacks of repository %s to %s", len(index.Stacks), repo.Name, config.to)
+				return nil
+			}
+			err = os.MkdirAll(config.to, os.FileMode(0755))
+			if err != nil {
+				return errors.Errorf("Error creating directory: %v", err)
+			}
+			return mirrorRepo(config, repo, index)
+		},
+	}
+	mirrorCmd.PersistentFlags().StringVar(&config.to, "to", "", "The directory to copy the repository to")
+	mirrorCmd.PersistentFlags().StringVar(&config.baseURL, "base-url", "", "The URL that the directory will be served from. The copied index uses file:// URLs by default.")
+	mirrorCmd.PersistentFlags().StringSliceVar(&config.stacks, "stacks", nil, "The IDs of the stacks to copy. All the stacks are copied by default.")
+	mirrorCmd.PersistentFlags().BoolVar(&config.images, "images", false, "Also save the stack images in the directory")
+	mirrorCmd.PersistentFlags().StringVar(&config.signKey, "sign-key", "", "The PEM private key file to sign the copied index with")
+	return mirrorCmd
+}
@@ -0,0 +1,199 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMirrorPaths(t *testing.T) {
+	var pathTests = []struct {
+		testName string
+		actual   string
+		expected string
+	}{
+		{"Template archive", cmd.MirrorArchivePath("nodejs", "0.3.1", "simple", "https://github.com/appsody/stacks/releases/download/nodejs-v0.3.1/incubator.nodejs.v0.3.1.templates.simple.tar.gz"), "stacks/nodejs/0.3.1/simple/incubator.nodejs.v0.3.1.templates.simple.tar.gz"},
+		{"Source archive without a file name", cmd.MirrorArchivePath("nodejs", "0.3.1", "source", "https://example.com/"), "stacks/nodejs/0.3.1/source/archive.tar.gz"},
+		{"Image archive", cmd.MirrorImagePath("docker.io/appsody/nodejs:0.3"), "images/docker.io_appsody_nodejs_0.3.tar"},
+	}
+	for _, testData := range pathTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			if tt.actual != tt.expected {
+				t.Errorf("Expected %s but got %s", tt.expected, tt.actual)
+			}
+		})
+	}
+
+	mirrorURL, err := cmd.MirrorURL("/mirror", "https://repo.example.com/incubator/", "index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mirrorURL != "https://repo.example.com/incubator/index.yaml" {
+		t.Errorf("Expected the mirror URL to be under the base URL, got %s", mirrorURL)
+	}
+}
+
+func TestRepoMirror(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	template := []byte("template archive")
+	sum := sha256.Sum256(template)
+	var mutex sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests[r.URL.Path]++
+		mutex.Unlock()
+		_, _ = w.Write(template)
+	}))
+	defer server.Close()
+
+	index := fmt.Sprintf(`apiVersion: v2
+stacks:
+- id: nodejs
+  name: Node.js
+  version: 0.3.1
+  description: Runtime for Node.js applications
+  src: %[1]s/nodejs.v0.3.1.source.tar.gz
+  templates:
+  - id: simple
+    url: %[1]s/nodejs.v0.3.1.templates.simple.tar.gz
+    sha256: %[2]s
+- id: java
+  name: Java
+  version: 0.2.0
+  description: Runtime for Java applications
+  templates:
+  - id: default
+    url: %[1]s/java.v0.2.0.templates.default.tar.gz
+`, server.URL, hex.EncodeToString(sum[:]))
+	indexFile := filepath.Join(sandbox.TestDataPath, "mirror-source-index.yaml")
+	err := ioutil.WriteFile(indexFile, []byte(index), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmdtest.AddLocalRepo(sandbox, "source", indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirrorDir := filepath.Join(sandbox.ProjectDir, "mirror")
+	output, err := cmdtest.RunAppsody(sandbox, "repo", "mirror", "source", "--to", mirrorDir, "--stacks", "nodejs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "2 files downloaded, 0 unchanged") {
+		t.Errorf("Expected the source and template archives to be downloaded:\n%s", output)
+	}
+	if requests["/java.v0.2.0.templates.default.tar.gz"] != 0 {
+		t.Error("Expected only the stacks given with --stacks to be mirrored")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(mirrorDir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mirrored cmd.IndexYaml
+	err = yaml.Unmarshal(data, &mirrored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrored.Stacks) != 1 || len(mirrored.Stacks[0].Templates) != 1 {
+		t.Fatalf("Expected the mirrored index to have the nodejs stack and its template:\n%s", data)
+	}
+	templateURL := mirrored.Stacks[0].Templates[0].URL
+	if !strings.HasPrefix(templateURL, "file://") || !strings.HasSuffix(templateURL, "stacks/nodejs/0.3.1/simple/nodejs.v0.3.1.templates.simple.tar.gz") {
+		t.Errorf("Expected the template URL to point to the mirror, got %s", templateURL)
+	}
+	if mirrored.Stacks[0].SourceSHA256 == "" {
+		t.Error("Expected the mirrored index to have the checksum of the source archive")
+	}
+
+	// mirroring again only downloads what changed
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "mirror", "source", "--to", mirrorDir, "--stacks", "nodejs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "0 files downloaded, 2 unchanged") {
+		t.Errorf("Expected the archives not to be downloaded again:\n%s", output)
+	}
+	if requests["/nodejs.v0.3.1.templates.simple.tar.gz"] != 1 {
+		t.Errorf("Expected the template to be downloaded once, got %d downloads", requests["/nodejs.v0.3.1.templates.simple.tar.gz"])
+	}
+
+	_, err = cmdtest.AddLocalRepo(sandbox, "mirror", filepath.Join(mirrorDir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "list", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "nodejs") {
+		t.Errorf("Expected the mirror to list the nodejs stack:\n%s", output)
+	}
+
+	// mirroring another stack keeps the stacks that were mirrored before
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "mirror", "source", "--to", mirrorDir, "--stacks", "java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output, "Removing") {
+		t.Errorf("Expected the archives of the nodejs stack not to be removed:\n%s", output)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(mirrorDir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrored = cmd.IndexYaml{}
+	err = yaml.Unmarshal(data, &mirrored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrored.Stacks) != 2 {
+		t.Errorf("Expected the mirrored index to have the java and nodejs stacks:\n%s", data)
+	}
+	exists, err := cmd.Exists(filepath.Join(mirrorDir, "stacks", "nodejs", "0.3.1", "simple", "nodejs.v0.3.1.templates.simple.tar.gz"))
+	if err != nil || !exists {
+		t.Errorf("Expected the template archive of the nodejs stack to be kept, got %v", err)
+	}
+	state, err := ioutil.ReadFile(filepath.Join(mirrorDir, cmd.MirrorStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(state), "stacks/nodejs/0.3.1/simple/") || !strings.Contains(string(state), "stacks/java/0.2.0/default/") {
+		t.Errorf("Expected the mirror state to have the archives of both stacks:\n%s", state)
+	}
+
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "mirror", "source", "--to", mirrorDir, "--stacks", "python")
+	if err == nil || !strings.Contains(output, "Could not find stack python") {
+		t.Errorf("Expected an error for a stack that is not in the repository, got %v", err)
+	}
+}
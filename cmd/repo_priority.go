@@ -0,0 +1,162 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ReposByPriority returns the repositories with the highest priority first.
+// The default repository comes first among repositories with the same priority, then the order of the repository file.
+func (r *RepositoryFile) ReposByPriority() []*RepositoryEntry {
+	repos := make([]*RepositoryEntry, len(r.Repositories))
+	copy(repos, r.Repositories)
+	sort.SliceStable(repos, func(i, j int) bool {
+		if repos[i].Priority != repos[j].Priority {
+			return repos[i].Priority > repos[j].Priority
+		}
+		return repos[i].IsDefault && !repos[j].IsDefault
+	})
+	return repos
+}
+
+// StackCandidates returns the names of the repositories that have the stack, highest priority first
+func (r *RepositoryFile) StackCandidates(indices RepoIndices, stackID string) []string {
+	var candidates []string
+	for _, repo := range r.ReposByPriority() {
+		index := indices[repo.Name]
+		if index != nil && len(index.stackVersions(stackID)) > 0 {
+			candidates = append(candidates, repo.Name)
+		}
+	}
+	return candidates
+}
+
+// StackShadows returns the repository that takes precedence for each stack that is in more than one repository,
+// keyed by the repository name and the stack ID of the stacks that are shadowed
+func (r *RepositoryFile) StackShadows(indices RepoIndices) map[string]string {
+	shadows := make(map[string]string)
+	first := make(map[string]string)
+	for _, repo := range r.ReposByPriority() {
+		index := indices[repo.Name]
+		if index == nil {
+			continue
+		}
+		for _, stack := range index.latestStackVersions() {
+			if winner, ok := first[stack.ID]; ok {
+				shadows[repo.Name+"/"+stack.ID] = winner
+			} else {
+				first[stack.ID] = repo.Name
+			}
+		}
+	}
+	return shadows
+}
+
+// resolveStackRepo returns the repository with the highest priority that has the stack,
+// or the default repository if no repository has it
+func (r *RepositoryFile) resolveStackRepo(config *RootCommandConfig, stackID string) (string, error) {
+	defaultRepoName, err := r.GetDefaultRepoName(config)
+	if err != nil {
+		return "", err
+	}
+	if len(r.Repositories) == 1 {
+		return defaultRepoName, nil
+	}
+	indices, err := r.GetIndices(config)
+	if err != nil {
+		config.Debug.logf("The following indices could not be read, skipping:\n%v", err)
+	}
+	candidates := r.StackCandidates(indices, stackID)
+	if len(candidates) == 0 {
+		return defaultRepoName, nil
+	}
+	if len(candidates) > 1 {
+		descriptions := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			descriptions = append(descriptions, fmt.Sprintf("%s (priority %d)", candidate, r.GetRepo(candidate).Priority))
+		}
+		config.Warning.logf("The stack %s is in more than one repository: %s. Using %s/%s, specify <repository>/%s to use another repository.",
+			stackID, strings.Join(descriptions, ", "), candidates[0], stackID, stackID)
+	}
+	config.Debug.logf("Resolved stack %s to repository %s", stackID, candidates[0])
+	return candidates[0], nil
+}
+
+// highestPriority returns the highest priority of the repositories other than the named one
+func (r *RepositoryFile) highestPriority(except string) (int, bool) {
+	found := false
+	highest := 0
+	for _, repo := range r.Repositories {
+		if repo.Name != except && (!found || repo.Priority > highest) {
+			highest = repo.Priority
+			found = true
+		}
+	}
+	return highest, found
+}
+
+func newRepoSetPriorityCmd(config *RootCommandConfig) *cobra.Command {
+	var setPriorityCmd = &cobra.Command{
+		Use:   "set-priority <repository> <priority>",
+		Short: "Set the priority of a repository.",
+		Long: `Set the priority of a repository, which is a number that is 0 by default.
+
+When you specify a stack without a repository, for example "appsody init nodejs-express", the stack is taken from the repository with the highest priority that has the stack. The default repository comes first among repositories with the same priority, so setting the default repository with "appsody repo set-default" also gives it the highest priority.`,
+		Example: `  appsody repo set-priority my-fork 10
+  Uses the stacks of the "my-fork" repository instead of the stacks with the same IDs in repositories with a lower priority.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("You must specify a repository name and a priority")
+			}
+			if len(args) > 2 {
+				return errors.Errorf("Two arguments expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			repoName := args[0]
+			priority, err := strconv.Atoi(args[1])
+			if err != nil {
+				return errors.Errorf("The priority %s is not a whole number", args[1])
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(config)
+			if repoErr != nil {
+				return repoErr
+			}
+			repo := repoFile.GetRepo(repoName)
+			if repo == nil {
+				return errors.New("The repository '" + repoName + "' is not in your configured list of repositories")
+			}
+			if config.Dryrun {
+				config.Info.logf("Dry Run - Skipping appsody repo set-priority %s %d", repoName, priority)
+				return nil
+			}
+			adoptRepo(config, repo)
+			repo.Priority = priority
+			err = repoFile.WriteFile(getRepoFileLocation(config))
+			if err != nil {
+				return errors.Errorf("Failed to write file to repository location: %v", err)
+			}
+			config.Info.logf("The priority of repository %s is now %d", repoName, priority)
+			return nil
+		},
+	}
+	return setPriorityCmd
+}
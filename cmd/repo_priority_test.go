@@ -0,0 +1,140 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+)
+
+func TestStackCandidates(t *testing.T) {
+	repoFile := newTestRepoFile(
+		cmd.RepositoryEntry{Name: "incubator", URL: "https://example.com/incubator.yaml", IsDefault: true},
+		cmd.RepositoryEntry{Name: "experimental", URL: "https://example.com/experimental.yaml"},
+		cmd.RepositoryEntry{Name: "fork", URL: "https://example.com/fork.yaml", Priority: 10},
+	)
+	indices := cmd.RepoIndices{
+		"incubator":    {Stacks: []cmd.IndexYamlStack{{ID: "nodejs"}, {ID: "java"}}},
+		"experimental": {Stacks: []cmd.IndexYamlStack{{ID: "java"}, {ID: "rust"}}},
+		"fork":         {Stacks: []cmd.IndexYamlStack{{ID: "nodejs"}}},
+	}
+
+	var candidateTests = []struct {
+		stackID            string
+		expectedCandidates string
+	}{
+		{"nodejs", "fork,incubator"},
+		{"java", "incubator,experimental"},
+		{"rust", "experimental"},
+		{"python", ""},
+	}
+	for _, testData := range candidateTests {
+		tt := testData
+		t.Run(tt.stackID, func(t *testing.T) {
+			actual := strings.Join(repoFile.StackCandidates(indices, tt.stackID), ",")
+			if actual != tt.expectedCandidates {
+				t.Errorf("Expected the candidates %s but got %s", tt.expectedCandidates, actual)
+			}
+		})
+	}
+
+	shadows := repoFile.StackShadows(indices)
+	expectedShadows := map[string]string{"incubator/nodejs": "fork", "experimental/java": "incubator"}
+	if len(shadows) != len(expectedShadows) {
+		t.Errorf("Expected the shadowed stacks %v but got %v", expectedShadows, shadows)
+	}
+	for stack, winner := range expectedShadows {
+		if shadows[stack] != winner {
+			t.Errorf("Expected %s to be shadowed by %s but got %q", stack, winner, shadows[stack])
+		}
+	}
+}
+
+func TestRepoPriority(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	index := `apiVersion: v2
+stacks:
+- id: nodejs
+  name: Node.js
+  version: %s
+  description: Runtime for Node.js applications
+  templates:
+  - id: simple
+    url: file:///simple.tar.gz
+`
+	for repoName, version := range map[string]string{"upstream": "0.3.1", "fork": "0.3.1-fork"} {
+		indexFile := filepath.Join(sandbox.TestDataPath, repoName+"-index.yaml")
+		err := ioutil.WriteFile(indexFile, []byte(strings.Replace(index, "%s", version, 1)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = cmdtest.AddLocalRepo(sandbox, repoName, indexFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := cmdtest.RunAppsody(sandbox, "repo", "set-default", "upstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default repository is used first
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "info", "nodejs", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"repo":"upstream"`) || !strings.Contains(output, "The stack nodejs is in more than one repository") {
+		t.Errorf("Expected the stack of the default repository to be used, with a warning:\n%s", output)
+	}
+
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "set-priority", "fork", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "stack", "info", "nodejs", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"repo":"fork"`) {
+		t.Errorf("Expected the stack of the repository with the highest priority to be used:\n%s", output)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "nodejs [Shadowed by fork]") {
+		t.Errorf("Expected the stack of the default repository to be marked as shadowed:\n%s", output)
+	}
+
+	// setting the default repository gives it the highest priority
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "set-default", "experimental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "repo", "list", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"name":"experimental","url":"https://github.com/appsody/stacks/releases/latest/download/experimental-index.yaml","default":true,"priority":10`) {
+		t.Errorf("Expected the new default repository to have the highest priority:\n%s", output)
+	}
+}
@@ -48,6 +48,10 @@ You cannot remove the default repository, but you can make a different repositor
 			if config.Dryrun {
 				config.Info.log("Dry Run - Skipping appsody repo remove ", repoName)
 			} else {
+				if repo := repoFile.GetRepo(repoName); repo != nil && repo.Source == repoSourceTeam {
+					location, _ := getTeamRepoFileLocation()
+					return errors.Errorf("The repository %s is configured in the team repository file %s and cannot be removed", repoName, location)
+				}
 				if repoFile.Has(repoName) {
 					defaultRepoName, err := repoFile.GetDefaultRepoName(config)
 					if err != nil {
@@ -65,6 +69,10 @@ You cannot remove the default repository, but you can make a different repositor
 				if err != nil {
 					log.Fatalf("Failed to write file repository location: %v", err)
 				}
+				err = removeRepoCredentials(config, repoName)
+				if err != nil {
+					return err
+				}
 			}
 			return nil
 		},
@@ -0,0 +1,260 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const indexFileSuffix = "-index.yaml"
+
+type repoServeCommandConfig struct {
+	*RootCommandConfig
+	port    int
+	dir     string
+	baseURL string
+}
+
+// repoServer serves the indices and archives in a directory, with the file:// URLs of the indices
+// replaced by URLs of the server
+type repoServer struct {
+	log     *LoggingConfig
+	dir     string
+	baseURL string
+	files   http.Handler
+}
+
+// NewRepoServer returns a handler that serves the repository indices and archives in a directory.
+// If baseURL is empty, the URLs are based on the host of each request.
+func NewRepoServer(log *LoggingConfig, dir string, baseURL string) http.Handler {
+	return &repoServer{log: log, dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), files: http.FileServer(http.Dir(dir))}
+}
+
+// filePath returns the local path of a file:// URL
+func filePath(fileURL string) string {
+	filePath := strings.TrimPrefix(fileURL, "file://")
+	if runtime.GOOS == "windows" {
+		// for windows, remove the leading slash added to the file:// URL
+		filePath = filepath.FromSlash(strings.TrimPrefix(filePath, "/"))
+	}
+	return filePath
+}
+
+// RewriteIndexURLs replaces the file:// URLs of the files in the directory with URLs under the base URL.
+// It returns true if the index was changed.
+func RewriteIndexURLs(index *IndexYaml, dir string, baseURL string) bool {
+	changed := false
+	rewrite := func(archiveURL string) string {
+		if !strings.HasPrefix(archiveURL, "file://") {
+			return archiveURL
+		}
+		rel, err := filepath.Rel(dir, filePath(archiveURL))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return archiveURL
+		}
+		changed = true
+		return strings.TrimSuffix(baseURL, "/") + "/" + filepath.ToSlash(rel)
+	}
+	for i := range index.Stacks {
+		stack := &index.Stacks[i]
+		stack.SourceURL = rewrite(stack.SourceURL)
+		for j := range stack.Templates {
+			stack.Templates[j].URL = rewrite(stack.Templates[j].URL)
+		}
+	}
+	return changed
+}
+
+// servedRepoName is the name that prefixes the Codewind display names of the stacks in an index
+func servedRepoName(indexFile string) string {
+	name := strings.TrimSuffix(path.Base(indexFile), indexFileSuffix)
+	if name == "dev.local" {
+		// the same name as the Codewind index that appsody stack package generates
+		return "Local"
+	}
+	return name
+}
+
+// readIndex reads an index in the served directory, returning false if the file is not a repository index
+func (s *repoServer) readIndex(name string) ([]byte, *IndexYaml, os.FileInfo, bool) {
+	file := filepath.Join(s.dir, filepath.FromSlash(name))
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return nil, nil, nil, false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, nil, false
+	}
+	var index IndexYaml
+	if err = yaml.Unmarshal(data, &index); err != nil || index.APIVersion == "" {
+		return nil, nil, nil, false
+	}
+	return data, &index, info, true
+}
+
+func (s *repoServer) requestBaseURL(r *http.Request) string {
+	if s.baseURL != "" {
+		return s.baseURL
+	}
+	return "http://" + r.Host
+}
+
+func (s *repoServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Only GET and HEAD requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	s.log.Debug.logf("%s %s from %s", r.Method, name, r.RemoteAddr)
+
+	indexName := name
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		indexName = strings.TrimSuffix(name, ".json") + ".yaml"
+	case strings.HasSuffix(name, ".yaml"+indexSignatureSuffix):
+		indexName = strings.TrimSuffix(name, indexSignatureSuffix)
+	}
+	data, index, info, ok := s.readIndex(indexName)
+	if !ok {
+		s.files.ServeHTTP(w, r)
+		return
+	}
+	changed := RewriteIndexURLs(index, s.dir, s.requestBaseURL(r))
+
+	var err error
+	switch {
+	case strings.HasSuffix(name, indexSignatureSuffix):
+		if changed {
+			// the signature is for the index with the file:// URLs
+			http.Error(w, "The index is rewritten by the server, so its signature does not apply", http.StatusNotFound)
+			return
+		}
+		s.files.ServeHTTP(w, r)
+		return
+	case strings.HasSuffix(name, ".json"):
+		w.Header().Set("Content-Type", "application/json")
+		data, err = codewindJSON(*index, servedRepoName(indexName))
+	default:
+		w.Header().Set("Content-Type", "application/x-yaml")
+		if changed {
+			data, err = yaml.Marshal(index)
+		}
+	}
+	if err != nil {
+		s.log.Error.logf("Could not serve %s: %v", name, err)
+		http.Error(w, "Could not serve the index", http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(data))
+}
+
+// servedIndices returns the names of the repository indices in the directory
+func servedIndices(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	server := &repoServer{dir: dir}
+	var indices []string
+	for _, file := range files {
+		name := filepath.Base(file)
+		if _, _, _, ok := server.readIndex(name); ok {
+			indices = append(indices, name)
+		}
+	}
+	return indices, nil
+}
+
+func newRepoServeCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &repoServeCommandConfig{RootCommandConfig: rootConfig}
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a directory of Appsody repository indices and archives over HTTP.",
+		Long: `Serve the repository indices in a directory, with the template and source archives of their stacks, so that your team can add them as repositories.
+
+By default, the "stacks/dev.local" directory of your Appsody home directory is served, where "appsody stack package" writes the dev.local index and archives. The file:// URLs of the archives in the served directory are replaced by URLs of the server when an index is downloaded. The Codewind index of each <name>.yaml index is served as <name>.json.
+
+The URLs are based on the host name that each request uses, unless you specify --base-url, which is useful behind a proxy. Signatures are only served for indices that do not need their URLs replaced.`,
+		Example: `  appsody repo serve
+  Serves the stacks that you packaged with "appsody stack package" on port 8080. Your team can add them with "appsody repo add dev-stacks http://<your host>:8080/dev.local-index.yaml".
+
+  appsody repo serve --dir ./mirror --port 9000
+  Serves the indices and archives in the "mirror" directory on port 9000.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			dir := config.dir
+			if dir == "" {
+				dir = filepath.Join(getHome(rootConfig), "stacks", "dev.local")
+			}
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return errors.Errorf("The directory %s does not exist. Use appsody stack package to package a stack, or specify another directory with --dir", dir)
+			}
+			indices, err := servedIndices(dir)
+			if err != nil {
+				return err
+			}
+			if len(indices) == 0 {
+				rootConfig.Warning.logf("There are no repository indices in %s", dir)
+			}
+
+			hostURL := config.baseURL
+			if hostURL == "" {
+				host, err := os.Hostname()
+				if err != nil {
+					host = "localhost"
+				}
+				hostURL = "http://" + host + ":" + strconv.Itoa(config.port)
+			}
+			for _, index := range indices {
+				rootConfig.Info.logf("Serving %s/%s", strings.TrimSuffix(hostURL, "/"), index)
+			}
+			if rootConfig.Dryrun {
+				rootConfig.Info.logf("Dry Run - Skipping serving %s on port %d", dir, config.port)
+				return nil
+			}
+			rootConfig.Info.logf("Serving %s on port %d. Press Ctrl-C to stop", dir, config.port)
+			server := &http.Server{
+				Addr:              ":" + strconv.Itoa(config.port),
+				Handler:           NewRepoServer(rootConfig.LoggingConfig, dir, config.baseURL),
+				ReadHeaderTimeout: 30 * time.Second,
+			}
+			return server.ListenAndServe()
+		},
+	}
+	serveCmd.PersistentFlags().IntVar(&config.port, "port", 8080, "The port to serve the repository on")
+	serveCmd.PersistentFlags().StringVar(&config.dir, "dir", "", "The directory to serve. The default is the stacks/dev.local directory of your Appsody home directory.")
+	serveCmd.PersistentFlags().StringVar(&config.baseURL, "base-url", "", "The URL that clients reach the server with, if it is not the host name of their requests")
+	return serveCmd
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRewriteIndexURLs(t *testing.T) {
+	dir, err := filepath.Abs("repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inDir, _ := cmd.MirrorURL(dir, "", "nodejs.v0.3.1.templates.simple.tar.gz")
+	outside, _ := cmd.MirrorURL(filepath.Dir(dir), "", "other.tar.gz")
+	index := &cmd.IndexYaml{
+		APIVersion: "v2",
+		Stacks: []cmd.IndexYamlStack{{
+			ID:        "nodejs",
+			SourceURL: outside,
+			Templates: []cmd.IndexYamlStackTemplate{
+				{ID: "simple", URL: inDir},
+				{ID: "remote", URL: "https://github.com/appsody/stacks/releases/download/nodejs-v0.3.1/remote.tar.gz"},
+			},
+		}},
+	}
+	if !cmd.RewriteIndexURLs(index, dir, "http://stacks.example.com:8080/") {
+		t.Fatal("Expected the index to be rewritten")
+	}
+	stack := index.Stacks[0]
+	if stack.Templates[0].URL != "http://stacks.example.com:8080/nodejs.v0.3.1.templates.simple.tar.gz" {
+		t.Errorf("Expected the file:// URL in the directory to be rewritten, got %s", stack.Templates[0].URL)
+	}
+	if stack.SourceURL != outside {
+		t.Errorf("Expected the file:// URL outside of the directory to be kept, got %s", stack.SourceURL)
+	}
+	if !strings.HasPrefix(stack.Templates[1].URL, "https://github.com/") {
+		t.Errorf("Expected the https URL to be kept, got %s", stack.Templates[1].URL)
+	}
+}
+
+func TestRepoServe(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	dir := filepath.Join(sandbox.ProjectDir, "dev.local")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := []byte("template archive")
+	err = ioutil.WriteFile(filepath.Join(dir, "nodejs.v0.3.1.templates.simple.tar.gz"), template, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateURL, _ := cmd.MirrorURL(dir, "", "nodejs.v0.3.1.templates.simple.tar.gz")
+	index := fmt.Sprintf(`apiVersion: v2
+stacks:
+- id: nodejs
+  name: Node.js
+  version: 0.3.1
+  description: Runtime for Node.js applications
+  templates:
+  - id: simple
+    url: %s
+`, templateURL)
+	err = ioutil.WriteFile(filepath.Join(dir, "dev.local-index.yaml"), []byte(index), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "dev.local-index.yaml.sig"), []byte("signature\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outBuffer bytes.Buffer
+	loggingConfig := &cmd.LoggingConfig{}
+	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	server := httptest.NewServer(cmd.NewRepoServer(loggingConfig, dir, ""))
+	defer server.Close()
+
+	get := func(name string) (int, []byte) {
+		resp, err := http.Get(server.URL + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, body := get("dev.local-index.yaml")
+	if status != http.StatusOK {
+		t.Fatalf("Expected the index to be served, got status %d", status)
+	}
+	var served cmd.IndexYaml
+	err = yaml.Unmarshal(body, &served)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedURL := server.URL + "/nodejs.v0.3.1.templates.simple.tar.gz"
+	if served.Stacks[0].Templates[0].URL != expectedURL {
+		t.Errorf("Expected the template URL %s but got %s", expectedURL, served.Stacks[0].Templates[0].URL)
+	}
+
+	status, body = get("dev.local-index.json")
+	if status != http.StatusOK || !strings.Contains(string(body), expectedURL) || !strings.Contains(string(body), "Local Node.js simple template") {
+		t.Errorf("Expected the Codewind index to be served with the server URLs, got status %d:\n%s", status, body)
+	}
+	status, body = get("nodejs.v0.3.1.templates.simple.tar.gz")
+	if status != http.StatusOK || !bytes.Equal(body, template) {
+		t.Errorf("Expected the template archive to be served, got status %d", status)
+	}
+	status, _ = get("dev.local-index.yaml.sig")
+	if status != http.StatusNotFound {
+		t.Errorf("Expected the signature of a rewritten index not to be served, got status %d", status)
+	}
+	status, _ = get("../" + filepath.Base(sandbox.ConfigDir) + "/config.yaml")
+	if status == http.StatusOK {
+		t.Error("Expected files outside of the directory not to be served")
+	}
+
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "add", "team", server.URL+"/dev.local-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := cmdtest.RunAppsody(sandbox, "list", "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "nodejs") {
+		t.Errorf("Expected the served repository to list the nodejs stack:\n%s", output)
+	}
+}
@@ -26,7 +26,7 @@ func newRepoDefaultCmd(config *RootCommandConfig) *cobra.Command {
 		Short: "Set a default repository.",
 		Long: `Set your specified repository to be the default repository.
 
-The default repository is used when you run the "appsody init" command without specifying a repository name. Use "appsody repo list" or "appsody list" to see which repository is currently the default (denoted by an asterisk).`,
+The default repository is used when you run the "appsody init" command without specifying a repository name, unless a repository with a higher priority has the stack. The default repository is given the highest priority of your repositories, so that its stacks are used before the stacks with the same IDs in other repositories. Use "appsody repo list" or "appsody list" to see which repository is currently the default (denoted by an asterisk).`,
 		Example: `  appsody repo set-default my-local-repo
   Sets your default repository to "my-local-repo".`,
 		RunE: func(cmd *cobra.Command, args []string) error {
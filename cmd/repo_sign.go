@@ -0,0 +1,269 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+// the detached signature of an index is published next to it, with this suffix
+const indexSignatureSuffix = ".sig"
+
+func indexSignatureURL(indexURL string) string {
+	return indexURL + indexSignatureSuffix
+}
+
+// crypto/x509 only parses Ed25519 keys from Go 1.13, so they are parsed here
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+type pkixPublicKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	// the optional attributes are not needed
+}
+
+// parseEd25519PrivateKey returns the key of a PKCS#8 document, or nil if it is not an Ed25519 key
+func parseEd25519PrivateKey(der []byte) (ed25519.PrivateKey, error) {
+	var privateKey pkcs8PrivateKey
+	if _, err := asn1.Unmarshal(der, &privateKey); err != nil || !privateKey.Algorithm.Algorithm.Equal(oidEd25519) {
+		return nil, nil
+	}
+	var seed []byte
+	if rest, err := asn1.Unmarshal(privateKey.PrivateKey, &seed); err != nil || len(rest) > 0 || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// parseEd25519PublicKey returns the key of a PKIX document, or nil if it is not an Ed25519 key
+func parseEd25519PublicKey(der []byte) (ed25519.PublicKey, error) {
+	var publicKey pkixPublicKey
+	if _, err := asn1.Unmarshal(der, &publicKey); err != nil || !publicKey.Algorithm.Algorithm.Equal(oidEd25519) {
+		return nil, nil
+	}
+	key := publicKey.PublicKey.RightAlign()
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// verifyECDSA checks an ASN.1 DER encoded ECDSA signature
+func verifyECDSA(key *ecdsa.PublicKey, digest []byte, sig []byte) bool {
+	var signature struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(sig, &signature); err != nil || len(rest) > 0 {
+		return false
+	}
+	if signature.R == nil || signature.S == nil || signature.R.Sign() <= 0 || signature.S.Sign() <= 0 {
+		return false
+	}
+	return ecdsa.Verify(key, digest, signature.R, signature.S)
+}
+
+// parsePrivateKey reads a PEM encoded PKCS#8, PKCS#1 or EC private key
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("The signing key is not a PEM encoded private key")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		var ed25519Key ed25519.PrivateKey
+		ed25519Key, err = parseEd25519PrivateKey(block.Bytes)
+		if ed25519Key != nil {
+			key = ed25519Key
+		} else if err == nil {
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the signing key: %v", err)
+	}
+	switch signer := key.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey, *rsa.PrivateKey:
+		return signer.(crypto.Signer), nil
+	}
+	return nil, errors.Errorf("The signing key type %T is not supported. Use an Ed25519, ECDSA or RSA key", key)
+}
+
+// ParsePublicKey reads a PEM encoded PKIX public key
+func ParsePublicKey(keyPEM string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("The trusted key is not a PEM encoded public key")
+	}
+	var key crypto.PublicKey
+	ed25519Key, err := parseEd25519PublicKey(block.Bytes)
+	if ed25519Key != nil {
+		key = ed25519Key
+	} else if err == nil {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not parse the trusted key: %v", err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	}
+	return nil, errors.Errorf("The trusted key type %T is not supported. Use an Ed25519, ECDSA or RSA key", key)
+}
+
+// SignIndex returns the base64 encoded detached signature of an index.
+// Ed25519 keys sign the index itself, ECDSA and RSA keys sign its sha256 digest.
+func SignIndex(index []byte, keyPEM []byte) ([]byte, error) {
+	signer, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err = signer.Sign(rand.Reader, index, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(index)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not sign the index: %v", err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+}
+
+// VerifyIndexSignature checks the detached signature of an index against a trusted public key
+func VerifyIndexSignature(index []byte, signature []byte, publicKeyPEM string) error {
+	key, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return errors.Errorf("The index signature is not base64 encoded: %v", err)
+	}
+	digest := sha256.Sum256(index)
+	valid := false
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, index, sig)
+	case *ecdsa.PublicKey:
+		valid = verifyECDSA(key, digest[:], sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	if !valid {
+		return errors.New("The index signature does not match the trusted key of the repository")
+	}
+	return nil
+}
+
+// readTrustKey reads a public key file and returns its PEM, so that it can be pinned in repository.yaml
+func readTrustKey(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Errorf("Could not read the trusted key %s: %v", file, err)
+	}
+	_, err = ParsePublicKey(string(data))
+	if err != nil {
+		return "", errors.Errorf("%s: %v", file, err)
+	}
+	return string(bytes.TrimSpace(data)) + "\n", nil
+}
+
+// signIndexFile writes the detached signature of an index file next to it.
+// Without a key, the signature of a previous version of the index is removed, as it no longer matches.
+func signIndexFile(log *LoggingConfig, indexFile string, keyFile string) error {
+	signatureFile := indexFile + indexSignatureSuffix
+	if keyFile == "" {
+		if _, err := os.Stat(signatureFile); err == nil {
+			log.Warning.log("Removing the signature of the previous index, use --sign-key to sign the updated index: ", signatureFile)
+			return os.Remove(signatureFile)
+		}
+		return nil
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return errors.Errorf("Could not read the signing key %s: %v", keyFile, err)
+	}
+	index, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		return errors.Errorf("Could not read the index %s to sign it: %v", indexFile, err)
+	}
+	signature, err := SignIndex(index, keyPEM)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(signatureFile, signature, 0644)
+	if err != nil {
+		return errors.Errorf("Could not write the index signature %s: %v", signatureFile, err)
+	}
+	log.Info.log("Index signature written to ", signatureFile)
+	return nil
+}
+
+// getVerifiedIndex returns the index of a repository. If the repository has a pinned key,
+// its signature is fetched through the cache as well, and the index is rejected unless the signature matches.
+func getVerifiedIndex(cache *IndexCache, repo *RepositoryEntry, refresh bool) ([]byte, error) {
+	index, err := cache.Get(repo.URL, refresh)
+	if err != nil {
+		return nil, err
+	}
+	if repo.TrustKey == "" {
+		return index, nil
+	}
+	signature, err := cache.Get(indexSignatureURL(repo.URL), refresh)
+	if err == nil {
+		err = VerifyIndexSignature(index, signature, repo.TrustKey)
+	}
+	if err != nil {
+		// the cached index and signature can be from either side of an update of the repository
+		if !refresh && !cache.Offline && !strings.HasPrefix(repo.URL, "file://") {
+			cache.log.Debug.logf("Refreshing the index of %s after its signature could not be verified: %v", repo.URL, err)
+			return getVerifiedIndex(cache, repo, true)
+		}
+		return nil, errors.Errorf("The index of repository %s is rejected because it has a trusted key and its signature %s could not be verified: %v", repo.Name, indexSignatureURL(repo.URL), err)
+	}
+	cache.log.Debug.log("Verified the signature of ", repo.URL)
+	return index, nil
+}
@@ -0,0 +1,197 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+	"golang.org/x/crypto/ed25519"
+)
+
+// marshalEd25519Key returns the PKCS#8 and PKIX documents of an Ed25519 key, as openssl writes them,
+// because crypto/x509 only marshals Ed25519 keys from Go 1.13
+func marshalEd25519Key(t *testing.T, private ed25519.PrivateKey) ([]byte, []byte) {
+	t.Helper()
+	algorithm := pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 3, 101, 112}}
+	seed, err := asn1.Marshal(private.Seed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := asn1.Marshal(struct {
+		Version    int
+		Algorithm  pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}{0, algorithm, seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := private.Public().(ed25519.PublicKey)
+	publicDER, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{algorithm, asn1.BitString{Bytes: publicKey, BitLength: 8 * len(publicKey)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateDER, publicDER
+}
+
+// newSigningKey returns a PEM encoded private key and its PEM encoded public key
+func newSigningKey(t *testing.T, keyType string) ([]byte, string) {
+	t.Helper()
+	var private crypto.Signer
+	var err error
+	switch keyType {
+	case "ed25519":
+		var ed25519Key ed25519.PrivateKey
+		_, ed25519Key, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateDER, publicDER := marshalEd25519Key(t, ed25519Key)
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	case "ecdsa":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+}
+
+func TestSignIndex(t *testing.T) {
+	index := []byte("apiVersion: v2\nstacks: []\n")
+	for _, keyType := range []string{"ed25519", "ecdsa", "rsa"} {
+		keyType := keyType
+		t.Run(keyType, func(t *testing.T) {
+			privateKey, publicKey := newSigningKey(t, keyType)
+			_, otherPublicKey := newSigningKey(t, keyType)
+
+			signature, err := cmd.SignIndex(index, privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cmd.VerifyIndexSignature(index, signature, publicKey)
+			if err != nil {
+				t.Errorf("Expected the signature to be valid: %v", err)
+			}
+
+			tampered := []byte("apiVersion: v2\nstacks:\n- id: evil\n")
+			err = cmd.VerifyIndexSignature(tampered, signature, publicKey)
+			if err == nil {
+				t.Error("Expected the signature of a tampered index to be rejected")
+			}
+			err = cmd.VerifyIndexSignature(index, signature, otherPublicKey)
+			if err == nil {
+				t.Error("Expected the signature to be rejected by another key")
+			}
+		})
+	}
+}
+
+func TestSignIndexErrors(t *testing.T) {
+	index := []byte("apiVersion: v2\n")
+	privateKey, publicKey := newSigningKey(t, "ed25519")
+
+	_, err := cmd.SignIndex(index, []byte(publicKey))
+	if err == nil || !strings.Contains(err.Error(), "Could not parse the signing key") {
+		t.Errorf("Expected a public key to be rejected as a signing key, got %v", err)
+	}
+	_, err = cmd.SignIndex(index, []byte("not a key"))
+	if err == nil || !strings.Contains(err.Error(), "not a PEM encoded private key") {
+		t.Errorf("Expected an error for a signing key that is not PEM encoded, got %v", err)
+	}
+	err = cmd.VerifyIndexSignature(index, []byte("not base64!"), publicKey)
+	if err == nil || !strings.Contains(err.Error(), "not base64 encoded") {
+		t.Errorf("Expected an error for a signature that is not base64 encoded, got %v", err)
+	}
+	_, err = cmd.ParsePublicKey(string(privateKey))
+	if err == nil {
+		t.Error("Expected a private key to be rejected as a trusted key")
+	}
+}
+
+func TestRepoAddTrustKey(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	privateKey, publicKey := newSigningKey(t, "ed25519")
+	publicKeyFile := filepath.Join(sandbox.ProjectDir, "repo.pub")
+	err := ioutil.WriteFile(publicKeyFile, []byte(publicKey), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexFile := filepath.Join(sandbox.TestDataPath, "dev.local-index.yaml")
+	index, err := ioutil.ReadFile(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexURL := "file://" + filepath.ToSlash(indexFile)
+
+	// an unsigned index is rejected
+	output, err := cmdtest.RunAppsody(sandbox, "repo", "add", "signed-repo", indexURL, "--trust-key", publicKeyFile)
+	if err == nil || !strings.Contains(output, "could not be verified") {
+		t.Errorf("Expected the unsigned index to be rejected, got %v", err)
+	}
+
+	signature, err := cmd.SignIndex(index, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(indexFile+".sig", signature, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmdtest.RunAppsody(sandbox, "repo", "add", "signed-repo", indexURL, "--trust-key", publicKeyFile)
+	if err != nil {
+		t.Fatalf("Expected the signed index to be accepted: %v", err)
+	}
+
+	// the key is pinned, so the index is verified whenever it is used
+	err = ioutil.WriteFile(indexFile, append(index, []byte("# tampered\n")...), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "list", "signed-repo")
+	if err == nil || !strings.Contains(output, "does not match the trusted key") {
+		t.Errorf("Expected the tampered index to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// the source of the repositories that are read from the team repository file
+const repoSourceTeam = "team"
+
+// the environment variable with the location of the team repository file
+const teamRepoFileEnv = "APPSODY_TEAM_REPOSITORY_FILE"
+
+// the location of the team repository file if the environment variable is not set
+const systemTeamRepoFile = "/etc/appsody/repository.yaml"
+
+// getTeamRepoFileLocation returns the location of the team repository file, and whether it was set explicitly
+func getTeamRepoFileLocation() (string, bool) {
+	if location := os.Getenv(teamRepoFileEnv); location != "" {
+		return location, true
+	}
+	if runtime.GOOS == "windows" {
+		return "", false
+	}
+	return systemTeamRepoFile, false
+}
+
+// readTeamRepoFile reads the team repository file, returning nil if there is none
+func readTeamRepoFile() (*RepositoryFile, string, error) {
+	location, explicit := getTeamRepoFileLocation()
+	if location == "" {
+		return nil, "", nil
+	}
+	data, err := ioutil.ReadFile(location)
+	if os.IsNotExist(err) && !explicit {
+		return nil, location, nil
+	}
+	if err != nil {
+		return nil, location, errors.Errorf("Could not read the team repository file %s: %v", location, err)
+	}
+	var team RepositoryFile
+	err = yaml.Unmarshal(data, &team)
+	if err != nil {
+		return nil, location, errors.Errorf("Failed to parse the team repository file %s: %v", location, err)
+	}
+	return &team, location, nil
+}
+
+// MergeTeamRepos adds the repositories of the team repository file that are not in the repository file.
+// Repositories in the repository file override team repositories with the same name, and the default
+// repository of the team is only used if the repository file has no default repository.
+func (r *RepositoryFile) MergeTeamRepos(team *RepositoryFile) {
+	hasDefault := false
+	for _, repo := range r.Repositories {
+		hasDefault = hasDefault || repo.IsDefault
+	}
+	for _, teamRepo := range team.Repositories {
+		if teamRepo.Name == "" || r.Has(teamRepo.Name) {
+			continue
+		}
+		repo := *teamRepo
+		repo.Source = repoSourceTeam
+		if hasDefault {
+			repo.IsDefault = false
+		}
+		hasDefault = hasDefault || repo.IsDefault
+		r.Add(&repo)
+	}
+}
+
+// mergeTeamRepoFile adds the repositories of the team repository file.
+// A team repository file that can't be read is skipped, so that your own repositories can still be used.
+func (r *RepositoryFile) mergeTeamRepoFile(rootConfig *RootCommandConfig) {
+	team, location, err := readTeamRepoFile()
+	if err != nil {
+		rootConfig.Warning.log(err)
+		return
+	}
+	if team == nil {
+		return
+	}
+	rootConfig.Debug.log("Adding the repositories of the team repository file ", location)
+	r.MergeTeamRepos(team)
+}
+
+// hasTeamRepos returns true if any of the repositories are from the team repository file
+func (r *RepositoryFile) hasTeamRepos() bool {
+	for _, repo := range r.Repositories {
+		if repo.Source == repoSourceTeam {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptRepo copies a team repository into your repository file, so that it can be changed
+func adoptRepo(rootConfig *RootCommandConfig, repo *RepositoryEntry) {
+	if repo.Source == repoSourceTeam {
+		rootConfig.Info.logf("Adding the team repository %s to your repository file", repo.Name)
+		repo.Source = ""
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newRepoUpdateCmd(config *RootCommandConfig) *cobra.Command {
+	var updateCmd = &cobra.Command{
+		Use:   "update [repository]",
+		Short: "Update the cached indices of your Appsody repositories.",
+		Long: `Download the indices of your configured Appsody repositories into the index cache, even if the cached indices have not expired.
+
+Appsody caches repository indices in the "repository/cache" directory of your Appsody home directory, and checks them for changes when they are older than the "indexcachettl" setting in the Appsody config file (1h by default). Run this command before you work with the --offline flag.`,
+		Example: `  appsody repo update
+  Updates the cached indices of all your repositories.
+
+  appsody repo update my-repo
+  Updates the cached index of your "my-repo" repository.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.Errorf("One argument expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			if isOffline(config) {
+				return errors.New("Repository indices cannot be updated offline")
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(config)
+			if repoErr != nil {
+				return repoErr
+			}
+			repos := repoFile.Repositories
+			if len(args) == 1 {
+				repo := repoFile.GetRepo(args[0])
+				if repo == nil {
+					return errors.New("cannot locate repository named " + args[0])
+				}
+				repos = []*RepositoryEntry{repo}
+			} else {
+				// the repositories that were removed no longer need their cached indices
+				var urls []string
+				for _, repo := range repoFile.Repositories {
+					urls = append(urls, repo.URL)
+					if repo.TrustKey != "" {
+						urls = append(urls, indexSignatureURL(repo.URL))
+					}
+				}
+				if err := getIndexCache(config).Prune(urls); err != nil {
+					config.Warning.log("Could not remove the cached indices of removed repositories: ", err)
+				}
+			}
+
+			brokenRepos := make([]indexError, 0)
+			for _, repo := range repos {
+				index, err := getIndex(config, repo, true)
+				if err != nil {
+					brokenRepos = append(brokenRepos, indexError{repo.Name, err})
+					continue
+				}
+				config.Info.logf("Updated the index of repository %s with %d stacks", repo.Name, len(index.Stacks))
+			}
+			if len(brokenRepos) > 0 {
+				return errors.Errorf("The following indices could not be updated:\n%v", &indexErrors{brokenRepos})
+			}
+			return nil
+		},
+	}
+	return updateCmd
+}
@@ -0,0 +1,181 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// checksumWriter computes the sha256 checksum and size of what is written to it
+type checksumWriter struct {
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumWriter() *checksumWriter {
+	return &checksumWriter{hash: sha256.New()}
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return w.hash.Write(p)
+}
+
+func (w *checksumWriter) sum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
+
+// ArchiveChecksum returns the sha256 checksum and the size in bytes of a file
+func ArchiveChecksum(file string) (string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, errors.Errorf("Could not open %s to compute its checksum: %v", file, err)
+	}
+	defer f.Close()
+	writer := newChecksumWriter()
+	_, err = io.Copy(writer, f)
+	if err != nil {
+		return "", 0, errors.Errorf("Could not read %s to compute its checksum: %v", file, err)
+	}
+	return writer.sum(), writer.size, nil
+}
+
+// VerifyChecksum checks the checksum and size of an archive against the values in the repository index.
+// Values that the index does not have are not checked.
+func VerifyChecksum(url string, checksum string, size int64, expectedChecksum string, expectedSize int64) error {
+	if expectedSize != 0 && size != expectedSize {
+		return errors.Errorf("The archive %s does not match the repository index: its size is %d bytes, but the index has %d bytes", url, size, expectedSize)
+	}
+	if expectedChecksum != "" && checksum != expectedChecksum {
+		return errors.Errorf("The archive %s does not match the repository index: its sha256 checksum is %s, but the index has %s", url, checksum, expectedChecksum)
+	}
+	return nil
+}
+
+// verifyArchive checks a downloaded archive before it is extracted
+func verifyArchive(config *RootCommandConfig, file string, url string, expectedChecksum string, expectedSize int64, skipVerify bool) error {
+	if config.Dryrun {
+		config.Info.log("Dry Run - Skipping verification of ", url)
+		return nil
+	}
+	if skipVerify {
+		config.Warning.log("Skipping the verification of ", url)
+		return nil
+	}
+	if expectedChecksum == "" && expectedSize == 0 {
+		config.Debug.log("The repository index does not have a checksum for ", url)
+		return nil
+	}
+	checksum, size, err := ArchiveChecksum(file)
+	if err != nil {
+		return err
+	}
+	err = VerifyChecksum(url, checksum, size, expectedChecksum, expectedSize)
+	if err != nil {
+		return errors.Errorf("%v. If you trust the archive, use --insecure-skip-verify to skip this check", err)
+	}
+	config.Debug.logf("Verified %s with sha256 checksum %s", url, checksum)
+	return nil
+}
+
+// verifyRemoteArchive downloads an archive and checks it against the repository index
+func verifyRemoteArchive(config *RootCommandConfig, url string, expectedChecksum string, expectedSize int64) string {
+	if expectedChecksum == "" && expectedSize == 0 {
+		return "no checksum"
+	}
+	writer := newChecksumWriter()
+	err := downloadFile(config.LoggingConfig, getCredentialStore(config), url, writer)
+	if err != nil {
+		return err.Error()
+	}
+	err = VerifyChecksum(url, writer.sum(), writer.size, expectedChecksum, expectedSize)
+	if err != nil {
+		return err.Error()
+	}
+	return "verified"
+}
+
+func newRepoVerifyCmd(config *RootCommandConfig) *cobra.Command {
+	var verifyCmd = &cobra.Command{
+		Use:   "verify <repository>",
+		Short: "Verify the archives of an Appsody repository.",
+		Long: `Download every source and template archive in the index of an Appsody repository, and check them against the sha256 checksums and sizes in the index.
+
+Archives that do not have a checksum in the index are listed, but are not treated as failures.`,
+		Example: `  appsody repo verify incubator
+  Verifies the archives of the stacks in the "incubator" repository.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("You must specify repository name")
+			}
+			if len(args) > 1 {
+				return errors.Errorf("One argument expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			var repoFile RepositoryFile
+			_, repoErr := repoFile.getRepos(config)
+			if repoErr != nil {
+				return repoErr
+			}
+			repo := repoFile.GetRepo(args[0])
+			if repo == nil {
+				return errors.New("cannot locate repository named " + args[0])
+			}
+			index, err := getIndex(config, repo, false)
+			if err != nil {
+				return err
+			}
+			if config.Dryrun {
+				config.Info.log("Dry Run - Skipping verification of the archives of repository ", repo.Name)
+				return nil
+			}
+
+			table := uitable.New()
+			table.MaxColWidth = 100
+			table.Wrap = true
+			table.AddRow("STACK", "ARCHIVE", "RESULT")
+			failures := 0
+			check := func(stackID string, archive string, url string, checksum string, size int64) {
+				config.Debug.logf("Verifying %s of stack %s from %s", archive, stackID, url)
+				result := verifyRemoteArchive(config, url, checksum, size)
+				if result != "verified" && result != "no checksum" {
+					failures++
+				}
+				table.AddRow(stackID, archive, result)
+			}
+			for _, stack := range index.Stacks {
+				if stack.SourceURL != "" {
+					check(stack.ID, "source", stack.SourceURL, stack.SourceSHA256, stack.SourceSize)
+				}
+				for _, template := range stack.Templates {
+					check(stack.ID, "template "+template.ID, template.URL, template.SHA256, template.Size)
+				}
+			}
+			config.Info.log("\n", table)
+			if failures > 0 {
+				return errors.Errorf("%d archives in repository %s could not be verified", failures, repo.Name)
+			}
+			return nil
+		},
+	}
+	return verifyCmd
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+// the sha256 checksum of "hello\n"
+const helloChecksum = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func TestArchiveChecksum(t *testing.T) {
+	file, err := ioutil.TempFile("", "appsody-checksum-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("hello\n")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checksum, size, err := cmd.ArchiveChecksum(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != helloChecksum || size != 6 {
+		t.Errorf("Expected checksum %s and size 6 but got %s and %d", helloChecksum, checksum, size)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	var verifyTests = []struct {
+		testName         string
+		expectedChecksum string
+		expectedSize     int64
+		expectedError    string
+	}{
+		{"Match", helloChecksum, 6, ""},
+		{"Checksum only", helloChecksum, 0, ""},
+		{"Size only", "", 6, ""},
+		{"No checksum", "", 0, ""},
+		{"Wrong checksum", strings.Repeat("0", 64), 6, "its sha256 checksum is " + helloChecksum},
+		{"Wrong size", helloChecksum, 7, "its size is 6 bytes, but the index has 7 bytes"},
+	}
+	for _, testData := range verifyTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			err := cmd.VerifyChecksum("https://example.com/hello.tar.gz", helloChecksum, 6, tt.expectedChecksum, tt.expectedSize)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected an error containing %q but got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -64,6 +64,10 @@ type RootCommandConfig struct {
 	UnsupportedRepos  []string
 	StackRegistry     string
 	StackRegistryInit string
+	KubeConfigFile    string
+	KubeContext       string
+	KubeClient        *KubeClient
+	Offline           bool
 
 	// package scoped, these are mostly for caching
 	setupConfigRun bool
@@ -96,6 +100,7 @@ Complete documentation is available at https://appsody.dev`,
 	rootCmd.PersistentFlags().StringVar(&rootConfig.CfgFile, "config", "", "The absolute path to the Appsody config file. Use this option when you want to specify your own, customized config file (default '$HOME/.appsody/.appsody.yaml')")
 	rootCmd.PersistentFlags().BoolVarP(&rootConfig.Verbose, "verbose", "v", false, "Prints more detailed log output, to the console and to a file in $HOME/.appsody/logs")
 	rootCmd.PersistentFlags().BoolVar(&rootConfig.Dryrun, "dryrun", false, "Shows the commands that are called by this command, without running them.")
+	rootCmd.PersistentFlags().BoolVar(&rootConfig.Offline, "offline", false, "Uses the cached repository indices instead of downloading them. You can also set offline: true in the Appsody config file.")
 
 	// parse the root flags and init logging before adding all the other commands in case those log messages
 	rootCmd.SetArgs(args)
@@ -122,9 +127,10 @@ Complete documentation is available at https://appsody.dev`,
 		newDocsCmd(rootConfig.LoggingConfig, rootCmd),
 		newListCmd(rootConfig),
 		newOperatorCmd(rootConfig),
-		newPsCmd(rootConfig.LoggingConfig),
+		newPsCmd(rootConfig),
 		newRepoCmd(rootConfig),
 		newRunCmd(rootConfig),
+		newSearchCmd(rootConfig),
 		newStackCmd(rootConfig),
 		newStopCmd(rootConfig),
 		newTestCmd(rootConfig),
@@ -194,6 +200,8 @@ func InitConfig(config *RootCommandConfig) error {
 	cliConfig.SetDefault("operator", operatorHome)
 	cliConfig.SetDefault("tektonserver", "")
 	cliConfig.SetDefault("lastversioncheck", "none")
+	cliConfig.SetDefault("offline", false)
+	cliConfig.SetDefault("indexcachettl", defaultIndexCacheTTL.String())
 	if config.CfgFile != "" {
 		// Use config file from the flag.
 		cliConfig.SetConfigFile(config.CfgFile)
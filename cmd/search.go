@@ -0,0 +1,247 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type searchCommandConfig struct {
+	*RootCommandConfig
+	language          string
+	license           string
+	repo              string
+	includeDeprecated bool
+	output            string
+}
+
+// StackSearch is a search for stacks across the repositories
+type StackSearch struct {
+	Terms             []string
+	Language          string
+	License           string
+	Repo              string
+	IncludeDeprecated bool
+}
+
+// SearchResult is a stack that matches a search, with how well it matches
+type SearchResult struct {
+	Repo        string `yaml:"repo" json:"repo"`
+	ID          string `yaml:"id" json:"id"`
+	Version     string `yaml:"version" json:"version"`
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Language    string `yaml:"language,omitempty" json:"language,omitempty"`
+	License     string `yaml:"license,omitempty" json:"license,omitempty"`
+	Deprecated  string `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	Score       int    `yaml:"score" json:"score"`
+}
+
+// the score of a search term, depending on where it is found in a stack
+const (
+	scoreExactID     = 100
+	scoreIDPrefix    = 50
+	scoreID          = 30
+	scoreName        = 20
+	scoreDescription = 10
+)
+
+// scoreTerm returns how well a search term matches a stack, or 0 if it does not match
+func scoreTerm(stack *IndexYamlStack, term string) int {
+	id := strings.ToLower(stack.ID)
+	score := 0
+	switch {
+	case id == term:
+		score = scoreExactID
+	case strings.HasPrefix(id, term):
+		score = scoreIDPrefix
+	case strings.Contains(id, term):
+		score = scoreID
+	}
+	if strings.Contains(strings.ToLower(stack.Name), term) {
+		score += scoreName
+	}
+	if strings.Contains(strings.ToLower(stack.Description), term) {
+		score += scoreDescription
+	}
+	return score
+}
+
+// Match returns the score of a stack for the search, or false if it does not match.
+// Every term must match the ID, name or description of the stack, and the filters must match exactly.
+func (s *StackSearch) Match(repoName string, stack *IndexYamlStack) (int, bool) {
+	if s.Repo != "" && s.Repo != repoName {
+		return 0, false
+	}
+	if stack.Deprecated != "" && !s.IncludeDeprecated {
+		return 0, false
+	}
+	if s.Language != "" && !strings.EqualFold(s.Language, stack.Language) {
+		return 0, false
+	}
+	if s.License != "" && !strings.EqualFold(s.License, stack.License) {
+		return 0, false
+	}
+	total := 0
+	for _, term := range s.Terms {
+		score := scoreTerm(stack, strings.ToLower(term))
+		if score == 0 {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// SearchStacks returns the stacks in the indices that match the search, best matches first
+func SearchStacks(indices RepoIndices, search *StackSearch) []SearchResult {
+	results := make([]SearchResult, 0)
+	for repoName, index := range indices {
+		stacks := index.latestStackVersions()
+		for i := range stacks {
+			stack := &stacks[i]
+			score, match := search.Match(repoName, stack)
+			if !match {
+				continue
+			}
+			results = append(results, SearchResult{
+				Repo:        repoName,
+				ID:          stack.ID,
+				Version:     stack.Version,
+				Name:        stack.Name,
+				Description: stack.Description,
+				Language:    stack.Language,
+				License:     stack.License,
+				Deprecated:  stack.Deprecated,
+				Score:       score,
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Repo != results[j].Repo {
+			return results[i].Repo < results[j].Repo
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+func formatSearchResults(results []SearchResult) string {
+	table := uitable.New()
+	table.MaxColWidth = 60
+	table.Wrap = true
+	table.AddRow("REPO", "ID", "VERSION", "LANGUAGE", "DESCRIPTION")
+	for _, result := range results {
+		id := result.ID
+		if result.Deprecated != "" {
+			id = id + " [Deprecated]"
+		}
+		table.AddRow(result.Repo, id, result.Version, result.Language, result.Description)
+	}
+	return table.String()
+}
+
+// formatOutput marshals a value for the -o yaml|json flag
+func formatOutput(output string, value interface{}) (string, error) {
+	var bytes []byte
+	var err error
+	switch output {
+	case "yaml":
+		bytes, err = yaml.Marshal(value)
+	case "json":
+		bytes, err = json.Marshal(value)
+	default:
+		return "", errors.Errorf("The output format %s is not supported. Use yaml or json", output)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+func newSearchCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &searchCommandConfig{RootCommandConfig: rootConfig}
+	var searchCmd = &cobra.Command{
+		Use:   "search [term...]",
+		Short: "Search for Appsody stacks.",
+		Long: `Search the stacks in your repositories by their ID, name and description.
+
+Stacks whose ID matches a term are listed before stacks whose name or description matches it. When you specify more than one term, a stack must match every term. Deprecated stacks are only listed with --include-deprecated.`,
+		Example: `  appsody search node
+  Lists the stacks that mention "node", with the stacks whose ID starts with "node" first.
+
+  appsody search --language java --repo incubator
+  Lists the Java stacks of your "incubator" repository.
+
+  appsody search spring boot -o json
+  Lists the stacks that mention both "spring" and "boot", in JSON format.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			search := &StackSearch{
+				Terms:             args,
+				Language:          config.language,
+				License:           config.license,
+				Repo:              config.repo,
+				IncludeDeprecated: config.includeDeprecated,
+			}
+			if len(args) == 0 && search.Language == "" && search.License == "" && search.Repo == "" {
+				return errors.New("You must specify a search term, --language, --license or --repo")
+			}
+			var repos RepositoryFile
+			if _, err := repos.getRepos(rootConfig); err != nil {
+				return err
+			}
+			if search.Repo != "" && !repos.Has(search.Repo) {
+				return errors.New("cannot locate repository named " + search.Repo)
+			}
+			indices, err := repos.GetIndices(rootConfig)
+			if err != nil {
+				rootConfig.Error.logf("The following indices could not be read, skipping:\n%v", err)
+			}
+
+			results := SearchStacks(indices, search)
+			if config.output == "" {
+				if len(results) == 0 {
+					rootConfig.Info.log("No stacks match your search")
+					return nil
+				}
+				rootConfig.Info.log("\n", formatSearchResults(results))
+				return nil
+			}
+			result, err := formatOutput(config.output, results)
+			if err != nil {
+				return err
+			}
+			rootConfig.Info.log("\n", result)
+			return nil
+		},
+	}
+
+	searchCmd.PersistentFlags().StringVar(&config.language, "language", "", "Only list the stacks for this language")
+	searchCmd.PersistentFlags().StringVar(&config.license, "license", "", "Only list the stacks with this license")
+	searchCmd.PersistentFlags().StringVar(&config.repo, "repo", "", "Only search this repository")
+	searchCmd.PersistentFlags().BoolVar(&config.includeDeprecated, "include-deprecated", false, "Also list deprecated stacks")
+	searchCmd.PersistentFlags().StringVarP(&config.output, "output", "o", "", "Output the results in yaml or json format")
+	return searchCmd
+}
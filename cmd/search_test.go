@@ -0,0 +1,100 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+)
+
+func newSearchIndices() cmd.RepoIndices {
+	return cmd.RepoIndices{
+		"incubator": &cmd.IndexYaml{Stacks: []cmd.IndexYamlStack{
+			{ID: "nodejs", Name: "Node.js", Description: "Runtime for Node.js applications", Language: "nodejs", License: "Apache-2.0"},
+			{ID: "nodejs-express", Name: "Node.js Express", Description: "Express web framework for Node.js", Language: "nodejs", License: "Apache-2.0"},
+			{ID: "java-spring-boot2", Name: "Spring Boot®", Description: "Spring Boot using OpenJ9 and Maven", Language: "java", License: "Apache-2.0"},
+			{ID: "swift", Name: "Swift", Description: "Runtime for Swift applications", Language: "swift", License: "Apache-2.0", Deprecated: "Use kitura"},
+		}},
+		"experimental": &cmd.IndexYaml{Stacks: []cmd.IndexYamlStack{
+			{ID: "rust", Name: "Rust", Description: "Rust runtime, with a Node.js tools image", Language: "rust", License: "MIT"},
+		}},
+	}
+}
+
+func TestSearchStacks(t *testing.T) {
+	var searchTests = []struct {
+		testName string
+		search   cmd.StackSearch
+		expected []string
+	}{
+		{"ID before name and description", cmd.StackSearch{Terms: []string{"node"}}, []string{"incubator/nodejs", "incubator/nodejs-express", "experimental/rust"}},
+		{"Exact ID first", cmd.StackSearch{Terms: []string{"nodejs"}}, []string{"incubator/nodejs", "incubator/nodejs-express"}},
+		{"Every term must match", cmd.StackSearch{Terms: []string{"node", "express"}}, []string{"incubator/nodejs-express"}},
+		{"Case insensitive", cmd.StackSearch{Terms: []string{"SPRING"}}, []string{"incubator/java-spring-boot2"}},
+		{"Language filter", cmd.StackSearch{Language: "Java"}, []string{"incubator/java-spring-boot2"}},
+		{"License filter", cmd.StackSearch{License: "mit"}, []string{"experimental/rust"}},
+		{"Repo filter", cmd.StackSearch{Terms: []string{"node"}, Repo: "experimental"}, []string{"experimental/rust"}},
+		{"Deprecated stacks are hidden", cmd.StackSearch{Terms: []string{"swift"}}, []string{}},
+		{"Include deprecated", cmd.StackSearch{Terms: []string{"swift"}, IncludeDeprecated: true}, []string{"incubator/swift"}},
+		{"No match", cmd.StackSearch{Terms: []string{"cobol"}}, []string{}},
+	}
+	for _, testData := range searchTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			results := cmd.SearchStacks(newSearchIndices(), &tt.search)
+			actual := make([]string, 0, len(results))
+			for _, result := range results {
+				actual = append(actual, result.Repo+"/"+result.ID)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSearchJSON(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+
+	_, err := cmdtest.AddLocalRepo(sandbox, "LocalTestRepo", filepath.Join(sandbox.TestDataPath, "dev.local-index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := cmdtest.RunAppsody(sandbox, "search", "microprofile", "--repo", "LocalTestRepo", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []cmd.SearchResult
+	err = json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &results)
+	if err != nil {
+		t.Fatalf("Could not parse the search results: %v\n%s", err, output)
+	}
+	if len(results) != 1 || results[0].ID != "java-microprofile" || results[0].Language != "java" {
+		t.Errorf("Expected the java-microprofile stack but got %+v", results)
+	}
+
+	output, err = cmdtest.RunAppsody(sandbox, "search")
+	if err == nil || !strings.Contains(output, "You must specify a search term") {
+		t.Errorf("Expected an error for a search without terms or filters, got %v", err)
+	}
+}
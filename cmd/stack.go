@@ -29,6 +29,8 @@ func newStackCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	stackCmd.AddCommand(newStackValidateCmd(rootConfig))
 	stackCmd.AddCommand(newStackPackageCmd(rootConfig))
 	stackCmd.AddCommand(newStackAddToRepoCmd(rootConfig))
+	stackCmd.AddCommand(newStackPublishCmd(rootConfig))
 	stackCmd.AddCommand(newStackRemoveFromRepoCmd(rootConfig))
+	stackCmd.AddCommand(newStackInfoCmd(rootConfig))
 	return stackCmd
 }
@@ -30,6 +30,7 @@ func newStackAddToRepoCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	var releaseURL string
 	var useLocalCache bool
 	var repoName string
+	var signKey string
 
 	log := rootConfig.LoggingConfig
 
@@ -42,6 +43,8 @@ Adds stack information to a new or existing Appsody repository, specified by the
 
 The updated repository index file is created in  ~/.appsody/stacks/dev.local directory.
 
+If you specify a PEM encoded private key with --sign-key, a detached signature of the index is written next to it with a .sig suffix. Publish the signature with the index, so that users who add the repository with 'appsody repo add --trust-key' can verify it.
+
 Run this command from the root directory of your Appsody project.`,
 		Example: `  appsody stack add-to-repo incubator
   Creates a new repository index file for the incubator repository, setting the template URLs to begin with a default URL of https://github.com/appsody/stacks/releases/latest/download/
@@ -50,7 +53,10 @@ Run this command from the root directory of your Appsody project.`,
   Create a new index file for the myrepository repository, setting the template URLs to begin with https://github.com/mygitorg/myrepository/releases/latest/download/
 
   appsody stack add-to-repo myrepository --release-url https://github.com/appsody/stacks/releases/latest/download/ --use-local-cache
-  Use an existing index for the myrepository repository or create it if it doesnt exist, setting the template URLs to begin with https://github.com/mygitorg/myrepository/releases/latest/download/`,
+  Use an existing index for the myrepository repository or create it if it doesnt exist, setting the template URLs to begin with https://github.com/mygitorg/myrepository/releases/latest/download/
+
+  appsody stack add-to-repo myrepository --sign-key myrepository-key.pem
+  Updates the index file for the myrepository repository, and signs it with the myrepository-key.pem private key`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			log.Info.Log("******************************************")
@@ -69,6 +75,7 @@ Run this command from the root directory of your Appsody project.`,
 			log.Debug.Log("repoName is: ", repoName)
 			log.Debug.Log("releaseURL is: ", releaseURL)
 			log.Debug.Log("useLocalCache is: ", useLocalCache)
+			log.Debug.Log("signKey is: ", signKey)
 
 			var repoFile RepositoryFile
 			createNewLocalIndex := false
@@ -127,17 +134,15 @@ Run this command from the root directory of your Appsody project.`,
 				return errors.Errorf("Unmarshal: Error unmarshalling index.yaml")
 			}
 
-			stackFound := false
-			var stackToAddImage string
+			var packagedStack *IndexYamlStack
 			for i, stack := range devLocalIndexYaml.Stacks {
 				if stackID == stack.ID {
 					log.Debug.Log("Found stack attempting to add to repo in dev.local-index.yaml")
-					stackFound = true
-					stackToAddImage = devLocalIndexYaml.Stacks[i].Image
+					packagedStack = &devLocalIndexYaml.Stacks[i]
 					break
 				}
 			}
-			if !stackFound {
+			if packagedStack == nil {
 				return errors.Errorf("Couldn't find stack in dev.local-index.yaml. Have you packaged this stack?")
 			}
 
@@ -169,7 +174,7 @@ Run this command from the root directory of your Appsody project.`,
 						log.Debug.Log(localIndexFile, " doesnt exist in the appsody directory or use-local-cache is false")
 						log.Info.Log("Downloading the remote index file from: ", url)
 						log.Info.log("Creating repository index file: ", localIndexFile)
-						err := downloadFileToDisk(log, url, localIndexFile, false)
+						err := downloadFileToDisk(log, getCredentialStore(rootConfig), url, localIndexFile, false)
 						if err != nil {
 							return err
 						}
@@ -222,14 +227,6 @@ Run this command from the root directory of your Appsody project.`,
 				}
 			}
 
-			// At this point we should have the indexFile loaded that want to use for updating / adding stack info
-			// find the index of the stack
-			indexYaml, stackExists := findStackAndRemove(log, stackID, indexYaml)
-
-			if stackExists {
-				log.Debug.Logf("Stack: %v already exists in repo", stackID)
-			}
-
 			// get the necessary data from the current stack.yaml
 			stackYaml, err := getStackData(stackPath)
 			if err != nil {
@@ -237,7 +234,7 @@ Run this command from the root directory of your Appsody project.`,
 			}
 
 			// build up stack struct for the new stack
-			newStackStruct := initialiseStackData(stackID, stackToAddImage, stackYaml)
+			newStackStruct := initialiseStackData(stackID, packagedStack.Image, stackYaml)
 
 			versionArchiveTar := stackID + ".v" + stackYaml.Version + ".source.tar.gz"
 			log.Debug.Log("versionedArchiveTar is: ", versionArchiveTar)
@@ -246,6 +243,9 @@ Run this command from the root directory of your Appsody project.`,
 			log.Debug.Log("full release URL is: ", sourceURL)
 
 			newStackStruct.SourceURL = sourceURL
+			// the released archives are the ones created by stack package, so they have the same checksums
+			newStackStruct.SourceSHA256 = packagedStack.SourceSHA256
+			newStackStruct.SourceSize = packagedStack.SourceSize
 
 			// find and open the template path so we can loop through the templates
 			templatePath := filepath.Join(stackPath, "templates")
@@ -278,14 +278,18 @@ Run this command from the root directory of your Appsody project.`,
 				newTemplateStruct := IndexYamlStackTemplate{}
 				newTemplateStruct.ID = templates[i]
 				newTemplateStruct.URL = templateURL
+				if packaged := findTemplate(*packagedStack, templates[i]); packaged != nil {
+					newTemplateStruct.SHA256 = packaged.SHA256
+					newTemplateStruct.Size = packaged.Size
+				}
 
 				newStackStruct.Templates = append(newStackStruct.Templates, newTemplateStruct)
 			}
 
 			t.Close()
 
-			// add the new stack struct to the existing struct
-			indexYaml.Stacks = append(indexYaml.Stacks, newStackStruct)
+			// add the new version of the stack to the existing versions
+			indexYaml = addStackVersion(log, indexYaml, newStackStruct)
 
 			// Last thing to do is write the data to the file
 			data, err := yaml.Marshal(indexYaml)
@@ -303,6 +307,11 @@ Run this command from the root directory of your Appsody project.`,
 				return errors.Errorf("Could not generate json file from yaml index: %v", err)
 			}
 
+			err = signIndexFile(log, localIndexFile, signKey)
+			if err != nil {
+				return err
+			}
+
 			log.Info.Log("Repository index file updated successfully")
 
 			return nil
@@ -311,6 +320,7 @@ Run this command from the root directory of your Appsody project.`,
 
 	stackAddToRepoCmd.PersistentFlags().StringVar(&releaseURL, "release-url", "https://github.com/appsody/stacks/releases/download/", "URL to use within the repository to access the stack assets")
 	stackAddToRepoCmd.PersistentFlags().BoolVar(&useLocalCache, "use-local-cache", false, "Whether to use a local file if exists or create a new file")
+	stackAddToRepoCmd.PersistentFlags().StringVar(&signKey, "sign-key", "", "A PEM encoded private key file used to write a detached signature of the index")
 
 	return stackAddToRepoCmd
 }
@@ -29,7 +29,8 @@ import (
 
 type stackCreateCommandConfig struct {
 	*RootCommandConfig
-	copy string
+	copy               string
+	insecureSkipVerify bool
 }
 
 func newStackCreateCmd(rootConfig *RootCommandConfig) *cobra.Command {
@@ -115,7 +116,7 @@ The stack name must start with a lowercase letter, and can contain only lowercas
 			}
 
 			// download source.tar.gz of selected stack source
-			err = downloadFileToDisk(rootConfig.LoggingConfig, stackEntryURL, extractDirFile, config.Dryrun)
+			err = downloadFileToDisk(rootConfig.LoggingConfig, getCredentialStore(rootConfig), stackEntryURL, extractDirFile, config.Dryrun)
 			if err != nil {
 				return err
 			}
@@ -123,6 +124,11 @@ The stack name must start with a lowercase letter, and can contain only lowercas
 			//deleting the stacks targz
 			defer os.Remove(extractDirFile)
 
+			err = verifyArchive(rootConfig, extractDirFile, stackEntryURL, stackEntry.SourceSHA256, stackEntry.SourceSize, config.insecureSkipVerify)
+			if err != nil {
+				return err
+			}
+
 			extractFile, err := os.Open(extractDirFile)
 			if err != nil {
 				return err
@@ -138,21 +144,19 @@ The stack name must start with a lowercase letter, and can contain only lowercas
 		},
 	}
 	stackCmd.PersistentFlags().StringVar(&config.copy, "copy", "incubator/starter", "Copy the specified stack. The format is <repository>/<stack>")
+	stackCmd.PersistentFlags().BoolVar(&config.insecureSkipVerify, "insecure-skip-verify", false, "Create the stack even if its source archive does not match the checksum in the repository index.")
 	return stackCmd
 }
 
+// getStack returns the newest version of the stack in the index, or nil if it is not in the index
 func getStack(stackList *IndexYaml, name string) *IndexYamlStack {
-	for _, stackFound := range stackList.Stacks {
-		if stackFound.ID == name {
-			return &stackFound
-		}
-	}
-	return nil
+	stack, _ := FindStackVersion(stackList, name, "")
+	return stack
 }
 
 // To be removed in next release
 func oldCreateMethod(log *LoggingConfig, rootConfig *RootCommandConfig, config *stackCreateCommandConfig, stack string, currentTime string) error {
-	err := downloadFileToDisk(rootConfig.LoggingConfig, "https://github.com/appsody/stacks/archive/master.zip", filepath.Join(getHome(rootConfig), "extract", "repo.zip"), config.Dryrun)
+	err := downloadFileToDisk(rootConfig.LoggingConfig, nil, "https://github.com/appsody/stacks/archive/master.zip", filepath.Join(getHome(rootConfig), "extract", "repo.zip"), config.Dryrun)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,314 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type stackInfoCommandConfig struct {
+	*RootCommandConfig
+	output string
+	pull   bool
+}
+
+// StackInfo is the metadata of a stack in a repository index, with what is known about it locally
+type StackInfo struct {
+	Repo            string              `yaml:"repo" json:"repo"`
+	ID              string              `yaml:"id" json:"id"`
+	Name            string              `yaml:"name" json:"name"`
+	Version         string              `yaml:"version" json:"version"`
+	Description     string              `yaml:"description" json:"description"`
+	Language        string              `yaml:"language,omitempty" json:"language,omitempty"`
+	License         string              `yaml:"license,omitempty" json:"license,omitempty"`
+	Maintainers     []StackMaintainer   `yaml:"maintainers,omitempty" json:"maintainers,omitempty"`
+	Image           string              `yaml:"image" json:"image"`
+	SourceURL       string              `yaml:"src,omitempty" json:"src,omitempty"`
+	DefaultTemplate string              `yaml:"defaultTemplate,omitempty" json:"defaultTemplate,omitempty"`
+	Templates       []StackInfoTemplate `yaml:"templates" json:"templates"`
+	Deprecated      string              `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	Requirements    []RequirementCheck  `yaml:"requirements,omitempty" json:"requirements,omitempty"`
+	ImageConfig     *StackImageConfig   `yaml:"imageConfig,omitempty" json:"imageConfig,omitempty"`
+}
+
+// StackMaintainer is a maintainer of a stack, as listed in the repository index
+type StackMaintainer struct {
+	Name     string `yaml:"name" json:"name"`
+	Email    string `yaml:"email,omitempty" json:"email,omitempty"`
+	GithubID string `yaml:"github-id,omitempty" json:"githubId,omitempty"`
+}
+
+// StackInfoTemplate is a template of a stack, with the URL of its archive
+type StackInfoTemplate struct {
+	ID  string `yaml:"id" json:"id"`
+	URL string `yaml:"url" json:"url"`
+}
+
+// StackImageConfig is read from the stack image, if it is available locally
+type StackImageConfig struct {
+	Ports  []string          `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Env    map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// NewStackInfo returns the metadata of a stack from its repository index
+func NewStackInfo(repoName string, stack *IndexYamlStack) *StackInfo {
+	info := &StackInfo{
+		Repo:            repoName,
+		ID:              stack.ID,
+		Name:            stack.Name,
+		Version:         stack.Version,
+		Description:     stack.Description,
+		Language:        stack.Language,
+		License:         stack.License,
+		Image:           stack.Image,
+		SourceURL:       stack.SourceURL,
+		DefaultTemplate: stack.DefaultTemplate,
+		Templates:       make([]StackInfoTemplate, 0, len(stack.Templates)),
+		Deprecated:      stack.Deprecated,
+	}
+	for _, maintainer := range stack.Maintainers {
+		info.Maintainers = append(info.Maintainers, StackMaintainer(maintainer))
+	}
+	for _, template := range stack.Templates {
+		info.Templates = append(info.Templates, StackInfoTemplate{ID: template.ID, URL: template.URL})
+	}
+	return info
+}
+
+// ParseImageConfig reads the exposed ports, environment variables and labels from the output of docker or buildah inspect
+func ParseImageConfig(inspectOut string, buildah bool) (*StackImageConfig, error) {
+	var containerConfig map[string]interface{}
+	if buildah {
+		var buildahData map[string]interface{}
+		err := json.Unmarshal([]byte(inspectOut), &buildahData)
+		if err != nil {
+			return nil, errors.Errorf("Error unmarshaling data from inspect command: %v", err)
+		}
+		containerConfig, _ = buildahData["config"].(map[string]interface{})
+	} else {
+		var data []map[string]interface{}
+		err := json.Unmarshal([]byte(inspectOut), &data)
+		if err != nil {
+			return nil, errors.Errorf("Error unmarshaling data from inspect command: %v", err)
+		}
+		if len(data) > 0 {
+			containerConfig, _ = data[0]["Config"].(map[string]interface{})
+		}
+	}
+	if containerConfig == nil {
+		return nil, errors.New("The inspect command did not return the configuration of the image")
+	}
+
+	imageConfig := &StackImageConfig{Env: make(map[string]string), Labels: make(map[string]string)}
+	if exposedPorts, ok := containerConfig["ExposedPorts"].(map[string]interface{}); ok {
+		for port := range exposedPorts {
+			imageConfig.Ports = append(imageConfig.Ports, strings.Split(port, "/tcp")[0])
+		}
+		sort.Strings(imageConfig.Ports)
+	}
+	if envVars, ok := containerConfig["Env"].([]interface{}); ok {
+		for _, envVar := range envVars {
+			nameValuePair := strings.SplitN(fmt.Sprint(envVar), "=", 2)
+			if len(nameValuePair) == 2 {
+				imageConfig.Env[nameValuePair[0]] = nameValuePair[1]
+			}
+		}
+	}
+	if labels, ok := containerConfig["Labels"].(map[string]interface{}); ok {
+		for key, value := range labels {
+			imageConfig.Labels[key] = fmt.Sprint(value)
+		}
+	}
+	return imageConfig, nil
+}
+
+// getStackImageConfig inspects the stack image if it is available locally, or after pulling it if pull is set
+func getStackImageConfig(config *RootCommandConfig, image string, pull bool) (*StackImageConfig, error) {
+	if pull {
+		if err := pullImage(image, config); err != nil {
+			return nil, err
+		}
+	} else if !checkImageExistsLocally(config.LoggingConfig, image, config.Buildah) {
+		return nil, errors.Errorf("The stack image %s is not available locally. Use --pull to read its ports and environment variables", image)
+	}
+	inspectOut, err := inspectImage(image, config)
+	if err != nil {
+		return nil, err
+	}
+	return ParseImageConfig(inspectOut, config.Buildah)
+}
+
+func formatSortedMap(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+values[key])
+	}
+	return lines
+}
+
+// FormatStackInfo returns the stack metadata as a table
+func FormatStackInfo(info *StackInfo) string {
+	table := uitable.New()
+	table.MaxColWidth = 100
+	table.Wrap = true
+	table.AddRow("Stack:", info.Repo+"/"+info.ID)
+	table.AddRow("Name:", info.Name)
+	table.AddRow("Version:", info.Version)
+	table.AddRow("Description:", info.Description)
+	if info.Deprecated != "" {
+		table.AddRow("Deprecated:", info.Deprecated)
+	}
+	table.AddRow("Language:", info.Language)
+	table.AddRow("License:", info.License)
+	for i, maintainer := range info.Maintainers {
+		label := ""
+		if i == 0 {
+			label = "Maintainers:"
+		}
+		description := maintainer.Name
+		if maintainer.Email != "" {
+			description += " <" + maintainer.Email + ">"
+		}
+		if maintainer.GithubID != "" {
+			description += " (@" + maintainer.GithubID + ")"
+		}
+		table.AddRow(label, description)
+	}
+	table.AddRow("Image:", info.Image)
+	if info.SourceURL != "" {
+		table.AddRow("Source:", info.SourceURL)
+	}
+	for i, template := range info.Templates {
+		label := ""
+		if i == 0 {
+			label = "Templates:"
+		}
+		id := template.ID
+		if id == info.DefaultTemplate {
+			id = id + "*"
+		}
+		table.AddRow(label, id+" "+template.URL)
+	}
+	for i, check := range info.Requirements {
+		label := ""
+		if i == 0 {
+			label = "Requirements:"
+		}
+		result := check.Result
+		if check.Found != "" {
+			result = fmt.Sprintf("%s (found %s)", result, check.Found)
+		}
+		table.AddRow(label, fmt.Sprintf("%s %s: %s", check.Technology, check.Required, result))
+	}
+	if info.ImageConfig != nil {
+		table.AddRow("Ports:", strings.Join(info.ImageConfig.Ports, ", "))
+		for i, env := range formatSortedMap(info.ImageConfig.Env) {
+			label := ""
+			if i == 0 {
+				label = "Environment:"
+			}
+			table.AddRow(label, env)
+		}
+	}
+	return table.String()
+}
+
+func newStackInfoCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &stackInfoCommandConfig{RootCommandConfig: rootConfig}
+	var infoCmd = &cobra.Command{
+		Use:   "info <repository>/<stack>",
+		Short: "Show the details of an Appsody stack.",
+		Long: `Show the metadata of a stack from its repository index, including its image, templates, maintainers and requirements.
+
+The requirements of the stack are checked against the versions of Docker, Buildah and Appsody that are installed locally. If the stack image is available locally, or if you specify --pull, the ports and environment variables of the image are also shown.`,
+		Example: `  appsody stack info incubator/nodejs-express
+  Shows the details of the nodejs-express stack of the incubator repository.
+
+  appsody stack info java-microprofile --pull -o json
+  Pulls the image of the java-microprofile stack of your default repository, and shows the details of the stack in JSON format.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("Required parameter missing. You must specify a stack, in the format <repository>/<stack>")
+			}
+			if len(args) > 1 {
+				return errors.Errorf("One argument expected. Use 'appsody [command] --help' for more information about a command")
+			}
+			repoName, stackID, err := parseProjectParm(args[0], rootConfig)
+			if err != nil {
+				return err
+			}
+			var repoFile RepositoryFile
+			if _, err := repoFile.getRepos(rootConfig); err != nil {
+				return err
+			}
+			repo := repoFile.GetRepo(repoName)
+			if repo == nil {
+				return errors.New("cannot locate repository named " + repoName)
+			}
+			index, err := getIndex(rootConfig, repo, false)
+			if err != nil {
+				return err
+			}
+			stack := getStack(index, stackID)
+			if stack == nil {
+				return errors.Errorf("Could not find stack %s in repository %s", stackID, repoName)
+			}
+
+			info := NewStackInfo(repoName, stack)
+			info.Requirements = StackRequirementChecks(rootConfig.LoggingConfig, map[string]string{
+				"Docker":  stack.Requirements.Docker,
+				"Appsody": stack.Requirements.Appsody,
+				"Buildah": stack.Requirements.Buildah,
+			}, rootConfig.Buildah)
+			if stack.Image == "" {
+				rootConfig.Debug.log("The repository index does not have an image for stack ", stackID)
+			} else if rootConfig.Dryrun {
+				rootConfig.Info.log("Dry Run - Skipping the inspection of image ", stack.Image)
+			} else {
+				info.ImageConfig, err = getStackImageConfig(rootConfig, stack.Image, config.pull)
+				if err != nil {
+					rootConfig.Warning.log(err)
+				}
+			}
+
+			if config.output == "" {
+				rootConfig.Info.log("\n", FormatStackInfo(info))
+				return nil
+			}
+			result, err := formatOutput(config.output, info)
+			if err != nil {
+				return err
+			}
+			rootConfig.Info.log("\n", result)
+			return nil
+		},
+	}
+	infoCmd.PersistentFlags().StringVarP(&config.output, "output", "o", "", "Output the stack details in yaml or json format")
+	infoCmd.PersistentFlags().BoolVar(&config.pull, "pull", false, "Pull the stack image to show its ports and environment variables")
+	return infoCmd
+}
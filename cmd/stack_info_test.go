@@ -0,0 +1,91 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+)
+
+func TestParseImageConfig(t *testing.T) {
+	var parseTests = []struct {
+		testName   string
+		inspectOut string
+		buildah    bool
+	}{
+		{"Docker", `[{"Id":"sha256:1234","Config":{"ExposedPorts":{"8080/tcp":{},"3000/tcp":{}},"Env":["PATH=/usr/bin","APPSODY_RUN=npm start"],"Labels":{"dev.appsody.stack.id":"nodejs"}}}]`, false},
+		{"Buildah", `{"config":{"ExposedPorts":{"8080/tcp":{},"3000/tcp":{}},"Env":["PATH=/usr/bin","APPSODY_RUN=npm start"],"Labels":{"dev.appsody.stack.id":"nodejs"}}}`, true},
+	}
+	for _, testData := range parseTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			imageConfig, err := cmd.ParseImageConfig(tt.inspectOut, tt.buildah)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(imageConfig.Ports, []string{"3000", "8080"}) {
+				t.Errorf("Expected ports 3000 and 8080 but got %v", imageConfig.Ports)
+			}
+			if imageConfig.Env["APPSODY_RUN"] != "npm start" || len(imageConfig.Env) != 2 {
+				t.Errorf("Expected the environment of the image but got %v", imageConfig.Env)
+			}
+			if imageConfig.Labels["dev.appsody.stack.id"] != "nodejs" {
+				t.Errorf("Expected the labels of the image but got %v", imageConfig.Labels)
+			}
+		})
+	}
+
+	_, err := cmd.ParseImageConfig(`[]`, false)
+	if err == nil {
+		t.Error("Expected an error for inspect output without an image")
+	}
+}
+
+func TestFormatStackInfo(t *testing.T) {
+	stack := &cmd.IndexYamlStack{
+		ID:              "nodejs",
+		Name:            "Node.js",
+		Version:         "0.3.2",
+		Description:     "Runtime for Node.js applications",
+		Language:        "nodejs",
+		License:         "Apache-2.0",
+		Maintainers:     []cmd.Maintainer{{Name: "Sam", Email: "sam@example.com", GithubID: "sam"}},
+		DefaultTemplate: "simple",
+		Templates: []cmd.IndexYamlStackTemplate{
+			{ID: "simple", URL: "https://example.com/nodejs.simple.tar.gz"},
+		},
+		Image: "appsody/nodejs:0.3",
+	}
+	info := cmd.NewStackInfo("incubator", stack)
+	info.Requirements = []cmd.RequirementCheck{{Technology: "Docker", Required: ">= 17.09.0", Found: "19.3.5", Result: "met"}}
+	info.ImageConfig = &cmd.StackImageConfig{Ports: []string{"3000"}, Env: map[string]string{"APPSODY_RUN": "npm start"}}
+
+	output := cmd.FormatStackInfo(info)
+	for _, expected := range []string{
+		"incubator/nodejs",
+		"Sam <sam@example.com> (@sam)",
+		"appsody/nodejs:0.3",
+		"simple* https://example.com/nodejs.simple.tar.gz",
+		"Docker >= 17.09.0: met (found 19.3.5)",
+		"APPSODY_RUN=npm start",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the stack info to contain %q:\n%s", expected, output)
+		}
+	}
+}
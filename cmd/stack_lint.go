@@ -17,27 +17,126 @@ package cmd
 import (
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
+	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// exists reports a finding of the rule if a file or directory of the stack does not exist
+func (l *stackLinter) exists(file string, ruleID string, args ...interface{}) bool {
+	fileCheck, err := Exists(filepath.Join(l.stackPath, filepath.FromSlash(file)))
+	if err != nil {
+		l.report("file-access", file, 0, 0, "Error attempting to determine file: ", err)
+		return false
+	}
+	if !fileCheck {
+		l.report(ruleID, file, 0, 0, args...)
+	}
+	return fileCheck
+}
+
+// lintStack checks the structure, Dockerfile-stack and stack.yaml of a stack
+func lintStack(l *stackLinter) {
+	stackPath := l.stackPath
+	imagePath := filepath.Join(stackPath, "image")
+	templatePath := filepath.Join(stackPath, "/templates")
+	configPath := filepath.Join(imagePath, "/config")
+	projectPath := filepath.Join(imagePath, "/project")
+
+	stackID := filepath.Base(stackPath)
+	l.info("LINTING ", stackID)
+
+	validStackID, err := IsValidProjectName(stackID)
+	if !validStackID {
+		l.report("stack-id", lintStackYamlFile, 0, 0, "Stack directory name is invalid. ", err)
+	}
+
+	l.exists("README.md", "readme-missing", "Missing README.md in: ", stackPath)
+	l.exists("stack.yaml", "stack-yaml-missing", "Missing stack.yaml in: ", stackPath)
+	l.exists("image", "image-dir-missing", "Missing image directory in ", stackPath)
+	l.exists("image/Dockerfile-stack", "dockerfile-stack-missing", "Missing Dockerfile-stack in ", imagePath)
+	l.exists("image/LICENSE", "license-missing", "Missing LICENSE in ", imagePath)
+	l.exists("image/config", "config-dir-missing", "Missing config directory in ", imagePath, " (Knative deployment will be used over Kubernetes)")
+	l.exists("image/config/app-deploy.yaml", "app-deploy-missing", "Missing app-deploy.yaml in ", configPath, " (Knative deployment will be used over Kubernetes)")
+	l.exists("image/project", "project-dir-missing", "Missing project directory in ", imagePath)
+	l.exists("image/project/Dockerfile", "project-dockerfile-missing", "Missing Dockerfile in ", projectPath)
+	l.exists("templates", "templates-dir-missing", "Missing template directory in: ", stackPath)
+
+	if IsEmptyDir(templatePath) {
+		l.report("templates-empty", "templates", 0, 0, "No templates found in: ", templatePath)
+	}
+
+	templates, _ := ioutil.ReadDir(templatePath)
+	for _, f := range templates {
+		fileCheck, err := Exists(filepath.Join(templatePath, f.Name(), ConfigFile))
+		if (err != nil) && f.Name() != ".DS_Store" {
+			l.report("file-access", "templates/"+f.Name(), 0, 0, "Error attempting to determine file: ", err)
+		} else if fileCheck && f.Name() != ".DS_Store" {
+			l.report("template-project-config", "templates/"+f.Name()+"/"+ConfigFile, 0, 0, "Unexpected .appsody-config.yaml in ", filepath.Join(templatePath, f.Name()))
+		}
+	}
+
+	lintDockerFileStack(l)
+
+	var stackDetails StackYaml
+	stackDetails.validateYaml(l)
+}
+
+// listLintRules returns a table of the lint rules, with their severities as changed by the lint configuration
+func listLintRules(config *LintConfig) string {
+	table := uitable.New()
+	table.MaxColWidth = 70
+	table.Wrap = true
+	table.AddRow("RULE", "SEVERITY", "DESCRIPTION")
+	for _, rule := range lintRules {
+		table.AddRow(rule.ID, config.severity(rule), rule.Description)
+	}
+	return table.String()
+}
+
 func newStackLintCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	var output string
+	var strict bool
+	var listRules bool
 	var lintCmd = &cobra.Command{
 		Use:   "lint [path]",
 		Short: "Check your stack structure.",
 		Long: `Check that the structure of your stack is valid. Error messages indicate critical issues in your stack structure, such as missing files, directories, or stack variables. Warning messages suggest optional stack enhancements.
 
+Dockerfile-stack is parsed the way it is built: the checks apply to the environment of its final stage, including ARG defaults that are substituted into ENV values, variables of the stages it is built from, and variables of its base image if the image is available locally. Appsody variables that are set in other stages are reported, because they are not in the stack image.
+
+Use -o to write the findings in a machine readable format for CI. Each finding has a rule ID, a severity, the file of the stack that it is about, with its line and column when they are known, a message and a hint to fix it:
+  - json: the findings, with the number of errors and warnings.
+  - sarif: a SARIF 2.1.0 log, which you can upload to code scanning dashboards. The file paths are relative to the current directory when the stack is in it.
+  - junit: a JUnit test suite with a test case for each rule. Errors fail their test case, and warnings are written to its output.
+The command fails when there are errors, whatever the output format, and with --strict when there are warnings too.
+
+Each check has a rule ID, which you can list with --list-rules. To change the rules for a stack, add a .appsody-lint.yaml to the root directory of the stack:
+  rules:
+    description-length: off     # turns the rule off
+    env-missing: error          # changes the severity of the rule to error or warning
+  suppressions:
+    - rule: env-missing         # suppresses the findings of the rule that match the file, line and message
+      file: image/Dockerfile-stack
+      message: APPSODY_DEBUG_PORT
+      reason: The stack is not debugged through a port
+You can also suppress findings with a comment on the line of the finding or the line before it, for example "# appsody-lint-ignore: kill-value the reason". A comment anywhere in a file suppresses findings that are not about a particular line of that file, such as a missing stack.yaml field. Variables that Dockerfile-stack does not set are reported on the FROM instruction of its final stage. Suppressed findings are listed in the json and sarif output, but are not counted.
+
 Run this command from the root directory of your stack, or specify the path to your stack.`,
 		Example: `  appsody stack lint
   Checks the structure of the stack in the current directory"
 		
   appsody stack lint path/to/my-stack
-  Checks the structure of the stack "my-stack" in the path "path/to/my-stack"`,
-		RunE: func(cmd *cobra.Command, args []string) error {
+  Checks the structure of the stack "my-stack" in the path "path/to/my-stack"
 
-			var stackLintErrorCount int
-			var stackLintWarningCount int
+  appsody stack lint incubator/my-stack -o sarif > lint.sarif
+  Checks the stack "my-stack" and writes the findings in the SARIF format, to upload them to a code scanning dashboard
+
+  appsody stack lint --strict
+  Checks the stack in the current directory, and fails if there are warnings`,
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			stackPath := rootConfig.ProjectDir
 
@@ -47,146 +146,72 @@ Run this command from the root directory of your stack, or specify the path to y
 			if len(args) > 1 {
 				return errors.Errorf("Too many arguments. Use 'appsody [command] --help' for more information about a command")
 			}
-
-			imagePath := filepath.Join(stackPath, "image")
-			templatePath := filepath.Join(stackPath, "/templates")
-			configPath := filepath.Join(imagePath, "/config")
-			projectPath := filepath.Join(imagePath, "/project")
-
-			stackID := filepath.Base(stackPath)
-			rootConfig.Info.log("LINTING ", stackID)
-
-			validStackID, err := IsValidProjectName(stackID)
-			if !validStackID {
-				rootConfig.Error.log("Stack directory name is invalid. ", err)
-				stackLintErrorCount++
+			if output != "" && output != "json" && output != "sarif" && output != "junit" {
+				return errors.Errorf("The output format %s is not supported. Use json, sarif or junit", output)
 			}
 
-			fileCheck, err := Exists(filepath.Join(stackPath, "/README.md"))
+			lintConfig, err := readLintConfig(stackPath)
 			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing README.md in: ", stackPath)
-				stackLintErrorCount++
+				return err
 			}
-			fileCheck, err = Exists(filepath.Join(stackPath, "/stack.yaml"))
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing stack.yaml in: ", stackPath)
-				stackLintErrorCount++
+			if listRules {
+				rootConfig.Info.log(listLintRules(lintConfig))
+				return nil
 			}
 
-			fileCheck, err = Exists(imagePath)
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing image directory in ", stackPath)
-				stackLintErrorCount++
-			}
-
-			fileCheck, err = Exists(filepath.Join(imagePath, "/Dockerfile-stack"))
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing Dockerfile-stack in ", imagePath)
-				stackLintErrorCount++
-			}
-
-			fileCheck, err = Exists(filepath.Join(imagePath, "/LICENSE"))
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing LICENSE in ", imagePath)
-				stackLintErrorCount++
-			}
-
-			fileCheck, err = Exists(configPath)
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Warning.log("Missing config directory in ", imagePath, " (Knative deployment will be used over Kubernetes)")
-				stackLintWarningCount++
-			}
-
-			fileCheck, err = Exists(filepath.Join(configPath, "/app-deploy.yaml"))
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Warning.log("Missing app-deploy.yaml in ", configPath, " (Knative deployment will be used over Kubernetes)")
-				stackLintWarningCount++
-			}
-
-			fileCheck, err = Exists(projectPath)
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Warning.log("Missing project directory in ", imagePath)
-				stackLintWarningCount++
-			}
-
-			fileCheck, err = Exists(filepath.Join(projectPath, "/Dockerfile"))
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Warning.log("Missing Dockerfile in ", projectPath)
-				stackLintWarningCount++
-			}
-
-			fileCheck, err = Exists(templatePath)
-			if err != nil {
-				rootConfig.Error.log("Error attempting to determine file: ", err)
-				stackLintErrorCount++
-			} else if !fileCheck {
-				rootConfig.Error.log("Missing template directory in: ", stackPath)
-				stackLintErrorCount++
-			}
-
-			if IsEmptyDir(templatePath) {
-				rootConfig.Error.log("No templates found in: ", templatePath)
-				stackLintErrorCount++
-			}
-
-			templates, _ := ioutil.ReadDir(templatePath)
-			for _, f := range templates {
-				fileCheck, err = Exists(filepath.Join(templatePath, f.Name(), ConfigFile))
-				if (err != nil) && f.Name() != ".DS_Store" {
-					rootConfig.Error.log("Error attempting to determine file: ", err)
-					stackLintErrorCount++
-				} else if fileCheck && f.Name() != ".DS_Store" {
-					rootConfig.Error.log("Unexpected .appsody-config.yaml in ", filepath.Join(templatePath, f.Name()))
-					stackLintErrorCount++
+			linter := newStackLinter(rootConfig.LoggingConfig, stackPath, lintConfig, output != "")
+			linter.baseImageEnv = func(image string) (map[string]string, error) {
+				// the base image is only inspected if it is available locally, lint does not pull images
+				inspectOut, err := inspectImage(image, rootConfig)
+				if err != nil {
+					rootConfig.Debug.logf("Not checking the environment variables of the base image %s: %v", image, err)
+					return nil, nil
+				}
+				imageConfig, err := ParseImageConfig(inspectOut, rootConfig.Buildah)
+				if err != nil {
+					return nil, err
+				}
+				return imageConfig.Env, nil
+			}
+			lintStack(linter)
+			report := linter.lintReport(filepath.Base(stackPath), strict)
+
+			if output != "" {
+				// the SARIF file paths are relative to the directory that is scanned, which is usually the current directory
+				sourcePath := ""
+				if absStackPath, err := filepath.Abs(stackPath); err == nil {
+					if rel, err := filepath.Rel(rootConfig.ProjectDir, absStackPath); err == nil && !strings.HasPrefix(rel, "..") {
+						sourcePath = filepath.ToSlash(rel)
+					}
+				}
+				result, err := formatLintReport(output, report, sourcePath)
+				if err != nil {
+					return err
+				}
+				rootConfig.Info.log(result)
+			} else {
+				rootConfig.Info.log("TOTAL ERRORS: ", report.Errors)
+				rootConfig.Info.log("TOTAL WARNINGS: ", report.Warnings)
+				if report.Suppressed > 0 {
+					rootConfig.Info.log("TOTAL SUPPRESSED: ", report.Suppressed)
 				}
 			}
 
-			dockerFileErrorCount, dockerFileWarningCount := lintDockerFileStack(rootConfig.LoggingConfig, stackPath)
-			stackLintErrorCount += dockerFileErrorCount
-			stackLintWarningCount += dockerFileWarningCount
-
-			var stackDetails StackYaml
-			stackYamlErrorCount, stackYamlWarningCount := stackDetails.validateYaml(rootConfig, stackPath)
-			stackLintErrorCount += stackYamlErrorCount
-			stackLintWarningCount += stackYamlWarningCount
-
-			rootConfig.Info.log("TOTAL ERRORS: ", stackLintErrorCount)
-			rootConfig.Info.log("TOTAL WARNINGS: ", stackLintWarningCount)
-
-			if stackLintErrorCount > 0 {
+			if !report.Passed {
+				if report.Errors == 0 {
+					return errors.Errorf("LINT TEST FAILED. There are warnings and --strict is set")
+				}
 				return errors.Errorf("LINT TEST FAILED")
 			}
 
-			rootConfig.Info.log("LINT TEST PASSED")
+			if output == "" {
+				rootConfig.Info.log("LINT TEST PASSED")
+			}
 			return nil
 		},
 	}
+	lintCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "Output the findings in json, sarif or junit format")
+	lintCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Fail when there are warnings, not only when there are errors")
+	lintCmd.PersistentFlags().BoolVar(&listRules, "list-rules", false, "List the lint rules with their severities, as changed by the .appsody-lint.yaml of the stack")
 	return lintCmd
 }
@@ -0,0 +1,148 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LintConfigFile configures the rules of appsody stack lint for a stack, in the root directory of the stack
+const LintConfigFile = ".appsody-lint.yaml"
+
+// the severity that turns a rule off in the lint configuration
+const lintSeverityOff = "off"
+
+// inline suppressions are comments like "# appsody-lint-ignore: env-missing, mount-single-file the reason"
+var lintIgnoreCommentRegexp = regexp.MustCompile(`#\s*appsody-lint-ignore:?\s+([a-z0-9-]+(?:\s*,\s*[a-z0-9-]+)*)`)
+
+// LintSuppression suppresses the findings of a rule. The file, line and message narrow down the findings it applies to:
+// the file is relative to the stack directory, and the message matches findings whose message contains it.
+type LintSuppression struct {
+	Rule    string `yaml:"rule"`
+	File    string `yaml:"file,omitempty"`
+	Line    int    `yaml:"line,omitempty"`
+	Message string `yaml:"message,omitempty"`
+	Reason  string `yaml:"reason,omitempty"`
+}
+
+// LintConfig is the content of the .appsody-lint.yaml of a stack
+type LintConfig struct {
+	// Rules changes the severity of rules to error or warning, or turns them off
+	Rules        map[string]string `yaml:"rules,omitempty"`
+	Suppressions []LintSuppression `yaml:"suppressions,omitempty"`
+}
+
+func isLintRule(id string) bool {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// readLintConfig reads the lint configuration of a stack, which is empty if the stack does not have one
+func readLintConfig(stackPath string) (*LintConfig, error) {
+	config := &LintConfig{}
+	file := filepath.Join(stackPath, LintConfigFile)
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("Could not read %s: %v", file, err)
+	}
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, errors.Errorf("Could not parse %s: %v", file, err)
+	}
+	for id, severity := range config.Rules {
+		if !isLintRule(id) {
+			return nil, errors.Errorf("The rule %s in %s does not exist. Use appsody stack lint --list-rules to list the rules", id, file)
+		}
+		if severity != lintSeverityError && severity != lintSeverityWarning && severity != lintSeverityOff {
+			return nil, errors.Errorf("The severity %s of rule %s in %s is not valid. Use error, warning or off", severity, id, file)
+		}
+	}
+	for _, suppression := range config.Suppressions {
+		if !isLintRule(suppression.Rule) {
+			return nil, errors.Errorf("The rule %s of a suppression in %s does not exist. Use appsody stack lint --list-rules to list the rules", suppression.Rule, file)
+		}
+	}
+	return config, nil
+}
+
+// severity returns the severity of a rule, as changed by the configuration
+func (c *LintConfig) severity(rule LintRule) string {
+	if severity, ok := c.Rules[rule.ID]; ok {
+		return severity
+	}
+	return rule.Severity
+}
+
+// suppression returns the reason that the configuration suppresses the finding, and whether it does
+func (c *LintConfig) suppression(finding LintFinding) (string, bool) {
+	for _, suppression := range c.Suppressions {
+		if suppression.Rule != finding.RuleID ||
+			(suppression.File != "" && filepath.ToSlash(filepath.Clean(suppression.File)) != finding.File) ||
+			(suppression.Line != 0 && suppression.Line != finding.Line) ||
+			(suppression.Message != "" && !strings.Contains(finding.Message, suppression.Message)) {
+			continue
+		}
+		return suppression.Reason, true
+	}
+	return "", false
+}
+
+// ignoredRules returns the rules that an appsody-lint-ignore comment in the line suppresses, with the reason that follows them
+func ignoredRules(line string) ([]string, string) {
+	match := lintIgnoreCommentRegexp.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, ""
+	}
+	var rules []string
+	for _, rule := range strings.Split(line[match[2]:match[3]], ",") {
+		rules = append(rules, strings.TrimSpace(rule))
+	}
+	return rules, strings.TrimSpace(line[match[1]:])
+}
+
+// inlineSuppression returns the reason that a comment in the stack file suppresses the finding, and whether it does.
+// A comment suppresses the findings of its line and the next line. Findings that are not about a particular line,
+// such as a missing variable, are suppressed by a comment anywhere in their file.
+func (l *stackLinter) inlineSuppression(finding LintFinding) (string, bool) {
+	if finding.File == "" {
+		return "", false
+	}
+	for i, line := range l.fileLines(finding.File) {
+		if finding.Line != 0 && i+1 != finding.Line && i+2 != finding.Line {
+			continue
+		}
+		rules, reason := ignoredRules(line)
+		for _, rule := range rules {
+			if rule == finding.RuleID {
+				return reason, true
+			}
+		}
+	}
+	return "", false
+}
@@ -15,58 +15,103 @@
 package cmd
 
 import (
-	"bufio"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-)
-
-func getENVDockerfile(log *LoggingConfig, stackPath string) (dockerfileStack map[string]string) {
-	arg := filepath.Join(stackPath, "image/Dockerfile-stack")
 
-	file, err := os.Open(arg)
+	"github.com/pkg/errors"
+)
 
-	if err != nil {
-		log.Error.log("failed opening file: ", err)
+// lintEnv reports a finding about a variable of the final stage of Dockerfile-stack, on the line that sets it.
+// Findings about variables that are not set are reported on the FROM instruction of the final stage.
+func (l *stackLinter) lintEnv(ruleID string, variable string, args ...interface{}) {
+	line, column := 0, 0
+	if l.stage != nil {
+		line, column = l.stage.Line, 1
+		if v, ok := l.stage.Env[variable]; ok {
+			line, column = l.locateVariable(v)
+		}
 	}
+	l.report(ruleID, lintDockerfileStackFile, line, column, args...)
+}
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	var txtlines []string
-
-	for scanner.Scan() {
-		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "ENV") {
-			txtlines = append(txtlines, strings.TrimSpace(scanner.Text()))
+// locateVariable returns the line and column of the name of a variable in the ENV instruction that sets it,
+// which can span several lines
+func (l *stackLinter) locateVariable(v DockerfileVariable) (int, int) {
+	lines := l.fileLines(lintDockerfileStackFile)
+	if !v.Inherited {
+		for line := v.StartLine; line <= v.EndLine && line <= len(lines); line++ {
+			text := lines[line-1]
+			for index := strings.Index(text, v.Name); index >= 0; {
+				end := index + len(v.Name)
+				if end == len(text) || text[end] == '=' || text[end] == ' ' || text[end] == '\t' {
+					return line, index + 1
+				}
+				next := strings.Index(text[end:], v.Name)
+				if next < 0 {
+					break
+				}
+				index = end + next
+			}
 		}
 	}
+	return v.StartLine, 1
+}
 
-	file.Close()
-
-	dockerfileMap := make(map[string]string)
-
-	for _, eachline := range txtlines {
-		s := strings.Split(eachline, "=")
-		key := strings.TrimPrefix(s[0], "ENV")
-		key = strings.TrimSpace(key)
-		value := strings.TrimPrefix(eachline, s[0]+"=")
-		dockerfileMap[key] = value
+// sortedVariables returns the variables of a stage in the order they are set in the Dockerfile
+func sortedVariables(env map[string]DockerfileVariable) []DockerfileVariable {
+	variables := make([]DockerfileVariable, 0, len(env))
+	for _, variable := range env {
+		variables = append(variables, variable)
 	}
-
-	return dockerfileMap
+	sort.Slice(variables, func(i, j int) bool {
+		if variables[i].StartLine != variables[j].StartLine {
+			return variables[i].StartLine < variables[j].StartLine
+		}
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
 }
 
-func lintDockerFileStack(log *LoggingConfig, stackPath string) (int, int) {
+func lintDockerFileStack(l *stackLinter) {
+	stackPath := l.stackPath
 	optionalEnvironmentVariables := [...]string{"APPSODY_DEBUG", "APPSODY_TEST", "APPSODY_DEPS", "APPSODY_PROJECT_DIR", "APPSODY_MOUNTS", "APPSODY_RUN", "APPSODY_DEBUG_PORT"}
 
-	stackLintErrorCount := 0
-	stackLintWarningCount := 0
 	arg := filepath.Join(stackPath, "image/Dockerfile-stack")
+	if _, err := os.Stat(arg); err != nil {
+		// a missing Dockerfile-stack is already reported
+		return
+	}
 
-	log.Info.log("Linting Dockerfile-stack: ", arg)
+	l.info("Linting Dockerfile-stack: ", arg)
 
-	dockerfileStack := getENVDockerfile(log, stackPath)
+	dockerfile, err := ReadDockerfile(arg, l.baseImageEnv)
+	if err != nil {
+		line := 0
+		if dockerfileErr, ok := errors.Cause(err).(*DockerfileError); ok {
+			line = dockerfileErr.Line
+		}
+		l.report("dockerfile-invalid", lintDockerfileStackFile, line, 0, err)
+		return
+	}
+	for _, stage := range dockerfile.Stages {
+		if dockerfile.InImage(stage) {
+			continue
+		}
+		for _, variable := range stage.Set {
+			if strings.HasPrefix(variable.Name, "APPSODY_") {
+				line, column := l.locateVariable(variable)
+				l.report("env-not-final-stage", lintDockerfileStackFile, line, column, variable.Name, " is set in a build stage that is not the final stage, so it is not in the stack image")
+			}
+		}
+	}
+	l.stage = dockerfile.FinalStage()
+	dockerfileStack := l.stage.EnvValues()
+	variables := sortedVariables(l.stage.Env)
 
 	variableFound := false
 	appsodyDebugFound := false
@@ -87,8 +132,7 @@ func lintDockerFileStack(log *LoggingConfig, stackPath string) (int, int) {
 			if appsodyDebugFound && optionalEnvironmentVariables[i] == "APPSODY_DEBUG_PORT" {
 				errMsg = ". The APPSODY_DEBUG environment variable was found but no APPSODY_DEBUG_PORT."
 			}
-			log.Warning.log("Missing ", variable, errMsg)
-			stackLintWarningCount++
+			l.lintEnv("env-missing", "", "Missing ", variable, errMsg)
 		}
 		variableFound = false
 	}
@@ -110,47 +154,40 @@ func lintDockerFileStack(log *LoggingConfig, stackPath string) (int, int) {
 	}
 
 	if count == len(dockerfileStack) && !onChangeFound {
-		log.Warning.log("APPSODY_WATCH_DIR is defined, but no ON_CHANGE variable is defined")
-		stackLintWarningCount++
+		l.lintEnv("watch-dir-on-change", "APPSODY_WATCH_DIR", "APPSODY_WATCH_DIR is defined, but no ON_CHANGE variable is defined")
 	}
 
-	for k, v := range dockerfileStack {
+	for _, v := range variables {
+		k := v.Name
 		if strings.Contains(k, "APPSODY_INSTALL") {
-			log.Warning.log("APPSODY_INSTALL has been deprecated. Please use APPSODY_PREP instead")
-			stackLintWarningCount++
+			l.lintEnv("install-deprecated", k, "APPSODY_INSTALL has been deprecated. Please use APPSODY_PREP instead")
 		}
 
 		if strings.Contains(k, "_KILL") {
-			if !(v == "true" || v == "false") {
-				log.Error.log(k, " can only have value true/false")
-				stackLintErrorCount++
+			if !(v.Value == "true" || v.Value == "false") {
+				l.lintEnv("kill-value", k, k, " can only have value true/false")
 			}
 		}
 
 		if strings.Contains(k, "APPSODY_WATCH_REGEX") {
-			_, err := regexp.Compile(v)
+			_, err := regexp.Compile(v.Value)
 
 			if err != nil {
-				log.Error.log(err)
-				stackLintErrorCount++
+				l.lintEnv("watch-regex", k, err)
 			}
 		}
 	}
 	mountVar := dockerfileStack["APPSODY_MOUNTS"]
-	lintMountWarnings, lintMountErrors := lintMountVar(mountVar, log, stackPath)
-	stackLintWarningCount = stackLintWarningCount + lintMountWarnings
-	stackLintErrorCount = stackLintErrorCount + lintMountErrors
-	return stackLintErrorCount, stackLintWarningCount
+	lintMountVar(mountVar, l)
 }
 
-func lintMountVar(mountListSource string, log *LoggingConfig, stackPath string) (int, int) {
-	errCount := 0
-	warningCount := 0
+func lintMountVar(mountListSource string, l *stackLinter) {
+	log := l.log
+	stackPath := l.stackPath
 
 	if mountListSource == "" {
-		log.Warning.log("No APPSODY MOUNTS exists, mount paths can not be validated.")
-		warningCount++
-		return warningCount, errCount
+		l.lintEnv("mounts-missing", "", "No APPSODY MOUNTS exists, mount paths can not be validated.")
+		return
 	}
 	mountList := strings.Split(mountListSource, ";")
 
@@ -159,19 +196,16 @@ func lintMountVar(mountListSource string, log *LoggingConfig, stackPath string)
 	fileCheck, err := Exists(templatePath)
 	log.Debug.log("Template path exists: ", fileCheck)
 	if err != nil {
-		log.Error.log("Error attempting to determine if template path exists: ", err)
-		errCount++
-		return warningCount, errCount
+		l.report("file-access", "templates", 0, 0, "Error attempting to determine if template path exists: ", err)
+		return
 	}
 	if !fileCheck {
-		log.Error.log("Missing template directory in: ", stackPath)
-		errCount++
-		return warningCount, errCount
+		l.report("templates-dir-missing", "templates", 0, 0, "Missing template directory in: ", stackPath)
+		return
 	}
 	if IsEmptyDir(templatePath) {
-		log.Error.log("No templates found in: ", templatePath)
-		errCount++
-		return warningCount, errCount
+		l.report("templates-empty", "templates", 0, 0, "No templates found in: ", templatePath)
+		return
 	}
 	templates, _ := ioutil.ReadDir(templatePath)
 
@@ -183,14 +217,12 @@ func lintMountVar(mountListSource string, log *LoggingConfig, stackPath string)
 			log.Debug.log("mount pair: ", traceMount)
 			localPaths := strings.Split(traceMount, ":")
 			if len(localPaths) != 2 {
-				log.Error.log("Mount is not properly formatted it is missing the single colon: ", traceMount)
-				errCount++
+				l.lintEnv("mount-format", "APPSODY_MOUNTS", "Mount is not properly formatted it is missing the single colon: ", traceMount)
 			} else {
 
 				localPath := localPaths[0]
 				if localPath == "" {
-					log.Error.logf("Path for mount %s is empty: ", traceMount)
-					errCount++
+					l.lintEnv("mount-path-empty", "APPSODY_MOUNTS", fmt.Sprintf("Path for mount %s is empty: ", traceMount))
 				} else {
 
 					log.Debug.log("local path: ", localPath)
@@ -202,33 +234,26 @@ func lintMountVar(mountListSource string, log *LoggingConfig, stackPath string)
 						mountFilePath := filepath.Join(stackPath, "templates", f.Name(), localPath)
 
 						log.Debug.log("mountFilePath: ", mountFilePath)
-						warnings, valErrors := validateMountPath(mountFilePath, traceMount, log)
-						warningCount = warningCount + warnings
-						errCount = errCount + valErrors
-
+						validateMountPath(mountFilePath, traceMount, l)
 					}
 				}
 			}
 		}
 	}
-	return warningCount, errCount
 }
-func validateMountPath(path string, mount string, log *LoggingConfig) (int, int) {
+func validateMountPath(path string, mount string, l *stackLinter) {
+	log := l.log
 	log.Debug.log("Attempting to validate mount path: ", path)
 
-	warningCount, errorCount := 0, 0
 	file, err := os.Stat(path)
 	if err != nil {
-		errorCount = 1
-		log.Error.logf("Could not stat path: %s for mount %s", path, mount)
+		l.lintEnv("mount-path-missing", "APPSODY_MOUNTS", fmt.Sprintf("Could not stat path: %s for mount %s", path, mount))
 	} else {
 		if file.Mode().IsDir() {
 			log.Debug.logf("Path %s for mount %s is a directory", path, mount)
 		} else {
-			warningCount = 1
-			log.Warning.logf("Path %s for mount %s points to a single file.  Single file Docker mount paths cause unexpected behavior and will be deprecated in the future.", path, mount)
+			l.lintEnv("mount-single-file", "APPSODY_MOUNTS", fmt.Sprintf("Path %s for mount %s points to a single file.  Single file Docker mount paths cause unexpected behavior and will be deprecated in the future.", path, mount))
 		}
 
 	}
-	return warningCount, errorCount
 }
@@ -0,0 +1,476 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+)
+
+// the stack files that findings refer to, relative to the stack directory
+const (
+	lintStackYamlFile       = "stack.yaml"
+	lintDockerfileStackFile = "image/Dockerfile-stack"
+)
+
+// LintRule is a check of appsody stack lint
+type LintRule struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	Fix         string `json:"fix"`
+}
+
+// LintFinding is a problem that appsody stack lint found in a stack.
+// The file is relative to the stack directory, and the line and column are 0 when they are not known.
+type LintFinding struct {
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+	// Suppression is set when the finding is suppressed, in which case it does not count as an error or a warning
+	Suppression *LintFindingSuppression `json:"suppression,omitempty"`
+}
+
+// LintFindingSuppression is how a finding was suppressed: by a "comment" in the stack file, or by the "config" in .appsody-lint.yaml
+type LintFindingSuppression struct {
+	Kind   string `json:"kind"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// LintReport is the result of linting a stack, as written by appsody stack lint -o json
+type LintReport struct {
+	Stack      string        `json:"stack"`
+	Errors     int           `json:"errors"`
+	Warnings   int           `json:"warnings"`
+	Suppressed int           `json:"suppressed"`
+	Strict     bool          `json:"strict,omitempty"`
+	Passed     bool          `json:"passed"`
+	Findings   []LintFinding `json:"findings"`
+}
+
+// lintRules are the checks of appsody stack lint, in the order they run
+var lintRules = []LintRule{
+	{"stack-id", lintSeverityError, "The stack directory name must be a valid stack ID", "Rename the stack directory so that it starts with a lowercase letter, only has lowercase letters, digits and dashes, and is 68 characters or less"},
+	{"file-access", lintSeverityError, "The stack files must be readable", "Check the permissions of the stack directory"},
+	{"readme-missing", lintSeverityError, "The stack must have a README.md", "Add a README.md that describes the stack"},
+	{"stack-yaml-missing", lintSeverityError, "The stack must have a stack.yaml", "Add a stack.yaml with the details of the stack"},
+	{"image-dir-missing", lintSeverityError, "The stack must have an image directory", "Add an image directory with the Dockerfile-stack of the stack"},
+	{"dockerfile-stack-missing", lintSeverityError, "The image directory must have a Dockerfile-stack", "Add image/Dockerfile-stack, which builds the stack image"},
+	{"license-missing", lintSeverityError, "The image directory must have a LICENSE", "Add the license of the stack as image/LICENSE"},
+	{"config-dir-missing", lintSeverityWarning, "The image directory should have a config directory", "Add image/config with an app-deploy.yaml to deploy applications to Kubernetes"},
+	{"app-deploy-missing", lintSeverityWarning, "The config directory should have an app-deploy.yaml", "Add image/config/app-deploy.yaml to deploy applications to Kubernetes"},
+	{"project-dir-missing", lintSeverityWarning, "The image directory should have a project directory", "Add image/project with the Dockerfile that builds applications"},
+	{"project-dockerfile-missing", lintSeverityWarning, "The project directory should have a Dockerfile", "Add image/project/Dockerfile, which builds the images of applications"},
+	{"templates-dir-missing", lintSeverityError, "The stack must have a templates directory", "Add a templates directory with at least one template"},
+	{"templates-empty", lintSeverityError, "The stack must have at least one template", "Add a template directory under templates"},
+	{"template-project-config", lintSeverityError, "Templates must not have a .appsody-config.yaml", "Remove the .appsody-config.yaml from the template, appsody stack package creates it"},
+	{"dockerfile-invalid", lintSeverityError, "Dockerfile-stack must be a valid Dockerfile", "Fix the syntax of image/Dockerfile-stack"},
+	{"env-not-final-stage", lintSeverityWarning, "Appsody environment variables must be set in the final stage of Dockerfile-stack", "Move the ENV instruction to the final stage, or to a stage that the final stage is built FROM"},
+	{"env-missing", lintSeverityWarning, "Dockerfile-stack should set the Appsody environment variables", "Add an ENV instruction for the variable to image/Dockerfile-stack"},
+	{"watch-dir-on-change", lintSeverityWarning, "APPSODY_WATCH_DIR should be used with an _ON_CHANGE variable", "Set APPSODY_RUN_ON_CHANGE, APPSODY_DEBUG_ON_CHANGE or APPSODY_TEST_ON_CHANGE"},
+	{"install-deprecated", lintSeverityWarning, "APPSODY_INSTALL is deprecated", "Rename APPSODY_INSTALL to APPSODY_PREP"},
+	{"kill-value", lintSeverityError, "The _KILL variables must be true or false", "Set the variable to true or false"},
+	{"watch-regex", lintSeverityError, "APPSODY_WATCH_REGEX must be a valid regular expression", "Fix the regular expression, see https://golang.org/s/re2syntax"},
+	{"mounts-missing", lintSeverityWarning, "Dockerfile-stack should set APPSODY_MOUNTS", "Set APPSODY_MOUNTS to the mounts of the project, for example APPSODY_MOUNTS=.:/project/user-app"},
+	{"mount-format", lintSeverityError, "Each mount must be a local path and a container path separated by a colon", "Separate mounts with semicolons, and the local and container path of each mount with a colon"},
+	{"mount-path-empty", lintSeverityError, "The local path of a mount must not be empty", "Set the local path of the mount, relative to the project directory"},
+	{"mount-path-missing", lintSeverityError, "The local path of a mount must exist in every template", "Add the path to each template or change the mount"},
+	{"mount-single-file", lintSeverityWarning, "Mounts should be directories", "Mount the directory that contains the file instead"},
+	{"stack-yaml-invalid", lintSeverityError, "stack.yaml must be valid YAML", "Fix the syntax of stack.yaml"},
+	{"field-missing", lintSeverityError, "stack.yaml must have a value for each field", "Add the field to stack.yaml"},
+	{"version-format", lintSeverityError, "The stack version must be a semantic version", "Set the version to major.minor.patch, see https://semver.org"},
+	{"maintainer-missing", lintSeverityError, "stack.yaml must list at least one maintainer", "Add a maintainer with a name, email and github-id"},
+	{"description-length", lintSeverityError, "The stack description must be under 70 characters", "Shorten the description"},
+	{"name-length", lintSeverityError, "The stack name must be under 30 characters", "Shorten the name"},
+	{"license-id", lintSeverityWarning, "The license must be a valid SPDX license ID", "Use an ID from https://spdx.org/licenses"},
+	{"requirement-format", lintSeverityError, "Requirements must be semantic version constraints", "See https://github.com/Masterminds/semver for the valid constraints"},
+	{"templating-data-key", lintSeverityError, "templating-data keys must be alphanumeric", "Rename the key so that it only has letters and digits"},
+}
+
+// lintRule returns the rule with the ID
+func lintRule(id string) LintRule {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return LintRule{ID: id, Severity: lintSeverityError}
+}
+
+// stackLinter records the findings of appsody stack lint, and logs them unless they are written in a machine readable format
+type stackLinter struct {
+	log       *LoggingConfig
+	stackPath string
+	config    *LintConfig
+	quiet     bool
+	findings  []LintFinding
+	lines     map[string][]string
+	// baseImageEnv returns the environment variables of the base image of Dockerfile-stack, if it can be inspected
+	baseImageEnv func(image string) (map[string]string, error)
+	// stage is the final stage of Dockerfile-stack, once it is parsed
+	stage *DockerfileStage
+}
+
+func newStackLinter(log *LoggingConfig, stackPath string, config *LintConfig, quiet bool) *stackLinter {
+	return &stackLinter{log: log, stackPath: stackPath, config: config, quiet: quiet, lines: make(map[string][]string)}
+}
+
+// info logs progress, which is left out of machine readable output
+func (l *stackLinter) info(args ...interface{}) {
+	if !l.quiet {
+		l.log.Info.log(args...)
+	}
+}
+
+// report records a finding of a rule, which is logged with the message of the args.
+// The severity of the rule is taken from the lint configuration, and findings of rules that are off are dropped.
+func (l *stackLinter) report(ruleID string, file string, line int, column int, args ...interface{}) {
+	rule := lintRule(ruleID)
+	finding := LintFinding{
+		RuleID:   rule.ID,
+		Severity: l.config.severity(rule),
+		File:     file,
+		Line:     line,
+		Column:   column,
+		Message:  fmt.Sprint(args...),
+		Fix:      rule.Fix,
+	}
+	if finding.Severity == lintSeverityOff {
+		l.log.Debug.logf("Skipping %s, the rule %s is off: %s", findingLocation(finding), rule.ID, finding.Message)
+		return
+	}
+	if reason, ok := l.inlineSuppression(finding); ok {
+		finding.Suppression = &LintFindingSuppression{Kind: "comment", Reason: reason}
+	} else if reason, ok := l.config.suppression(finding); ok {
+		finding.Suppression = &LintFindingSuppression{Kind: "config", Reason: reason}
+	}
+	l.findings = append(l.findings, finding)
+	if finding.Suppression != nil {
+		l.log.Debug.logf("Suppressed %s by %s: %s", rule.ID, finding.Suppression.Kind, finding.Message)
+		return
+	}
+	if l.quiet {
+		return
+	}
+	if finding.Severity == lintSeverityError {
+		l.log.Error.log(args...)
+	} else {
+		l.log.Warning.log(args...)
+	}
+}
+
+// count returns the number of findings with the severity that are not suppressed
+func (l *stackLinter) count(severity string) int {
+	count := 0
+	for _, finding := range l.findings {
+		if finding.Severity == severity && finding.Suppression == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// fileLines returns the lines of a stack file, or nil if it cannot be read
+func (l *stackLinter) fileLines(file string) []string {
+	lines, ok := l.lines[file]
+	if !ok {
+		data, err := ioutil.ReadFile(filepath.Join(l.stackPath, filepath.FromSlash(file)))
+		if err == nil {
+			lines = strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+		}
+		l.lines[file] = lines
+	}
+	return lines
+}
+
+// locate returns the line and column of the first line of a stack file where match returns a column,
+// or 0 and 0 if there is no such line
+func (l *stackLinter) locate(file string, match func(line string) int) (int, int) {
+	for i, line := range l.fileLines(file) {
+		if column := match(line); column > 0 {
+			return i + 1, column
+		}
+	}
+	return 0, 0
+}
+
+// yamlKey matches the line of a YAML key, returning its column
+func yamlKey(key string) func(string) int {
+	return func(line string) int {
+		trimmed := strings.TrimLeft(strings.TrimPrefix(strings.TrimSpace(line), "- "), " ")
+		if strings.HasPrefix(trimmed, key+":") {
+			return strings.Index(line, key) + 1
+		}
+		return 0
+	}
+}
+
+// lintReport returns the findings of the linter with their totals. In strict mode, warnings fail the lint like errors.
+func (l *stackLinter) lintReport(stackID string, strict bool) LintReport {
+	findings := l.findings
+	if findings == nil {
+		findings = []LintFinding{}
+	}
+	report := LintReport{
+		Stack:    stackID,
+		Errors:   l.count(lintSeverityError),
+		Warnings: l.count(lintSeverityWarning),
+		Strict:   strict,
+		Findings: findings,
+	}
+	report.Suppressed = len(findings) - report.Errors - report.Warnings
+	report.Passed = report.Errors == 0 && (!strict || report.Warnings == 0)
+	return report
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	RuleIndex    int                `json:"ruleIndex"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifReport returns the report in the SARIF 2.1.0 format of code scanning tools.
+// The file URIs are prefixed with the path of the stack relative to the root of the scanned source.
+func sarifReport(report LintReport, sourcePath string) sarifLog {
+	driver := sarifDriver{
+		Name:           "appsody stack lint",
+		Version:        VERSION,
+		InformationURI: "https://appsody.dev/docs/stacks/develop",
+		Rules:          make([]sarifRule, 0, len(lintRules)),
+	}
+	ruleIndex := make(map[string]int)
+	for i, rule := range lintRules {
+		ruleIndex[rule.ID] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			Help:                 sarifMessage{Text: rule.Fix},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Severity},
+		})
+	}
+	results := make([]sarifResult, 0, len(report.Findings))
+	for _, finding := range report.Findings {
+		uri := finding.File
+		if sourcePath != "" && sourcePath != "." {
+			uri = sourcePath + "/" + uri
+		}
+		// code scanning needs a start line, so findings about a whole file are reported on its first line
+		line := finding.Line
+		if line == 0 {
+			line = 1
+		}
+		message := finding.Message
+		if finding.Fix != "" {
+			message += ". " + finding.Fix
+		}
+		result := sarifResult{
+			RuleID:    finding.RuleID,
+			RuleIndex: ruleIndex[finding.RuleID],
+			Level:     finding.Severity,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region:           sarifRegion{StartLine: line, StartColumn: finding.Column},
+			}}},
+		}
+		if finding.Suppression != nil {
+			kind := "external"
+			if finding.Suppression.Kind == "comment" {
+				kind = "inSource"
+			}
+			result.Suppressions = []sarifSuppression{{Kind: kind, Justification: finding.Suppression.Reason}}
+		}
+		results = append(results, result)
+	}
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Time      string          `xml:"time,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr,omitempty"`
+	Failures  []junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped  `xml:"skipped,omitempty"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// findingLocation returns the file, line and column of a finding as file:line:column
+func findingLocation(finding LintFinding) string {
+	location := finding.File
+	if finding.Line > 0 {
+		location += fmt.Sprintf(":%d", finding.Line)
+		if finding.Column > 0 {
+			location += fmt.Sprintf(":%d", finding.Column)
+		}
+	}
+	return location
+}
+
+// junitReport returns the report as a JUnit test suite with a test case for each rule.
+// Errors fail the test case of their rule, and warnings are written to its output.
+func junitReport(report LintReport) junitTestSuites {
+	suite := junitTestSuite{Name: "appsody stack lint " + report.Stack}
+	for _, rule := range lintRules {
+		testCase := junitTestCase{Name: rule.ID, ClassName: "appsody.stack.lint." + report.Stack}
+		var warnings []string
+		for _, finding := range report.Findings {
+			if finding.RuleID != rule.ID || finding.Suppression != nil {
+				continue
+			}
+			text := findingLocation(finding) + ": " + finding.Message
+			if finding.Severity == lintSeverityError || report.Strict {
+				testCase.Failures = append(testCase.Failures, junitFailure{Message: finding.Message, Type: finding.Severity, Text: text + "\n" + finding.Fix})
+			} else {
+				warnings = append(warnings, "[Warning] "+text)
+			}
+		}
+		testCase.SystemOut = strings.Join(warnings, "\n")
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = len(suite.TestCases)
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+// formatLintReport returns the report in the json, sarif or junit format of appsody stack lint -o
+func formatLintReport(output string, report LintReport, sourcePath string) (string, error) {
+	var data []byte
+	var err error
+	switch output {
+	case "json":
+		data, err = json.Marshal(report)
+	case "sarif":
+		data, err = json.Marshal(sarifReport(report, sourcePath))
+	case "junit":
+		data, err = xml.MarshalIndent(junitReport(report), "", "  ")
+		if err == nil {
+			data = append([]byte(xml.Header), data...)
+		}
+	default:
+		return "", errors.Errorf("The output format %s is not supported. Use json, sarif or junit", output)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -14,12 +14,16 @@
 package cmd_test
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
+	cmd "github.com/appsody/appsody/cmd"
 	"github.com/appsody/appsody/cmd/cmdtest"
 )
 
@@ -179,3 +183,348 @@ func TestLinterMissingValues(t *testing.T) {
 		})
 	}
 }
+
+// newInvalidKillStack returns a copy of the test stack with an invalid _KILL value in its Dockerfile-stack
+func newInvalidKillStack(t *testing.T, sandbox *cmdtest.TestSandbox) string {
+	t.Helper()
+	testStackPath := filepath.Join(sandbox.TestDataPath, "test-stack")
+	dockerfile := filepath.Join(testStackPath, "image", "Dockerfile-stack")
+	file, err := ioutil.ReadFile(dockerfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(file), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "_KILL") {
+			lines[i] = "ENV APPSODY_DEBUG_KILL=trued"
+		}
+	}
+	err = ioutil.WriteFile(dockerfile, []byte(strings.Join(lines, "\n")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testStackPath
+}
+
+func TestLintJSONOutput(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := newInvalidKillStack(t, sandbox)
+
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "-o", "json")
+	if err == nil {
+		t.Fatal("Expected the lint to fail with an invalid _KILL value")
+	}
+	var report cmd.LintReport
+	err = json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &report)
+	if err != nil {
+		t.Fatalf("Could not parse the json output: %v\n%s", err, output)
+	}
+	if report.Stack != "test-stack" || report.Passed || report.Errors != 1 {
+		t.Errorf("Expected one error in test-stack, got %+v", report)
+	}
+	var finding *cmd.LintFinding
+	for i := range report.Findings {
+		if report.Findings[i].RuleID == "kill-value" {
+			finding = &report.Findings[i]
+		}
+	}
+	if finding == nil {
+		t.Fatalf("Expected a kill-value finding, got %+v", report.Findings)
+	}
+	if finding.Severity != "error" || finding.File != "image/Dockerfile-stack" || finding.Line == 0 || finding.Column != 5 || finding.Fix == "" {
+		t.Errorf("Expected the finding to have the location of the variable and a fix, got %+v", finding)
+	}
+	if strings.Contains(output, "LINTING") || strings.Contains(output, "TOTAL ERRORS") {
+		t.Errorf("Expected the json output not to have the text output, got:\n%s", output)
+	}
+}
+
+func TestLintSARIFOutput(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := newInvalidKillStack(t, sandbox)
+	// lint the stack from the parent directory of the test data, like a CI job that lints a stack in its repository
+	sandbox.ProjectDir = filepath.Dir(sandbox.TestDataPath)
+
+	output, _ := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "-o", "sarif")
+	var sarif struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string
+					}
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string
+						}
+						Region struct {
+							StartLine int
+						}
+					}
+				}
+			}
+		}
+	}
+	err := json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &sarif)
+	if err != nil {
+		t.Fatalf("Could not parse the sarif output: %v\n%s", err, output)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Tool.Driver.Rules) == 0 {
+		t.Fatalf("Expected a SARIF 2.1.0 log with the rules of the linter, got %+v", sarif)
+	}
+	found := false
+	for _, result := range sarif.Runs[0].Results {
+		if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation.Region.StartLine == 0 {
+			t.Errorf("Expected each result to have a location with a start line, got %+v", result)
+		}
+		if result.RuleID == "kill-value" {
+			found = true
+			if result.Level != "error" || result.Locations[0].PhysicalLocation.ArtifactLocation.URI != filepath.Base(sandbox.TestDataPath)+"/test-stack/image/Dockerfile-stack" {
+				t.Errorf("Expected an error in the Dockerfile-stack of the stack, got %+v", result)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a kill-value result, got %+v", sarif.Runs[0].Results)
+	}
+}
+
+func TestLintJUnitOutput(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := newInvalidKillStack(t, sandbox)
+
+	output, _ := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "-o", "junit")
+	start := strings.Index(output, "<testsuites>")
+	end := strings.Index(output, "</testsuites>")
+	if start < 0 || end < 0 {
+		t.Fatalf("Expected a JUnit report, got:\n%s", output)
+	}
+	var junit struct {
+		Suites []struct {
+			Failures  int `xml:"failures,attr"`
+			TestCases []struct {
+				Name     string `xml:"name,attr"`
+				Failures []struct {
+					Type string `xml:"type,attr"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	err := xml.Unmarshal([]byte(output[start:end+len("</testsuites>")]), &junit)
+	if err != nil {
+		t.Fatalf("Could not parse the junit output: %v\n%s", err, output)
+	}
+	if len(junit.Suites) != 1 || junit.Suites[0].Failures != 1 {
+		t.Fatalf("Expected one failed test case, got %+v", junit)
+	}
+	for _, testCase := range junit.Suites[0].TestCases {
+		if (len(testCase.Failures) > 0) != (testCase.Name == "kill-value") {
+			t.Errorf("Expected only the kill-value test case to fail, got %+v", testCase)
+		}
+	}
+}
+
+func TestLintInvalidOutput(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "lint", filepath.Join(sandbox.TestDataPath, "test-stack"), "-o", "xml")
+	if err == nil || !strings.Contains(output, "The output format xml is not supported") {
+		t.Errorf("Expected an error for an unsupported output format, got:\n%s", output)
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := filepath.Join(sandbox.TestDataPath, "test-stack")
+
+	// the test stack has warnings, so it only passes without --strict
+	_, err := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "--strict")
+	if err == nil {
+		t.Fatal("Expected the lint to fail with --strict when there are warnings")
+	}
+
+	lintConfig := `rules:
+  project-dir-missing: off
+  env-missing: error
+suppressions:
+  - rule: env-missing
+    message: APPSODY_DEBUG_PORT
+    reason: The stack is not debugged through a port
+  - rule: env-missing
+    file: image/Dockerfile-stack
+`
+	err = ioutil.WriteFile(filepath.Join(testStackPath, ".appsody-lint.yaml"), []byte(lintConfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dockerfile := filepath.Join(testStackPath, "image", "Dockerfile-stack")
+	file, err := ioutil.ReadFile(dockerfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := strings.Replace(string(file), "ENV APPSODY_INSTALL=", "# appsody-lint-ignore: install-deprecated kept for older versions of the CLI\nENV APPSODY_INSTALL=", 1)
+	err = ioutil.WriteFile(dockerfile, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "--strict", "-o", "json")
+	if err != nil {
+		t.Fatalf("Expected the lint to pass with every warning suppressed: %v\n%s", err, output)
+	}
+	var report cmd.LintReport
+	err = json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &report)
+	if err != nil {
+		t.Fatalf("Could not parse the json output: %v\n%s", err, output)
+	}
+	if report.Errors != 0 || report.Warnings != 0 || report.Suppressed != 4 || !report.Passed || !report.Strict {
+		t.Errorf("Expected 4 suppressed findings and no errors or warnings, got %+v", report)
+	}
+	for _, finding := range report.Findings {
+		switch {
+		case finding.Suppression == nil:
+			t.Errorf("Expected every finding to be suppressed, got %+v", finding)
+		case finding.RuleID == "install-deprecated":
+			if finding.Suppression.Kind != "comment" || finding.Suppression.Reason != "kept for older versions of the CLI" {
+				t.Errorf("Expected the finding to be suppressed by the comment, got %+v", finding.Suppression)
+			}
+		case finding.RuleID == "env-missing":
+			if finding.Severity != "error" || finding.Suppression.Kind != "config" {
+				t.Errorf("Expected the finding to be an error suppressed by the config, got %+v", finding)
+			}
+		}
+	}
+
+	err = ioutil.WriteFile(filepath.Join(testStackPath, ".appsody-lint.yaml"), []byte("rules:\n  no-such-rule: off\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err = cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath)
+	if err == nil || !strings.Contains(output, "The rule no-such-rule in") {
+		t.Errorf("Expected an error for a rule that does not exist, got:\n%s", output)
+	}
+}
+
+func TestLintListRules(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	testStackPath := filepath.Join(sandbox.TestDataPath, "test-stack")
+	err := ioutil.WriteFile(filepath.Join(testStackPath, ".appsody-lint.yaml"), []byte("rules:\n  readme-missing: warning\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandbox.ProjectDir = testStackPath
+
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "lint", "--list-rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range []string{"env-missing", "mount-single-file", "templating-data-key"} {
+		if !strings.Contains(output, rule) {
+			t.Errorf("Expected the rules to include %s, got:\n%s", rule, output)
+		}
+	}
+	if !regexp.MustCompile(`readme-missing\s+warning`).MatchString(output) {
+		t.Errorf("Expected the severity of readme-missing to be changed by the lint configuration, got:\n%s", output)
+	}
+}
+
+func TestLintMultiStageDockerfile(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := filepath.Join(sandbox.TestDataPath, "test-stack")
+	dockerfile := filepath.Join(testStackPath, "image", "Dockerfile-stack")
+	file, err := ioutil.ReadFile(dockerfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the APPSODY_DEPS of the build stage does not reach the image, and the ARG default is substituted into the kill value
+	contents := "ARG BUILDER=ubi\nFROM $BUILDER AS build\nENV APPSODY_DEPS=/project/deps\nARG KILL=sometimes\n\n" +
+		strings.Replace(string(file), "ENV APPSODY_TEST_KILL=true", "ARG KILL=maybe\nENV APPSODY_TEST_KILL=$KILL \\\n    APPSODY_DEBUG_KILL=false", 1)
+	err = ioutil.WriteFile(dockerfile, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, _ := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "-o", "json")
+	var report cmd.LintReport
+	err = json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &report)
+	if err != nil {
+		t.Fatalf("Could not parse the json output: %v\n%s", err, output)
+	}
+	expected := map[string]cmd.LintFinding{
+		"env-not-final-stage": {Line: 3, Column: 5, Message: "APPSODY_DEPS is set in a build stage that is not the final stage, so it is not in the stack image"},
+		"kill-value":          {Line: 21, Column: 5, Message: "APPSODY_TEST_KILL can only have value true/false"},
+		"install-deprecated":  {Line: 18, Column: 5, Message: "APPSODY_INSTALL has been deprecated. Please use APPSODY_PREP instead"},
+	}
+	found := make(map[string]bool)
+	for _, finding := range report.Findings {
+		if finding.RuleID == "env-missing" {
+			// variables that are missing are reported on the FROM of the final stage
+			if finding.Line != 7 {
+				t.Errorf("Expected %s on line 7, got %+v", finding.Message, finding)
+			}
+			if finding.Message == "Missing APPSODY_DEPS" {
+				found[finding.RuleID] = true
+			}
+			continue
+		}
+		if want, ok := expected[finding.RuleID]; ok {
+			found[finding.RuleID] = true
+			if finding.Line != want.Line || finding.Column != want.Column || finding.Message != want.Message {
+				t.Errorf("Expected %s at %d:%d with %q, got %+v", finding.RuleID, want.Line, want.Column, want.Message, finding)
+			}
+		}
+	}
+	for _, rule := range []string{"env-not-final-stage", "kill-value", "install-deprecated", "env-missing"} {
+		if !found[rule] {
+			t.Errorf("Expected a %s finding, got %+v", rule, report.Findings)
+		}
+	}
+}
+
+func TestLintInvalidDockerfile(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+	sandbox.Verbose = false
+	testStackPath := filepath.Join(sandbox.TestDataPath, "test-stack")
+	dockerfile := filepath.Join(testStackPath, "image", "Dockerfile-stack")
+	err := ioutil.WriteFile(dockerfile, []byte("FROM ubi\n\nENV APPSODY_RUN=${RUN\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := cmdtest.RunAppsody(sandbox, "stack", "lint", testStackPath, "-o", "json")
+	if err == nil {
+		t.Fatal("Expected the lint to fail for a Dockerfile-stack that can not be parsed")
+	}
+	var report cmd.LintReport
+	err = json.Unmarshal([]byte(cmdtest.ParseJSON(output)), &report)
+	if err != nil {
+		t.Fatalf("Could not parse the json output: %v\n%s", err, output)
+	}
+	for _, finding := range report.Findings {
+		if finding.RuleID == "dockerfile-invalid" {
+			if finding.Line != 3 || finding.File != "image/Dockerfile-stack" {
+				t.Errorf("Expected the error to be reported on line 3 of image/Dockerfile-stack, got %+v", finding)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected a dockerfile-invalid finding, got %+v", report.Findings)
+}
@@ -72,6 +72,8 @@ type IndexYamlStack struct {
 	Maintainers     []Maintainer
 	DefaultTemplate string `yaml:"default-template"`
 	SourceURL       string `yaml:"src"`
+	SourceSHA256    string `yaml:"src-sha256,omitempty"`
+	SourceSize      int64  `yaml:"src-size,omitempty"`
 	Templates       []IndexYamlStackTemplate
 	Requirements    StackRequirement `yaml:"requirements,omitempty"`
 	Image           string           `yaml:"image"`
@@ -81,6 +83,8 @@ type IndexYamlStackTemplate struct {
 	ID        string `yaml:"id"`
 	URL       string `yaml:"url"`
 	IsDefault bool   `yaml:"default,omitempty" json:"default,omitempty"`
+	SHA256    string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	Size      int64  `yaml:"size,omitempty" json:"size,omitempty"`
 }
 
 // struct to convert yaml to json files
@@ -338,6 +342,10 @@ The packaging process builds the stack image, generates the "tar.gz" archive fil
 			if err != nil {
 				return errors.Errorf("Error trying to tar: %v", err)
 			}
+			newStackStruct.SourceSHA256, newStackStruct.SourceSize, err = ArchiveChecksum(versionArchiveTar)
+			if err != nil {
+				return err
+			}
 
 			if runtime.GOOS == "windows" {
 				// for windows, add a leading slash and convert to unix style slashes
@@ -389,8 +397,6 @@ The packaging process builds the stack image, generates the "tar.gz" archive fil
 				newTemplateStruct.ID = templates[i]
 				newTemplateStruct.URL = versionArchiveTar
 
-				newStackStruct.Templates = append(newStackStruct.Templates, newTemplateStruct)
-
 				// create a config yaml file for the tarball
 				configYaml := filepath.Join(templatePath, templates[i], ConfigFile)
 				log.Debug.Log("configYaml is: ", configYaml)
@@ -417,6 +423,11 @@ The packaging process builds the stack image, generates the "tar.gz" archive fil
 				if err != nil {
 					return errors.Errorf("Error trying to tar: %v", err)
 				}
+				newTemplateStruct.SHA256, newTemplateStruct.Size, err = ArchiveChecksum(versionedArchive + templates[i] + ".tar.gz")
+				if err != nil {
+					return err
+				}
+				newStackStruct.Templates = append(newStackStruct.Templates, newTemplateStruct)
 
 				// remove the config yaml file
 				err = os.Remove(configYaml)
@@ -0,0 +1,430 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// the number of times the index is read and written again when someone else changes it at the same time
+const publishIndexAttempts = 3
+
+type stackPublishCommandConfig struct {
+	*RootCommandConfig
+	imageRegistry  string
+	imageNamespace string
+	releaseTarget  string
+	releaseURL     string
+	indexName      string
+	signKey        string
+}
+
+// StackImageTags returns the tags that appsody stack package gives the image of a stack version:
+// the full version, major.minor, major and latest
+func StackImageTags(version string) ([]string, error) {
+	versionFull := strings.Split(version, ".")
+	if len(versionFull) != 3 {
+		return nil, errors.Errorf("The stack version %s is not in the major.minor.patch format", version)
+	}
+	return []string{version, versionFull[0] + "." + versionFull[1], versionFull[0], "latest"}, nil
+}
+
+// imageRepository returns an image reference without its tag
+func imageRepository(image string) string {
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[:colon]
+	}
+	return image
+}
+
+// RewriteTemplateArchive sets the stack image in the .appsody-config.yaml of a template archive,
+// so that projects created from the published template use the published image.
+// The archive is returned unchanged if it already uses the image or has no .appsody-config.yaml.
+func RewriteTemplateArchive(archive []byte, stackImage string) ([]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Errorf("Could not read the template archive: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	var rewritten bytes.Buffer
+	gzipWriter := gzip.NewWriter(&rewritten)
+	tarWriter := tar.NewWriter(gzipWriter)
+	changed := false
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Errorf("Could not read the template archive: %v", err)
+		}
+		contents, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, errors.Errorf("Could not read %s from the template archive: %v", header.Name, err)
+		}
+		// archives created on windows use backslashes
+		if path.Clean(strings.Replace(header.Name, "\\", "/", -1)) == ConfigFile && header.Typeflag == tar.TypeReg {
+			var projectConfig yaml.MapSlice
+			err = yaml.Unmarshal(contents, &projectConfig)
+			if err != nil {
+				return nil, errors.Errorf("Could not parse %s in the template archive: %v", ConfigFile, err)
+			}
+			found := false
+			for i := range projectConfig {
+				if projectConfig[i].Key == "stack" {
+					found = true
+					if projectConfig[i].Value != stackImage {
+						projectConfig[i].Value = stackImage
+						changed = true
+					}
+				}
+			}
+			if !found {
+				projectConfig = append(projectConfig, yaml.MapItem{Key: "stack", Value: stackImage})
+				changed = true
+			}
+			contents, err = yaml.Marshal(projectConfig)
+			if err != nil {
+				return nil, err
+			}
+			header.Size = int64(len(contents))
+		}
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return nil, errors.Errorf("Could not write the template archive: %v", err)
+		}
+		if _, err = tarWriter.Write(contents); err != nil {
+			return nil, errors.Errorf("Could not write the template archive: %v", err)
+		}
+	}
+	if !changed {
+		return archive, nil
+	}
+	if err = tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return rewritten.Bytes(), nil
+}
+
+// stackPublisher uploads the archives and the index entry of a packaged stack to a release target
+type stackPublisher struct {
+	log        *LoggingConfig
+	target     ReleaseTarget
+	releaseURL string
+	dryrun     bool
+}
+
+// url returns the URL of a published file in the repository index
+func (p *stackPublisher) url(name string) string {
+	if p.releaseURL != "" {
+		return strings.TrimSuffix(p.releaseURL, "/") + "/" + name
+	}
+	return p.target.URL(name)
+}
+
+func (p *stackPublisher) upload(name string, data []byte) error {
+	if p.dryrun {
+		p.log.Info.logf("Dry Run - Skipping upload of %s to %s", name, p.target.URL(name))
+		return nil
+	}
+	p.log.Info.logf("Uploading %s", p.target.URL(name))
+	return p.target.Put(name, data, "")
+}
+
+// PublishStackArchives uploads the source and template archives of a packaged stack, with the templates set to use
+// the published image, and returns the entry of the stack for the published repository index.
+// If releaseURL is not empty, the archives are downloaded from URLs under it instead of the URLs of the release target.
+func PublishStackArchives(log *LoggingConfig, target ReleaseTarget, releaseURL string, packaged IndexYamlStack, stackImage string, dryrun bool) (IndexYamlStack, error) {
+	p := &stackPublisher{log: log, target: target, releaseURL: releaseURL, dryrun: dryrun}
+	published := packaged
+	published.Image = stackImage
+	published.Templates = make([]IndexYamlStackTemplate, 0, len(packaged.Templates))
+
+	source, err := ioutil.ReadFile(filePath(packaged.SourceURL))
+	if err != nil {
+		return published, errors.Errorf("Could not read the source archive of stack %s: %v. Run appsody stack package again.", packaged.ID, err)
+	}
+	name := path.Base(filepath.ToSlash(filePath(packaged.SourceURL)))
+	if err = p.upload(name, source); err != nil {
+		return published, err
+	}
+	published.SourceURL = p.url(name)
+
+	// templates refer to the image by its major.minor tag, like appsody stack package does
+	templateImage := stackImage
+	if tags, err := StackImageTags(packaged.Version); err == nil {
+		templateImage = imageRepository(stackImage) + ":" + tags[1]
+	}
+	for _, template := range packaged.Templates {
+		archive, err := ioutil.ReadFile(filePath(template.URL))
+		if err != nil {
+			return published, errors.Errorf("Could not read the archive of template %s: %v. Run appsody stack package again.", template.ID, err)
+		}
+		archive, err = RewriteTemplateArchive(archive, templateImage)
+		if err != nil {
+			return published, errors.Wrapf(err, "Could not set the stack image of template %s", template.ID)
+		}
+		name := path.Base(filepath.ToSlash(filePath(template.URL)))
+		if err = p.upload(name, archive); err != nil {
+			return published, err
+		}
+		checksum := newChecksumWriter()
+		_, _ = checksum.Write(archive)
+		template.URL = p.url(name)
+		template.SHA256 = checksum.sum()
+		template.Size = checksum.size
+		published.Templates = append(published.Templates, template)
+	}
+	return published, nil
+}
+
+// UpdateReleaseIndex adds the stack to the repository index of the release target, replacing the entry for the same version.
+// The index is uploaded in a single request after the archives. When the target returns an ETag for the index,
+// it is only replaced if nobody else changed it in the meantime, and it is read again otherwise.
+func UpdateReleaseIndex(log *LoggingConfig, target ReleaseTarget, indexName string, stack IndexYamlStack, keyPEM []byte, dryrun bool) error {
+	for attempt := 1; ; attempt++ {
+		current, err := target.Get(indexName)
+		if err != nil {
+			return err
+		}
+		var indexYaml IndexYaml
+		if current == nil {
+			log.Info.log("Creating repository index ", target.URL(indexName))
+			indexYaml.APIVersion = supportedIndexAPIVersion
+			indexYaml.Stacks = make([]IndexYamlStack, 0, 1)
+		} else {
+			log.Info.log("Updating repository index ", target.URL(indexName))
+			err = yaml.Unmarshal(current.Data, &indexYaml)
+			if err != nil {
+				return errors.Errorf("Could not parse the repository index %s: %v", target.URL(indexName), err)
+			}
+		}
+		indexYaml = addStackVersion(log, indexYaml, stack)
+		data, err := yaml.Marshal(indexYaml)
+		if err != nil {
+			return err
+		}
+		if dryrun {
+			log.Info.logf("Dry Run - Skipping upload of %s", target.URL(indexName))
+			return nil
+		}
+
+		etag := ""
+		if current != nil {
+			etag = current.ETag
+		}
+		err = target.Put(indexName, data, etag)
+		if err == ErrReleaseFileChanged && attempt < publishIndexAttempts {
+			log.Warning.logf("The repository index %s changed while it was being updated, trying again", target.URL(indexName))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		signatureName := indexName + indexSignatureSuffix
+		if keyPEM == nil {
+			if signature, err := target.Get(signatureName); err == nil && signature != nil {
+				log.Warning.logf("The repository index has a signature %s that no longer matches it, use --sign-key to sign the updated index", target.URL(signatureName))
+			}
+			return nil
+		}
+		signature, err := SignIndex(data, keyPEM)
+		if err != nil {
+			return err
+		}
+		err = target.Put(signatureName, signature, "")
+		if err != nil {
+			return err
+		}
+		log.Info.log("Index signature uploaded to ", target.URL(signatureName))
+		return nil
+	}
+}
+
+// tagImage adds a tag to a local image
+func tagImage(config *RootCommandConfig, image string, tag string) error {
+	cmdName := "docker"
+	if config.Buildah {
+		cmdName = "buildah"
+	}
+	cmdArgs := []string{"tag", image, tag}
+	if config.Dryrun {
+		config.Info.log("Dry run - skipping execution of: ", cmdName, " ", strings.Join(cmdArgs, " "))
+		return nil
+	}
+	config.Debug.log("Running command: ", cmdName, " ", strings.Join(cmdArgs, " "))
+	output, err := SeparateOutput(exec.Command(cmdName, cmdArgs...))
+	if err != nil {
+		return errors.Errorf("Could not tag the image %s as %s: %v %s", image, tag, err, output)
+	}
+	return nil
+}
+
+// publishStackImages tags the packaged image of a stack with every tag of the version in the published repository, and pushes the tags
+func publishStackImages(config *RootCommandConfig, packagedImage string, publishedRepo string, version string) error {
+	tags, err := StackImageTags(version)
+	if err != nil {
+		return err
+	}
+	packagedRepo := imageRepository(packagedImage)
+	for _, tag := range tags {
+		published := publishedRepo + ":" + tag
+		if packagedRepo != publishedRepo {
+			err = tagImage(config, packagedRepo+":"+tag, published)
+			if err != nil {
+				return err
+			}
+		}
+		err = ImagePush(config.LoggingConfig, published, config.Buildah, config.Dryrun)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStackPublishCmd(rootConfig *RootCommandConfig) *cobra.Command {
+	config := &stackPublishCommandConfig{RootCommandConfig: rootConfig}
+	log := rootConfig.LoggingConfig
+
+	var stackPublishCmd = &cobra.Command{
+		Use:   "publish",
+		Short: "Publish a packaged stack to an image registry and a release store",
+		Long: `Publishes the stack that you packaged with appsody stack package, so that others can use it.
+
+The image of the stack is tagged with the registry and namespace that you specify, and every tag that appsody stack package creates is pushed: the full version, major.minor, major and latest. Log in to the registry first, for example with docker login.
+
+The source and template archives are uploaded to the release target, and the stack is added to the repository index of the release target, replacing the entry for the same version. The release target is one of:
+  - a directory, for example one that a web server serves. Files are written to a temporary file and renamed.
+  - an http:// or https:// URL that accepts PUT requests. The credentials that you set with appsody repo add for the host are used.
+  - an s3://<bucket>/<prefix> location. The credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and the region from AWS_REGION. Set AWS_ENDPOINT_URL for S3 compatible object stores, such as MinIO.
+
+The index is uploaded last, in a single request, so that it never refers to archives that are not uploaded yet. If the target returns an ETag for the index, the index is only replaced if nobody else changed it while it was being updated.
+
+The templates are changed to use the published image. If your templates use other image variables, such as {{.stack.image.registry}}, run appsody stack package with the same --image-registry and --image-namespace before you publish.
+
+Run this command from the root directory of your stack.`,
+		Example: `  appsody stack publish --image-registry quay.io --image-namespace my-org --release-target s3://my-bucket/stacks
+  Pushes the image of the stack to quay.io/my-org, uploads its archives to the "stacks" folder of the "my-bucket" bucket, and adds the stack to the index.yaml in that folder
+
+  appsody stack publish --image-namespace my-org --release-target /var/www/stacks --release-url https://stacks.example.com
+  Pushes the image of the stack to docker.io/my-org, writes its archives and index to /var/www/stacks, and uses URLs under https://stacks.example.com in the index
+
+  appsody stack publish --image-namespace my-org --release-target https://artifacts.example.com/stacks --index my-stacks-index.yaml --sign-key my-key.pem
+  Uploads the archives with PUT requests, and adds the stack to my-stacks-index.yaml, which is signed with the my-key.pem private key`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
+			}
+			if config.releaseTarget == "" {
+				return errors.New("Required flag missing. You must specify the directory or URL to publish the stack to with --release-target")
+			}
+			if config.indexName == "" || path.IsAbs(config.indexName) || strings.HasPrefix(path.Clean(config.indexName), "..") {
+				return errors.Errorf("The index %s must be a path relative to the release target", config.indexName)
+			}
+
+			stackPath := rootConfig.ProjectDir
+			log.Debug.Log("stackPath is: ", stackPath)
+
+			// check for templates dir, error out if its not there
+			check, err := Exists(filepath.Join(stackPath, "templates"))
+			if err != nil {
+				return errors.New("Error checking stack root directory: " + err.Error())
+			}
+			if !check {
+				// if we can't find the templates directory then we are not starting from a valid root of the stack directory
+				return errors.New("Unable to reach templates directory. Current directory must be the root of the stack")
+			}
+
+			stackID := filepath.Base(stackPath)
+			stackYaml, err := getStackData(stackPath)
+			if err != nil {
+				return err
+			}
+
+			devLocalIndexFile := filepath.Join(getHome(rootConfig), "stacks", "dev.local", "dev.local-index.yaml")
+			source, err := ioutil.ReadFile(devLocalIndexFile)
+			if err != nil {
+				return errors.Errorf("Could not read %s: %v. Run appsody stack package on your stack before running this command.", devLocalIndexFile, err)
+			}
+			var devLocalIndex IndexYaml
+			err = yaml.Unmarshal(source, &devLocalIndex)
+			if err != nil {
+				return errors.Errorf("Could not parse %s: %v", devLocalIndexFile, err)
+			}
+			packaged, err := FindStackVersion(&devLocalIndex, stackID, stackYaml.Version)
+			if err != nil || packaged == nil || packaged.Version != stackYaml.Version {
+				return errors.Errorf("Couldn't find version %s of stack %s in dev.local-index.yaml. Run appsody stack package on your stack before running this command.", stackYaml.Version, stackID)
+			}
+
+			publishedRepo := config.imageNamespace + "/" + stackID
+			if config.imageRegistry != "" {
+				publishedRepo = config.imageRegistry + "/" + publishedRepo
+			}
+			log.Debug.logf("The stack was packaged as %s and is published as %s", packaged.Image, publishedRepo)
+
+			target, err := NewReleaseTarget(log, getCredentialStore(rootConfig), config.releaseTarget)
+			if err != nil {
+				return err
+			}
+			var keyPEM []byte
+			if config.signKey != "" {
+				keyPEM, err = ioutil.ReadFile(config.signKey)
+				if err != nil {
+					return errors.Errorf("Could not read the signing key %s: %v", config.signKey, err)
+				}
+			}
+
+			err = publishStackImages(rootConfig, packaged.Image, publishedRepo, packaged.Version)
+			if err != nil {
+				return err
+			}
+			published, err := PublishStackArchives(log, target, config.releaseURL, *packaged, publishedRepo+":"+packaged.Version, rootConfig.Dryrun)
+			if err != nil {
+				return err
+			}
+			err = UpdateReleaseIndex(log, target, config.indexName, published, keyPEM, rootConfig.Dryrun)
+			if err != nil {
+				return err
+			}
+			log.Info.logf("Published version %s of stack %s. Add the repository with: appsody repo add <name> %s", packaged.Version, stackID, target.URL(config.indexName))
+			return nil
+		},
+	}
+
+	stackPublishCmd.PersistentFlags().StringVar(&config.imageRegistry, "image-registry", "docker.io", "Registry that the images are pushed to.")
+	stackPublishCmd.PersistentFlags().StringVar(&config.imageNamespace, "image-namespace", "appsody", "Namespace that the images are pushed to.")
+	stackPublishCmd.PersistentFlags().StringVar(&config.releaseTarget, "release-target", "", "The directory, http(s):// URL or s3://<bucket>/<prefix> location that the archives and the repository index are uploaded to")
+	stackPublishCmd.PersistentFlags().StringVar(&config.releaseURL, "release-url", "", "URL to use within the repository to access the stack assets, if it is not the URL of the release target")
+	stackPublishCmd.PersistentFlags().StringVar(&config.indexName, "index", "index.yaml", "The repository index to add the stack to, relative to the release target")
+	stackPublishCmd.PersistentFlags().StringVar(&config.signKey, "sign-key", "", "A PEM encoded private key file used to upload a detached signature of the index")
+	stackPublishCmd.PersistentFlags().BoolVar(&rootConfig.Buildah, "buildah", false, "Tag and push the images with buildah instead of Docker.")
+	return stackPublishCmd
+}
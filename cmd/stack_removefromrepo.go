@@ -28,22 +28,27 @@ import (
 
 func newStackRemoveFromRepoCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	var stackName string
+	var stackVersion string
 	var repoName string
 	var useLocalCache bool
+	var signKey string
 
 	log := rootConfig.LoggingConfig
 
 	var stackRemoveFromRepoCmd = &cobra.Command{
-		Use:   "remove-from-repo <repository> <stack>",
+		Use:   "remove-from-repo <repository> <stack>[@<version>]",
 		Short: "Remove stack information from an Appsody repository",
 		Long: `Removes stack information from an Appsody repository. 
 		
-Removes stack information, specified by <stack> from an Appsody repository, specified by the <repository> argument.
+Removes stack information, specified by <stack> from an Appsody repository, specified by the <repository> argument. If the repository has several versions of the stack, all of them are removed, unless you specify the version to remove as <stack>@<version>.
 
 The updated repository index file is created in  ~/.appsody/stacks/dev.local directory.`,
 
 		Example: `  appsody stack remove-from-repo incubator nodejs
-  Updates the repository index file for the incubator repository, removing the definition of the nodejs stack`,
+  Updates the repository index file for the incubator repository, removing the definition of the nodejs stack
+
+  appsody stack remove-from-repo incubator nodejs@0.3.1
+  Updates the repository index file for the incubator repository, removing version 0.3.1 of the nodejs stack`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			log.Info.Log("******************************************")
@@ -58,10 +63,15 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 			}
 
 			repoName = args[0]
-			stackName = args[1]
+			var err error
+			stackName, stackVersion, err = SplitStackVersion(args[1])
+			if err != nil {
+				return err
+			}
 
 			log.Debug.Log("repoName is: ", repoName)
 			log.Debug.Log("stackName is: ", stackName)
+			log.Debug.Log("stackVersion is: ", stackVersion)
 
 			var repoFile RepositoryFile
 
@@ -72,7 +82,7 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 			log.Debug.Log("devLocal is: ", devLocal)
 
 			// create the devLocal directory in appsody home
-			err := os.MkdirAll(devLocal, os.FileMode(0755))
+			err = os.MkdirAll(devLocal, os.FileMode(0755))
 			if err != nil {
 				return errors.Errorf("Error creating directory: %v", err)
 			}
@@ -109,7 +119,7 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 						log.Debug.Log(localIndexFile, " doesnt exist in the appsody directory")
 						log.Info.Log("Downloading the remote index file from: ", url)
 						log.Info.log("Creating repository index file: ", localIndexFile)
-						err := downloadFileToDisk(log, url, localIndexFile, false)
+						err := downloadFileToDisk(log, getCredentialStore(rootConfig), url, localIndexFile, false)
 						if err != nil {
 							return err
 						}
@@ -144,9 +154,9 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 
 			// At this point we should have the indexFile loaded that want to use for updating / adding stack info
 			// find the index of the stack
-			indexYaml, stackExists := findStackAndRemove(log, stackName, indexYaml)
+			indexYaml, stackExists := findStackVersionAndRemove(log, stackName, stackVersion, indexYaml)
 			if !stackExists {
-				log.Info.Logf("Stack: %v does not exist in repository index file", stackName)
+				log.Info.Logf("Stack: %v does not exist in repository index file", args[1])
 				return nil
 			}
 
@@ -164,6 +174,10 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 			if err != nil {
 				return errors.Errorf("Could not generate json file from yaml index: %v", err)
 			}
+			err = signIndexFile(log, localIndexFile, signKey)
+			if err != nil {
+				return err
+			}
 
 			log.Info.Log("Repository index file updated successfully")
 
@@ -172,6 +186,7 @@ The updated repository index file is created in  ~/.appsody/stacks/dev.local dir
 	}
 
 	stackRemoveFromRepoCmd.PersistentFlags().BoolVar(&useLocalCache, "use-local-cache", false, "Whether to use a local file if exists or create a new file")
+	stackRemoveFromRepoCmd.PersistentFlags().StringVar(&signKey, "sign-key", "", "A PEM encoded private key file used to write a detached signature of the index")
 
 	return stackRemoveFromRepoCmd
 }
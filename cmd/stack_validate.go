@@ -16,7 +16,9 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -31,6 +33,21 @@ import (
 // 4. appsody run
 // 5. appsody test
 // 6. appsody build
+// Steps 3 to 6 run for each template, in its own project directory, so that templates can be validated in parallel.
+
+// templateValidation validates a template of a packaged stack in its own project directory and container,
+// so that it does not collide with the validation of other templates
+type templateValidation struct {
+	rootConfig    *RootCommandConfig
+	stack         string
+	template      string
+	projectDir    string
+	containerName string
+	// publishAll publishes the ports of the containers to ports that docker assigns
+	publishAll bool
+	// prefix is printed before the output of the template, to tell apart the output of templates validated in parallel
+	prefix string
+}
 
 func newStackValidateCmd(rootConfig *RootCommandConfig) *cobra.Command {
 
@@ -39,6 +56,9 @@ func newStackValidateCmd(rootConfig *RootCommandConfig) *cobra.Command {
 	var noLint bool
 	var imageNamespace string
 	var imageRegistry string
+	var parallel int
+	var reportFile string
+	var selectedTemplates []string
 
 	var stackValidateCmd = &cobra.Command{
 		Use:   "validate",
@@ -52,24 +72,39 @@ Runs the following validation tests against the stack and its templates:
   * appsody run 
   * appsody test 
   * appsody build
+
+Each template is validated in its own project directory and container. Use --parallel to validate several templates at the same time, in which case the ports of the containers are published to ports that docker assigns, and each line of output starts with the name of its template. Use --templates to validate some of the templates only.
+
+Use --report to write the result of each step, with its duration, output and the reason it failed, to a file. The format of the report is JUnit XML if the file name ends with .xml, and JSON if it ends with .json.
   
 Run this command from the root directory of your Appsody project.`,
+		Example: `  appsody stack validate
+  Validates the stack in the current directory and all its templates
+
+  appsody stack validate --parallel 3 --report validate.xml
+  Validates three templates at a time, and writes a JUnit report to validate.xml
+
+  appsody stack validate --no-package --templates simple,scaffold
+  Validates the simple and scaffold templates of the stack that is already packaged`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			if len(args) > 0 {
 				return errors.New("Unexpected argument. Use 'appsody [command] --help' for more information about a command")
 			}
-			// vars to store test results
-			var testResults []string
-			var initFail bool // if init fails we can skip the rest of the tests
-			failCount := 0
-			passCount := 0
+			if parallel < 1 {
+				return errors.Errorf("The number of templates to validate in parallel must be at least 1, not %d", parallel)
+			}
+			if reportFile != "" {
+				if _, err := validationReportFormat(reportFile); err != nil {
+					return err
+				}
+			}
 
 			stackPath := rootConfig.ProjectDir
 			rootConfig.Info.Log("stackPath is: ", stackPath)
 
 			// check for templates dir, error out if its not there
-			check, err := Exists("templates")
+			check, err := Exists(filepath.Join(stackPath, "templates"))
 			if err != nil {
 				return errors.New("Error checking stack root directory: " + err.Error())
 			}
@@ -78,6 +113,12 @@ Run this command from the root directory of your Appsody project.`,
 				return errors.New("Unable to reach templates directory. Current directory must be the root of the stack")
 			}
 
+			// find the templates to validate before validating anything
+			templates, err := validationTemplates(filepath.Join(stackPath, "templates"), selectedTemplates)
+			if err != nil {
+				return err
+			}
+
 			// get the stack name from the stack path
 			stackName := filepath.Base(stackPath)
 			rootConfig.Info.Log("stackName is: ", stackName)
@@ -91,60 +132,30 @@ Run this command from the root directory of your Appsody project.`,
 				return errors.Errorf("Could not set environment variable APPSODY_PULL_POLICY. %v", err)
 			}
 
+			report := ValidationReport{Stack: stackName}
+			start := time.Now()
+
 			// call tests...
 
 			// lint
 			if !noLint {
-				_, err = RunAppsodyCmdExec([]string{"stack", "lint"}, stackPath, rootConfig)
-				if err != nil {
-					//logs error but keeps going
-					rootConfig.Error.Log(err)
-					testResults = append(testResults, ("FAILED: Lint for stack:" + stackName))
-					failCount++
-				} else {
-					testResults = append(testResults, ("PASSED: Lint for stack:" + stackName))
-					passCount++
-				}
+				report.Steps = append(report.Steps, runValidationStep(rootConfig.LoggingConfig, "lint", "", func() (string, error) {
+					return RunAppsodyCmdExec([]string{"stack", "lint"}, stackPath, rootConfig)
+				}))
 			}
 
 			// package
 			if !noPackage {
-				_, err = RunAppsodyCmdExec([]string{"stack", "package", "--image-namespace", imageNamespace, "--image-registry", imageRegistry},
-					stackPath, rootConfig)
-				if err != nil {
-					//logs error but keeps going
-					rootConfig.Error.Log(err)
-					testResults = append(testResults, ("FAILED: Package for stack:" + stackName))
-					failCount++
-				} else {
-					testResults = append(testResults, ("PASSED: Package for stack:" + stackName))
-					passCount++
-				}
-			}
-
-			// find and open the template path so we can loop through the templates
-			templatePath := filepath.Join(stackPath, "templates")
-
-			t, err := os.Open(templatePath)
-			if err != nil {
-				return errors.Errorf("Error opening directory: %v", err)
+				report.Steps = append(report.Steps, runValidationStep(rootConfig.LoggingConfig, "package", "", func() (string, error) {
+					return RunAppsodyCmdExec([]string{"stack", "package", "--image-namespace", imageNamespace, "--image-registry", imageRegistry},
+						stackPath, rootConfig)
+				}))
 			}
 
-			templates, err := t.Readdirnames(0)
-			if err != nil {
-				return errors.Errorf("Error reading directories: %v", err)
-			}
-
-			// loop through the template directories and create the id and url
-			for i := range templates {
-				rootConfig.Debug.Log("template is: ", templates[i])
-				if strings.Contains(templates[i], ".DS_Store") {
-					rootConfig.Debug.Log("Ignoring .DS_Store")
-					continue
-				}
-
-				// create temp project directory in the .appsody directory
-				projectDir := filepath.Join(getHome(rootConfig), "stacks", "validating-"+stackName)
+			// create a project directory in the .appsody directory for each template
+			validations := make([]*templateValidation, 0, len(templates))
+			for _, template := range templates {
+				projectDir := filepath.Join(getHome(rootConfig), "stacks", "validating-"+stackName+"-"+template)
 				rootConfig.Debug.Log("projectDir is: ", projectDir)
 
 				projectDirExists, err := Exists(projectDir)
@@ -168,74 +179,72 @@ Run this command from the root directory of your Appsody project.`,
 
 				defer os.RemoveAll(projectDir)
 
-				stack := "dev.local/" + stackName
-
-				// init
-				err = TestInit(rootConfig.LoggingConfig, stack, templates[i], projectDir, rootConfig)
-				if err != nil {
-					rootConfig.Error.Log(err)
-					testResults = append(testResults, ("FAILED: Init for stack:" + stackName + " template:" + templates[i]))
-					failCount++
-					initFail = true
-				} else {
-					testResults = append(testResults, ("PASSED: Init for stack:" + stackName + " template:" + templates[i]))
-					passCount++
-					initFail = false
+				validation := &templateValidation{
+					rootConfig:    rootConfig,
+					stack:         "dev.local/" + stackName,
+					template:      template,
+					projectDir:    projectDir,
+					containerName: "validate-" + stackName + "-" + template,
+					publishAll:    parallel > 1,
 				}
-
-				// run
-				if !initFail {
-					err = TestRun(rootConfig.LoggingConfig, stack, templates[i], projectDir, rootConfig)
-					if err != nil {
-						//logs error but keeps going
-						rootConfig.Error.Log(err)
-						testResults = append(testResults, ("FAILED: Run for stack:" + stackName + " template:" + templates[i]))
-						failCount++
-					} else {
-						testResults = append(testResults, ("PASSED: Run for stack:" + stackName + " template:" + templates[i]))
-						passCount++
-					}
-				}
-
-				// test
-				if !initFail {
-					err = TestTest(rootConfig.LoggingConfig, stack, templates[i], projectDir, rootConfig)
-					if err != nil {
-						//logs error but keeps going
-						rootConfig.Error.Log(err)
-						testResults = append(testResults, ("FAILED: Test for stack:" + stackName + " template:" + templates[i]))
-						failCount++
-					} else {
-						testResults = append(testResults, ("PASSED: Test for stack:" + stackName + " template:" + templates[i]))
-						passCount++
-					}
+				if parallel > 1 {
+					validation.prefix = "[" + template + "] "
 				}
+				validations = append(validations, validation)
+			}
 
-				// build
-				if !initFail {
-					err = TestBuild(rootConfig.LoggingConfig, stack, templates[i], projectDir, rootConfig)
-					if err != nil {
-						//logs error but keeps going
-						rootConfig.Error.Log(err)
-						testResults = append(testResults, ("FAILED: Build for stack:" + stackName + " template:" + templates[i]))
-						failCount++
-					} else {
-						testResults = append(testResults, ("PASSED: Build for stack:" + stackName + " template:" + templates[i]))
-						passCount++
+			// validate the templates with as many workers as templates are validated in parallel, in the order of the templates
+			templateSteps := make([][]ValidationStep, len(validations))
+			next := make(chan int)
+			var wg sync.WaitGroup
+			for worker := 0; worker < parallel && worker < len(validations); worker++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range next {
+						templateSteps[i] = validations[i].validate()
 					}
-				}
+				}()
+			}
+			for i := range validations {
+				next <- i
 			}
+			close(next)
+			wg.Wait()
+			for _, steps := range templateSteps {
+				report.Steps = append(report.Steps, steps...)
+			}
+			report.Duration = time.Since(start).Seconds()
 
 			rootConfig.Info.Log("@@@@@@@@@@@@@@@ Validate Summary Start @@@@@@@@@@@@@@@@")
-			for i := range testResults {
-				rootConfig.Info.Log(testResults[i])
+			for _, step := range report.Steps {
+				switch {
+				case step.Skipped:
+					report.Skipped++
+				case step.Passed:
+					report.Passed++
+				default:
+					report.Failed++
+				}
+				rootConfig.Info.Log(validationSummary(stackName, step))
+			}
+			rootConfig.Info.Log("Total PASSED: ", report.Passed)
+			rootConfig.Info.Log("Total FAILED: ", report.Failed)
+			if report.Skipped > 0 {
+				rootConfig.Info.Log("Total SKIPPED: ", report.Skipped)
 			}
-			rootConfig.Info.Log("Total PASSED: ", passCount)
-			rootConfig.Info.Log("Total FAILED: ", failCount)
 			rootConfig.Info.Log("@@@@@@@@@@@@@@@  Validate Summary End  @@@@@@@@@@@@@@@@")
 
-			if failCount > 0 {
-				return errors.Errorf("%d validation check(s) failed.", failCount)
+			if reportFile != "" {
+				err = WriteValidationReport(reportFile, report)
+				if err != nil {
+					return err
+				}
+				rootConfig.Info.Log("Wrote the validation report to ", reportFile)
+			}
+
+			if report.Failed > 0 {
+				return errors.Errorf("%d validation check(s) failed.", report.Failed)
 			}
 
 			return nil
@@ -247,31 +256,98 @@ Run this command from the root directory of your Appsody project.`,
 	stackValidateCmd.PersistentFlags().BoolVar(&noLint, "no-lint", false, "Skips running appsody stack lint")
 	stackValidateCmd.PersistentFlags().StringVar(&imageNamespace, "image-namespace", "appsody", "Namespace used for creating the images.")
 	stackValidateCmd.PersistentFlags().StringVar(&imageRegistry, "image-registry", "dev.local", "Registry used for creating the images.")
+	stackValidateCmd.PersistentFlags().IntVar(&parallel, "parallel", 1, "The number of templates to validate at the same time.")
+	stackValidateCmd.PersistentFlags().StringVar(&reportFile, "report", "", "Write a report of the validation to a file, in JUnit XML if its name ends with .xml, or in JSON if it ends with .json.")
+	stackValidateCmd.PersistentFlags().StringSliceVar(&selectedTemplates, "templates", nil, "The templates to validate. All the templates are validated by default.")
 
 	return stackValidateCmd
 }
 
-// Simple test for appsody init command
-func TestInit(log *LoggingConfig, stack string, template string, projectDir string, rootConfig *RootCommandConfig) error {
+// validationTemplates returns the templates of the stack to validate, which are all the templates unless some are selected
+func validationTemplates(templatePath string, selected []string) ([]string, error) {
+	t, err := os.Open(templatePath)
+	if err != nil {
+		return nil, errors.Errorf("Error opening directory: %v", err)
+	}
+	defer t.Close()
 
-	log.Info.Log("**************************************************************************")
-	log.Info.Log("Running appsody init against stack:" + stack + " template:" + template)
-	log.Info.Log("**************************************************************************")
-	_, err := RunAppsodyCmdExec([]string{"init", stack, template}, projectDir, rootConfig)
-	return err
+	names, err := t.Readdirnames(0)
+	if err != nil {
+		return nil, errors.Errorf("Error reading directories: %v", err)
+	}
+	var templates []string
+	for _, name := range names {
+		if strings.Contains(name, ".DS_Store") {
+			continue
+		}
+		templates = append(templates, name)
+	}
+	sort.Strings(templates)
+	if len(selected) == 0 {
+		return templates, nil
+	}
+
+	for _, name := range selected {
+		found := false
+		for _, template := range templates {
+			if template == name {
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("The stack does not have a template %s. The templates are: %s", name, strings.Join(templates, ", "))
+		}
+	}
+	return selected, nil
 }
 
-// Simple test for appsody run command. A future enhancement would be to verify the image that gets built.
-func TestRun(log *LoggingConfig, stack string, template string, projectDir string, rootConfig *RootCommandConfig) error {
+// validate runs appsody init, run, test and build for the template. When init fails, the other steps are skipped.
+func (v *templateValidation) validate() []ValidationStep {
+	log := v.rootConfig.LoggingConfig
+	initStep := runValidationStep(log, "init", v.template, v.init)
+	steps := []ValidationStep{initStep}
+	for _, step := range []struct {
+		name string
+		run  func() (string, error)
+	}{{"run", v.run}, {"test", v.test}, {"build", v.build}} {
+		if !initStep.Passed {
+			steps = append(steps, ValidationStep{Name: step.name, Template: v.template, Skipped: true, Failure: "appsody init failed"})
+			continue
+		}
+		steps = append(steps, runValidationStep(log, step.name, v.template, step.run))
+	}
+	return steps
+}
+
+func (v *templateValidation) banner(command string) {
+	log := v.rootConfig.LoggingConfig
+	log.Info.Log(v.prefix, "**************************************************************************")
+	log.Info.Log(v.prefix, "Running appsody "+command+" against stack:"+v.stack+" template:"+v.template)
+	log.Info.Log(v.prefix, "**************************************************************************")
+}
+
+// Simple test for appsody init command
+func (v *templateValidation) init() (string, error) {
+	v.banner("init")
+	return runAppsodyCmd([]string{"init", v.stack, v.template}, v.projectDir, v.rootConfig, v.prefix)
+}
 
-	runChannel := make(chan error)
-	containerName := "testRunContainer"
+// Simple test for appsody run command. A future enhancement would be to verify the image that gets built.
+func (v *templateValidation) run() (string, error) {
+	log := v.rootConfig.LoggingConfig
+	type runResult struct {
+		output string
+		err    error
+	}
+	runChannel := make(chan runResult, 1)
+	runArgs := []string{"run", "--name", v.containerName}
+	if v.publishAll {
+		runArgs = append(runArgs, "--publish-all")
+	}
 	go func() {
-		log.Info.Log("**************************************************************************")
-		log.Info.Log("Running appsody run against stack:" + stack + "template: " + template)
-		log.Info.Log("**************************************************************************")
-		_, err := RunAppsodyCmdExec([]string{"run", "--name", containerName}, projectDir, rootConfig)
-		runChannel <- err
+		v.banner("run")
+		output, err := runAppsodyCmd(runArgs, v.projectDir, v.rootConfig, v.prefix)
+		runChannel <- runResult{output, err}
 	}()
 
 	// check to see if we get an error from appsody run
@@ -285,97 +361,99 @@ func TestRun(log *LoggingConfig, stack string, template string, projectDir strin
 	isHealthy := false
 	for !(healthCheckWait >= healthCheckTimeout) {
 		select {
-		case err := <-runChannel:
+		case result := <-runChannel:
 			// appsody run exited, probably with an error
-			log.Error.Log("Appsody run failed")
-			return err
+			log.Error.Log(v.prefix, "Appsody run failed")
+			return result.output, result.err
 		case <-time.After(time.Duration(healthCheckFrequency) * time.Second):
 			// see if appsody ps has a container
 			healthCheckWait += healthCheckFrequency
 
-			log.Info.Log("about to run appsody ps")
-			stopOutput, errStop := RunAppsodyCmdExec([]string{"ps"}, projectDir, rootConfig)
+			log.Info.Log(v.prefix, "about to run appsody ps")
+			stopOutput, errStop := runAppsodyCmd([]string{"ps"}, v.projectDir, v.rootConfig, v.prefix)
 			if !strings.Contains(stopOutput, "CONTAINER") {
-				log.Info.Log("appsody ps output doesn't contain header line")
+				log.Info.Log(v.prefix, "appsody ps output doesn't contain header line")
 			}
-			if !strings.Contains(stopOutput, containerName) {
-				log.Info.Log("appsody ps output doesn't contain correct container name")
+			if !strings.Contains(stopOutput, v.containerName) {
+				log.Info.Log(v.prefix, "appsody ps output doesn't contain correct container name")
 			} else {
-				log.Info.Log("appsody ps contains correct container name")
+				log.Info.Log(v.prefix, "appsody ps contains correct container name")
 				isHealthy = true
 			}
 			if errStop != nil {
-				log.Error.Log(errStop)
-				return errStop
+				log.Error.Log(v.prefix, errStop)
+				return stopOutput, errStop
 			}
 		}
 	}
 
 	if !isHealthy {
-		log.Error.Log("appsody ps never found the correct container")
-		return errors.New("appsody ps never found the correct container")
+		log.Error.Log(v.prefix, "appsody ps never found the correct container")
+		v.stop()
+		return "", errors.New("appsody ps never found the correct container")
 	}
 
-	log.Info.Log("Appsody run did not fail")
+	log.Info.Log(v.prefix, "Appsody run did not fail")
 
-	// stop and clean up after the run
-	_, err := RunAppsodyCmdExec([]string{"stop", "--name", "testRunContainer"}, projectDir, rootConfig)
-	if err != nil {
-		log.Error.Log("appsody stop failed")
+	// stop and clean up after the run, and keep the output of appsody run
+	v.stop()
+	select {
+	case result := <-runChannel:
+		return result.output, nil
+	case <-time.After(time.Duration(healthCheckTimeout) * time.Second):
+		return "", nil
 	}
-
-	return nil
 }
 
-// Simple test for appsody build command. A future enhancement would be to verify the image that gets built.
-func TestTest(log *LoggingConfig, stack string, template string, projectDir string, rootConfig *RootCommandConfig) error {
+func (v *templateValidation) stop() {
+	_, err := runAppsodyCmd([]string{"stop", "--name", v.containerName}, v.projectDir, v.rootConfig, v.prefix)
+	if err != nil {
+		v.rootConfig.Error.Log(v.prefix, "appsody stop failed")
+	}
+}
 
-	log.Info.Log("**************************************************************************")
-	log.Info.Log("Running appsody test against stack:" + stack + " template:" + template)
-	log.Info.Log("**************************************************************************")
-	_, err := RunAppsodyCmdExec([]string{"test", "--no-watcher"}, projectDir, rootConfig)
-	return err
+// Simple test for appsody test command.
+func (v *templateValidation) test() (string, error) {
+	v.banner("test")
+	testArgs := []string{"test", "--no-watcher"}
+	if v.publishAll {
+		testArgs = append(testArgs, "--publish-all")
+	}
+	return runAppsodyCmd(testArgs, v.projectDir, v.rootConfig, v.prefix)
 }
 
 // Simple test for appsody build command. A future enhancement would be to verify the image that gets built.
-func TestBuild(log *LoggingConfig, stack string, template string, projectDir string, rootConfig *RootCommandConfig) error {
-
-	imageName := "dev.local/appsody" + filepath.Base(projectDir)
+func (v *templateValidation) build() (string, error) {
+	log := v.rootConfig.LoggingConfig
+	imageName := "dev.local/appsody" + filepath.Base(v.projectDir)
 
-	log.Info.Log("**************************************************************************")
-	log.Info.Log("Running appsody build against stack:" + stack + " template:" + template)
-	log.Info.Log("**************************************************************************")
-	_, err := RunAppsodyCmdExec([]string{"build", "--tag", imageName}, projectDir, rootConfig)
+	v.banner("build")
+	output, err := runAppsodyCmd([]string{"build", "--tag", imageName}, v.projectDir, v.rootConfig, v.prefix)
 	if err != nil {
-		log.Error.Log(err)
-		return err
+		log.Error.Log(v.prefix, err)
+		return output, err
 	}
 
 	// use docker image ls to check for the image
-	log.Info.log("calling docker image ls to check for the image")
-	imageBuilt := false
+	log.Info.log(v.prefix, "calling docker image ls to check for the image")
 	dockerOutput, dockerErr := RunDockerCmdExec([]string{"image", "ls", imageName}, log)
 	if dockerErr != nil {
-		log.Error.Log("Error running docker image ls "+imageName, dockerErr)
-		return dockerErr
+		log.Error.Log(v.prefix, "Error running docker image ls "+imageName, dockerErr)
+		return output, dockerErr
 
 	}
-	if strings.Contains(dockerOutput, imageName) {
-		log.Info.Log("docker image " + imageName + " was found")
-		imageBuilt = true
-	}
-
-	if !imageBuilt {
-		log.Error.Log("image was never built")
-		return err
+	if !strings.Contains(dockerOutput, imageName) {
+		log.Error.Log(v.prefix, "image was never built")
+		return output, errors.Errorf("The image %s was not found after appsody build", imageName)
 	}
+	log.Info.Log(v.prefix, "docker image "+imageName+" was found")
 
 	//delete the image
 	_, err = RunDockerCmdExec([]string{"image", "rm", imageName}, log)
 	if err != nil {
-		log.Error.Log(err)
-		return err
+		log.Error.Log(v.prefix, err)
+		return output, err
 	}
 
-	return nil
+	return output, nil
 }
@@ -0,0 +1,175 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ValidationStep is the result of a step of appsody stack validate: lint and package for the stack,
+// and init, run, test and build for each template
+type ValidationStep struct {
+	Name     string `json:"name"`
+	Template string `json:"template,omitempty"`
+	Passed   bool   `json:"passed"`
+	// Skipped is set for the steps of a template that are not run because appsody init failed
+	Skipped  bool    `json:"skipped,omitempty"`
+	Duration float64 `json:"durationSeconds"`
+	Failure  string  `json:"failure,omitempty"`
+	Output   string  `json:"output,omitempty"`
+}
+
+// ValidationReport is the result of appsody stack validate, as written by --report
+type ValidationReport struct {
+	Stack    string           `json:"stack"`
+	Passed   int              `json:"passed"`
+	Failed   int              `json:"failed"`
+	Skipped  int              `json:"skipped"`
+	Duration float64          `json:"durationSeconds"`
+	Steps    []ValidationStep `json:"steps"`
+}
+
+// runValidationStep runs a step, timing it and keeping its output and the reason it failed
+func runValidationStep(log *LoggingConfig, name string, template string, run func() (string, error)) ValidationStep {
+	start := time.Now()
+	output, err := run()
+	step := ValidationStep{Name: name, Template: template, Passed: err == nil, Duration: time.Since(start).Seconds(), Output: output}
+	if err != nil {
+		//logs error but keeps going
+		if template != "" {
+			log.Error.Log("[", template, "] ", err)
+		} else {
+			log.Error.Log(err)
+		}
+		step.Failure = validationFailure(output, err)
+	}
+	return step
+}
+
+// validationFailure returns the reason that a step failed, which is the last error that the appsody command printed,
+// or else the error of the step, such as the exit status of the command
+func validationFailure(output string, err error) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if index := strings.Index(lines[i], "[Error] "); index >= 0 {
+			return strings.TrimSpace(lines[i][index+len("[Error] "):])
+		}
+	}
+	return err.Error()
+}
+
+// validationSummary returns the line of the validation summary for a step
+func validationSummary(stackName string, step ValidationStep) string {
+	result := "PASSED"
+	if step.Skipped {
+		result = "SKIPPED"
+	} else if !step.Passed {
+		result = "FAILED"
+	}
+	summary := result + ": " + strings.Title(step.Name) + " for stack:" + stackName
+	if step.Template != "" {
+		summary += " template:" + step.Template
+	}
+	return summary
+}
+
+// validationReportFormat returns the format of a report file from its extension
+func validationReportFormat(file string) (string, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".xml":
+		return "junit", nil
+	case ".json":
+		return "json", nil
+	}
+	return "", errors.Errorf("The report file %s is not supported. Use a file name that ends with .xml for a JUnit report, or .json for a JSON report", file)
+}
+
+func junitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// junitValidationReport returns the report as JUnit test suites: one for the stack with the lint and package steps,
+// and one for each template with its init, run, test and build steps
+func junitValidationReport(report ValidationReport) junitTestSuites {
+	var suites []junitTestSuite
+	var seconds []float64
+	suiteIndex := make(map[string]int)
+	for _, step := range report.Steps {
+		name := "appsody stack validate " + report.Stack
+		className := "appsody.stack.validate." + report.Stack
+		if step.Template != "" {
+			name += " " + step.Template
+			className += "." + step.Template
+		}
+		i, ok := suiteIndex[name]
+		if !ok {
+			i = len(suites)
+			suiteIndex[name] = i
+			suites = append(suites, junitTestSuite{Name: name})
+			seconds = append(seconds, 0)
+		}
+		seconds[i] += step.Duration
+		suite := &suites[i]
+		testCase := junitTestCase{Name: step.Name, ClassName: className, Time: junitSeconds(step.Duration), SystemOut: step.Output}
+		switch {
+		case step.Skipped:
+			testCase.Skipped = &junitSkipped{Message: step.Failure}
+			suite.Skipped++
+		case !step.Passed:
+			testCase.Failures = []junitFailure{{Message: step.Failure, Type: "failure", Text: step.Failure}}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	for i := range suites {
+		suites[i].Time = junitSeconds(seconds[i])
+	}
+	return junitTestSuites{Suites: suites}
+}
+
+// WriteValidationReport writes the report to a file, in JUnit XML if its name ends with .xml, or in JSON if it ends with .json
+func WriteValidationReport(file string, report ValidationReport) error {
+	format, err := validationReportFormat(file)
+	if err != nil {
+		return err
+	}
+	if report.Steps == nil {
+		report.Steps = []ValidationStep{}
+	}
+	var data []byte
+	if format == "junit" {
+		data, err = xml.MarshalIndent(junitValidationReport(report), "", "  ")
+		data = append([]byte(xml.Header), data...)
+	} else {
+		data, err = json.MarshalIndent(report, "", "  ")
+	}
+	if err != nil {
+		return errors.Errorf("Could not write the %s report: %v", format, err)
+	}
+	err = ioutil.WriteFile(file, append(data, '\n'), 0644)
+	if err != nil {
+		return errors.Errorf("Could not write the report to %s: %v", file, err)
+	}
+	return nil
+}
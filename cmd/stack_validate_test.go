@@ -0,0 +1,166 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+)
+
+func TestValidateInvalidFlags(t *testing.T) {
+	var tests = []struct {
+		testName string
+		args     []string
+		expected string
+	}{
+		{"Unknown template", []string{"--templates", "default,nope"}, "The stack does not have a template nope. The templates are: default"},
+		{"Parallel zero", []string{"--parallel", "0"}, "must be at least 1, not 0"},
+		{"Report format", []string{"--report", "report.txt"}, "The report file report.txt is not supported"},
+	}
+	for _, testData := range tests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+			defer cleanup()
+			sandbox.ProjectDir = filepath.Join(sandbox.TestDataPath, "test-stack")
+
+			args := append([]string{"stack", "validate", "--no-lint", "--no-package"}, tt.args...)
+			output, err := cmdtest.RunAppsody(sandbox, args...)
+			if err == nil {
+				t.Fatalf("Expected an error with %s", tt.expected)
+			}
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("Expected the output to contain %s, got:\n%s", tt.expected, output)
+			}
+			if strings.Contains(output, "Validating stack") {
+				t.Error("Expected the flags to be checked before validating the stack")
+			}
+		})
+	}
+}
+
+func newValidationReport() cmd.ValidationReport {
+	return cmd.ValidationReport{
+		Stack:    "nodejs",
+		Passed:   3,
+		Failed:   1,
+		Skipped:  3,
+		Duration: 42.5,
+		Steps: []cmd.ValidationStep{
+			{Name: "lint", Passed: true, Duration: 0.25, Output: "LINT TEST PASSED"},
+			{Name: "init", Template: "simple", Passed: true, Duration: 2},
+			{Name: "run", Template: "simple", Passed: false, Duration: 10.5, Failure: "appsody ps never found the correct container", Output: "Running development environment..."},
+			{Name: "test", Template: "simple", Passed: true, Duration: 30},
+			{Name: "init", Template: "scaffold", Passed: false, Failure: "Could not find a template"},
+			{Name: "run", Template: "scaffold", Skipped: true, Failure: "appsody init failed"},
+			{Name: "test", Template: "scaffold", Skipped: true, Failure: "appsody init failed"},
+			{Name: "build", Template: "scaffold", Skipped: true, Failure: "appsody init failed"},
+		},
+	}
+}
+
+func TestWriteValidationReportJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appsody-validate-report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "validate.json")
+	report := newValidationReport()
+	err = cmd.WriteValidationReport(file, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written cmd.ValidationReport
+	err = json.Unmarshal(data, &written)
+	if err != nil {
+		t.Fatalf("Could not parse the report: %v\n%s", err, data)
+	}
+	if written.Stack != "nodejs" || written.Failed != 1 || len(written.Steps) != len(report.Steps) {
+		t.Errorf("Expected the report to be written as it is, got %+v", written)
+	}
+	if step := written.Steps[2]; step.Failure != "appsody ps never found the correct container" || step.Duration != 10.5 || step.Output == "" {
+		t.Errorf("Expected the run step to have its failure, duration and output, got %+v", step)
+	}
+}
+
+func TestWriteValidationReportJUnit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appsody-validate-report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "validate.xml")
+	err = cmd.WriteValidationReport(file, newValidationReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var junit struct {
+		Suites []struct {
+			Name      string `xml:"name,attr"`
+			Tests     int    `xml:"tests,attr"`
+			Failures  int    `xml:"failures,attr"`
+			Skipped   int    `xml:"skipped,attr"`
+			Time      string `xml:"time,attr"`
+			TestCases []struct {
+				Name      string `xml:"name,attr"`
+				ClassName string `xml:"classname,attr"`
+				Time      string `xml:"time,attr"`
+				Failure   *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				Skipped *struct{} `xml:"skipped"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	err = xml.Unmarshal(data, &junit)
+	if err != nil {
+		t.Fatalf("Could not parse the report: %v\n%s", err, data)
+	}
+	if len(junit.Suites) != 3 {
+		t.Fatalf("Expected a test suite for the stack and for each template, got %d:\n%s", len(junit.Suites), data)
+	}
+	stack, simple, scaffold := junit.Suites[0], junit.Suites[1], junit.Suites[2]
+	if stack.Name != "appsody stack validate nodejs" || stack.Tests != 1 || stack.Failures != 0 {
+		t.Errorf("Expected the stack suite to have the lint step, got %+v", stack)
+	}
+	if simple.Name != "appsody stack validate nodejs simple" || simple.Tests != 3 || simple.Failures != 1 || simple.Time != "42.500" {
+		t.Errorf("Expected the simple suite to have 3 steps, 1 failure and the total time, got %+v", simple)
+	}
+	run := simple.TestCases[1]
+	if run.Name != "run" || run.ClassName != "appsody.stack.validate.nodejs.simple" || run.Time != "10.500" || run.Failure == nil ||
+		run.Failure.Message != "appsody ps never found the correct container" {
+		t.Errorf("Expected the run test case to fail with its reason, got %+v", run)
+	}
+	if scaffold.Tests != 4 || scaffold.Failures != 1 || scaffold.Skipped != 3 || scaffold.TestCases[3].Skipped == nil {
+		t.Errorf("Expected the scaffold suite to have 1 failure and 3 skipped steps, got %+v", scaffold)
+	}
+}
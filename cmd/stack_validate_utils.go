@@ -16,7 +16,6 @@ package cmd
 import (
 	"bufio"
 	"bytes"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -28,23 +27,13 @@ import (
 // args will be passed to the appsody command
 // workingDir will be the directory the command runs in
 func RunAppsodyCmdExec(args []string, workingDir string, rootConfig *RootCommandConfig) (string, error) {
-	execDir, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		// replace the original working directory when this function completes
-		err := os.Chdir(execDir)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-
-	// set the working directory
-	if err := os.Chdir(workingDir); err != nil {
-		return "", err
-	}
+	return runAppsodyCmd(args, workingDir, rootConfig, "")
+}
 
+// runAppsodyCmd runs the appsody CLI like RunAppsodyCmdExec, printing each line of its output after the prefix.
+// The process runs in workingDir rather than changing the working directory of this process,
+// so that several commands can run at the same time.
+func runAppsodyCmd(args []string, workingDir string, rootConfig *RootCommandConfig, prefix string) (string, error) {
 	executable, _ := os.Executable()
 
 	cmdArgs := []string{executable}
@@ -52,42 +41,39 @@ func RunAppsodyCmdExec(args []string, workingDir string, rootConfig *RootCommand
 		cmdArgs = append(cmdArgs, "-v")
 	}
 	cmdArgs = append(cmdArgs, args...)
-	rootConfig.LoggingConfig.Info.log(cmdArgs)
+	rootConfig.LoggingConfig.Info.log(prefix, cmdArgs)
 
 	execCmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	execCmd.Dir = workingDir
 	outReader, outWriter, err := os.Pipe()
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		// Make sure to close the writer first or this will hang on Windows
-		outWriter.Close()
-		outReader.Close()
-	}()
+	defer outReader.Close()
 	execCmd.Stdout = outWriter
 	execCmd.Stderr = outWriter
 	outScanner := bufio.NewScanner(outReader)
 	var outBuffer bytes.Buffer
+	outDone := make(chan struct{})
 	go func() {
+		defer close(outDone)
 		for outScanner.Scan() {
 			out := outScanner.Bytes()
 			outBuffer.Write(out)
 			outBuffer.WriteByte('\n')
-			rootConfig.LoggingConfig.Info.logf(string(out))
+			rootConfig.LoggingConfig.Info.log(prefix, string(out))
 		}
 	}()
 
 	err = execCmd.Start()
+	// the child process has its own copy of the writer, the output ends when it exits
+	// Make sure to close the writer first or this will hang on Windows
+	outWriter.Close()
 	if err != nil {
 		return "", err
 	}
-
-	// replace the original working directory when this function completes
-	err = os.Chdir(execDir)
-	if err != nil {
-		log.Fatal(err)
-	}
 	err = execCmd.Wait()
+	<-outDone
 
 	return outBuffer.String(), err
 }
@@ -148,7 +134,7 @@ func AddLocalFileRepo(repoName string, repoFilePath string, config *RootCommandC
 	}
 	repoURL = "file://" + absPath
 	// add a new repo
-	err = repoAdd(repoName, repoURL, config)
+	err = repoAdd(repoName, repoURL, "", 0, nil, config)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,174 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// Indices with this apiVersion can list several versions of a stack, as entries with the same ID, newest first.
+// Older CLIs read them as v2 indices and use the first entry of each stack.
+const multiVersionIndexAPIVersion = "v2.1"
+
+// isNewerIndexAPIVersion returns true if the index has an apiVersion that this CLI does not know
+func isNewerIndexAPIVersion(apiVersion string) bool {
+	return strings.Compare(apiVersion, multiVersionIndexAPIVersion) == 1
+}
+
+// SplitStackVersion splits a <repository>/<stack>@<version> parameter into the stack and the version
+func SplitStackVersion(projectParm string) (string, string, error) {
+	parts := strings.SplitN(projectParm, "@", 2)
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+	if parts[1] == "" {
+		return "", "", errors.Errorf("malformed project parameter - specify a version or a version range after @ in %s", projectParm)
+	}
+	return parts[0], parts[1], nil
+}
+
+// StackVersionConstraint returns the semver constraint of a stack version parameter.
+// A version without a patch number, such as 1.4, matches the latest patch of that version, like ~1.4.
+func StackVersionConstraint(version string) (*semver.Constraints, error) {
+	constraint := version
+	if v, err := semver.NewVersion(version); err == nil && strings.Count(version, ".") < 2 {
+		constraint = "~" + v.Original()
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.Errorf("The stack version %s is not a valid version or version range: %v", version, err)
+	}
+	return c, nil
+}
+
+// stackVersions returns the entries of a stack in the index, newest first
+func (index *IndexYaml) stackVersions(stackID string) []*IndexYamlStack {
+	var versions []*IndexYamlStack
+	for i := range index.Stacks {
+		if index.Stacks[i].ID == stackID {
+			versions = append(versions, &index.Stacks[i])
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareStackVersions(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions
+}
+
+// compareStackVersions compares two versions, treating versions that are not semantic versions as older
+func compareStackVersions(a string, b string) int {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+// FindStackVersion returns the newest version of the stack that matches the version parameter,
+// or the newest version if no version is specified. It returns nil if the stack is not in the index.
+func FindStackVersion(index *IndexYaml, stackID string, version string) (*IndexYamlStack, error) {
+	versions := index.stackVersions(stackID)
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	if version == "" {
+		return versions[0], nil
+	}
+	constraint, err := StackVersionConstraint(version)
+	if err != nil {
+		return nil, err
+	}
+	available := make([]string, 0, len(versions))
+	for _, stack := range versions {
+		v, err := semver.NewVersion(stack.Version)
+		if err == nil && constraint.Check(v) {
+			return stack, nil
+		}
+		available = append(available, stack.Version)
+	}
+	return nil, errors.Errorf("Could not find a version of stack %s that matches %s. The available versions are: %s", stackID, version, strings.Join(available, ", "))
+}
+
+// latestStackVersions returns the newest version of each stack in the index
+func (index *IndexYaml) latestStackVersions() []IndexYamlStack {
+	latest := make([]IndexYamlStack, 0, len(index.Stacks))
+	seen := make(map[string]bool)
+	for _, stack := range index.Stacks {
+		if seen[stack.ID] {
+			continue
+		}
+		seen[stack.ID] = true
+		latest = append(latest, *index.stackVersions(stack.ID)[0])
+	}
+	return latest
+}
+
+// addStackVersion adds a version of a stack to the index, replacing the entry for the same version if there is one.
+// The versions of each stack are kept newest first, and the index is upgraded to the multi-version apiVersion
+// when it lists more than one version of a stack.
+func addStackVersion(log *LoggingConfig, indexYaml IndexYaml, newStack IndexYamlStack) IndexYaml {
+	stacks := make([]IndexYamlStack, 0, len(indexYaml.Stacks)+1)
+	for _, stack := range indexYaml.Stacks {
+		if stack.ID == newStack.ID && stack.Version == newStack.Version {
+			log.Debug.Logf("Replacing version %s of stack %s", stack.Version, stack.ID)
+			continue
+		}
+		stacks = append(stacks, stack)
+	}
+	// insert the new version before the first older version of the stack, or at the end
+	position := len(stacks)
+	for i, stack := range stacks {
+		if stack.ID == newStack.ID && compareStackVersions(newStack.Version, stack.Version) > 0 {
+			position = i
+			break
+		}
+	}
+	stacks = append(stacks, IndexYamlStack{})
+	copy(stacks[position+1:], stacks[position:])
+	stacks[position] = newStack
+	indexYaml.Stacks = stacks
+
+	if len(indexYaml.stackVersions(newStack.ID)) > 1 && indexYaml.APIVersion != multiVersionIndexAPIVersion {
+		log.Info.logf("The index has several versions of stack %s, setting its apiVersion to %s", newStack.ID, multiVersionIndexAPIVersion)
+		indexYaml.APIVersion = multiVersionIndexAPIVersion
+	}
+	return indexYaml
+}
+
+// findStackVersionAndRemove removes one version of a stack from the index, or every version if version is empty
+func findStackVersionAndRemove(log *LoggingConfig, stackID string, version string, indexYaml IndexYaml) (IndexYaml, bool) {
+	stacks := make([]IndexYamlStack, 0, len(indexYaml.Stacks))
+	removed := false
+	for _, stack := range indexYaml.Stacks {
+		if stack.ID == stackID && (version == "" || stack.Version == version) {
+			log.Debug.Logf("Removing version %s of stack %s", stack.Version, stackID)
+			removed = true
+			continue
+		}
+		stacks = append(stacks, stack)
+	}
+	indexYaml.Stacks = stacks
+	return indexYaml, removed
+}
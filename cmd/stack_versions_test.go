@@ -0,0 +1,162 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/appsody/appsody/cmd/cmdtest"
+)
+
+var multiVersionIndex = &cmd.IndexYaml{
+	APIVersion: "v2.1",
+	Stacks: []cmd.IndexYamlStack{
+		{ID: "nodejs", Version: "1.5.0"},
+		{ID: "nodejs", Version: "1.4.2"},
+		{ID: "nodejs", Version: "1.4.10"},
+		{ID: "nodejs", Version: "1.3.0"},
+		{ID: "java", Version: "0.2.1"},
+	},
+}
+
+func TestFindStackVersion(t *testing.T) {
+	var findTests = []struct {
+		testName        string
+		stackID         string
+		version         string
+		expectedVersion string
+		expectedError   string
+	}{
+		{"Latest version", "nodejs", "", "1.5.0", ""},
+		{"Partial version", "nodejs", "1.4", "1.4.10", ""},
+		{"Tilde range", "nodejs", "~1.4.2", "1.4.10", ""},
+		{"Exact version", "nodejs", "1.4.2", "1.4.2", ""},
+		{"Range", "nodejs", ">=1.3.0 <1.4.0", "1.3.0", ""},
+		{"Missing version", "nodejs", "2.0", "", "The available versions are: 1.5.0, 1.4.10, 1.4.2, 1.3.0"},
+		{"Invalid version", "nodejs", "latest", "", "not a valid version"},
+		{"Missing stack", "python", "", "", ""},
+	}
+	for _, testData := range findTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			stack, err := cmd.FindStackVersion(multiVersionIndex, tt.stackID, tt.version)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Expected an error containing %q but got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expectedVersion == "" {
+				if stack != nil {
+					t.Errorf("Expected no stack but got version %s", stack.Version)
+				}
+				return
+			}
+			if stack == nil || stack.Version != tt.expectedVersion {
+				t.Errorf("Expected version %s but got %v", tt.expectedVersion, stack)
+			}
+		})
+	}
+}
+
+func TestSplitStackVersion(t *testing.T) {
+	var splitTests = []struct {
+		parm            string
+		expectedStack   string
+		expectedVersion string
+		expectError     bool
+	}{
+		{"incubator/nodejs", "incubator/nodejs", "", false},
+		{"incubator/nodejs@1.4", "incubator/nodejs", "1.4", false},
+		{"nodejs@~1.4.2", "nodejs", "~1.4.2", false},
+		{"nodejs@", "", "", true},
+	}
+	for _, testData := range splitTests {
+		tt := testData
+		t.Run(tt.parm, func(t *testing.T) {
+			stack, version, err := cmd.SplitStackVersion(tt.parm)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error for %s", tt.parm)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stack != tt.expectedStack || version != tt.expectedVersion {
+				t.Errorf("Expected %s and %s but got %s and %s", tt.expectedStack, tt.expectedVersion, stack, version)
+			}
+		})
+	}
+}
+
+func TestListAllVersions(t *testing.T) {
+	sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
+	defer cleanup()
+
+	index := `apiVersion: v2.1
+stacks:
+- id: nodejs
+  name: Node.js
+  version: 1.5.0
+  description: Runtime for Node.js applications
+  templates:
+  - id: simple
+    url: file:///simple.tar.gz
+- id: nodejs
+  name: Node.js
+  version: 1.4.2
+  description: Runtime for Node.js applications
+  templates:
+  - id: simple
+    url: file:///simple.tar.gz
+`
+	indexFile := filepath.Join(sandbox.TestDataPath, "multi-version-index.yaml")
+	err := ioutil.WriteFile(indexFile, []byte(index), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cmdtest.AddLocalRepo(sandbox, "versions", indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := cmdtest.RunAppsody(sandbox, "list", "versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "1.5.0") || strings.Contains(output, "1.4.2") {
+		t.Errorf("Expected only the latest version of the stack to be listed:\n%s", output)
+	}
+
+	output, err = cmdtest.RunAppsody(sandbox, "list", "versions", "--all-versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "1.5.0") || !strings.Contains(output, "1.4.2") {
+		t.Errorf("Expected every version of the stack to be listed:\n%s", output)
+	}
+	if strings.Index(output, "1.5.0") > strings.Index(output, "1.4.2") {
+		t.Errorf("Expected the newest version to be listed first:\n%s", output)
+	}
+}
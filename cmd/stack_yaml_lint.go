@@ -15,155 +15,141 @@
 package cmd
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/Masterminds/semver"
 	"gopkg.in/yaml.v2"
 )
 
-func (stackDetails *StackYaml) validateYaml(rootConfig *RootCommandConfig, stackPath string) (int, int) {
-	stackLintErrorCount := 0
-	stackLintWarningCount := 0
-	arg := filepath.Join(stackPath, "/stack.yaml")
+// lintField reports a finding about a key of stack.yaml
+func (l *stackLinter) lintField(ruleID string, key string, args ...interface{}) {
+	line, column := l.locate(lintStackYamlFile, yamlKey(key))
+	l.report(ruleID, lintStackYamlFile, line, column, args...)
+}
+
+func (stackDetails *StackYaml) validateYaml(l *stackLinter) {
+	arg := filepath.Join(l.stackPath, "/stack.yaml")
 
-	rootConfig.Info.log("LINTING stack.yaml: ", arg)
+	l.info("LINTING stack.yaml: ", arg)
 
 	stackyaml, err := ioutil.ReadFile(arg)
 	if err != nil {
-		rootConfig.Error.log("stackyaml.Get err ", err)
-		stackLintErrorCount++
+		l.report("file-access", lintStackYamlFile, 0, 0, "stackyaml.Get err ", err)
 	}
 
 	err = yaml.Unmarshal([]byte(stackyaml), stackDetails)
 	if err != nil {
-		rootConfig.Error.log("Unmarshal: Error unmarshalling stack.yaml")
-		stackLintErrorCount++
+		l.report("stack-yaml-invalid", lintStackYamlFile, yamlErrorLine(err), 0, "Unmarshal: Error unmarshalling stack.yaml")
 	}
 
-	stackLintErrorCount += stackDetails.validateFields(rootConfig)
+	stackDetails.validateFields(l)
 	validSemver := CheckValidSemver(string(stackDetails.Version))
 	if validSemver != nil {
-		rootConfig.Error.log(validSemver)
-		stackLintErrorCount++
+		l.lintField("version-format", "version", validSemver)
 	}
 
-	stackLintErrorCount += stackDetails.checkDescLength(rootConfig.LoggingConfig)
-	stackLintWarningCount += stackDetails.checkLicense(rootConfig.LoggingConfig)
-	stackLintErrorCount += stackDetails.checkRequirements(rootConfig.LoggingConfig)
-	templateErrorCount, templateWarningCount := stackDetails.checkTemplatingData(rootConfig.LoggingConfig)
-	stackLintErrorCount += templateErrorCount
-	stackLintWarningCount += templateWarningCount
-	return stackLintErrorCount, stackLintWarningCount
+	stackDetails.checkDescLength(l)
+	stackDetails.checkLicense(l)
+	stackDetails.checkRequirements(l)
+	stackDetails.checkTemplatingData(l)
+}
+
+// yamlErrorLine returns the line of a YAML syntax error, or 0 if the error does not have one
+func yamlErrorLine(err error) int {
+	match := regexp.MustCompile(`line (\d+)`).FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
 }
 
-func (stackDetails *StackYaml) validateFields(rootConfig *RootCommandConfig) int {
-	stackLintErrorCount := 0
+func (stackDetails *StackYaml) validateFields(l *stackLinter) {
 	v := reflect.ValueOf(stackDetails).Elem()
 	yamlValues := make([]interface{}, v.NumField())
 
 	for i := 0; i < v.NumField(); i++ {
 		yamlValues[i] = v.Field(i).Interface()
 		if yamlValues[i] == "" && v.Type().Field(i).Name != "Deprecated" {
-			rootConfig.Error.log("Missing value for field: ", strings.ToLower(v.Type().Field(i).Name))
-			stackLintErrorCount++
+			field := strings.ToLower(v.Type().Field(i).Name)
+			if tag := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]; tag != "" {
+				field = tag
+			}
+			l.lintField("field-missing", field, "Missing value for field: ", strings.ToLower(v.Type().Field(i).Name))
 		}
 	}
 
-	stackLintErrorCount += stackDetails.checkMaintainer(rootConfig.LoggingConfig, yamlValues)
-	return stackLintErrorCount
-
+	stackDetails.checkMaintainer(l, yamlValues)
 }
 
-func (stackDetails *StackYaml) checkMaintainer(log *LoggingConfig, yamlValues []interface{}) int {
-	stackLintErrorCount := 0
+func (stackDetails *StackYaml) checkMaintainer(l *stackLinter, yamlValues []interface{}) {
 	Map := make(map[string]interface{})
 	Map["maintainerEmails"] = yamlValues[5]
 
 	maintainerEmails := Map["maintainerEmails"].([]Maintainer)
 
 	if len(maintainerEmails) == 0 {
-		log.Error.log("Please list a stack maintainer with the following details: Name, Email, and Github ID")
-		stackLintErrorCount++
+		l.lintField("maintainer-missing", "maintainers", "Please list a stack maintainer with the following details: Name, Email, and Github ID")
 	}
-
-	return stackLintErrorCount
 }
 
-func (stackDetails *StackYaml) checkDescLength(log *LoggingConfig) int {
-	stackLintErrorCount := 0
-
+func (stackDetails *StackYaml) checkDescLength(l *stackLinter) {
 	if len(stackDetails.Description) > 70 {
-		log.Error.log("Description must be under 70 characters")
-		stackLintErrorCount++
+		l.lintField("description-length", "description", "Description must be under 70 characters")
 	}
 
 	if len(stackDetails.Name) > 30 {
-		log.Error.log("Stack name must be under 30 characters")
-		stackLintErrorCount++
+		l.lintField("name-length", "name", "Stack name must be under 30 characters")
 	}
-
-	return stackLintErrorCount
 }
 
-func (stackDetails *StackYaml) checkTemplatingData(log *LoggingConfig) (int, int) {
-	stackLintErrorCount := 0
-	stackLintWarningCount := 0
+func (stackDetails *StackYaml) checkTemplatingData(l *stackLinter) {
 	keyRegex := regexp.MustCompile("^[a-zA-Z0-9]*$")
 
 	if len(stackDetails.TemplatingData) == 0 {
-		log.Info.log("No custom stack variables used (see https://appsody.dev/docs/stacks/develop/#custom-stack-variables)")
-		return stackLintErrorCount, stackLintWarningCount
+		l.info("No custom stack variables used (see https://appsody.dev/docs/stacks/develop/#custom-stack-variables)")
+		return
 	}
 
 	for key := range stackDetails.TemplatingData {
 		checkKey := keyRegex.FindString(string(key))
 
 		if checkKey == "" {
-			log.Error.log("stack.yaml templating-data key is not alphanumeric: ", key)
-			stackLintErrorCount++
+			l.lintField("templating-data-key", key, "stack.yaml templating-data key is not alphanumeric: ", key)
 		}
 
 	}
-	return stackLintErrorCount, stackLintWarningCount
 }
 
-func (stackDetails *StackYaml) checkLicense(log *LoggingConfig) int {
-	stackLintWarningCount := 0
-
-	if err := checkValidLicense(log, stackDetails.License); err != nil {
-		stackLintWarningCount++
-		log.Warning.logf("The stack.yaml SPDX license ID is invalid: %v.", err)
+func (stackDetails *StackYaml) checkLicense(l *stackLinter) {
+	if err := checkValidLicense(l.log, stackDetails.License); err != nil {
+		l.lintField("license-id", "license", fmt.Sprintf("The stack.yaml SPDX license ID is invalid: %v.", err))
 	}
 	if valid, err := IsValidKubernetesLabelValue(stackDetails.License); !valid {
-		stackLintWarningCount++
-		log.Warning.logf("The stack.yaml SPDX license ID is invalid: %v.", err)
+		l.lintField("license-id", "license", fmt.Sprintf("The stack.yaml SPDX license ID is invalid: %v.", err))
 	}
-	return stackLintWarningCount
 }
 
-func (stackDetails *StackYaml) checkRequirements(log *LoggingConfig) int {
-	stackLintErrorCount := 0
-
+func (stackDetails *StackYaml) checkRequirements(l *stackLinter) {
 	reqsMap := map[string]string{
-		"Docker":  stackDetails.Requirements.Docker,
-		"Appsody": stackDetails.Requirements.Appsody,
-		"Buildah": stackDetails.Requirements.Buildah,
+		"docker-version":  stackDetails.Requirements.Docker,
+		"appsody-version": stackDetails.Requirements.Appsody,
+		"buildah-version": stackDetails.Requirements.Buildah,
 	}
 
-	for _, req := range reqsMap {
+	for key, req := range reqsMap {
 		if req == "" {
 			continue
 		}
 		_, err := semver.NewConstraint(req)
 		if err != nil {
-			log.Error.log("Requirement: ", req, " is not in the correct format. See: https://github.com/Masterminds/semver for a list of valid requirement constraints.")
-			stackLintErrorCount++
+			l.lintField("requirement-format", key, "Requirement: ", req, " is not in the correct format. See: https://github.com/Masterminds/semver for a list of valid requirement constraints.")
 		}
 	}
-
-	return stackLintErrorCount
 }
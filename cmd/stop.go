@@ -16,7 +16,11 @@ package cmd
 
 import (
 	"errors"
+	"strings"
+
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func newStopCmd(rootConfig *RootCommandConfig) *cobra.Command {
@@ -28,7 +32,9 @@ func newStopCmd(rootConfig *RootCommandConfig) *cobra.Command {
 		Long: `Stop the local, running Appsody container for your project.
 
 By default, the command stops the Appsody container that was launched from the project in your current working directory. 
-To see a list of all your running Appsody containers, run the command 'appsody ps'.`,
+To see a list of all your running Appsody containers, run the command 'appsody ps'.
+
+When APPSODY_K8S_EXPERIMENTAL is set to TRUE, the command deletes the ingress, service and deployment of the project from the Kubernetes cluster instead. Use --kubeconfig and --context to select the cluster.`,
 		Example: `  appsody stop
   Stops the running Appsody container launched by the project in your current working directory.
   
@@ -48,30 +54,38 @@ To see a list of all your running Appsody containers, run the command 'appsody p
 				//dockerRemove(imageName) is not needed due to --rm flag
 				//os.Exit(1)
 			} else {
-				// this is the k8s path, runs kubectl delete for the ingress, service and deployment
+				// this is the k8s path, deletes the ingress, service and deployment
 				// Note for k8s the containerName does not need -dev
-				serviceArgName := containerName + "-service"
-				ingressArgName := containerName + "-ingress"
-				deploymentArgName := containerName
-				serviceArgs := []string{"service", serviceArgName}
-				deploymentArgs := []string{"deployment", deploymentArgName}
-				ingressArgs := []string{"ingress", ingressArgName}
-				_, err := RunKubeDelete(rootConfig.LoggingConfig, ingressArgs, rootConfig.Dryrun)
-				if err != nil {
-					rootConfig.Error.logf("kubectl delete failed for ingress %s, due to %v", ingressArgName, err)
+				resources := []struct {
+					gvk  schema.GroupVersionKind
+					name string
+				}{
+					{schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}, containerName + "-ingress"},
+					{schema.GroupVersionKind{Version: "v1", Kind: "Service"}, containerName + "-service"},
+					{schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, containerName},
 				}
-				_, err = RunKubeDelete(rootConfig.LoggingConfig, serviceArgs, rootConfig.Dryrun)
-				if err != nil {
-					rootConfig.Error.logf("kubectl delete failed for service %s, due to %v", serviceArgName, err)
+				if rootConfig.Dryrun {
+					for _, resource := range resources {
+						rootConfig.Info.logf("Dry run - skipping delete of %s %s", strings.ToLower(resource.gvk.Kind), resource.name)
+					}
+					return nil
 				}
-				_, err = RunKubeDelete(rootConfig.LoggingConfig, deploymentArgs, rootConfig.Dryrun)
+				client, err := getKubeClient(rootConfig)
 				if err != nil {
-					rootConfig.Error.logf("kubectl delete failed for deployment %s, due to %v", deploymentArgName, err)
+					return err
+				}
+				for _, resource := range resources {
+					rootConfig.Info.logf("Deleting %s %s", strings.ToLower(resource.gvk.Kind), resource.name)
+					err = client.DeleteResource(resource.gvk, resource.name, "", metav1.DeletePropagationBackground)
+					if err != nil {
+						rootConfig.Error.logf("Kubernetes delete failed for %s %s, due to %v", strings.ToLower(resource.gvk.Kind), resource.name, err)
+					}
 				}
 			}
 			return nil
 		},
 	}
 	addNameFlag(stopCmd, &containerName, rootConfig)
+	addKubeFlags(stopCmd, rootConfig)
 	return stopCmd
 }
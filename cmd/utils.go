@@ -31,6 +31,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -41,6 +42,10 @@ import (
 	"github.com/spf13/viper"
 
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type ProjectConfig struct {
@@ -51,6 +56,8 @@ type ProjectConfig struct {
 	Description     string
 	License         string
 	Maintainers     []Maintainer
+	Config          []DeployConfigSource
+	Secrets         []DeployConfigSource
 }
 type OwnerReference struct {
 	APIVersion         string `yaml:"apiVersion"`
@@ -548,7 +555,6 @@ func getStackIndexYaml(repoID string, stackID string, config *RootCommandConfig)
 	}
 
 	var stackEntry *IndexYamlStack
-	extractDir := filepath.Join(getHome(config), "extract")
 
 	// Get Repository directory and unmarshal
 	var repoFile RepositoryFile
@@ -569,31 +575,17 @@ func getStackIndexYaml(repoID string, stackID string, config *RootCommandConfig)
 	if repoEntry == nil {
 		return stackEntry, errors.Errorf("Repository: '%s' was not found in the repository.yaml file", repoID)
 	}
-	repoEntryURL := repoEntry.URL
-
-	if repoEntryURL == "" {
+	if repoEntry.URL == "" {
 		return stackEntry, errors.Errorf("URL for specified repository is empty")
 	}
 
-	var repoIndex IndexYaml
-	tempRepoIndex := filepath.Join(extractDir, "index.yaml")
-	err = downloadFileToDisk(config.LoggingConfig, repoEntryURL, tempRepoIndex, config.Dryrun)
-	if err != nil {
-		return stackEntry, err
-	}
-	defer os.Remove(tempRepoIndex)
-	tempRepoIndexFile, err := ioutil.ReadFile(tempRepoIndex)
-	if err != nil {
-		return stackEntry, errors.Errorf("Error trying to read: %v", err)
-	}
-
-	err = yaml.Unmarshal(tempRepoIndexFile, &repoIndex)
+	repoIndex, err := getIndex(config, repoEntry, false)
 	if err != nil {
-		return stackEntry, errors.Errorf("Error parsing the index.yaml file: %v", err)
+		return stackEntry, errors.Errorf("Error reading the index of repository %s: %v", repoID, err)
 	}
 
 	// get specified stack and get URL
-	stackEntry = getStack(&repoIndex, stackID)
+	stackEntry = getStack(repoIndex, stackID)
 	if stackEntry == nil {
 		return stackEntry, errors.New("Could not find stack specified in repository index")
 	}
@@ -1536,7 +1528,7 @@ func GenServiceYaml(log *LoggingConfig, appName string, ports []string, pdir str
 }
 
 //GenRouteYaml returns the file name of a generated K8S Service yaml
-func GenRouteYaml(log *LoggingConfig, appName string, pdir string, port int, dryrun bool) (fileName string, err error) {
+func GenRouteYaml(config *RootCommandConfig, appName string, pdir string, port int, dryrun bool) (fileName string, err error) {
 	type IngressPath struct {
 		Path    string `yaml:"path"`
 		Backend struct {
@@ -1575,7 +1567,7 @@ func GenRouteYaml(log *LoggingConfig, appName string, pdir string, port int, dry
 		ingress.Spec.Rules[0].Host = ingressHost
 	} else {
 		// We set it to a host name that's resolvable by nip.io
-		ingress.Spec.Rules[0].Host = fmt.Sprintf("%s.%s.%s", appName, getK8sMasterIP(log, dryrun), "nip.io")
+		ingress.Spec.Rules[0].Host = fmt.Sprintf("%s.%s.%s", appName, getK8sMasterIP(config, dryrun), "nip.io")
 	}
 
 	ingress.Spec.Rules[0].HTTP.Paths = make([]IngressPath, 1)
@@ -1585,14 +1577,14 @@ func GenRouteYaml(log *LoggingConfig, appName string, pdir string, port int, dry
 
 	yamlStr, err := yaml.Marshal(&ingress)
 	if err != nil {
-		log.Error.log("Could not create the YAML string from Map. Exiting.")
+		config.Error.log("Could not create the YAML string from Map. Exiting.")
 		return "", err
 	}
-	log.Debug.logf("Generated YAML: \n%s\n", yamlStr)
+	config.Debug.logf("Generated YAML: \n%s\n", yamlStr)
 	// Generate file based on supplied config, defaulting to app-deploy.yaml
 	yamlFile := filepath.Join(pdir, "app-ingress.yaml")
 	if dryrun {
-		log.Info.log("Skipping creation of yaml file with prefix: ", yamlFile)
+		config.Info.log("Skipping creation of yaml file with prefix: ", yamlFile)
 		return yamlFile, nil
 	}
 	err = ioutil.WriteFile(yamlFile, yamlStr, 0666)
@@ -1602,16 +1594,34 @@ func GenRouteYaml(log *LoggingConfig, appName string, pdir string, port int, dry
 	return yamlFile, nil
 }
 
-func getK8sMasterIP(log *LoggingConfig, dryrun bool) string {
-	cmdParms := []string{"node", "--selector", "node-role.kubernetes.io/master", "-o", "jsonpath={.items[0].status.addresses[?(.type==\"InternalIP\")].address}"}
-	ip, err := KubeGet(log, cmdParms, "", dryrun)
+func getK8sMasterIP(config *RootCommandConfig, dryrun bool) string {
+	if dryrun {
+		config.Info.log("Dry run - skipping lookup of the master node IP address")
+		return "x.x.x.x"
+	}
+	client, err := getKubeClient(config)
 	if err == nil {
-		return ip
+		var nodes *corev1.NodeList
+		nodes, err = client.Clientset.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/master"})
+		if err == nil && len(nodes.Items) > 0 {
+			if ip := nodeAddress(&nodes.Items[0], corev1.NodeInternalIP); ip != "" {
+				return ip
+			}
+		}
 	}
-	log.Debug.log("Could not retrieve the master IP address - returning x.x.x.x: ", err)
+	config.Debug.log("Could not retrieve the master IP address - returning x.x.x.x: ", err)
 	return "x.x.x.x"
 }
 
+func nodeAddress(node *corev1.Node, addressType corev1.NodeAddressType) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == addressType {
+			return address.Address
+		}
+	}
+	return ""
+}
+
 func getIngressPort(config *RootCommandConfig) int {
 	ports, err := getExposedPorts(config)
 
@@ -1701,194 +1711,215 @@ func DockerRunBashCmd(options []string, image string, bashCmd string, config *Ro
 	return strings.TrimSpace(string(kout[:])), nil
 }
 
-//KubeGet issues kubectl get <arg>
-func KubeGet(log *LoggingConfig, args []string, namespace string, dryrun bool) (string, error) {
-	log.Info.log("Attempting to get resource from Kubernetes ...")
-	kcmd := "kubectl"
-	kargs := []string{"get"}
-	kargs = append(kargs, args...)
-	if namespace != "" {
-		kargs = append(kargs, "--namespace", namespace)
-	}
-
+//KubeApply applies the objects in <filename> to the Kubernetes cluster with server-side apply
+func KubeApply(config *RootCommandConfig, fileToApply string, namespace string, dryrun bool) error {
+	config.Info.log("Attempting to apply resource in Kubernetes ...")
 	if dryrun {
-		log.Info.log("Dry run - skipping execution of: ", kcmd, " ", ArgsToString(kargs))
-		return "", nil
+		config.Info.log("Dry run - skipping apply of: ", fileToApply)
+		return nil
 	}
-	log.Info.log("Running command: ", kcmd, " ", ArgsToString(kargs))
-	execCmd := exec.Command(kcmd, kargs...)
-	kout, kerr := SeparateOutput(execCmd)
-	if kerr != nil {
-		return "", errors.Errorf("kubectl get failed: %s", kout)
+	objects, err := DecodeManifestFile(fileToApply)
+	if err != nil {
+		return errors.Errorf("Kubernetes apply failed: %v", err)
 	}
-	return kout, kerr
-}
-
-//KubeApply issues kubectl apply -f <filename>
-func KubeApply(log *LoggingConfig, fileToApply string, namespace string, dryrun bool) error {
-	log.Info.log("Attempting to apply resource in Kubernetes ...")
-	kcmd := "kubectl"
-	kargs := []string{"apply", "-f", fileToApply}
-	if namespace != "" {
-		kargs = append(kargs, "--namespace", namespace)
+	client, err := getKubeClient(config)
+	if err != nil {
+		return err
 	}
-
-	if dryrun {
-		log.Info.log("Dry run - skipping execution of: ", kcmd, " ", ArgsToString(kargs))
-		return nil
+	config.Info.log("Applying ", fileToApply, " to namespace ", client.namespaceOrDefault(namespace))
+	applied, err := client.Apply(objects, namespace)
+	if err != nil {
+		return errors.Wrap(err, "Kubernetes apply failed")
 	}
-	log.Info.log("Running command: ", kcmd, " ", ArgsToString(kargs))
-	execCmd := exec.Command(kcmd, kargs...)
-	kout, kerr := SeparateOutput(execCmd)
-	if kerr != nil {
-		return errors.Errorf("kubectl apply failed: %s", kout)
+	for _, object := range applied {
+		config.Debug.logf("Kubernetes apply success: %s/%s", strings.ToLower(object.GetKind()), object.GetName())
 	}
-	log.Debug.log("kubectl apply success: ", string(kout[:]))
-	return kerr
+	return nil
 }
 
-//KubeDelete issues kubectl delete -f <filename>
-func KubeDelete(log *LoggingConfig, fileToApply string, namespace string, dryrun bool) error {
-	log.Info.log("Attempting to delete resource from Kubernetes...")
-	kcmd := "kubectl"
-	kargs := []string{"delete", "-f", fileToApply}
-	if namespace != "" {
-		kargs = append(kargs, "--namespace", namespace)
-	}
-
+//KubeDelete deletes the objects in <filename> from the Kubernetes cluster
+func KubeDelete(config *RootCommandConfig, fileToApply string, namespace string, dryrun bool) error {
+	config.Info.log("Attempting to delete resource from Kubernetes...")
 	if dryrun {
-		log.Info.log("Dry run - skipping execution of: ", kcmd, " ", ArgsToString(kargs))
+		config.Info.log("Dry run - skipping delete of: ", fileToApply)
 		return nil
 	}
-	log.Info.log("Running command: ", kcmd, " ", ArgsToString(kargs))
-	execCmd := exec.Command(kcmd, kargs...)
-
-	kout, kerr := SeparateOutput(execCmd)
-	if kerr != nil {
-		return errors.Errorf("kubectl delete failed: %s", kout)
+	objects, err := DecodeManifestFile(fileToApply)
+	if err != nil {
+		return errors.Errorf("Kubernetes delete failed: %v", err)
+	}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return err
 	}
-	log.Debug.log("kubectl delete success: ", kout)
-	return kerr
+	config.Info.log("Deleting ", fileToApply, " from namespace ", client.namespaceOrDefault(namespace))
+	err = client.Delete(objects, namespace)
+	if err != nil {
+		return errors.Wrap(err, "Kubernetes delete failed")
+	}
+	config.Debug.log("Kubernetes delete success: ", fileToApply)
+	return nil
 }
 
-//KubeGetNodePortURLIBMCloud issues several kubectl commands and prints the concatenated URL
-func KubeGetNodePortURLIBMCloud(log *LoggingConfig, service string, namespace string, dryrun bool) (url string, err error) {
-	kargs := []string{"pod"}
-	kargs = append(kargs, "-l", "app.kubernetes.io/name="+service, "-o", "jsonpath={.items[].spec.nodeName}")
-	nodeName, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
+//KubeGetNodePortURLIBMCloud looks up the external IP of the node running the service and its node port
+func KubeGetNodePortURLIBMCloud(config *RootCommandConfig, service string, namespace string, dryrun bool) (url string, err error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping lookup of the node port URL for service: ", service)
+		return "", nil
+	}
+	client, err := getKubeClient(config)
 	if err != nil {
-		return "", errors.Errorf("Failed to find nodeName for deployed service: %s", err)
+		return "", err
 	}
-
-	kargs = append([]string{"node"}, nodeName)
-	kargs = append(kargs, "-o", "jsonpath=http://{.status.addresses[?(@.type=='ExternalIP')].address}")
-	hostURL, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
+	namespace = client.namespaceOrDefault(namespace)
+	pods, err := client.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: "app.kubernetes.io/name=" + service})
 	if err != nil {
-		return "", errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+		return "", errors.Errorf("Failed to find nodeName for deployed service: %v", err)
 	}
-	kargs = append([]string{"svc"}, service)
-	kargs = append(kargs, "-o", "jsonpath="+hostURL+":{.spec.ports[0].nodePort}")
-	out, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
+	if len(pods.Items) == 0 || pods.Items[0].Spec.NodeName == "" {
+		return "", errors.Errorf("Failed to find nodeName for deployed service: no pods are scheduled for service %s", service)
+	}
+	node, err := client.Clientset.CoreV1().Nodes().Get(pods.Items[0].Spec.NodeName, metav1.GetOptions{})
 	if err != nil {
-		return "", errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+		return "", errors.Errorf("Failed to find deployed service IP and Port: %v", err)
 	}
-	return out, nil
+	hostIP := nodeAddress(node, corev1.NodeExternalIP)
+	if hostIP == "" {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: node %s does not have an external IP address", node.Name)
+	}
+	svc, err := client.Clientset.CoreV1().Services(namespace).Get(service, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: %v", err)
+	}
+	if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: service %s does not have a node port", service)
+	}
+	return fmt.Sprintf("http://%s:%d", hostIP, svc.Spec.Ports[0].NodePort), nil
 }
 
-//KubeGetClusterURL kubectl get svc <service> -o jsonpath=http://{.spec.clusterIP}:{.spec.ports[0].port} and prints the return URL
-func KubeGetClusterURL(log *LoggingConfig, service string, namespace string, dryrun bool) (url string, err error) {
-	kargs := append([]string{"svc"}, service)
-	kargs = append(kargs, "-o", "jsonpath=http://{.spec.clusterIP}:{.spec.ports[0].port}")
-	out, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
+// getKubeService returns the service, or nil if this is a dry run
+func getKubeService(config *RootCommandConfig, service string, namespace string, dryrun bool) (*corev1.Service, error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping lookup of service: ", service)
+		return nil, nil
+	}
+	client, err := getKubeClient(config)
 	if err != nil {
-		return "", errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+		return nil, err
 	}
-	out = out + "\nHowever, as the ServiceType was specified as ClusterIP this url is only accessible to other applications in the same cluster." +
-		"\nTo access it try using 'kubectl port-forward', or exposing it further by 'oc expose'"
-	return out, nil
+	return client.Clientset.CoreV1().Services(client.namespaceOrDefault(namespace)).Get(service, metav1.GetOptions{})
 }
 
-//KubeGetNodePortURL kubectl get svc <service> -o jsonpath=http://{.status.loadBalancer.ingress[0].hostname}:{.spec.ports[0].nodePort} and prints the return URL
-func KubeGetNodePortURL(log *LoggingConfig, service string, namespace string, dryrun bool) (url string, err error) {
-	kargs := append([]string{"svc"}, service)
-	kargs = append(kargs, "-o", "jsonpath=http://{.status.loadBalancer.ingress[0].hostname}:{.spec.ports[0].nodePort}")
-	out, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
-	if err != nil || strings.Contains(out, "://:") {
-		return "", errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+//KubeGetClusterURL returns http://<clusterIP>:<port> for the service
+func KubeGetClusterURL(config *RootCommandConfig, service string, namespace string, dryrun bool) (url string, err error) {
+	svc, err := getKubeService(config, service, namespace, dryrun)
+	if err != nil {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: %v", err)
 	}
+	if svc == nil {
+		return "", nil
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: service %s does not expose any ports", service)
+	}
+	out := fmt.Sprintf("http://%s:%d", svc.Spec.ClusterIP, svc.Spec.Ports[0].Port)
+	out = out + "\nHowever, as the ServiceType was specified as ClusterIP this url is only accessible to other applications in the same cluster." +
+		"\nTo access it try using 'kubectl port-forward', or exposing it further by 'oc expose'"
 	return out, nil
 }
 
-//KubeGetRouteURL issues kubectl get svc <service> -o jsonpath=http://{.status.loadBalancer.ingress[0].hostname}:{.spec.ports[0].nodePort} and prints the return URL
-func KubeGetRouteURL(log *LoggingConfig, service string, namespace string, dryrun bool) (url string, err error) {
-	kargs := append([]string{"route"}, service)
-	kargs = append(kargs, "-o", "jsonpath={.status.ingress[0].host}")
-	out, err := KubeGet(log, kargs, namespace, dryrun)
-	// Performing the kubectl apply
+//KubeGetNodePortURL returns http://<load balancer hostname>:<nodePort> for the service
+func KubeGetNodePortURL(config *RootCommandConfig, service string, namespace string, dryrun bool) (url string, err error) {
+	svc, err := getKubeService(config, service, namespace, dryrun)
 	if err != nil {
-		return "", errors.Errorf("Failed to find deployed service IP and Port: %s", err)
+		return "", errors.Errorf("Failed to find deployed service IP and Port: %v", err)
 	}
-	return out, nil
+	if svc == nil {
+		return "", nil
+	}
+	if len(svc.Status.LoadBalancer.Ingress) == 0 || svc.Status.LoadBalancer.Ingress[0].Hostname == "" || len(svc.Spec.Ports) == 0 {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: service %s does not have a load balancer hostname", service)
+	}
+	return fmt.Sprintf("http://%s:%d", svc.Status.LoadBalancer.Ingress[0].Hostname, svc.Spec.Ports[0].NodePort), nil
 }
 
-//KubeGetKnativeURL issues kubectl get rt <service> -o jsonpath="{.status.url}" and prints the return URL
-func KubeGetKnativeURL(log *LoggingConfig, service string, namespace string, dryrun bool) (url string, err error) {
-	kcmd := "kubectl"
-	kargs := append([]string{"get", "rt"}, service)
-	kargs = append(kargs, "-o", "jsonpath=\"{.status.url}\"")
-	if namespace != "" {
-		kargs = append(kargs, "--namespace", namespace)
+//KubeGetRouteURL returns the host of the OpenShift route for the service
+func KubeGetRouteURL(config *RootCommandConfig, service string, namespace string, dryrun bool) (url string, err error) {
+	if dryrun {
+		config.Info.log("Dry run - skipping lookup of route: ", service)
+		return "", nil
+	}
+	client, err := getKubeClient(config)
+	if err != nil {
+		return "", err
+	}
+	route, err := client.GetByKind(schema.GroupKind{Group: "route.openshift.io", Kind: "Route"}, service, namespace)
+	if err != nil {
+		return "", errors.Errorf("Failed to find deployed service IP and Port: %v", err)
 	}
+	ingresses, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+	if len(ingresses) > 0 {
+		if ingress, ok := ingresses[0].(map[string]interface{}); ok {
+			if host, ok := ingress["host"].(string); ok && host != "" {
+				return host, nil
+			}
+		}
+	}
+	return "", errors.Errorf("Failed to find deployed service IP and Port: route %s has not been admitted", service)
+}
 
+//KubeGetKnativeURL returns the status URL of the Knative route for the service
+func KubeGetKnativeURL(config *RootCommandConfig, service string, namespace string, dryrun bool) (url string, err error) {
 	if dryrun {
-		log.Info.log("Dry run - skipping execution of: ", kcmd, " ", ArgsToString(kargs))
+		config.Info.log("Dry run - skipping lookup of Knative route: ", service)
 		return "", nil
 	}
-	log.Info.log("Running command: ", kcmd, " ", ArgsToString(kargs))
-	execCmd := exec.Command(kcmd, kargs...)
-	kout, kerr := SeparateOutput(execCmd)
-	if kerr != nil {
-		return "", errors.Errorf("kubectl get failed: %s", kout)
+	client, err := getKubeClient(config)
+	if err != nil {
+		return "", err
 	}
-	return kout, kerr
+	route, err := client.GetByKind(schema.GroupKind{Group: "serving.knative.dev", Kind: "Route"}, service, namespace)
+	if err != nil {
+		return "", errors.Errorf("Failed to get the Knative route: %v", err)
+	}
+	url, _, _ = unstructured.NestedString(route.Object, "status", "url")
+	if url == "" {
+		return "", errors.Errorf("Failed to get the Knative route: route %s does not have a URL yet", service)
+	}
+	return url, nil
 }
 
 //KubeGetDeploymentURL searches for an exposed hostname and port for the deployed service
-func KubeGetDeploymentURL(log *LoggingConfig, serviceName string, service map[string]interface{}, namespace string, dryrun bool) (url string, err error) {
+func KubeGetDeploymentURL(config *RootCommandConfig, serviceName string, service map[string]interface{}, namespace string, dryrun bool) (url string, err error) {
 	serviceType := ""
 	if service != nil {
 		serviceType = service["type"].(string)
 	}
 	if serviceType == "ClusterIP" {
 		// We have a ClusterIP type
-		url, err = KubeGetClusterURL(log, serviceName, namespace, dryrun)
+		url, err = KubeGetClusterURL(config, serviceName, namespace, dryrun)
 		if err == nil {
 			return url, nil
 		}
 	} else {
-		url, err = KubeGetKnativeURL(log, serviceName, namespace, dryrun)
+		url, err = KubeGetKnativeURL(config, serviceName, namespace, dryrun)
 		if err == nil {
 			return url, nil
 		}
-		url, err = KubeGetRouteURL(log, serviceName, namespace, dryrun)
+		url, err = KubeGetRouteURL(config, serviceName, namespace, dryrun)
 		if err == nil {
 			return url, nil
 		}
-		url, err = KubeGetNodePortURL(log, serviceName, namespace, dryrun)
+		url, err = KubeGetNodePortURL(config, serviceName, namespace, dryrun)
 		if err == nil {
 			return url, nil
 		}
-		url, err = KubeGetNodePortURLIBMCloud(log, serviceName, namespace, dryrun)
+		url, err = KubeGetNodePortURLIBMCloud(config, serviceName, namespace, dryrun)
 		if err == nil {
 			return url, nil
 		}
 	}
-	log.Error.log("Failed to get deployment hostname and port: ", err)
+	config.Error.log("Failed to get deployment hostname and port: ", err)
 	return "", err
 }
 
@@ -2186,6 +2217,10 @@ func getLastCheckTime(config *RootCommandConfig) string {
 }
 
 func checkTime(config *RootCommandConfig) {
+	if isOffline(config) {
+		config.Debug.log("Offline - skipping the check for a new version of the Appsody CLI")
+		return
+	}
 	var lastCheckTime = getLastCheckTime(config)
 
 	lastTime, err := time.Parse("2006-01-02 15:04:05 -0700 MST", lastCheckTime)
@@ -2230,35 +2265,16 @@ func IsEmptyDir(name string) bool {
 	return err == io.EOF
 }
 
-func downloadFile(log *LoggingConfig, href string, writer io.Writer) error {
+// downloadFile writes the contents of the URL to the writer, adding the credentials of its host
+func downloadFile(log *LoggingConfig, creds *CredentialStore, href string, writer io.Writer) error {
 
-	// allow file:// scheme
-	t := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}
-	log.Debug.log("Proxy function for HTTP transport set to: ", &t.Proxy)
-	if runtime.GOOS == "windows" {
-		// For Windows, remove the root url. It seems to work fine with an empty string.
-		t.RegisterProtocol("file", http.NewFileTransport(http.Dir("")))
-	} else {
-		t.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
-	}
-
-	httpClient := &http.Client{Transport: t}
+	httpClient := newDownloadClient(log, creds)
 
-	req, err := http.NewRequest("GET", href, nil)
+	req, err := newDownloadRequest(href, creds)
 	if err != nil {
 		return err
 	}
 
-	if strings.Contains(href, "http") {
-		token := os.Getenv("GH_READ_TOKEN")
-		if token != "" {
-			token = "token " + token
-			req.Header.Add("Authorization", token)
-		}
-	}
-
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
@@ -2282,7 +2298,43 @@ func downloadFile(log *LoggingConfig, href string, writer io.Writer) error {
 	return nil
 }
 
-func downloadFileToDisk(log *LoggingConfig, url string, destFile string, dryrun bool) error {
+// newDownloadClient returns an HTTP client that uses the proxy from the environment and allows the file:// scheme.
+// Credentials are only sent to the hosts they are for, including when a request is redirected.
+func newDownloadClient(log *LoggingConfig, creds *CredentialStore) *http.Client {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+	log.Debug.log("Proxy function for HTTP transport set to: ", &t.Proxy)
+	if runtime.GOOS == "windows" {
+		// For Windows, remove the root url. It seems to work fine with an empty string.
+		t.RegisterProtocol("file", http.NewFileTransport(http.Dir("")))
+	} else {
+		t.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	}
+
+	checkRedirect := func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return creds.redirect(req, via[0])
+	}
+	return &http.Client{Transport: t, CheckRedirect: checkRedirect}
+}
+
+// newDownloadRequest returns a GET request for the URL, with the credentials of its host
+func newDownloadRequest(href string, creds *CredentialStore) (*http.Request, error) {
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = creds.Authorize(req)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func downloadFileToDisk(log *LoggingConfig, creds *CredentialStore, url string, destFile string, dryrun bool) error {
 	if dryrun {
 		log.Info.logf("Dry Run -Skipping download of url: %s to destination %s", url, destFile)
 
@@ -2293,7 +2345,7 @@ func downloadFileToDisk(log *LoggingConfig, url string, destFile string, dryrun
 		}
 		defer outFile.Close()
 
-		err = downloadFile(log, url, outFile)
+		err = downloadFile(log, creds, url, outFile)
 		if err != nil {
 			return err
 		}
@@ -2366,37 +2418,61 @@ func Targz(log *LoggingConfig, source, target, filename string) error {
 		})
 }
 
-//Compares the minimum requirements of a stack against the user to determine whether they can use the stack or not.
-func CheckStackRequirements(log *LoggingConfig, requirementArray map[string]string, buildah bool) error {
-	versionRegex := regexp.MustCompile(`(\d)+\.(\d)+\.(\d)+`)
-	upgradesRequired := 0
+// the results of checking a stack requirement against the local environment
+const (
+	requirementMet      = "met"
+	requirementUpgrade  = "upgrade required"
+	requirementMissing  = "not installed"
+	requirementSkipped  = "skipped"
+	requirementUnknown  = "unknown"
+	requirementInvalid  = "invalid requirement"
+	requirementLocalCLI = "local build"
+)
 
-	log.Info.log("Checking stack requirements...")
+// RequirementCheck is the result of comparing the version of a technology against the minimum version required by a stack
+type RequirementCheck struct {
+	Technology string `yaml:"technology" json:"technology"`
+	Required   string `yaml:"required" json:"required"`
+	Found      string `yaml:"found,omitempty" json:"found,omitempty"`
+	Result     string `yaml:"result" json:"result"`
+}
 
+// StackRequirementChecks checks each of the requirements of a stack against the local environment.
+// The Docker requirement is skipped when Buildah is used, and the other way around.
+func StackRequirementChecks(log *LoggingConfig, requirementArray map[string]string, buildah bool) []RequirementCheck {
+	versionRegex := regexp.MustCompile(`(\d)+\.(\d)+\.(\d)+`)
+	technologies := make([]string, 0, len(requirementArray))
 	for technology, minVersion := range requirementArray {
-		if minVersion == "" {
-			continue
+		if minVersion != "" {
+			technologies = append(technologies, technology)
 		}
-		if technology == "Docker" && buildah {
-			log.Debug.log("Skipping Docker requirement - Buildah is being used.")
-			continue
-		}
-		if technology == "Buildah" && !buildah {
-			log.Debug.log("Skipping Buildah requirement - Docker is being used.")
+	}
+	sort.Strings(technologies)
+
+	checks := make([]RequirementCheck, 0, len(technologies))
+	for _, technology := range technologies {
+		minVersion := requirementArray[technology]
+		check := RequirementCheck{Technology: technology, Required: minVersion}
+		checks = append(checks, check)
+		result := &checks[len(checks)-1]
+		if (technology == "Docker" && buildah) || (technology == "Buildah" && !buildah) {
+			result.Result = requirementSkipped
 			continue
 		}
 		log.Debug.logf("Checking version requirement: %s %s", technology, minVersion)
 
 		setConstraint, err := semver.NewConstraint(minVersion)
 		if err != nil {
-			log.Warning.logf("Skipping %s version requirement because the minimum version is invalid: %s", technology, err)
+			log.Debug.logf("The minimum %s version %s is invalid: %s", technology, minVersion, err)
+			result.Result = requirementInvalid
 			continue
 		}
 
 		var runVersionCmd string
 		if strings.ToLower(technology) == "appsody" {
 			if VERSION == "0.0.0" || VERSION == "vlatest" {
-				log.Warning.log("Skipping appsody version requirement because this is a local build of appsody ", VERSION)
+				result.Found = VERSION
+				result.Result = requirementLocalCLI
 				continue
 			}
 			runVersionCmd = VERSION
@@ -2409,8 +2485,8 @@ func CheckStackRequirements(log *LoggingConfig, requirementArray map[string]stri
 			}
 			runVersionCmd, err = SeparateOutput(cmd)
 			if err != nil {
-				log.Error.log(err, " - Are you sure ", technology, " is installed?")
-				upgradesRequired++
+				log.Debug.logf("Could not run %s version: %v", strings.ToLower(technology), err)
+				result.Result = requirementMissing
 				continue
 			}
 			log.Debug.logf("Output of running %s: %s", strings.ToLower(technology)+" version", runVersionCmd)
@@ -2419,17 +2495,49 @@ func CheckStackRequirements(log *LoggingConfig, requirementArray map[string]stri
 		cutCmdOutput := versionRegex.FindString(runVersionCmd)
 		parseUserVersion, parseErr := semver.NewVersion(cutCmdOutput)
 		if parseErr != nil {
-			log.Warning.logf("Unable to parse %s version - This stack may not work in your current development environment. %s", technology, parseErr)
-			// Continue when the version can not be determined
+			log.Debug.logf("Unable to parse %s version: %s", technology, parseErr)
+			result.Result = requirementUnknown
 			continue
 		}
 		log.Debug.logf("Found version of %s to be %s", technology, parseUserVersion)
-		compareVersion := setConstraint.Check(parseUserVersion)
-
-		if compareVersion {
-			log.Info.log(technology + " requirements met")
+		result.Found = parseUserVersion.String()
+		if setConstraint.Check(parseUserVersion) {
+			result.Result = requirementMet
 		} else {
-			log.Error.log("The required version of " + technology + " to use this stack is " + minVersion + " - Please upgrade.")
+			result.Result = requirementUpgrade
+		}
+	}
+	return checks
+}
+
+//Compares the minimum requirements of a stack against the user to determine whether they can use the stack or not.
+func CheckStackRequirements(log *LoggingConfig, requirementArray map[string]string, buildah bool) error {
+	upgradesRequired := 0
+
+	log.Info.log("Checking stack requirements...")
+
+	for _, check := range StackRequirementChecks(log, requirementArray, buildah) {
+		switch check.Result {
+		case requirementSkipped:
+			if buildah {
+				log.Debug.log("Skipping Docker requirement - Buildah is being used.")
+			} else {
+				log.Debug.log("Skipping Buildah requirement - Docker is being used.")
+			}
+		case requirementInvalid:
+			log.Warning.logf("Skipping %s version requirement because the minimum version %s is invalid", check.Technology, check.Required)
+		case requirementLocalCLI:
+			log.Warning.log("Skipping appsody version requirement because this is a local build of appsody ", VERSION)
+		case requirementMissing:
+			log.Error.log("Could not find the version of ", check.Technology, " - Are you sure ", check.Technology, " is installed?")
+			upgradesRequired++
+		case requirementUnknown:
+			// Continue when the version can not be determined
+			log.Warning.logf("Unable to parse %s version - This stack may not work in your current development environment.", check.Technology)
+		case requirementMet:
+			log.Info.log(check.Technology + " requirements met")
+		case requirementUpgrade:
+			log.Error.log("The required version of " + check.Technology + " to use this stack is " + check.Required + " - Please upgrade.")
 			upgradesRequired++
 		}
 	}
@@ -2626,9 +2734,27 @@ func RemoveIfExists(path string) error {
 }
 
 func generateCodewindJSON(log *LoggingConfig, indexYaml IndexYaml, indexFilePath string, repoName string) error {
+	data, err := codewindJSON(indexYaml, repoName)
+	if err != nil {
+		return err
+	}
+	indexFilePath = strings.Replace(indexFilePath, ".yaml", ".json", 1)
+
+	err = ioutil.WriteFile(indexFilePath, data, 0666)
+	if err != nil {
+		return errors.Errorf("Error writing to json file: %v", err)
+	}
+
+	log.Info.logf("Succesfully generated file: %s", indexFilePath)
+	return nil
+}
+
+// codewindJSON returns the Codewind index of the templates of the newest version of each stack
+func codewindJSON(indexYaml IndexYaml, repoName string) ([]byte, error) {
 	indexJSONStack := make([]IndexJSONStack, 0)
 	prefixName := strings.Title(repoName)
-	for _, stack := range indexYaml.Stacks {
+	// Codewind offers the newest version of each stack
+	for _, stack := range indexYaml.latestStackVersions() {
 		for _, template := range stack.Templates {
 			stackJSON := IndexJSONStack{}
 			if stack.Deprecated != "" {
@@ -2651,20 +2777,7 @@ func generateCodewindJSON(log *LoggingConfig, indexYaml IndexYaml, indexFilePath
 		}
 	}
 
-	// Last thing to do is write the data to the file
-	data, err := json.MarshalIndent(&indexJSONStack, "", "	")
-	if err != nil {
-		return err
-	}
-	indexFilePath = strings.Replace(indexFilePath, ".yaml", ".json", 1)
-
-	err = ioutil.WriteFile(indexFilePath, data, 0666)
-	if err != nil {
-		return errors.Errorf("Error writing to json file: %v", err)
-	}
-
-	log.Info.logf("Succesfully generated file: %s", indexFilePath)
-	return nil
+	return json.MarshalIndent(&indexJSONStack, "", "	")
 }
 
 func getProjectYamlPath(rootConfig *RootCommandConfig) string {
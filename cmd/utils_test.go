@@ -28,7 +28,15 @@ import (
 
 	cmd "github.com/appsody/appsody/cmd"
 	"github.com/appsody/appsody/cmd/cmdtest"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var validProjectNameTests = []string{
@@ -964,57 +972,49 @@ func TestImagePushNoReg(t *testing.T) {
 	}
 }
 
-func TestKubeGetFailNoRes(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
+func newKubeTestConfig(objects ...k8sruntime.Object) (*cmd.RootCommandConfig, *bytes.Buffer) {
 	var outBuffer bytes.Buffer
 	loggingConfig := &cmd.LoggingConfig{}
 	loggingConfig.InitLogging(&outBuffer, &outBuffer)
-
-	cmdParms := []string{}
-	_, err := cmd.KubeGet(loggingConfig, cmdParms, "", false)
-
-	if err != nil {
-		if !strings.Contains(err.Error(), "exit status 1: You must specify the type of resource to get.") {
-			t.Errorf("String \"exit status 1: You must specify the type of resource to get.\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
-	}
+	config := &cmd.RootCommandConfig{LoggingConfig: loggingConfig, KubeClient: cmdtest.NewFakeKubeClient(objects...)}
+	return config, &outBuffer
 }
 
-func TestKubeGetFailIncorrectRes(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func writeKubeManifest(t *testing.T, manifest string) string {
+	file, err := ioutil.TempFile("", "appsody-kube-test-*.yaml")
+	if err != nil {
+		t.Fatal(err)
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
-
-	cmdParms := []string{"invalid"}
-	_, err := cmd.KubeGet(loggingConfig, cmdParms, "", false)
-
+	defer file.Close()
+	_, err = file.WriteString(manifest)
 	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl get failed: exit status 1: error: the server doesn't have a resource type \"invalid\"") {
-			t.Errorf("String \"kubectl get failed: exit status 1: error: the server doesn't have a resource type \"invalid\"\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
+		t.Fatal(err)
 	}
-}
+	return file.Name()
+}
+
+const kubeTestManifest = `apiVersion: v1
+kind: Service
+metadata:
+  name: my-service
+spec:
+  ports:
+  - port: 8080
+---
+apiVersion: appsody.dev/v1beta1
+kind: AppsodyApplication
+metadata:
+  name: my-app
+spec:
+  applicationImage: my-image
+`
 
 func TestKubeApplyDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	fileName := "file"
 
-	err := cmd.KubeApply(loggingConfig, fileName, "namespace", true)
+	err := cmd.KubeApply(config, fileName, "namespace", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube apply: %v", err)
@@ -1022,20 +1022,15 @@ func TestKubeApplyDryrun(t *testing.T) {
 }
 
 func TestKubeApplyFailFNF(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	fileName := "file"
 
-	err := cmd.KubeApply(loggingConfig, fileName, "", false)
+	err := cmd.KubeApply(config, fileName, "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl apply failed: exit status 1: error: the path \""+fileName+"\" does not exist") {
-			t.Errorf("String \"kubectl apply failed: exit status 1: error: the path \"%s\" does not exist\" not found in output: '%v'", fileName, err.Error())
+		if !strings.Contains(err.Error(), "Kubernetes apply failed: open file: no such file or directory") {
+			t.Errorf("String \"Kubernetes apply failed: open file: no such file or directory\" not found in output: '%v'", err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
@@ -1043,91 +1038,89 @@ func TestKubeApplyFailFNF(t *testing.T) {
 }
 
 func TestKubeApplyFailFileInvalid(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
-
-	fileName := "file"
+	config, _ := newKubeTestConfig()
 
-	// Ensure that the fake yaml file is deleted
-	defer func() {
-		err := os.Remove(fileName)
-		if err != nil {
-			t.Errorf("Error removing the file: %s", err)
-		}
-	}()
-
-	// Attempt to create the fake file
-	_, err := os.Create(fileName)
-	if err != nil {
-		t.Errorf("Error creating the file: %v", err)
-	}
+	fileName := writeKubeManifest(t, "")
+	defer os.Remove(fileName)
 
-	err = cmd.KubeApply(loggingConfig, fileName, "", false)
+	err := cmd.KubeApply(config, fileName, "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl apply failed: exit status 1: error: no objects passed to apply") {
-			t.Errorf("String \"kubectl apply failed: exit status 1: error: no objects passed to apply\" not found in output: '%v'", err.Error())
+		if !strings.Contains(err.Error(), "No objects found in "+fileName) {
+			t.Errorf("String \"No objects found in %s\" not found in output: '%v'", fileName, err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
 	}
 }
 
-func TestKubeApplyFailPermission(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeApplyFailUnknownKind(t *testing.T) {
+	config, _ := newKubeTestConfig()
+
+	fileName := writeKubeManifest(t, "apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: unknown\n")
+	defer os.Remove(fileName)
+
+	err := cmd.KubeApply(config, fileName, "", false)
+
+	if err == nil {
+		t.Fatal("Expected an error to be returned from command, but error was nil")
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	if !meta.IsNoMatchError(errors.Cause(err)) {
+		t.Errorf("Expected a no match error but got: %v", err)
+	}
+}
 
-	fileName := "file"
+func TestKubeApplyAndDelete(t *testing.T) {
+	config, _ := newKubeTestConfig()
 
-	// Ensure that the fake yaml file is deleted
-	defer func() {
-		err := os.Remove(fileName)
+	fileName := writeKubeManifest(t, kubeTestManifest)
+	defer os.Remove(fileName)
+
+	// applying twice updates the objects created by the first apply
+	for i := 0; i < 2; i++ {
+		err := cmd.KubeApply(config, fileName, "my-namespace", false)
 		if err != nil {
-			t.Errorf("Error removing the file: %s", err)
+			t.Fatalf("Unexpected error from kube apply: %v", err)
 		}
-	}()
+	}
 
-	// Attempt to create the fake file
-	file, err := os.Create(fileName)
+	app, err := config.KubeClient.Get(schema.GroupVersionKind{Group: "appsody.dev", Version: "v1beta1", Kind: "AppsodyApplication"}, "my-app", "my-namespace")
 	if err != nil {
-		t.Errorf("Error creating the file: %v", err)
+		t.Fatalf("Expected the AppsodyApplication to be applied but got: %v", err)
+	}
+	if image, _, _ := unstructured.NestedString(app.Object, "spec", "applicationImage"); image != "my-image" {
+		t.Errorf("Expected the application image to be my-image but got %s", image)
 	}
 
-	err = file.Chmod(0333)
+	err = cmd.KubeDelete(config, fileName, "my-namespace", false)
 	if err != nil {
-		t.Errorf("Error changing file permissions: %s", err)
+		t.Fatalf("Unexpected error from kube delete: %v", err)
 	}
+	_, err = config.KubeClient.Get(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, "my-service", "my-namespace")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the service to be deleted but got: %v", err)
+	}
+}
 
-	err = cmd.KubeApply(loggingConfig, fileName, "", false)
+func TestKubeApplyFailNamespaceMismatch(t *testing.T) {
+	config, _ := newKubeTestConfig()
 
-	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl apply failed: exit status 1: error: open file: permission denied") {
-			t.Errorf("String \"kubectl apply failed: exit status 1: error: open file: permission denied\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
+	fileName := writeKubeManifest(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: my-service\n  namespace: other\n")
+	defer os.Remove(fileName)
+
+	err := cmd.KubeApply(config, fileName, "my-namespace", false)
+
+	if err == nil || !strings.Contains(err.Error(), "does not match the namespace my-namespace") {
+		t.Errorf("Expected a namespace mismatch error but got: %v", err)
 	}
 }
 
 func TestKubeDeleteDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	fileName := "file"
 
-	err := cmd.KubeDelete(loggingConfig, fileName, "namespace", true)
+	err := cmd.KubeDelete(config, fileName, "namespace", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube apply: %v", err)
@@ -1135,101 +1128,65 @@ func TestKubeDeleteDryrun(t *testing.T) {
 }
 
 func TestKubeDeleteFailFNF(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	fileName := "file"
 
-	err := cmd.KubeDelete(loggingConfig, fileName, "", false)
+	err := cmd.KubeDelete(config, fileName, "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl delete failed: exit status 1: error: the path \""+fileName+"\" does not exist") {
-			t.Errorf("String \"kubectl delete failed: exit status 1: error: the path \"%s\" does not exist\" not found in output: '%v'", fileName, err.Error())
+		if !strings.Contains(err.Error(), "Kubernetes delete failed: open file: no such file or directory") {
+			t.Errorf("String \"Kubernetes delete failed: open file: no such file or directory\" not found in output: '%v'", err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
 	}
 }
 
-func TestKubeDeleteFailPermission(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+func TestKubeDeleteFailNotFound(t *testing.T) {
+	config, _ := newKubeTestConfig()
 
-	fileName := "file"
+	fileName := writeKubeManifest(t, kubeTestManifest)
+	defer os.Remove(fileName)
 
-	// Ensure that the fake yaml file is deleted
-	defer func() {
-		err := os.Remove(fileName)
-		if err != nil {
-			t.Errorf("Error removing the file: %s", err)
-		}
-	}()
+	err := cmd.KubeDelete(config, fileName, "", false)
 
-	// Attempt to create the fake file
-	file, err := os.Create(fileName)
-	if err != nil {
-		t.Errorf("Error creating the file: %v", err)
+	if err == nil {
+		t.Fatal("Expected an error to be returned from command, but error was nil")
 	}
-
-	err = file.Chmod(0333)
-	if err != nil {
-		t.Errorf("Error changing file permissions: %s", err)
-	}
-
-	err = cmd.KubeDelete(loggingConfig, fileName, "", false)
-
-	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl delete failed: exit status 1: error: open file: permission denied") {
-			t.Errorf("String \"kubectl delete failed: exit status 1: error: open file: permission denied\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
+	if !apierrors.IsNotFound(errors.Cause(err)) {
+		t.Errorf("Expected a not found error but got: %v", err)
 	}
 }
 
-func TestKubeGetNodePortURLFailNoService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeGetNodePortURL(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "namespace"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 8080, NodePort: 30080}}},
+		Status:     corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{Hostname: "my-host"}}}},
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig(service)
 
-	_, err := cmd.KubeGetNodePortURL(loggingConfig, "", "namespace", false)
+	url, err := cmd.KubeGetNodePortURL(config, "svc", "namespace", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: resource name may not be empty") {
-			t.Errorf("String \"Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: resource name may not be empty\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
+		t.Fatalf("Unexpected error from kube get: %v", err)
+	}
+	if url != "http://my-host:30080" {
+		t.Errorf("Expected http://my-host:30080 but got %s", url)
 	}
 }
 
 func TestKubeGetNodePortURLFailInvalidService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	service := "definitelynotaservice"
 
-	_, err := cmd.KubeGetNodePortURL(loggingConfig, service, "", false)
+	_, err := cmd.KubeGetNodePortURL(config, service, "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: kubectl get failed: exit status 1: Error from server (NotFound): services \""+service+"\" not found") {
-			t.Errorf("String \"Failed to find deployed service IP and Port: kubectl get failed: exit status 1: Error from server (NotFound): services \"%s\" not found\" not found in output: '%v'", service, err.Error())
+		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: services \""+service+"\" not found") {
+			t.Errorf("String \"Failed to find deployed service IP and Port: services \"%s\" not found\" not found in output: '%v'", service, err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
@@ -1237,56 +1194,71 @@ func TestKubeGetNodePortURLFailInvalidService(t *testing.T) {
 }
 
 func TestKubeGetNodePortURLDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	service := "svc"
 
-	_, err := cmd.KubeGetNodePortURL(loggingConfig, service, "", true)
+	_, err := cmd.KubeGetNodePortURL(config, service, "", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube get: %v", err)
 	}
 }
 
-func TestKubeGetDeploymentURLFailNoService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeGetNodePortURLIBMCloud(t *testing.T) {
+	objects := []k8sruntime.Object{
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc-1234", Namespace: "default", Labels: map[string]string{"app.kubernetes.io/name": "svc"}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}, {Type: corev1.NodeExternalIP, Address: "169.1.2.3"}}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 8080, NodePort: 30080}}},
+		},
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig(objects...)
 
-	_, err := cmd.KubeGetDeploymentURL(loggingConfig, "", nil, "namespace", false)
+	url, err := cmd.KubeGetNodePortURLIBMCloud(config, "svc", "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: resource name may not be empty") {
-			t.Errorf("String \"Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: resource name may not be empty\" not found in output: '%v'", err.Error())
-		}
-	} else {
-		t.Error("Expected an error to be returned from command, but error was nil")
+		t.Fatalf("Unexpected error from kube get: %v", err)
+	}
+	if url != "http://169.1.2.3:30080" {
+		t.Errorf("Expected http://169.1.2.3:30080 but got %s", url)
 	}
 }
 
-func TestKubeGetDeploymentURLFailInvalidService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeGetDeploymentURLClusterIP(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "namespace"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.1.2.3", Ports: []corev1.ServicePort{{Port: 8080}}},
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig(service)
+
+	url, err := cmd.KubeGetDeploymentURL(config, "svc", map[string]interface{}{"type": "ClusterIP"}, "namespace", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error from kube get: %v", err)
+	}
+	if !strings.HasPrefix(url, "http://10.1.2.3:8080\n") {
+		t.Errorf("Expected the URL to start with http://10.1.2.3:8080 but got %s", url)
+	}
+}
+
+func TestKubeGetDeploymentURLFailInvalidService(t *testing.T) {
+	config, _ := newKubeTestConfig()
 
 	service := "definitelynotaservice"
 
-	_, err := cmd.KubeGetDeploymentURL(loggingConfig, service, nil, "", false)
+	_, err := cmd.KubeGetDeploymentURL(config, service, nil, "", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: kubectl get failed: exit status 1: Error from server (NotFound): services \""+service+"\" not found") {
-			t.Errorf("String \"Failed to find deployed service IP and Port: kubectl get failed: exit status 1: Error from server (NotFound): services \"%s\" not found\" not found in output: '%v'", service, err.Error())
+		if !strings.Contains(err.Error(), "Failed to find nodeName for deployed service") {
+			t.Errorf("String \"Failed to find nodeName for deployed service\" not found in output: '%v'", err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
@@ -1294,35 +1266,46 @@ func TestKubeGetDeploymentURLFailInvalidService(t *testing.T) {
 }
 
 func TestKubeGetDeploymentURLDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	service := "svc"
 
-	_, err := cmd.KubeGetDeploymentURL(loggingConfig, service, nil, "", true)
+	_, err := cmd.KubeGetDeploymentURL(config, service, nil, "", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube get: %v", err)
 	}
 }
 
-func TestKubeGetRouteURLFailInvalidService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeGetRouteURL(t *testing.T) {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": "svc", "namespace": "namespace"},
+		"status": map[string]interface{}{
+			"ingress": []interface{}{map[string]interface{}{"host": "svc-namespace.apps.example.com"}},
+		},
+	}}
+	config, _ := newKubeTestConfig(route)
+
+	url, err := cmd.KubeGetRouteURL(config, "svc", "namespace", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error from kube get: %v", err)
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	if url != "svc-namespace.apps.example.com" {
+		t.Errorf("Expected svc-namespace.apps.example.com but got %s", url)
+	}
+}
 
-	_, err := cmd.KubeGetRouteURL(loggingConfig, "", "namespace", false)
+func TestKubeGetRouteURLFailInvalidService(t *testing.T) {
+	config, _ := newKubeTestConfig()
+
+	_, err := cmd.KubeGetRouteURL(config, "svc", "namespace", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: the server doesn't have a resource type") {
-			t.Errorf("String \"Failed to find deployed service IP and Port: kubectl get failed: exit status 1: error: the server doesn't have a resource type\" not found\" not found in output: '%v'", err.Error())
+		if !strings.Contains(err.Error(), "Failed to find deployed service IP and Port: routes.route.openshift.io \"svc\" not found") {
+			t.Errorf("String \"Failed to find deployed service IP and Port: routes.route.openshift.io \"svc\" not found\" not found in output: '%v'", err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
@@ -1330,35 +1313,44 @@ func TestKubeGetRouteURLFailInvalidService(t *testing.T) {
 }
 
 func TestKubeGetRouteURLDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	service := "svc"
 
-	_, err := cmd.KubeGetRouteURL(loggingConfig, service, "", true)
+	_, err := cmd.KubeGetRouteURL(config, service, "", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube get: %v", err)
 	}
 }
 
-func TestKubeGetKnativeURLFailInvalidService(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
+func TestKubeGetKnativeURL(t *testing.T) {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": "svc", "namespace": "default"},
+		"status":     map[string]interface{}{"url": "http://svc.default.example.com"},
+	}}
+	config, _ := newKubeTestConfig(route)
+
+	url, err := cmd.KubeGetKnativeURL(config, "svc", "", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error from kube get: %v", err)
 	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	if url != "http://svc.default.example.com" {
+		t.Errorf("Expected http://svc.default.example.com but got %s", url)
+	}
+}
+
+func TestKubeGetKnativeURLFailInvalidService(t *testing.T) {
+	config, _ := newKubeTestConfig()
 
-	_, err := cmd.KubeGetKnativeURL(loggingConfig, "", "namespace", false)
+	_, err := cmd.KubeGetKnativeURL(config, "svc", "namespace", false)
 
 	if err != nil {
-		if !strings.Contains(err.Error(), "kubectl get failed: exit status 1: error: the server doesn't have a resource type") {
-			t.Errorf("String \"kubectl get failed: exit status 1: error: the server doesn't have a resource type\" not found in output: '%v'", err.Error())
+		if !strings.Contains(err.Error(), "Failed to get the Knative route: routes.serving.knative.dev \"svc\" not found") {
+			t.Errorf("String \"Failed to get the Knative route: routes.serving.knative.dev \"svc\" not found\" not found in output: '%v'", err.Error())
 		}
 	} else {
 		t.Error("Expected an error to be returned from command, but error was nil")
@@ -1366,16 +1358,11 @@ func TestKubeGetKnativeURLFailInvalidService(t *testing.T) {
 }
 
 func TestKubeGetKnativeURLDryrun(t *testing.T) {
-	if !cmdtest.TravisTesting {
-		t.Skip()
-	}
-	var outBuffer bytes.Buffer
-	loggingConfig := &cmd.LoggingConfig{}
-	loggingConfig.InitLogging(&outBuffer, &outBuffer)
+	config, _ := newKubeTestConfig()
 
 	service := "svc"
 
-	_, err := cmd.KubeGetKnativeURL(loggingConfig, service, "", true)
+	_, err := cmd.KubeGetKnativeURL(config, service, "", true)
 
 	if err != nil {
 		t.Errorf("Unexpected error from kube get: %v", err)
@@ -0,0 +1,303 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// WorkspaceFile is the file, in a parent directory of Appsody projects, that lists the projects of a multi-project application
+const WorkspaceFile = "appsody-workspace.yaml"
+
+// the default number of workspace projects that are built at the same time
+const defaultWorkspaceParallel = 4
+
+// the status of a workspace project after a build or deploy
+const (
+	workspaceSucceeded = "succeeded"
+	workspaceFailed    = "failed"
+	workspaceSkipped   = "skipped"
+)
+
+type Workspace struct {
+	// the directory that contains the workspace file
+	Dir      string             `yaml:"-"`
+	Projects []WorkspaceProject `yaml:"projects"`
+}
+
+type WorkspaceProject struct {
+	// the name used in the summary and by depends-on, which defaults to the directory name
+	Name string `yaml:"name,omitempty"`
+	// the directory of the project, relative to the workspace file
+	Path      string   `yaml:"path"`
+	DependsOn []string `yaml:"depends-on,omitempty"`
+}
+
+type WorkspaceResult struct {
+	Project  WorkspaceProject
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// FindWorkspace looks for the workspace file in the directory and its parents
+func FindWorkspace(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		file := filepath.Join(dir, WorkspaceFile)
+		exists, err := Exists(file)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return ReadWorkspace(file)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, errors.Errorf("Could not find %s in the current directory or any of its parents", WorkspaceFile)
+		}
+		dir = parent
+	}
+}
+
+// ReadWorkspace reads and checks a workspace file
+func ReadWorkspace(file string) (*Workspace, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Errorf("Failed reading workspace file %s: %v", file, err)
+	}
+	var workspace Workspace
+	err = yaml.UnmarshalStrict(data, &workspace)
+	if err != nil {
+		return nil, errors.Errorf("Failed to parse workspace file %s: %v", file, err)
+	}
+	workspace.Dir = filepath.Dir(file)
+	if len(workspace.Projects) == 0 {
+		return nil, errors.Errorf("Workspace file %s does not list any projects", file)
+	}
+
+	names := make(map[string]bool)
+	for i := range workspace.Projects {
+		project := &workspace.Projects[i]
+		if project.Path == "" {
+			return nil, errors.Errorf("Project %d in workspace file %s does not have a path", i+1, file)
+		}
+		if project.Name == "" {
+			project.Name = filepath.Base(filepath.Clean(project.Path))
+		}
+		if names[project.Name] {
+			return nil, errors.Errorf("Project %s is listed more than once in workspace file %s", project.Name, file)
+		}
+		names[project.Name] = true
+	}
+	for _, project := range workspace.Projects {
+		for _, dependency := range project.DependsOn {
+			if !names[dependency] {
+				return nil, errors.Errorf("Project %s depends on %s, which is not in workspace file %s", project.Name, dependency, file)
+			}
+		}
+	}
+	_, err = workspace.DeployOrder()
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// ProjectDir returns the absolute directory of a project in the workspace
+func (w *Workspace) ProjectDir(project WorkspaceProject) string {
+	if filepath.IsAbs(project.Path) {
+		return project.Path
+	}
+	return filepath.Join(w.Dir, project.Path)
+}
+
+// DeployOrder returns the projects so that each project comes after the projects it depends on,
+// otherwise keeping the order of the workspace file
+func (w *Workspace) DeployOrder() ([]WorkspaceProject, error) {
+	var ordered []WorkspaceProject
+	done := make(map[string]bool)
+	for len(ordered) < len(w.Projects) {
+		progress := false
+		for _, project := range w.Projects {
+			if done[project.Name] {
+				continue
+			}
+			ready := true
+			for _, dependency := range project.DependsOn {
+				if !done[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, project)
+				done[project.Name] = true
+				progress = true
+				// start again so that earlier projects keep their place
+				break
+			}
+		}
+		if !progress {
+			var cycle []string
+			for _, project := range w.Projects {
+				if !done[project.Name] {
+					cycle = append(cycle, project.Name)
+				}
+			}
+			return nil, errors.Errorf("The dependencies of projects %s in the workspace form a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// RunWorkspaceParallel runs the function for each project, with at most parallel projects at the same time
+func RunWorkspaceParallel(projects []WorkspaceProject, parallel int, run func(WorkspaceProject) error) []WorkspaceResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]WorkspaceResult, len(projects))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func(i int, project WorkspaceProject) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = runWorkspaceProject(project, run)
+		}(i, project)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunWorkspaceOrdered runs the function for each project in turn, skipping the projects whose dependencies failed
+func RunWorkspaceOrdered(projects []WorkspaceProject, run func(WorkspaceProject) error) []WorkspaceResult {
+	var results []WorkspaceResult
+	failed := make(map[string]bool)
+	for _, project := range projects {
+		var failedDependencies []string
+		for _, dependency := range project.DependsOn {
+			if failed[dependency] {
+				failedDependencies = append(failedDependencies, dependency)
+			}
+		}
+		if len(failedDependencies) > 0 {
+			failed[project.Name] = true
+			results = append(results, WorkspaceResult{Project: project, Status: workspaceSkipped, Err: errors.Errorf("Dependencies %s failed", strings.Join(failedDependencies, ", "))})
+			continue
+		}
+		result := runWorkspaceProject(project, run)
+		if result.Status != workspaceSucceeded {
+			failed[project.Name] = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func runWorkspaceProject(project WorkspaceProject, run func(WorkspaceProject) error) WorkspaceResult {
+	start := time.Now()
+	err := run(project)
+	result := WorkspaceResult{Project: project, Status: workspaceSucceeded, Duration: time.Since(start).Round(time.Second), Err: err}
+	if err != nil {
+		result.Status = workspaceFailed
+	}
+	return result
+}
+
+// FormatWorkspaceSummary returns a table of the results, with an error if any project did not succeed
+func FormatWorkspaceSummary(results []WorkspaceResult) (string, error) {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.Wrap = true
+	table.AddRow("PROJECT", "STATUS", "DURATION", "ERROR")
+	failures := 0
+	for _, result := range results {
+		message := ""
+		if result.Err != nil {
+			message = result.Err.Error()
+		}
+		if result.Status != workspaceSucceeded {
+			failures++
+		}
+		table.AddRow(result.Project.Name, result.Status, result.Duration, message)
+	}
+	if failures > 0 {
+		return table.String(), errors.Errorf("%d of %d projects in the workspace did not succeed", failures, len(results))
+	}
+	return table.String(), nil
+}
+
+// newWorkspaceProjectConfig returns a copy of the root config for a project in the workspace.
+// The caches of the root config are not shared, so that projects can be built at the same time.
+func newWorkspaceProjectConfig(config *RootCommandConfig, projectDir string) (*RootCommandConfig, error) {
+	exists, err := Exists(filepath.Join(projectDir, ConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.Errorf("%s is not an Appsody project", projectDir)
+	}
+	projectConfig := *config
+	projectConfig.ProjectDir = projectDir
+	projectConfig.ProjectConfig = nil
+	projectConfig.imagePulled = nil
+	projectConfig.CachedEnvVars = nil
+	return &projectConfig, nil
+}
+
+// getWorkspace finds the workspace for the --all flag, starting from the project directory
+func getWorkspace(config *RootCommandConfig) (*Workspace, error) {
+	dir := config.ProjectDir
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+	workspace, err := FindWorkspace(dir)
+	if err != nil {
+		return nil, err
+	}
+	config.Info.logf("Using workspace %s with %d projects", filepath.Join(workspace.Dir, WorkspaceFile), len(workspace.Projects))
+	return workspace, nil
+}
+
+// workspaceImageTag returns the tag for a project when building with --all, where --tag is the image repository
+func workspaceImageTag(config *RootCommandConfig, repository string) (string, error) {
+	if repository == "" {
+		return "", nil
+	}
+	projectName, err := getProjectName(config)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(repository, "/") + "/" + projectName, nil
+}
@@ -0,0 +1,179 @@
+// Copyright © 2019 IBM Corporation and others.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cmd "github.com/appsody/appsody/cmd"
+	"github.com/pkg/errors"
+)
+
+func writeWorkspace(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "appsody-workspace-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, cmd.WorkspaceFile), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func projectNames(projects []cmd.WorkspaceProject) []string {
+	var names []string
+	for _, project := range projects {
+		names = append(names, project.Name)
+	}
+	return names
+}
+
+func TestFindWorkspace(t *testing.T) {
+	dir := writeWorkspace(t, `projects:
+- path: frontend
+  depends-on: [api]
+- name: api
+  path: services/api
+  depends-on: [db]
+- path: db
+- path: docs
+`)
+	defer os.RemoveAll(dir)
+	subDir := filepath.Join(dir, "services", "api")
+	err := os.MkdirAll(subDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspace, err := cmd.FindWorkspace(subDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(projectNames(workspace.Projects), []string{"frontend", "api", "db", "docs"}) {
+		t.Errorf("Unexpected projects %v", projectNames(workspace.Projects))
+	}
+	if projectDir := workspace.ProjectDir(workspace.Projects[1]); projectDir != subDir {
+		t.Errorf("Expected project directory %s but got %s", subDir, projectDir)
+	}
+	order, err := workspace.DeployOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(projectNames(order), []string{"db", "api", "frontend", "docs"}) {
+		t.Errorf("Expected the deploy order [db api frontend docs] but got %v", projectNames(order))
+	}
+}
+
+func TestReadWorkspaceErrors(t *testing.T) {
+	var workspaceErrorTests = []struct {
+		testName string
+		content  string
+		expected string
+	}{
+		{"No projects", "projects: []\n", "does not list any projects"},
+		{"No path", "projects:\n- name: a\n", "does not have a path"},
+		{"Duplicate", "projects:\n- path: a\n- path: other/a\n", "more than once"},
+		{"Unknown dependency", "projects:\n- path: a\n  depends-on: [b]\n", "which is not in workspace file"},
+		{"Cycle", "projects:\n- path: a\n  depends-on: [b]\n- path: b\n  depends-on: [a]\n- path: c\n", "projects a, b in the workspace form a cycle"},
+		{"Unknown field", "projects:\n- path: a\n  dependson: [b]\n", "Failed to parse"},
+	}
+	for _, testData := range workspaceErrorTests {
+		tt := testData
+		t.Run(tt.testName, func(t *testing.T) {
+			dir := writeWorkspace(t, tt.content)
+			defer os.RemoveAll(dir)
+			_, err := cmd.ReadWorkspace(filepath.Join(dir, cmd.WorkspaceFile))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRunWorkspaceParallel(t *testing.T) {
+	projects := []cmd.WorkspaceProject{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	results := cmd.RunWorkspaceParallel(projects, 2, func(project cmd.WorkspaceProject) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		if project.Name == "c" {
+			return errors.New("build failed")
+		}
+		return nil
+	})
+	if maxRunning > 2 {
+		t.Errorf("Expected at most 2 projects at the same time but there were %d", maxRunning)
+	}
+	for i, result := range results {
+		expected := "succeeded"
+		if result.Project.Name == "c" {
+			expected = "failed"
+		}
+		if result.Project.Name != projects[i].Name || result.Status != expected {
+			t.Errorf("Expected project %s to have %s but got %s %s", projects[i].Name, expected, result.Project.Name, result.Status)
+		}
+	}
+	summary, err := cmd.FormatWorkspaceSummary(results)
+	if err == nil || !strings.Contains(err.Error(), "1 of 5 projects") {
+		t.Errorf("Expected an error for the failed project, got %v", err)
+	}
+	if !strings.Contains(summary, "build failed") {
+		t.Errorf("Expected the summary to contain the error:\n%s", summary)
+	}
+}
+
+func TestRunWorkspaceOrdered(t *testing.T) {
+	projects := []cmd.WorkspaceProject{
+		{Name: "db"},
+		{Name: "api", DependsOn: []string{"db"}},
+		{Name: "frontend", DependsOn: []string{"api"}},
+		{Name: "docs"},
+	}
+	var ran []string
+	results := cmd.RunWorkspaceOrdered(projects, func(project cmd.WorkspaceProject) error {
+		ran = append(ran, project.Name)
+		if project.Name == "db" {
+			return errors.New("deploy failed")
+		}
+		return nil
+	})
+	if !reflect.DeepEqual(ran, []string{"db", "docs"}) {
+		t.Errorf("Expected only db and docs to run but got %v", ran)
+	}
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	if !reflect.DeepEqual(statuses, []string{"failed", "skipped", "skipped", "succeeded"}) {
+		t.Errorf("Unexpected statuses %v", statuses)
+	}
+}
@@ -14,13 +14,14 @@
 package functest
 
 import (
-	"bytes"
 	"strings"
 	"testing"
 
 	cmd "github.com/appsody/appsody/cmd"
 	cmdtest "github.com/appsody/appsody/cmd/cmdtest"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestOperatorInstallCases(t *testing.T) {
@@ -73,19 +74,20 @@ func TestOperatorInstallAndUninstall(t *testing.T) {
 			sandbox, cleanup := cmdtest.TestSetupWithSandbox(t, true)
 			defer cleanup()
 
-			var outBuffer bytes.Buffer
-			log := &cmd.LoggingConfig{}
-			log.InitLogging(&outBuffer, &outBuffer)
+			client, err := cmd.NewKubeClient("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
 
 			defer func() {
-				err := removeNamespace(log, tt.namespace)
+				err := removeNamespace(client, tt.namespace)
 				if err != nil {
 					t.Fatal(err)
 				}
 			}()
 
-			namespaceKargs := []string{"create", "namespace", tt.namespace}
-			_, namespaceErr := cmd.RunKube(log, namespaceKargs, false)
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: tt.namespace}}
+			_, namespaceErr := client.Clientset.CoreV1().Namespaces().Create(namespace)
 			if namespaceErr != nil {
 				t.Fatal(namespaceErr)
 			}
@@ -101,9 +103,8 @@ func TestOperatorInstallAndUninstall(t *testing.T) {
 	}
 }
 
-func removeNamespace(log *cmd.LoggingConfig, namespace string) error {
-	kargs := []string{"delete", "namespace", namespace}
-	_, namespaceErr := cmd.RunKube(log, kargs, false)
+func removeNamespace(client *cmd.KubeClient, namespace string) error {
+	namespaceErr := client.Clientset.CoreV1().Namespaces().Delete(namespace, &metav1.DeleteOptions{})
 
 	if namespaceErr != nil {
 		return errors.Errorf("Error removing namespace created for test: %s", namespaceErr)